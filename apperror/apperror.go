@@ -0,0 +1,89 @@
+package apperror
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrValidation   = errors.New("validation failed")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	// ErrTooManyRequests reports an action the caller has to wait before
+	// repeating.
+	ErrTooManyRequests = errors.New("too many requests")
+	// ErrPreconditionFailed reports a conditional write against an object
+	// that has changed since the client last read it.
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+// Error carries one of the sentinel kinds above together with an optional
+// message and cause, so callers can branch with errors.Is while the original
+// error text is preserved.
+type Error struct {
+	kind       error
+	msg        string
+	err        error
+	current    interface{}
+	retryAfter time.Duration
+}
+
+func New(kind error, msg string) error {
+	return &Error{kind: kind, msg: msg}
+}
+
+func Wrap(kind error, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{kind: kind, err: err}
+}
+
+// Stale returns an ErrPreconditionFailed error carrying the current server
+// copy of the object so the client can reconcile its changes.
+func Stale(msg string, current interface{}) error {
+	return &Error{kind: ErrPreconditionFailed, msg: msg, current: current}
+}
+
+// Current returns the server copy attached by Stale, if any.
+func Current(err error) (interface{}, bool) {
+	var e *Error
+	if errors.As(err, &e) && e.current != nil {
+		return e.current, true
+	}
+	return nil, false
+}
+
+// Throttled returns an ErrTooManyRequests error telling the client how long
+// to wait.
+func Throttled(msg string, retryAfter time.Duration) error {
+	return &Error{kind: ErrTooManyRequests, msg: msg, retryAfter: retryAfter}
+}
+
+// RetryAfter returns the wait attached by Throttled, if any.
+func RetryAfter(err error) (time.Duration, bool) {
+	var e *Error
+	if errors.As(err, &e) && e.retryAfter > 0 {
+		return e.retryAfter, true
+	}
+	return 0, false
+}
+
+func (e *Error) Error() string {
+	if e.msg != "" {
+		return e.msg
+	}
+	if e.err != nil {
+		return e.err.Error()
+	}
+	return e.kind.Error()
+}
+
+func (e *Error) Unwrap() []error {
+	if e.err == nil {
+		return []error{e.kind}
+	}
+	return []error{e.kind, e.err}
+}
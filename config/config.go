@@ -0,0 +1,436 @@
+package config
+
+import (
+	"echo-rest-api/logging"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	EnvDev        = "dev"
+	EnvTest       = "test"
+	EnvProduction = "production"
+)
+
+// Config holds every setting of the API. A field tagged env is read from
+// that environment variable, or from the file named by the variable with a
+// _FILE suffix; a struct field's env tag is a prefix for its own fields.
+// Fields tagged secret are hidden by Redacted.
+type Config struct {
+	Env               string            `yaml:"env" env:"GO_ENV"`
+	Port              string            `yaml:"port" env:"PORT"`
+	ShutdownTimeout   time.Duration     `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	DrainDelay        time.Duration     `yaml:"shutdown_drain_delay" env:"SHUTDOWN_DRAIN_DELAY"`
+	RequestTimeout    time.Duration     `yaml:"request_timeout" env:"REQUEST_TIMEOUT"`
+	HealthTimeout     time.Duration     `yaml:"health_check_timeout" env:"HEALTH_CHECK_TIMEOUT"`
+	LogLevel          string            `yaml:"log_level" env:"LOG_LEVEL"`
+	APIDomain         string            `yaml:"api_domain" env:"API_DOMAIN"`
+	FEURL             string            `yaml:"fe_url" env:"FE_URL"`
+	Secret            string            `yaml:"secret" env:"SECRET" secret:"true"`
+	JWTKeysDir        string            `yaml:"jwt_keys_dir" env:"JWT_KEYS_DIR"`
+	AccessTokenTTL    time.Duration     `yaml:"access_token_ttl" env:"ACCESS_TOKEN_TTL"`
+	RefreshTokenTTL   time.Duration     `yaml:"refresh_token_ttl" env:"REFRESH_TOKEN_TTL"`
+	Postgres          Postgres          `yaml:"postgres" env:"POSTGRES_"`
+	Notebook          Notebook          `yaml:"notebook" env:"NOTEBOOK_"`
+	Trash             Trash             `yaml:"trash" env:"TRASH_"`
+	Account           Account           `yaml:"account" env:"ACCOUNT_"`
+	Memo              Memo              `yaml:"memo" env:"MEMO_"`
+	Mail              Mail              `yaml:"mail" env:""`
+	PasswordReset     PasswordReset     `yaml:"password_reset" env:"PASSWORD_RESET_"`
+	EmailVerification EmailVerification `yaml:"email_verification" env:""`
+	TOTPIssuer        string            `yaml:"totp_issuer" env:"TOTP_ISSUER"`
+	Login             Login             `yaml:"login" env:"LOGIN_"`
+	OIDC              OIDC              `yaml:"oidc" env:"OIDC_"`
+	// TrustedProxies are the CIDRs of the proxies whose X-Forwarded-For
+	// header gives the client address. Without any, the address is that of
+	// the connection, so that a client cannot pick one to dodge a lockout.
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+}
+
+type Postgres struct {
+	User     string `yaml:"user" env:"USER"`
+	Password string `yaml:"password" env:"PW" secret:"true"`
+	Host     string `yaml:"host" env:"HOST"`
+	Port     string `yaml:"port" env:"PORT"`
+	DB       string `yaml:"db" env:"DB"`
+	// SlowQueryThreshold is how long a query may take before it is logged
+	// as a warning; zero disables the warning.
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold" env:"SLOW_QUERY_THRESHOLD"`
+}
+
+type Notebook struct {
+	MaxDepth int `yaml:"max_depth" env:"MAX_DEPTH"`
+}
+
+type Trash struct {
+	Retention     time.Duration `yaml:"retention" env:"RETENTION"`
+	SweepInterval time.Duration `yaml:"sweep_interval" env:"SWEEP_INTERVAL"`
+}
+
+type Account struct {
+	DeletionGrace time.Duration `yaml:"deletion_grace" env:"DELETION_GRACE"`
+	SweepInterval time.Duration `yaml:"sweep_interval" env:"SWEEP_INTERVAL"`
+}
+
+type Memo struct {
+	MaxRevisions   int  `yaml:"max_revisions" env:"MAX_REVISIONS"`
+	RequireIfMatch bool `yaml:"require_if_match" env:"REQUIRE_IF_MATCH"`
+}
+
+type Mail struct {
+	From string `yaml:"from" env:"MAIL_FROM"`
+	Dir  string `yaml:"dir" env:"MAIL_DIR"`
+	SMTP SMTP   `yaml:"smtp" env:"SMTP_"`
+}
+
+type SMTP struct {
+	Host     string `yaml:"host" env:"HOST"`
+	Port     string `yaml:"port" env:"PORT"`
+	Username string `yaml:"username" env:"USERNAME"`
+	Password string `yaml:"password" env:"PASSWORD" secret:"true"`
+}
+
+type PasswordReset struct {
+	URL string        `yaml:"url" env:"URL"`
+	TTL time.Duration `yaml:"ttl" env:"TTL"`
+}
+
+type EmailVerification struct {
+	URL            string        `yaml:"url" env:"EMAIL_VERIFICATION_URL"`
+	TTL            time.Duration `yaml:"ttl" env:"EMAIL_VERIFICATION_TTL"`
+	ResendInterval time.Duration `yaml:"resend_interval" env:"EMAIL_VERIFICATION_RESEND_INTERVAL"`
+	Policy         string        `yaml:"unverified_policy" env:"UNVERIFIED_EMAIL_POLICY"`
+	MemoLimit      int           `yaml:"unverified_memo_limit" env:"UNVERIFIED_MEMO_LIMIT"`
+}
+
+type Login struct {
+	AttemptStore   string        `yaml:"attempt_store" env:"ATTEMPT_STORE"`
+	EmailThreshold int           `yaml:"email_threshold" env:"EMAIL_THRESHOLD"`
+	IPThreshold    int           `yaml:"ip_threshold" env:"IP_THRESHOLD"`
+	Lockout        time.Duration `yaml:"lockout" env:"LOCKOUT"`
+	MaxLockout     time.Duration `yaml:"max_lockout" env:"MAX_LOCKOUT"`
+	AttemptWindow  time.Duration `yaml:"attempt_window" env:"ATTEMPT_WINDOW"`
+	// MFAChallengeAttempts is the number of wrong codes an MFA challenge
+	// takes before the login has to start over.
+	MFAChallengeAttempts int `yaml:"mfa_challenge_attempts" env:"MFA_CHALLENGE_ATTEMPTS"`
+}
+
+// OIDC lists its providers by name. The environment adds the ones named in
+// OIDC_PROVIDERS and sets each field of e.g. "google" from
+// OIDC_GOOGLE_ISSUER, OIDC_GOOGLE_CLIENT_ID and so on.
+type OIDC struct {
+	Providers  map[string]OIDCProvider `yaml:"providers"`
+	SuccessURL string                  `yaml:"success_url" env:"SUCCESS_URL"`
+}
+
+type OIDCProvider struct {
+	Issuer       string   `yaml:"issuer" env:"ISSUER"`
+	ClientID     string   `yaml:"client_id" env:"CLIENT_ID"`
+	ClientSecret string   `yaml:"client_secret" env:"CLIENT_SECRET" secret:"true"`
+	RedirectURL  string   `yaml:"redirect_url" env:"REDIRECT_URL"`
+	Scopes       []string `yaml:"scopes" env:"SCOPES"`
+}
+
+func defaults() Config {
+	return Config{
+		Port:            "8080",
+		ShutdownTimeout: 10 * time.Second,
+		DrainDelay:      5 * time.Second,
+		RequestTimeout:  30 * time.Second,
+		LogLevel:        "info",
+		Postgres:        Postgres{SlowQueryThreshold: 200 * time.Millisecond},
+	}
+}
+
+// Load builds the config from, in increasing precedence, the defaults, the
+// YAML file named by CONFIG_FILE and the environment. In the dev environment
+// .env is loaded into the environment first.
+func Load() (Config, error) {
+	if os.Getenv("GO_ENV") == EnvDev {
+		if err := godotenv.Load(); err != nil {
+			return Config{}, fmt.Errorf("config: %w", err)
+		}
+	}
+	cfg := defaults()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := loadFile(&cfg, path); err != nil {
+			return Config{}, err
+		}
+	}
+	if err := loadEnv(reflect.ValueOf(&cfg).Elem(), ""); err != nil {
+		return Config{}, err
+	}
+	if err := loadOIDCEnv(&cfg.OIDC); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+	default:
+		return fmt.Errorf("config: %s: only YAML files are supported", path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	defer f.Close()
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// loadEnv sets the fields of the struct v from the variables named by their
+// env tags after prefix.
+func loadEnv(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct {
+			if err := loadEnv(v.Field(i), prefix+tag); err != nil {
+				return err
+			}
+			continue
+		}
+		name := prefix + tag
+		value, ok, err := lookup(name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := set(v.Field(i), value); err != nil {
+			return fmt.Errorf("config: %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func loadOIDCEnv(o *OIDC) error {
+	names := map[string]bool{}
+	for name := range o.Providers {
+		names[name] = true
+	}
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names[name] = true
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	if o.Providers == nil {
+		o.Providers = map[string]OIDCProvider{}
+	}
+	for name := range names {
+		provider := o.Providers[name]
+		if err := loadEnv(reflect.ValueOf(&provider).Elem(), "OIDC_"+strings.ToUpper(name)+"_"); err != nil {
+			return err
+		}
+		o.Providers[name] = provider
+	}
+	return nil
+}
+
+// lookup reads name from the environment, or from the file that name_FILE
+// points to, which is how container platforms usually hand out secrets.
+func lookup(name string) (string, bool, error) {
+	value, ok := os.LookupEnv(name)
+	path, fromFile := os.LookupEnv(name + "_FILE")
+	if !fromFile {
+		return value, ok, nil
+	}
+	if ok {
+		return "", false, fmt.Errorf("config: both %s and %s_FILE are set", name, name)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("config: %s_FILE: %w", name, err)
+	}
+	return strings.TrimRight(string(content), "\r\n"), true, nil
+}
+
+func set(v reflect.Value, value string) error {
+	if v.Type() == durationType {
+		if value == "" {
+			v.SetInt(0)
+			return nil
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int:
+		if value == "" {
+			v.SetInt(0)
+			return nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case reflect.Bool:
+		if value == "" {
+			v.SetBool(false)
+			return nil
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		v.Set(reflect.ValueOf(strings.Fields(value)))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// IsProduction reports whether cookies must be Secure and cross-site.
+func (c Config) IsProduction() bool {
+	return c.Env == EnvProduction
+}
+
+// Validate reports every setting the API cannot start with.
+func (c Config) Validate() error {
+	errs := []error{}
+	switch c.Env {
+	case "", EnvDev, EnvTest, EnvProduction:
+	default:
+		errs = append(errs, fmt.Errorf("GO_ENV must be one of %s, %s or %s", EnvDev, EnvTest, EnvProduction))
+	}
+	if c.Secret == "" {
+		errs = append(errs, errors.New("SECRET is required"))
+	}
+	if _, err := strconv.ParseUint(c.Port, 10, 16); err != nil {
+		errs = append(errs, errors.New("PORT must be a port number"))
+	}
+	if _, err := logging.ParseLevel(c.LogLevel); err != nil {
+		errs = append(errs, errors.New("LOG_LEVEL must be one of debug, info, warn or error"))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("SHUTDOWN_TIMEOUT must be positive"))
+	}
+	if c.DrainDelay < 0 {
+		errs = append(errs, errors.New("SHUTDOWN_DRAIN_DELAY must not be negative"))
+	}
+	if c.RequestTimeout < 0 {
+		errs = append(errs, errors.New("REQUEST_TIMEOUT must not be negative"))
+	}
+	for _, cidr := range c.TrustedProxies {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			errs = append(errs, fmt.Errorf("TRUSTED_PROXIES: %s is not a CIDR", cidr))
+		}
+	}
+	if err := c.ValidateDB(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Mail.SMTP.Host != "" && c.Mail.SMTP.Port == "" {
+		errs = append(errs, errors.New("SMTP_PORT is required with SMTP_HOST"))
+	}
+	switch c.EmailVerification.Policy {
+	case "", "off", "limit", "block":
+	default:
+		errs = append(errs, errors.New("UNVERIFIED_EMAIL_POLICY must be one of off, limit or block"))
+	}
+	switch c.Login.AttemptStore {
+	case "", "memory", "db":
+	default:
+		errs = append(errs, errors.New("LOGIN_ATTEMPT_STORE must be memory or db"))
+	}
+	names := make([]string, 0, len(c.OIDC.Providers))
+	for name := range c.OIDC.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p := c.OIDC.Providers[name]
+		if p.Issuer == "" || p.ClientID == "" || p.RedirectURL == "" {
+			errs = append(errs, fmt.Errorf("OIDC provider %s needs an issuer, a client id and a redirect url", name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateDB checks only what connecting to the database needs, for
+// commands such as migrate that do not serve the API.
+func (c Config) ValidateDB() error {
+	if c.Env == EnvTest {
+		return nil
+	}
+	if c.Postgres.User == "" || c.Postgres.Host == "" || c.Postgres.Port == "" || c.Postgres.DB == "" {
+		return errors.New("POSTGRES_USER, POSTGRES_HOST, POSTGRES_PORT and POSTGRES_DB are required")
+	}
+	return nil
+}
+
+// Redacted lists the effective settings as environment variables, one per
+// line, with secrets masked so that the output can be logged.
+func (c Config) Redacted() string {
+	var b strings.Builder
+	writeRedacted(&b, reflect.ValueOf(c), "")
+	names := make([]string, 0, len(c.OIDC.Providers))
+	for name := range c.OIDC.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(&b, "OIDC_PROVIDERS=%s\n", strings.Join(names, ","))
+	for _, name := range names {
+		writeRedacted(&b, reflect.ValueOf(c.OIDC.Providers[name]), "OIDC_"+strings.ToUpper(name)+"_")
+	}
+	return b.String()
+}
+
+func writeRedacted(b *strings.Builder, v reflect.Value, prefix string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct {
+			writeRedacted(b, v.Field(i), prefix+tag)
+			continue
+		}
+		value := fmt.Sprint(v.Field(i).Interface())
+		switch {
+		case field.Type.Kind() == reflect.Slice:
+			value = strings.Join(v.Field(i).Interface().([]string), " ")
+		case field.Tag.Get("secret") == "true" && value != "":
+			value = "[redacted]"
+		}
+		fmt.Fprintf(b, "%s%s=%s\n", prefix, tag, value)
+	}
+}
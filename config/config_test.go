@@ -0,0 +1,155 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Env(t *testing.T) {
+	t.Setenv("GO_ENV", EnvProduction)
+	t.Setenv("SECRET", "s3cret")
+	t.Setenv("POSTGRES_USER", "memo")
+	t.Setenv("ACCESS_TOKEN_TTL", "15m")
+	t.Setenv("MEMO_REQUIRE_IF_MATCH", "true")
+	t.Setenv("SMTP_HOST", "smtp.example.com")
+	t.Setenv("UNVERIFIED_MEMO_LIMIT", "3")
+	t.Setenv("OIDC_PROVIDERS", "google")
+	t.Setenv("OIDC_GOOGLE_CLIENT_ID", "client")
+	t.Setenv("OIDC_GOOGLE_SCOPES", "openid email")
+	cfg, err := Load()
+	assert.Nil(t, err)
+	assert.True(t, cfg.IsProduction())
+	assert.Equal(t, "8080", cfg.Port)
+	assert.Equal(t, 10*time.Second, cfg.ShutdownTimeout)
+	assert.Equal(t, 5*time.Second, cfg.DrainDelay)
+	assert.Equal(t, 30*time.Second, cfg.RequestTimeout)
+	assert.Equal(t, "s3cret", cfg.Secret)
+	assert.Equal(t, "memo", cfg.Postgres.User)
+	assert.Equal(t, 15*time.Minute, cfg.AccessTokenTTL)
+	assert.True(t, cfg.Memo.RequireIfMatch)
+	assert.Equal(t, "smtp.example.com", cfg.Mail.SMTP.Host)
+	assert.Equal(t, 3, cfg.EmailVerification.MemoLimit)
+	assert.Equal(t, "client", cfg.OIDC.Providers["google"].ClientID)
+	assert.Equal(t, []string{"openid", "email"}, cfg.OIDC.Providers["google"].Scopes)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	t.Setenv("ACCESS_TOKEN_TTL", "soon")
+	_, err := Load()
+	assert.ErrorContains(t, err, "ACCESS_TOKEN_TTL")
+}
+
+func TestLoad_File(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+port: "9000"
+secret: from-file
+postgres:
+  host: db
+login:
+  lockout: 30s
+oidc:
+  providers:
+    google:
+      issuer: https://accounts.google.com
+      client_id: file-client
+`)
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("POSTGRES_HOST", "db.internal")
+	t.Setenv("OIDC_GOOGLE_CLIENT_ID", "env-client")
+	cfg, err := Load()
+	assert.Nil(t, err)
+	assert.Equal(t, "9000", cfg.Port)
+	assert.Equal(t, "from-file", cfg.Secret)
+	assert.Equal(t, "db.internal", cfg.Postgres.Host)
+	assert.Equal(t, 30*time.Second, cfg.Login.Lockout)
+	assert.Equal(t, "https://accounts.google.com", cfg.OIDC.Providers["google"].Issuer)
+	assert.Equal(t, "env-client", cfg.OIDC.Providers["google"].ClientID)
+
+	t.Setenv("CONFIG_FILE", writeFile(t, "config.yaml", "prot: \"9000\"\n"))
+	_, err = Load()
+	assert.ErrorContains(t, err, "field prot not found")
+	t.Setenv("CONFIG_FILE", writeFile(t, "config.toml", "port = \"9000\"\n"))
+	_, err = Load()
+	assert.ErrorContains(t, err, "only YAML")
+}
+
+func TestLoad_SecretFile(t *testing.T) {
+	t.Setenv("POSTGRES_PW_FILE", writeFile(t, "pw", "hunter2\n"))
+	cfg, err := Load()
+	assert.Nil(t, err)
+	assert.Equal(t, "hunter2", cfg.Postgres.Password)
+
+	t.Setenv("POSTGRES_PW", "hunter3")
+	_, err = Load()
+	assert.ErrorContains(t, err, "both POSTGRES_PW and POSTGRES_PW_FILE are set")
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Config{
+		Env:             EnvDev,
+		Port:            "8080",
+		ShutdownTimeout: time.Second,
+		LogLevel:        "debug",
+		Secret:          "s3cret",
+		Postgres:        Postgres{User: "memo", Host: "localhost", Port: "5432", DB: "memo"},
+	}
+	assert.Nil(t, cfg.Validate())
+
+	invalid := cfg
+	invalid.Secret = ""
+	invalid.Port = "http"
+	invalid.LogLevel = "verbose"
+	invalid.RequestTimeout = -time.Second
+	invalid.DrainDelay = -time.Second
+	invalid.Postgres.Host = ""
+	invalid.Login.AttemptStore = "redis"
+	invalid.TrustedProxies = []string{"10.0.0.1"}
+	invalid.OIDC.Providers = map[string]OIDCProvider{"google": {ClientID: "client"}}
+	err := invalid.Validate()
+	assert.ErrorContains(t, err, "SECRET is required")
+	assert.ErrorContains(t, err, "PORT must be")
+	assert.ErrorContains(t, err, "LOG_LEVEL")
+	assert.ErrorContains(t, err, "REQUEST_TIMEOUT")
+	assert.ErrorContains(t, err, "SHUTDOWN_DRAIN_DELAY")
+	assert.ErrorContains(t, err, "POSTGRES_HOST")
+	assert.ErrorContains(t, err, "LOGIN_ATTEMPT_STORE")
+	assert.ErrorContains(t, err, "TRUSTED_PROXIES")
+	assert.ErrorContains(t, err, "OIDC provider google")
+
+	assert.Nil(t, Config{Env: EnvTest}.ValidateDB())
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Config{
+		Port:     "8080",
+		Secret:   "s3cret",
+		Postgres: Postgres{User: "memo", Password: "hunter2"},
+		Mail:     Mail{SMTP: SMTP{Host: "smtp.example.com"}},
+		OIDC: OIDC{Providers: map[string]OIDCProvider{
+			"google": {ClientID: "client", ClientSecret: "shh", Scopes: []string{"openid", "email"}},
+		}},
+	}
+	redacted := cfg.Redacted()
+	assert.Contains(t, redacted, "PORT=8080\n")
+	assert.Contains(t, redacted, "SECRET=[redacted]\n")
+	assert.Contains(t, redacted, "POSTGRES_USER=memo\n")
+	assert.Contains(t, redacted, "POSTGRES_PW=[redacted]\n")
+	assert.Contains(t, redacted, "SMTP_HOST=smtp.example.com\n")
+	assert.Contains(t, redacted, "SMTP_PASSWORD=\n")
+	assert.Contains(t, redacted, "OIDC_PROVIDERS=google\n")
+	assert.Contains(t, redacted, "OIDC_GOOGLE_CLIENT_SECRET=[redacted]\n")
+	assert.Contains(t, redacted, "OIDC_GOOGLE_SCOPES=openid email\n")
+	assert.NotContains(t, redacted, "s3cret")
+	assert.NotContains(t, redacted, "hunter2")
+	assert.NotContains(t, redacted, "shh")
+}
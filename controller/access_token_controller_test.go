@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateAccessToken(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/tokens", strings.NewReader(`{"name":"ci","scopes":["read"]}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mockUsecase := newMockAccessTokenUsecase()
+	mockUsecase.(*mockAccessTokenUsecase).
+		On("CreateAccessToken", model.AccessTokenRequest{Name: "ci", Scopes: []string{"read"}}, uint(1)).
+		Return(model.AccessTokenCreatedResponse{
+			AccessTokenResponse: model.AccessTokenResponse{ID: 1, Name: "ci", Scopes: []string{"read"}, CreatedAt: createdAt},
+			Token:               "emp_secret",
+		}, nil)
+	controller := NewAccessTokenController(mockUsecase)
+
+	handle(mockContext, controller.CreateAccessToken)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.JSONEq(t, `{"id":1,"name":"ci","scopes":["read"],"expires_at":null,"last_used_at":null,"created_at":"2024-01-01T00:00:00Z","token":"emp_secret"}`, rec.Body.String())
+	mockUsecase.(*mockAccessTokenUsecase).AssertExpectations(t)
+}
+
+func TestRevokeAccessToken(t *testing.T) {
+	req := httptest.NewRequest(http.MethodDelete, "/tokens/2", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockContext.SetPath("/tokens/:tokenId")
+	mockContext.SetParamNames("tokenId")
+	mockContext.SetParamValues("2")
+	mockUsecase := newMockAccessTokenUsecase()
+	mockUsecase.(*mockAccessTokenUsecase).
+		On("RevokeAccessToken", uint(1), uint(2)).
+		Return(nil)
+	controller := NewAccessTokenController(mockUsecase)
+
+	handle(mockContext, controller.RevokeAccessToken)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	mockUsecase.(*mockAccessTokenUsecase).AssertExpectations(t)
+}
+
+func TestAuthenticate(t *testing.T) {
+	cases := []struct {
+		method string
+		scope  string
+	}{
+		{http.MethodGet, model.ScopeRead},
+		{http.MethodPost, model.ScopeWrite},
+		{http.MethodDelete, model.ScopeWrite},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, "/memos", nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer emp_secret")
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		mockUsecase := newMockAccessTokenUsecase()
+		mockUsecase.(*mockAccessTokenUsecase).
+			On("Authenticate", "emp_secret", tc.scope).
+			Return(model.AccessToken{ID: 3, UserId: 7, Scopes: "read write"}, nil)
+		controller := NewAccessTokenController(mockUsecase)
+		var userId interface{}
+
+		handle(c, controller.Authenticate(func(c echo.Context) error {
+			userId = c.Get("user").(*jwt.Token).Claims.(jwt.MapClaims)["user_id"]
+			assert.True(t, AuthenticatedByAccessToken(c))
+			return c.NoContent(http.StatusOK)
+		}))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, float64(7), userId)
+		mockUsecase.(*mockAccessTokenUsecase).AssertExpectations(t)
+	}
+}
+
+func TestAuthenticate_Rejected(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/memos/1", nil)
+	req.Header.Set(echo.HeaderAuthorization, "bearer emp_readonly")
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.Echo().HTTPErrorHandler = HTTPErrorHandler
+	mockUsecase := newMockAccessTokenUsecase()
+	mockUsecase.(*mockAccessTokenUsecase).
+		On("Authenticate", "emp_readonly", model.ScopeWrite).
+		Return(nil, apperror.New(apperror.ErrForbidden, "access token lacks the write scope"))
+	controller := NewAccessTokenController(mockUsecase)
+	called := false
+
+	handle(c, controller.Authenticate(func(c echo.Context) error {
+		called = true
+		return nil
+	}))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.False(t, called)
+}
+
+func TestAuthenticate_NoBearer(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/memos", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Basic dXNlcjpwYXNz")
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	mockUsecase := newMockAccessTokenUsecase()
+	controller := NewAccessTokenController(mockUsecase)
+	called := false
+
+	handle(c, controller.Authenticate(func(c echo.Context) error {
+		called = true
+		assert.False(t, AuthenticatedByAccessToken(c))
+		return nil
+	}))
+	assert.True(t, called)
+	assert.False(t, HasBearerToken(c))
+	mockUsecase.(*mockAccessTokenUsecase).AssertNotCalled(t, "Authenticate")
+}
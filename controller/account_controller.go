@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"echo-rest-api/model"
+	"echo-rest-api/usecase"
+	"net/http"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+)
+
+type IAccountController interface {
+	ChangePassword(c echo.Context) error
+	ChangeEmail(c echo.Context) error
+	DeleteAccount(c echo.Context) error
+	CancelDeletion(c echo.Context) error
+}
+
+type accountController struct {
+	au usecase.IAccountUsecase
+	vu usecase.IEmailVerificationUsecase
+}
+
+func NewAccountController(au usecase.IAccountUsecase, vu usecase.IEmailVerificationUsecase) IAccountController {
+	return &accountController{au, vu}
+}
+
+func (ac *accountController) ChangePassword(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+	sessionId, _ := claims["jti"].(string)
+	req := model.PasswordChangeRequest{}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := ac.au.ChangePassword(c.Request().Context(), uint(userId.(float64)), sessionId, req.CurrentPassword, req.NewPassword); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ChangeEmail answers 202 as the new address only takes effect once the link
+// mailed to it is opened.
+func (ac *accountController) ChangeEmail(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+	req := model.EmailChangeRequest{}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := ac.au.ChangeEmail(c.Request().Context(), uint(userId.(float64)), req.Password, req.Email); err != nil {
+		return err
+	}
+	if err := ac.vu.SendVerification(c.Request().Context(), uint(userId.(float64))); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusAccepted)
+}
+
+func (ac *accountController) DeleteAccount(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+	req := model.PasswordConfirmRequest{}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	userRes, err := ac.au.DeleteAccount(c.Request().Context(), uint(userId.(float64)), req.Password)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusAccepted, userRes)
+}
+
+func (ac *accountController) CancelDeletion(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+
+	userRes, err := ac.au.CancelDeletion(c.Request().Context(), uint(userId.(float64)))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, userRes)
+}
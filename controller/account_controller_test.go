@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangePassword(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/account/password", strings.NewReader(`{"current_password":"old password","new_password":"new password"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockUsecase := newMockAccountUsecase()
+	mockUsecase.(*mockAccountUsecase).
+		On("ChangePassword", uint(1), "session1", "old password", "new password").
+		Return(nil)
+	controller := NewAccountController(mockUsecase, newMockEmailVerificationUsecase())
+
+	handle(mockContext, controller.ChangePassword)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	mockUsecase.(*mockAccountUsecase).AssertExpectations(t)
+}
+
+func TestChangeEmail(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/account/email", strings.NewReader(`{"email":"new@example.com","password":"password"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockUsecase := newMockAccountUsecase()
+	mockUsecase.(*mockAccountUsecase).
+		On("ChangeEmail", uint(1), "password", "new@example.com").
+		Return(nil)
+	verificationUsecase := newMockEmailVerificationUsecase()
+	verificationUsecase.(*mockEmailVerificationUsecase).On("SendVerification", uint(1)).Return(nil)
+	controller := NewAccountController(mockUsecase, verificationUsecase)
+
+	handle(mockContext, controller.ChangeEmail)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	verificationUsecase.(*mockEmailVerificationUsecase).AssertExpectations(t)
+}
+
+func TestChangeEmail_Conflict(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/account/email", strings.NewReader(`{"email":"taken@example.com","password":"password"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockUsecase := newMockAccountUsecase()
+	mockUsecase.(*mockAccountUsecase).
+		On("ChangeEmail", uint(1), "password", "taken@example.com").
+		Return(apperror.New(apperror.ErrConflict, "email already registered"))
+	verificationUsecase := newMockEmailVerificationUsecase()
+	controller := NewAccountController(mockUsecase, verificationUsecase)
+
+	handle(mockContext, controller.ChangeEmail)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	verificationUsecase.(*mockEmailVerificationUsecase).AssertNotCalled(t, "SendVerification")
+}
+
+func TestDeleteAccount(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/account/delete", strings.NewReader(`{"password":"password"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	at := time.Date(2024, 2, 7, 0, 0, 0, 0, time.UTC)
+	mockUsecase := newMockAccountUsecase()
+	mockUsecase.(*mockAccountUsecase).
+		On("DeleteAccount", uint(1), "password").
+		Return(model.UserResponse{ID: 1, Email: "user@example.com", DeletionScheduledAt: &at}, nil)
+	controller := NewAccountController(mockUsecase, newMockEmailVerificationUsecase())
+
+	handle(mockContext, controller.DeleteAccount)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.JSONEq(t, `{"id":1,"email":"user@example.com","email_verified":false,"deletion_scheduled_at":"2024-02-07T00:00:00Z"}`, rec.Body.String())
+}
+
+func TestCancelDeletion(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/account/delete/cancel", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockUsecase := newMockAccountUsecase()
+	mockUsecase.(*mockAccountUsecase).
+		On("CancelDeletion", uint(1)).
+		Return(nil, apperror.New(apperror.ErrConflict, "account deletion is not scheduled"))
+	controller := NewAccountController(mockUsecase, newMockEmailVerificationUsecase())
+
+	handle(mockContext, controller.CancelDeletion)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
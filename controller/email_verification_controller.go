@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"echo-rest-api/usecase"
+	"net/http"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+)
+
+type IEmailVerificationController interface {
+	VerifyEmail(c echo.Context) error
+	ResendVerification(c echo.Context) error
+	RequireVerifiedEmail(next echo.HandlerFunc) echo.HandlerFunc
+}
+
+type emailVerificationController struct {
+	vu usecase.IEmailVerificationUsecase
+}
+
+func NewEmailVerificationController(vu usecase.IEmailVerificationUsecase) IEmailVerificationController {
+	return &emailVerificationController{vu}
+}
+
+func (vc *emailVerificationController) VerifyEmail(c echo.Context) error {
+	userRes, err := vc.vu.VerifyEmail(c.Request().Context(), c.QueryParam("token"))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, userRes)
+}
+
+func (vc *emailVerificationController) ResendVerification(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+
+	if err := vc.vu.SendVerification(c.Request().Context(), uint(userId.(float64))); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusAccepted)
+}
+
+// RequireVerifiedEmail applies the unverified email policy to memo writes.
+// A POST without a memoId creates a memo; every other unsafe method changes
+// an existing one.
+func (vc *emailVerificationController) RequireVerifiedEmail(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		method := c.Request().Method
+		if method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions {
+			return next(c)
+		}
+		user := c.Get("user").(*jwt.Token)
+		claims := user.Claims.(jwt.MapClaims)
+		userId := claims["user_id"]
+		create := method == http.MethodPost && c.Param("memoId") == ""
+		if err := vc.vu.CheckMemoWrite(c.Request().Context(), uint(userId.(float64)), create); err != nil {
+			return err
+		}
+		return next(c)
+	}
+}
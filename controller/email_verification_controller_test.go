@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyEmail(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/verify-email?token=signed", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockUsecase := newMockEmailVerificationUsecase()
+	mockUsecase.(*mockEmailVerificationUsecase).
+		On("VerifyEmail", "signed").
+		Return(model.UserResponse{ID: 1, Email: "user@example.com", EmailVerified: true}, nil)
+	controller := NewEmailVerificationController(mockUsecase)
+
+	handle(mockContext, controller.VerifyEmail)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"id":1,"email":"user@example.com","email_verified":true}`, rec.Body.String())
+	mockUsecase.(*mockEmailVerificationUsecase).AssertExpectations(t)
+}
+
+func TestResendVerification_Throttled(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/verify-email/resend", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockUsecase := newMockEmailVerificationUsecase()
+	mockUsecase.(*mockEmailVerificationUsecase).
+		On("SendVerification", uint(1)).
+		Return(apperror.New(apperror.ErrTooManyRequests, "verification email sent recently"))
+	controller := NewEmailVerificationController(mockUsecase)
+
+	handle(mockContext, controller.ResendVerification)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	mockUsecase.(*mockEmailVerificationUsecase).AssertExpectations(t)
+}
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	cases := []struct {
+		method  string
+		memoId  string
+		create  bool
+		checked bool
+	}{
+		{http.MethodGet, "", false, false},
+		{http.MethodPost, "", true, true},
+		{http.MethodPost, "1", false, true},
+		{http.MethodPut, "1", false, true},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, "/memos", nil)
+		rec := httptest.NewRecorder()
+		mockContext := createMockContext(req, rec)
+		if tc.memoId != "" {
+			mockContext.SetParamNames("memoId")
+			mockContext.SetParamValues(tc.memoId)
+		}
+		mockUsecase := newMockEmailVerificationUsecase()
+		mockUsecase.(*mockEmailVerificationUsecase).
+			On("CheckMemoWrite", uint(1), tc.create).
+			Return(apperror.New(apperror.ErrForbidden, "email address is not verified"))
+		controller := NewEmailVerificationController(mockUsecase)
+		called := false
+
+		handle(mockContext, controller.RequireVerifiedEmail(func(c echo.Context) error {
+			called = true
+			return c.NoContent(http.StatusOK)
+		}))
+		assert.Equal(t, !tc.checked, called)
+		if tc.checked {
+			assert.Equal(t, http.StatusForbidden, rec.Code)
+			mockUsecase.(*mockEmailVerificationUsecase).AssertExpectations(t)
+		}
+	}
+}
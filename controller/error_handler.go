@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	MIMEApplicationProblemJSON = "application/problem+json"
+	HeaderRetryAfter           = "Retry-After"
+)
+
+// problem is an RFC 7807 problem details object.
+type problem struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	Errors   map[string]string `json:"errors,omitempty"`
+	// Current is the server copy of the object when a conditional request
+	// failed because the client's copy is stale.
+	Current interface{} `json:"current,omitempty"`
+}
+
+var problemTypes = map[int]string{
+	http.StatusBadRequest:            "bad-request",
+	http.StatusUnauthorized:          "unauthorized",
+	http.StatusForbidden:             "forbidden",
+	http.StatusNotFound:              "not-found",
+	http.StatusMethodNotAllowed:      "method-not-allowed",
+	http.StatusConflict:              "conflict",
+	http.StatusPreconditionFailed:    "precondition-failed",
+	http.StatusPreconditionRequired:  "precondition-required",
+	http.StatusRequestEntityTooLarge: "request-too-large",
+	http.StatusUnsupportedMediaType:  "unsupported-media-type",
+	http.StatusUnprocessableEntity:   "validation-error",
+	http.StatusTooManyRequests:       "too-many-requests",
+	http.StatusInternalServerError:   "internal-error",
+	http.StatusServiceUnavailable:    "service-unavailable",
+}
+
+// HTTPErrorHandler is installed as the Echo error handler so that every
+// controller can simply return the error from its usecase and the client
+// always receives an application/problem+json body.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+	p := newProblem(err, c)
+	if p.Status == http.StatusInternalServerError {
+		requestLogger(c).Error("request failed", "error", err)
+	}
+	if retryAfter, ok := apperror.RetryAfter(err); ok {
+		c.Response().Header().Set(HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(p.Status)
+	} else {
+		c.Response().Header().Set(echo.HeaderContentType, MIMEApplicationProblemJSON)
+		err = c.JSON(p.Status, p)
+	}
+	if err != nil {
+		requestLogger(c).Error("writing error response failed", "error", err)
+	}
+}
+
+func newProblem(err error, c echo.Context) problem {
+	status, detail := statusOf(err)
+	p := problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: instanceOf(c),
+	}
+	if t, ok := problemTypes[status]; ok {
+		p.Type = "urn:problem-type:" + t
+	}
+	var ve validation.Errors
+	var be *echo.BindingError
+	switch {
+	case errors.As(err, &ve):
+		p.Errors = map[string]string{}
+		for field, fieldErr := range ve {
+			p.Errors[field] = fieldErr.Error()
+		}
+	case errors.As(err, &be):
+		p.Errors = map[string]string{be.Field: detail}
+	}
+	if current, ok := apperror.Current(err); ok {
+		p.Current = current
+	}
+	return p
+}
+
+// instanceOf identifies this particular occurrence of the problem by the
+// request id, falling back to the request path when none was assigned.
+func instanceOf(c echo.Context) string {
+	id := c.Response().Header().Get(echo.HeaderXRequestID)
+	if id == "" {
+		id = c.Request().Header.Get(echo.HeaderXRequestID)
+	}
+	if id == "" {
+		return c.Request().URL.Path
+	}
+	return "urn:request:" + id
+}
+
+func statusOf(err error) (int, string) {
+	var he *echo.HTTPError
+	var be *echo.BindingError
+	switch {
+	case errors.Is(err, apperror.ErrNotFound):
+		return http.StatusNotFound, err.Error()
+	case errors.Is(err, apperror.ErrConflict):
+		return http.StatusConflict, err.Error()
+	case errors.Is(err, apperror.ErrValidation):
+		return http.StatusUnprocessableEntity, err.Error()
+	case errors.Is(err, apperror.ErrUnauthorized):
+		return http.StatusUnauthorized, err.Error()
+	case errors.Is(err, apperror.ErrForbidden):
+		return http.StatusForbidden, err.Error()
+	case errors.Is(err, apperror.ErrTooManyRequests):
+		return http.StatusTooManyRequests, err.Error()
+	case errors.Is(err, apperror.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed, err.Error()
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable, "request timed out"
+	case errors.Is(err, context.Canceled):
+		// The client went away, so nobody reads the answer.
+		return http.StatusServiceUnavailable, "request canceled"
+	case errors.As(err, &be):
+		return be.Code, fmt.Sprint(be.Message)
+	case errors.As(err, &he):
+		if m, ok := he.Message.(string); ok {
+			return he.Code, m
+		}
+		return he.Code, http.StatusText(he.Code)
+	default:
+		return http.StatusInternalServerError, ""
+	}
+}
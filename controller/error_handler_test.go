@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"echo-rest-api/validator"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPErrorHandler(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+		body   string
+	}{
+		{apperror.New(apperror.ErrNotFound, "object does not exist"), http.StatusNotFound,
+			`{"type":"urn:problem-type:not-found","title":"Not Found","status":404,"detail":"object does not exist","instance":"/memos/1"}`},
+		{apperror.New(apperror.ErrConflict, "email already registered"), http.StatusConflict,
+			`{"type":"urn:problem-type:conflict","title":"Conflict","status":409,"detail":"email already registered","instance":"/memos/1"}`},
+		{apperror.New(apperror.ErrUnauthorized, "invalid email or password"), http.StatusUnauthorized,
+			`{"type":"urn:problem-type:unauthorized","title":"Unauthorized","status":401,"detail":"invalid email or password","instance":"/memos/1"}`},
+		{apperror.New(apperror.ErrForbidden, "email address is not verified"), http.StatusForbidden,
+			`{"type":"urn:problem-type:forbidden","title":"Forbidden","status":403,"detail":"email address is not verified","instance":"/memos/1"}`},
+		{apperror.New(apperror.ErrTooManyRequests, "verification email sent recently"), http.StatusTooManyRequests,
+			`{"type":"urn:problem-type:too-many-requests","title":"Too Many Requests","status":429,"detail":"verification email sent recently","instance":"/memos/1"}`},
+		{echo.NewHTTPError(http.StatusBadRequest, "bad"), http.StatusBadRequest,
+			`{"type":"urn:problem-type:bad-request","title":"Bad Request","status":400,"detail":"bad","instance":"/memos/1"}`},
+		{echo.ErrServiceUnavailable.WithInternal(context.DeadlineExceeded), http.StatusServiceUnavailable,
+			`{"type":"urn:problem-type:service-unavailable","title":"Service Unavailable","status":503,"detail":"request timed out","instance":"/memos/1"}`},
+		{errors.New("secret db failure"), http.StatusInternalServerError,
+			`{"type":"urn:problem-type:internal-error","title":"Internal Server Error","status":500,"instance":"/memos/1"}`},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/memos/1", nil)
+		rec := httptest.NewRecorder()
+		mockContext := createMockContext(req, rec)
+		HTTPErrorHandler(tc.err, mockContext)
+		assert.Equal(t, tc.status, rec.Code)
+		assert.Equal(t, MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+		assert.JSONEq(t, tc.body, rec.Body.String())
+	}
+}
+
+func TestHTTPErrorHandler_Validation(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/signup", nil)
+	req.Header.Set(echo.HeaderXRequestID, "req-1")
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	verr := validator.NewUserValidator().UserValidate(model.User{Email: "invalid", Password: "12345"})
+
+	HTTPErrorHandler(apperror.Wrap(apperror.ErrValidation, verr), mockContext)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.JSONEq(t, `{
+		"type": "urn:problem-type:validation-error",
+		"title": "Unprocessable Entity",
+		"status": 422,
+		"detail": "email: invalid email format; password: limited min 6 max 30 char.",
+		"instance": "urn:request:req-1",
+		"errors": {
+			"email": "invalid email format",
+			"password": "limited min 6 max 30 char"
+		}
+	}`, rec.Body.String())
+}
+
+func TestHTTPErrorHandler_RetryAfter(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+
+	HTTPErrorHandler(apperror.Throttled("too many failed logins", 1500*time.Millisecond), mockContext)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get(HeaderRetryAfter))
+	assert.Contains(t, rec.Body.String(), "too many failed logins")
+}
+
+func TestHTTPErrorHandler_ContextTimeout(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Use(middleware.ContextTimeout(10 * time.Millisecond))
+	e.GET("/memos", func(c echo.Context) error {
+		<-c.Request().Context().Done()
+		return c.Request().Context().Err()
+	})
+	req := httptest.NewRequest(http.MethodGet, "/memos", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"detail":"request timed out"`)
+}
@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderETag    = "ETag"
+	HeaderIfMatch = "If-Match"
+)
+
+func setMemoETag(c echo.Context, version uint) {
+	c.Response().Header().Set(HeaderETag, strconv.Quote(strconv.FormatUint(uint64(version), 10)))
+}
+
+// ifMatchVersion reads the memo version from the If-Match header. It returns
+// 0 when the header is absent or "*", meaning the update is unconditional.
+func ifMatchVersion(c echo.Context) (uint, error) {
+	header := strings.TrimSpace(c.Request().Header.Get(HeaderIfMatch))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	if strings.Contains(header, ",") {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "If-Match must contain a single entity tag")
+	}
+	if strings.HasPrefix(header, "W/") {
+		// If-Match uses the strong comparison, which a weak tag never passes.
+		return 0, echo.NewHTTPError(http.StatusPreconditionFailed, "memo has been modified")
+	}
+	unquoted, err := strconv.Unquote(header)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "If-Match must be a quoted entity tag")
+	}
+	version, err := strconv.ParseUint(unquoted, 10, 64)
+	if err != nil || version == 0 {
+		// a tag we never issued cannot match the current version
+		return 0, echo.NewHTTPError(http.StatusPreconditionFailed, "memo has been modified")
+	}
+	return uint(version), nil
+}
+
+// RequireIfMatch rejects requests that do not carry an If-Match header so
+// that clients cannot overwrite changes they have not seen.
+func RequireIfMatch(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if c.Request().Header.Get(HeaderIfMatch) == "" {
+			return echo.NewHTTPError(http.StatusPreconditionRequired, "If-Match header is required")
+		}
+		return next(c)
+	}
+}
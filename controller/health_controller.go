@@ -0,0 +1,36 @@
+package controller
+
+import (
+	"echo-rest-api/model"
+	"echo-rest-api/usecase"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type IHealthController interface {
+	Livez(c echo.Context) error
+	Readyz(c echo.Context) error
+}
+
+type healthController struct {
+	hu usecase.IHealthUsecase
+}
+
+func NewHealthController(hu usecase.IHealthUsecase) IHealthController {
+	return &healthController{hu}
+}
+
+// Livez only tells that the process serves requests; it checks no
+// dependencies so that an outage of one does not get the instance restarted.
+func (hc *healthController) Livez(c echo.Context) error {
+	return c.JSON(http.StatusOK, echo.Map{"status": model.HealthStatusOK})
+}
+
+func (hc *healthController) Readyz(c echo.Context) error {
+	report := hc.hu.Ready(c.Request().Context())
+	if report.Status != model.HealthStatusOK {
+		return c.JSON(http.StatusServiceUnavailable, report)
+	}
+	return c.JSON(http.StatusOK, report)
+}
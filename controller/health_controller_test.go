@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"echo-rest-api/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLivez(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/livez", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockUsecase := newMockHealthUsecase()
+	controller := NewHealthController(mockUsecase)
+
+	handle(mockContext, controller.Livez)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+	mockUsecase.(*mockHealthUsecase).AssertNotCalled(t, "Ready")
+}
+
+func TestReadyz(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockUsecase := newMockHealthUsecase()
+	mockUsecase.(*mockHealthUsecase).On("Ready").Return(model.HealthReport{
+		Status: model.HealthStatusOK,
+		Checks: map[string]model.HealthCheck{"database": {Status: model.HealthStatusOK, LatencyMs: 1.5}},
+	})
+	controller := NewHealthController(mockUsecase)
+
+	handle(mockContext, controller.Readyz)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"ok","checks":{"database":{"status":"ok","latency_ms":1.5}}}`, rec.Body.String())
+}
+
+func TestReadyz_Unavailable(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockUsecase := newMockHealthUsecase()
+	mockUsecase.(*mockHealthUsecase).On("Ready").Return(model.HealthReport{
+		Status: model.HealthStatusUnavailable,
+		Checks: map[string]model.HealthCheck{"database": {Status: model.HealthStatusUnavailable, LatencyMs: 2, Error: "connection refused"}},
+	})
+	controller := NewHealthController(mockUsecase)
+
+	handle(mockContext, controller.Readyz)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.JSONEq(t, `{"status":"unavailable","checks":{"database":{"status":"unavailable","latency_ms":2,"error":"connection refused"}}}`, rec.Body.String())
+}
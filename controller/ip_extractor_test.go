@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"echo-rest-api/apperror"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func failLogin(e *echo.Echo, forwardedFor string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"email":"testlogin@example.com","password":"wrongpassword"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderXForwardedFor, forwardedFor)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIPExtractor_IgnoresForgedForwardedFor(t *testing.T) {
+	usecase := newMockUserUsecase()
+	usecase.(*mockUserUsecase).
+		On("Login", mock.Anything).
+		Return(nil, apperror.New(apperror.ErrUnauthorized, "invalid email or password"))
+	attemptUsecase := newMockLoginAttemptUsecase()
+	// Every failure counts against the address of the connection, whatever
+	// the header claims.
+	attemptUsecase.(*mockLoginAttemptUsecase).On("CheckLogin", "testlogin@example.com", "192.0.2.1").Return(nil)
+	attemptUsecase.(*mockLoginAttemptUsecase).On("RecordFailure", "testlogin@example.com", "192.0.2.1").Return(nil)
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.IPExtractor = IPExtractor(nil)
+	e.POST("/login", NewUserController(usecase, nil, nil, attemptUsecase, CookieConfig{}).Login)
+
+	for _, forged := range []string{"203.0.113.1", "203.0.113.2", "203.0.113.3"} {
+		assert.Equal(t, http.StatusUnauthorized, failLogin(e, forged).Code)
+	}
+	attemptUsecase.(*mockLoginAttemptUsecase).AssertNumberOfCalls(t, "RecordFailure", 3)
+	attemptUsecase.(*mockLoginAttemptUsecase).AssertExpectations(t)
+}
+
+func TestIPExtractor_TrustedProxy(t *testing.T) {
+	usecase := newMockUserUsecase()
+	usecase.(*mockUserUsecase).
+		On("Login", mock.Anything).
+		Return(nil, apperror.New(apperror.ErrUnauthorized, "invalid email or password"))
+	attemptUsecase := newMockLoginAttemptUsecase()
+	attemptUsecase.(*mockLoginAttemptUsecase).On("CheckLogin", "testlogin@example.com", "203.0.113.1").Return(nil)
+	attemptUsecase.(*mockLoginAttemptUsecase).On("RecordFailure", "testlogin@example.com", "203.0.113.1").Return(nil)
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.IPExtractor = IPExtractor([]string{"192.0.2.0/24"})
+	e.POST("/login", NewUserController(usecase, nil, nil, attemptUsecase, CookieConfig{}).Login)
+
+	assert.Equal(t, http.StatusUnauthorized, failLogin(e, "203.0.113.1").Code)
+	attemptUsecase.(*mockLoginAttemptUsecase).AssertExpectations(t)
+}
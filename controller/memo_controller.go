@@ -3,19 +3,34 @@ package controller
 import (
 	"echo-rest-api/model"
 	"echo-rest-api/usecase"
+	"encoding/json"
+	"mime"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/labstack/echo/v4"
 )
 
+const MIMEApplicationMergePatchJSON = "application/merge-patch+json"
+
 type IMemoController interface {
 	GetAllMemos(c echo.Context) error
 	GetMemoById(c echo.Context) error
+	SearchMemos(c echo.Context) error
 	CreateMemo(c echo.Context) error
 	UpdateMemo(c echo.Context) error
+	PatchMemo(c echo.Context) error
+	MoveMemo(c echo.Context) error
 	DeleteMemo(c echo.Context) error
+	GetTrashedMemos(c echo.Context) error
+	RestoreMemo(c echo.Context) error
+	PurgeMemo(c echo.Context) error
+	EmptyTrash(c echo.Context) error
+	GetMemoRevisions(c echo.Context) error
+	GetMemoRevision(c echo.Context) error
+	RestoreMemoRevision(c echo.Context) error
 }
 
 type memoController struct {
@@ -30,9 +45,31 @@ func (mc *memoController) GetAllMemos(c echo.Context) error {
 	user := c.Get("user").(*jwt.Token)
 	claims := user.Claims.(jwt.MapClaims)
 	userId := claims["user_id"]
-	memoRes, err := mc.mu.GetAllMemos(uint(userId.(float64)))
+	query := model.MemoQuery{}
+	var notebookId uint
+	err := echo.QueryParamsBinder(c).
+		Int("limit", &query.Limit).
+		String("cursor", &query.Cursor).
+		String("sort", &query.SortBy).
+		String("order", &query.Order).
+		Time("created_from", &query.CreatedFrom, time.RFC3339).
+		Time("created_to", &query.CreatedTo, time.RFC3339).
+		Time("updated_from", &query.UpdatedFrom, time.RFC3339).
+		Time("updated_to", &query.UpdatedTo, time.RFC3339).
+		Strings("tag", &query.Tags).
+		String("tag_match", &query.TagMatch).
+		Uint("notebook_id", &notebookId).
+		Bool("recursive", &query.Recursive).
+		BindError()
+	if err != nil {
+		return err
+	}
+	if c.QueryParam("notebook_id") != "" {
+		query.NotebookId = &notebookId
+	}
+	memoRes, err := mc.mu.GetAllMemos(c.Request().Context(), uint(userId.(float64)), query)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 	return c.JSON(http.StatusOK, memoRes)
 }
@@ -43,9 +80,29 @@ func (mc *memoController) GetMemoById(c echo.Context) error {
 	userId := claims["user_id"]
 	id := c.Param("memoId")
 	memoId, _ := strconv.Atoi(id)
-	memoRes, err := mc.mu.GetMemoById(uint(userId.(float64)), uint(memoId))
+	memoRes, err := mc.mu.GetMemoById(c.Request().Context(), uint(userId.(float64)), uint(memoId))
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
+	}
+	setMemoETag(c, memoRes.Version)
+	return c.JSON(http.StatusOK, memoRes)
+}
+
+func (mc *memoController) SearchMemos(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+	query := model.MemoSearchQuery{}
+	err := echo.QueryParamsBinder(c).
+		String("q", &query.Query).
+		Int("limit", &query.Limit).
+		BindError()
+	if err != nil {
+		return err
+	}
+	memoRes, err := mc.mu.SearchMemos(c.Request().Context(), uint(userId.(float64)), query)
+	if err != nil {
+		return err
 	}
 	return c.JSON(http.StatusOK, memoRes)
 }
@@ -57,13 +114,14 @@ func (mc *memoController) CreateMemo(c echo.Context) error {
 
 	memo := model.Memo{}
 	if err := c.Bind(&memo); err != nil {
-		return c.JSON(http.StatusBadRequest, err.Error())
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 	memo.UserId = uint(userId.(float64))
-	memoRes, err := mc.mu.CreateMemo(memo)
+	memoRes, err := mc.mu.CreateMemo(c.Request().Context(), memo)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
+	setMemoETag(c, memoRes.Version)
 	return c.JSON(http.StatusCreated, memoRes)
 }
 
@@ -74,14 +132,67 @@ func (mc *memoController) UpdateMemo(c echo.Context) error {
 	id := c.Param("memoId")
 	memoId, _ := strconv.Atoi(id)
 
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return err
+	}
 	memo := model.Memo{}
 	if err := c.Bind(&memo); err != nil {
-		return c.JSON(http.StatusBadRequest, err.Error())
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
-	memoRes, err := mc.mu.UpdateMemo(memo, uint(userId.(float64)), uint(memoId))
+	memo.Version = version
+	memoRes, err := mc.mu.UpdateMemo(c.Request().Context(), memo, uint(userId.(float64)), uint(memoId))
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
+	setMemoETag(c, memoRes.Version)
+	return c.JSON(http.StatusOK, memoRes)
+}
+
+func (mc *memoController) PatchMemo(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+	id := c.Param("memoId")
+	memoId, _ := strconv.Atoi(id)
+
+	mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	if mediaType != MIMEApplicationMergePatchJSON {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, "Content-Type must be "+MIMEApplicationMergePatchJSON)
+	}
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return err
+	}
+	patch := model.MemoPatch{}
+	if err := json.NewDecoder(c.Request().Body).Decode(&patch); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid merge patch: "+err.Error())
+	}
+	patch.Version = version
+	memoRes, err := mc.mu.PatchMemo(c.Request().Context(), patch, uint(userId.(float64)), uint(memoId))
+	if err != nil {
+		return err
+	}
+	setMemoETag(c, memoRes.Version)
+	return c.JSON(http.StatusOK, memoRes)
+}
+
+func (mc *memoController) MoveMemo(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+	id := c.Param("memoId")
+	memoId, _ := strconv.Atoi(id)
+
+	move := model.MemoMove{}
+	if err := c.Bind(&move); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	memoRes, err := mc.mu.MoveMemo(c.Request().Context(), uint(userId.(float64)), uint(memoId), move.NotebookId)
+	if err != nil {
+		return err
+	}
+	setMemoETag(c, memoRes.Version)
 	return c.JSON(http.StatusOK, memoRes)
 }
 
@@ -92,9 +203,104 @@ func (mc *memoController) DeleteMemo(c echo.Context) error {
 	id := c.Param("memoId")
 	memoId, _ := strconv.Atoi(id)
 
-	err := mc.mu.DeleteMemo(uint(userId.(float64)), uint(memoId))
+	err := mc.mu.DeleteMemo(c.Request().Context(), uint(userId.(float64)), uint(memoId))
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 	return c.NoContent(http.StatusNoContent)
 }
+
+func (mc *memoController) GetTrashedMemos(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+	memoRes, err := mc.mu.GetTrashedMemos(c.Request().Context(), uint(userId.(float64)))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, memoRes)
+}
+
+func (mc *memoController) RestoreMemo(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+	id := c.Param("memoId")
+	memoId, _ := strconv.Atoi(id)
+
+	memoRes, err := mc.mu.RestoreMemo(c.Request().Context(), uint(userId.(float64)), uint(memoId))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, memoRes)
+}
+
+func (mc *memoController) PurgeMemo(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+	id := c.Param("memoId")
+	memoId, _ := strconv.Atoi(id)
+
+	err := mc.mu.PurgeMemo(c.Request().Context(), uint(userId.(float64)), uint(memoId))
+	if err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (mc *memoController) EmptyTrash(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+
+	err := mc.mu.EmptyTrash(c.Request().Context(), uint(userId.(float64)))
+	if err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (mc *memoController) GetMemoRevisions(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+	id := c.Param("memoId")
+	memoId, _ := strconv.Atoi(id)
+
+	revisionRes, err := mc.mu.GetMemoRevisions(c.Request().Context(), uint(userId.(float64)), uint(memoId))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, revisionRes)
+}
+
+func (mc *memoController) GetMemoRevision(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+	id := c.Param("memoId")
+	memoId, _ := strconv.Atoi(id)
+	rev, _ := strconv.Atoi(c.Param("rev"))
+
+	revisionRes, err := mc.mu.GetMemoRevision(c.Request().Context(), uint(userId.(float64)), uint(memoId), uint(rev))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, revisionRes)
+}
+
+func (mc *memoController) RestoreMemoRevision(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+	id := c.Param("memoId")
+	memoId, _ := strconv.Atoi(id)
+	rev, _ := strconv.Atoi(c.Param("rev"))
+
+	memoRes, err := mc.mu.RestoreMemoRevision(c.Request().Context(), uint(userId.(float64)), uint(memoId), uint(rev))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, memoRes)
+}
@@ -2,12 +2,14 @@ package controller
 
 import (
 	"bytes"
+	"echo-rest-api/apperror"
 	"echo-rest-api/model"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
@@ -18,25 +20,28 @@ func TestGetAllMemos(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/memos", nil)
 	rec := httptest.NewRecorder()
 	mockContext := createMockContext(req, rec)
-	memoResponse := []model.MemoResponse{
-		{
-			ID:      1,
-			Title:   "memo1 title",
-			Content: "memo1 content",
-		},
-		{
-			ID:      2,
-			Title:   "memo2 title",
-			Content: "memo2 content",
+	memoResponse := model.MemoPageResponse{
+		Memos: []model.MemoResponse{
+			{
+				ID:      1,
+				Title:   "memo1 title",
+				Content: "memo1 content",
+			},
+			{
+				ID:      2,
+				Title:   "memo2 title",
+				Content: "memo2 content",
+			},
 		},
+		NextCursor: "next",
 	}
 	mockUsecase := newMockMemoUsecase()
 	mockUsecase.(*mockMemoUsecase).
-		On("GetAllMemos", uint(1)).
+		On("GetAllMemos", uint(1), model.MemoQuery{}).
 		Return(memoResponse, nil)
 	controller := NewMemoController(mockUsecase)
 
-	controller.GetAllMemos(mockContext)
+	handle(mockContext, controller.GetAllMemos)
 	assert.Equal(t, http.StatusOK, rec.Code)
 
 	memoJSON, err := json.Marshal(memoResponse)
@@ -51,15 +56,52 @@ func TestGetAllMemos_Error(t *testing.T) {
 	mockContext := createMockContext(req, rec)
 	mockUsecase := newMockMemoUsecase()
 	mockUsecase.(*mockMemoUsecase).
-		On("GetAllMemos", uint(1)).
+		On("GetAllMemos", uint(1), model.MemoQuery{}).
 		Return(nil, errors.New("error"))
 	controller := NewMemoController(mockUsecase)
 
-	controller.GetAllMemos(mockContext)
+	handle(mockContext, controller.GetAllMemos)
 	assert.Equal(t, http.StatusInternalServerError, rec.Code)
 	mockUsecase.(*mockMemoUsecase).AssertExpectations(t)
 }
 
+func TestGetAllMemos_Query(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/memos?limit=10&cursor=abc&sort=title&order=asc&created_from=2024-01-01T00:00:00Z&tag=work&tag=idea&tag_match=all", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	query := model.MemoQuery{
+		Limit:       10,
+		Cursor:      "abc",
+		SortBy:      model.MemoSortTitle,
+		Order:       model.SortAsc,
+		CreatedFrom: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Tags:        []string{"work", "idea"},
+		TagMatch:    model.TagMatchAll,
+	}
+	mockUsecase := newMockMemoUsecase()
+	mockUsecase.(*mockMemoUsecase).
+		On("GetAllMemos", uint(1), query).
+		Return(model.MemoPageResponse{Memos: []model.MemoResponse{}}, nil)
+	controller := NewMemoController(mockUsecase)
+
+	handle(mockContext, controller.GetAllMemos)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	mockUsecase.(*mockMemoUsecase).AssertExpectations(t)
+}
+
+func TestGetAllMemos_BadQuery(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/memos?limit=ten", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockUsecase := newMockMemoUsecase()
+	controller := NewMemoController(mockUsecase)
+
+	handle(mockContext, controller.GetAllMemos)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"errors":{"limit"`)
+	mockUsecase.(*mockMemoUsecase).AssertNotCalled(t, "GetAllMemos")
+}
+
 func TestGetMemoById(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/memos/1", nil)
 	rec := httptest.NewRecorder()
@@ -71,6 +113,7 @@ func TestGetMemoById(t *testing.T) {
 		ID:      1,
 		Title:   "memo1 title",
 		Content: "memo1 content",
+		Version: 4,
 	}
 	mockUsecase := newMockMemoUsecase()
 	mockUsecase.(*mockMemoUsecase).
@@ -78,8 +121,9 @@ func TestGetMemoById(t *testing.T) {
 		Return(memoResponse, nil)
 	controller := NewMemoController(mockUsecase)
 
-	controller.GetMemoById(mockContext)
+	handle(mockContext, controller.GetMemoById)
 	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"4"`, rec.Header().Get(HeaderETag))
 
 	memoJSON, err := json.Marshal(memoResponse)
 	assert.Nil(t, err)
@@ -100,11 +144,33 @@ func TestGetMemoById_Error(t *testing.T) {
 		Return(nil, errors.New("error"))
 	controller := NewMemoController(mockUsecase)
 
-	controller.GetMemoById(mockContext)
+	handle(mockContext, controller.GetMemoById)
 	assert.Equal(t, http.StatusInternalServerError, rec.Code)
 	mockUsecase.(*mockMemoUsecase).AssertExpectations(t)
 }
 
+func TestSearchMemos(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/memos/search?q=bread&limit=5", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	searchResponse := []model.MemoSearchResponse{
+		{ID: 1, Title: "grocery list", Content: "buy bread", Snippet: "buy <mark>bread</mark>"},
+	}
+	mockUsecase := newMockMemoUsecase()
+	mockUsecase.(*mockMemoUsecase).
+		On("SearchMemos", uint(1), model.MemoSearchQuery{Query: "bread", Limit: 5}).
+		Return(searchResponse, nil)
+	controller := NewMemoController(mockUsecase)
+
+	handle(mockContext, controller.SearchMemos)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	searchJSON, err := json.Marshal(searchResponse)
+	assert.Nil(t, err)
+	assert.JSONEq(t, string(searchJSON), rec.Body.String())
+	mockUsecase.(*mockMemoUsecase).AssertExpectations(t)
+}
+
 func TestCreateMemo(t *testing.T) {
 	input := model.Memo{
 		Title:   "created memo",
@@ -122,7 +188,7 @@ func TestCreateMemo(t *testing.T) {
 		Return(nil)
 	controller := NewMemoController(mockUsecase)
 
-	controller.CreateMemo(mockContext)
+	handle(mockContext, controller.CreateMemo)
 	assert.Equal(t, http.StatusCreated, rec.Code)
 
 	memoJSON, err := json.Marshal(model.MemoResponse{
@@ -154,7 +220,7 @@ func TestCreateMemo_Error(t *testing.T) {
 		Return(errors.New("error"))
 	controller := NewMemoController(mockUsecase)
 
-	controller.CreateMemo(mockContext)
+	handle(mockContext, controller.CreateMemo)
 	assert.Equal(t, http.StatusInternalServerError, rec.Code)
 	mockUsecase.(*mockMemoUsecase).AssertExpectations(t)
 }
@@ -176,7 +242,7 @@ func TestCreateMemo_BadRequest(t *testing.T) {
 		Return(errors.New("error"))
 	controller := NewMemoController(mockUsecase)
 
-	controller.CreateMemo(mockContext)
+	handle(mockContext, controller.CreateMemo)
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 	mockUsecase.(*mockMemoUsecase).AssertNotCalled(t, "CreateMemo")
 }
@@ -201,7 +267,7 @@ func TestUpdateMemo(t *testing.T) {
 		Return(nil)
 	controller := NewMemoController(mockUsecase)
 
-	controller.UpdateMemo(mockContext)
+	handle(mockContext, controller.UpdateMemo)
 	assert.Equal(t, http.StatusOK, rec.Code)
 
 	memoJSON, err := json.Marshal(model.MemoResponse{
@@ -216,6 +282,163 @@ func TestUpdateMemo(t *testing.T) {
 	mockUsecase.(*mockMemoUsecase).AssertExpectations(t)
 }
 
+func TestUpdateMemo_IfMatch(t *testing.T) {
+	cases := []struct {
+		ifMatch string
+		version uint
+	}{
+		{"", 0},
+		{"*", 0},
+		{`"3"`, 3},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPut, "/memos/1", bytes.NewBufferString(`{"title":"updated memo"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if tc.ifMatch != "" {
+			req.Header.Set(HeaderIfMatch, tc.ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		mockContext := createMockContext(req, rec)
+		mockContext.SetPath("/memos/:memoId")
+		mockContext.SetParamNames("memoId")
+		mockContext.SetParamValues("1")
+		mockUsecase := newMockMemoUsecase()
+		mockUsecase.(*mockMemoUsecase).
+			On("UpdateMemo", mock.MatchedBy(func(memo model.Memo) bool { return memo.Version == tc.version }), uint(1), uint(1)).
+			Return(nil)
+		controller := NewMemoController(mockUsecase)
+
+		handle(mockContext, controller.UpdateMemo)
+		assert.Equal(t, http.StatusOK, rec.Code, tc.ifMatch)
+		mockUsecase.(*mockMemoUsecase).AssertExpectations(t)
+	}
+}
+
+func TestUpdateMemo_PreconditionFailed(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/memos/1", bytes.NewBufferString(`{"title":"stale title"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(HeaderIfMatch, `"2"`)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockContext.SetPath("/memos/:memoId")
+	mockContext.SetParamNames("memoId")
+	mockContext.SetParamValues("1")
+	current := model.MemoResponse{ID: 1, Title: "newer title", Tags: []string{}, Version: 3}
+	mockUsecase := newMockMemoUsecase()
+	mockUsecase.(*mockMemoUsecase).
+		On("UpdateMemo", mock.AnythingOfType("model.Memo"), uint(1), uint(1)).
+		Return(apperror.Stale("memo has been modified", current))
+	controller := NewMemoController(mockUsecase)
+
+	handle(mockContext, controller.UpdateMemo)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	assert.JSONEq(t, `{
+		"type": "urn:problem-type:precondition-failed",
+		"title": "Precondition Failed",
+		"status": 412,
+		"detail": "memo has been modified",
+		"instance": "/memos/1",
+		"current": {
+			"id": 1,
+			"title": "newer title",
+			"content": "",
+			"tags": [],
+			"notebook_id": null,
+			"version": 3,
+			"created_at": "0001-01-01T00:00:00Z",
+			"updated_at": "0001-01-01T00:00:00Z"
+		}
+	}`, rec.Body.String())
+	mockUsecase.(*mockMemoUsecase).AssertExpectations(t)
+}
+
+func TestUpdateMemo_BadIfMatch(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/memos/1", bytes.NewBufferString(`{"title":"updated memo"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(HeaderIfMatch, `"1", "2"`)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockContext.SetPath("/memos/:memoId")
+	mockContext.SetParamNames("memoId")
+	mockContext.SetParamValues("1")
+	mockUsecase := newMockMemoUsecase()
+	controller := NewMemoController(mockUsecase)
+
+	handle(mockContext, controller.UpdateMemo)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	mockUsecase.(*mockMemoUsecase).AssertNotCalled(t, "UpdateMemo")
+}
+
+func TestUpdateMemo_WeakIfMatch(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/memos/1", bytes.NewBufferString(`{"title":"updated memo"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(HeaderIfMatch, `W/"3"`)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockContext.SetPath("/memos/:memoId")
+	mockContext.SetParamNames("memoId")
+	mockContext.SetParamValues("1")
+	mockUsecase := newMockMemoUsecase()
+	controller := NewMemoController(mockUsecase)
+
+	handle(mockContext, controller.UpdateMemo)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	mockUsecase.(*mockMemoUsecase).AssertNotCalled(t, "UpdateMemo")
+}
+
+func TestRequireIfMatch(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/memos/1", bytes.NewBufferString(`{"title":"updated memo"}`))
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockUsecase := newMockMemoUsecase()
+	controller := NewMemoController(mockUsecase)
+
+	handle(mockContext, RequireIfMatch(controller.UpdateMemo))
+	assert.Equal(t, http.StatusPreconditionRequired, rec.Code)
+	mockUsecase.(*mockMemoUsecase).AssertNotCalled(t, "UpdateMemo")
+}
+
+func TestPatchMemo(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPatch, "/memos/1", bytes.NewBufferString(`{"content":null,"tags":["go"]}`))
+	req.Header.Set(echo.HeaderContentType, MIMEApplicationMergePatchJSON)
+	req.Header.Set(HeaderIfMatch, `"2"`)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockContext.SetPath("/memos/:memoId")
+	mockContext.SetParamNames("memoId")
+	mockContext.SetParamValues("1")
+	memoResponse := model.MemoResponse{ID: 1, Title: "memo1 title", Tags: []string{"go"}, Version: 3}
+	mockUsecase := newMockMemoUsecase()
+	mockUsecase.(*mockMemoUsecase).
+		On("PatchMemo", mock.MatchedBy(func(patch model.MemoPatch) bool {
+			return !patch.Title.Set && patch.Content.Set && patch.Content.Null &&
+				patch.Tags.Set && len(patch.Tags.Value) == 1 && !patch.NotebookId.Set && patch.Version == 2
+		}), uint(1), uint(1)).
+		Return(memoResponse, nil)
+	controller := NewMemoController(mockUsecase)
+
+	handle(mockContext, controller.PatchMemo)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"3"`, rec.Header().Get(HeaderETag))
+	mockUsecase.(*mockMemoUsecase).AssertExpectations(t)
+}
+
+func TestPatchMemo_UnsupportedMediaType(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPatch, "/memos/1", bytes.NewBufferString(`{"content":null}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockContext.SetPath("/memos/:memoId")
+	mockContext.SetParamNames("memoId")
+	mockContext.SetParamValues("1")
+	mockUsecase := newMockMemoUsecase()
+	controller := NewMemoController(mockUsecase)
+
+	handle(mockContext, controller.PatchMemo)
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+	mockUsecase.(*mockMemoUsecase).AssertNotCalled(t, "PatchMemo")
+}
+
 func TestUpdateMemo_Error(t *testing.T) {
 	input := model.Memo{
 		Title:   "updated memo",
@@ -236,7 +459,7 @@ func TestUpdateMemo_Error(t *testing.T) {
 		Return(errors.New("error"))
 	controller := NewMemoController(mockUsecase)
 
-	controller.UpdateMemo(mockContext)
+	handle(mockContext, controller.UpdateMemo)
 	assert.Equal(t, http.StatusInternalServerError, rec.Code)
 	mockUsecase.(*mockMemoUsecase).AssertExpectations(t)
 }
@@ -261,11 +484,36 @@ func TestUpdateMemo_BadRequest(t *testing.T) {
 		Return(errors.New("error"))
 	controller := NewMemoController(mockUsecase)
 
-	controller.UpdateMemo(mockContext)
+	handle(mockContext, controller.UpdateMemo)
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 	mockUsecase.(*mockMemoUsecase).AssertNotCalled(t, "UpdateMemo")
 }
 
+func TestMoveMemo(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/memos/1/notebook", bytes.NewBufferString(`{"notebook_id":3}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockContext.SetPath("/memos/:memoId/notebook")
+	mockContext.SetParamNames("memoId")
+	mockContext.SetParamValues("1")
+	notebookId := uint(3)
+	memoResponse := model.MemoResponse{ID: 1, Title: "memo1 title", NotebookId: &notebookId}
+	mockUsecase := newMockMemoUsecase()
+	mockUsecase.(*mockMemoUsecase).
+		On("MoveMemo", uint(1), uint(1), &notebookId).
+		Return(memoResponse, nil)
+	controller := NewMemoController(mockUsecase)
+
+	handle(mockContext, controller.MoveMemo)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	memoJSON, err := json.Marshal(memoResponse)
+	assert.Nil(t, err)
+	assert.JSONEq(t, string(memoJSON), rec.Body.String())
+	mockUsecase.(*mockMemoUsecase).AssertExpectations(t)
+}
+
 func TestDeleteMemo(t *testing.T) {
 	req := httptest.NewRequest(http.MethodDelete, "/memos/1", nil)
 	rec := httptest.NewRecorder()
@@ -279,7 +527,7 @@ func TestDeleteMemo(t *testing.T) {
 		Return(nil)
 	controller := NewMemoController(mockUsecase)
 
-	controller.DeleteMemo(mockContext)
+	handle(mockContext, controller.DeleteMemo)
 	assert.Equal(t, http.StatusNoContent, rec.Code)
 	mockUsecase.(*mockMemoUsecase).AssertExpectations(t)
 }
@@ -297,7 +545,109 @@ func TestDeleteMemo_Error(t *testing.T) {
 		Return(errors.New("error"))
 	controller := NewMemoController(mockUsecase)
 
-	controller.DeleteMemo(mockContext)
+	handle(mockContext, controller.DeleteMemo)
 	assert.Equal(t, http.StatusInternalServerError, rec.Code)
 	mockUsecase.(*mockMemoUsecase).AssertExpectations(t)
 }
+
+func TestGetTrashedMemos(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/memos/trash", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	deletedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	memoResponse := []model.MemoResponse{
+		{ID: 1, Title: "memo1 title", Tags: []string{}, DeletedAt: &deletedAt},
+	}
+	mockUsecase := newMockMemoUsecase()
+	mockUsecase.(*mockMemoUsecase).
+		On("GetTrashedMemos", uint(1)).
+		Return(memoResponse, nil)
+	controller := NewMemoController(mockUsecase)
+
+	handle(mockContext, controller.GetTrashedMemos)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	memoJSON, err := json.Marshal(memoResponse)
+	assert.Nil(t, err)
+	assert.JSONEq(t, string(memoJSON), rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"deleted_at":"2024-01-01T00:00:00Z"`)
+	mockUsecase.(*mockMemoUsecase).AssertExpectations(t)
+}
+
+func TestRestoreMemo_NotInTrash(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/memos/1/restore", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockContext.SetPath("/memos/:memoId/restore")
+	mockContext.SetParamNames("memoId")
+	mockContext.SetParamValues("1")
+	mockUsecase := newMockMemoUsecase()
+	mockUsecase.(*mockMemoUsecase).
+		On("RestoreMemo", uint(1), uint(1)).
+		Return(nil, apperror.New(apperror.ErrNotFound, "object does not exist in trash"))
+	controller := NewMemoController(mockUsecase)
+
+	handle(mockContext, controller.RestoreMemo)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	mockUsecase.(*mockMemoUsecase).AssertExpectations(t)
+}
+
+func TestEmptyTrash(t *testing.T) {
+	req := httptest.NewRequest(http.MethodDelete, "/memos/trash", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockUsecase := newMockMemoUsecase()
+	mockUsecase.(*mockMemoUsecase).
+		On("EmptyTrash", uint(1)).
+		Return(nil)
+	controller := NewMemoController(mockUsecase)
+
+	handle(mockContext, controller.EmptyTrash)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	mockUsecase.(*mockMemoUsecase).AssertExpectations(t)
+}
+
+func TestGetMemoRevision(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/memos/1/revisions/2", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockContext.SetPath("/memos/:memoId/revisions/:rev")
+	mockContext.SetParamNames("memoId", "rev")
+	mockContext.SetParamValues("1", "2")
+	revisionResponse := model.MemoRevisionDiffResponse{
+		MemoRevisionResponse: model.MemoRevisionResponse{Revision: 2, Title: "memo1 title", Content: "old"},
+		Diff:                 "--- revision 2\n+++ current\n@@ -1 +1 @@\n-old\n+new\n",
+	}
+	mockUsecase := newMockMemoUsecase()
+	mockUsecase.(*mockMemoUsecase).
+		On("GetMemoRevision", uint(1), uint(1), uint(2)).
+		Return(revisionResponse, nil)
+	controller := NewMemoController(mockUsecase)
+
+	handle(mockContext, controller.GetMemoRevision)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	revisionJSON, err := json.Marshal(revisionResponse)
+	assert.Nil(t, err)
+	assert.JSONEq(t, string(revisionJSON), rec.Body.String())
+	mockUsecase.(*mockMemoUsecase).AssertExpectations(t)
+}
+
+func TestRestoreMemoRevision(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/memos/1/revisions/2/restore", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockContext.SetPath("/memos/:memoId/revisions/:rev/restore")
+	mockContext.SetParamNames("memoId", "rev")
+	mockContext.SetParamValues("1", "2")
+	memoResponse := model.MemoResponse{ID: 1, Title: "memo1 title", Content: "old", Tags: []string{}}
+	mockUsecase := newMockMemoUsecase()
+	mockUsecase.(*mockMemoUsecase).
+		On("RestoreMemoRevision", uint(1), uint(1), uint(2)).
+		Return(memoResponse, nil)
+	controller := NewMemoController(mockUsecase)
+
+	handle(mockContext, controller.RestoreMemoRevision)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	mockUsecase.(*mockMemoUsecase).AssertExpectations(t)
+}
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"echo-rest-api/model"
+	"echo-rest-api/usecase"
+	"net/http"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+)
+
+type IMFAController interface {
+	EnrollTOTP(c echo.Context) error
+	ConfirmTOTP(c echo.Context) error
+	DisableTOTP(c echo.Context) error
+}
+
+type mfaController struct {
+	mu usecase.IMFAUsecase
+}
+
+func NewMFAController(mu usecase.IMFAUsecase) IMFAController {
+	return &mfaController{mu}
+}
+
+func (mc *mfaController) EnrollTOTP(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+
+	enrollmentRes, err := mc.mu.EnrollTOTP(c.Request().Context(), uint(userId.(float64)))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, enrollmentRes)
+}
+
+func (mc *mfaController) ConfirmTOTP(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+	req := model.MFACodeRequest{}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	codesRes, err := mc.mu.ConfirmTOTP(c.Request().Context(), uint(userId.(float64)), req.Code)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, codesRes)
+}
+
+func (mc *mfaController) DisableTOTP(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+	req := model.PasswordConfirmRequest{}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := mc.mu.DisableTOTP(c.Request().Context(), uint(userId.(float64)), req.Password); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
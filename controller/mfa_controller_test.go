@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnrollTOTP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/mfa/totp", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockUsecase := newMockMFAUsecase()
+	mockUsecase.(*mockMFAUsecase).
+		On("EnrollTOTP", uint(1)).
+		Return(model.TOTPEnrollmentResponse{Secret: "JBSWY3DPEHPK3PXP", URI: "otpauth://totp/x"}, nil)
+	controller := NewMFAController(mockUsecase)
+
+	handle(mockContext, controller.EnrollTOTP)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.JSONEq(t, `{"secret":"JBSWY3DPEHPK3PXP","otpauth_uri":"otpauth://totp/x"}`, rec.Body.String())
+	mockUsecase.(*mockMFAUsecase).AssertExpectations(t)
+}
+
+func TestConfirmTOTP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/mfa/totp/confirm", strings.NewReader(`{"code":"123456"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockUsecase := newMockMFAUsecase()
+	mockUsecase.(*mockMFAUsecase).
+		On("ConfirmTOTP", uint(1), "123456").
+		Return(model.RecoveryCodesResponse{Codes: []string{"aaaa-bbbb-cccc-dddd"}}, nil)
+	controller := NewMFAController(mockUsecase)
+
+	handle(mockContext, controller.ConfirmTOTP)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"recovery_codes":["aaaa-bbbb-cccc-dddd"]}`, rec.Body.String())
+	mockUsecase.(*mockMFAUsecase).AssertExpectations(t)
+}
+
+func TestDisableTOTP_WrongPassword(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/mfa/totp/disable", strings.NewReader(`{"password":"wrong"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockUsecase := newMockMFAUsecase()
+	mockUsecase.(*mockMFAUsecase).
+		On("DisableTOTP", uint(1), "wrong").
+		Return(apperror.New(apperror.ErrUnauthorized, "invalid password"))
+	controller := NewMFAController(mockUsecase)
+
+	handle(mockContext, controller.DisableTOTP)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	mockUsecase.(*mockMFAUsecase).AssertExpectations(t)
+}
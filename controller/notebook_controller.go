@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"echo-rest-api/model"
+	"echo-rest-api/usecase"
+	"net/http"
+	"strconv"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+)
+
+type INotebookController interface {
+	GetAllNotebooks(c echo.Context) error
+	GetNotebookById(c echo.Context) error
+	CreateNotebook(c echo.Context) error
+	UpdateNotebook(c echo.Context) error
+	DeleteNotebook(c echo.Context) error
+}
+
+type notebookController struct {
+	nu usecase.INotebookUsecase
+}
+
+func NewNotebookController(nu usecase.INotebookUsecase) INotebookController {
+	return &notebookController{nu}
+}
+
+func (nc *notebookController) GetAllNotebooks(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+	notebookRes, err := nc.nu.GetAllNotebooks(c.Request().Context(), uint(userId.(float64)))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, notebookRes)
+}
+
+func (nc *notebookController) GetNotebookById(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+	id := c.Param("notebookId")
+	notebookId, _ := strconv.Atoi(id)
+	notebookRes, err := nc.nu.GetNotebookById(c.Request().Context(), uint(userId.(float64)), uint(notebookId))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, notebookRes)
+}
+
+func (nc *notebookController) CreateNotebook(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+
+	notebook := model.Notebook{}
+	if err := c.Bind(&notebook); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	notebook.UserId = uint(userId.(float64))
+	notebookRes, err := nc.nu.CreateNotebook(c.Request().Context(), notebook)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, notebookRes)
+}
+
+func (nc *notebookController) UpdateNotebook(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+	id := c.Param("notebookId")
+	notebookId, _ := strconv.Atoi(id)
+
+	notebook := model.Notebook{}
+	if err := c.Bind(&notebook); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	notebookRes, err := nc.nu.UpdateNotebook(c.Request().Context(), notebook, uint(userId.(float64)), uint(notebookId))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, notebookRes)
+}
+
+func (nc *notebookController) DeleteNotebook(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+	id := c.Param("notebookId")
+	notebookId, _ := strconv.Atoi(id)
+
+	err := nc.nu.DeleteNotebook(c.Request().Context(), uint(userId.(float64)), uint(notebookId), c.QueryParam("on_delete"))
+	if err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"bytes"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetAllNotebooks(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/notebooks", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	parentId := uint(1)
+	notebookResponse := []model.NotebookResponse{
+		{ID: 1, Name: "work"},
+		{ID: 2, Name: "project", ParentId: &parentId},
+	}
+	mockUsecase := newMockNotebookUsecase()
+	mockUsecase.(*mockNotebookUsecase).
+		On("GetAllNotebooks", uint(1)).
+		Return(notebookResponse, nil)
+	controller := NewNotebookController(mockUsecase)
+
+	handle(mockContext, controller.GetAllNotebooks)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	notebookJSON, err := json.Marshal(notebookResponse)
+	assert.Nil(t, err)
+	assert.JSONEq(t, string(notebookJSON), rec.Body.String())
+	mockUsecase.(*mockNotebookUsecase).AssertExpectations(t)
+}
+
+func TestCreateNotebook(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/notebooks", bytes.NewBufferString(`{"name":"project","parent_id":1}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	parentId := uint(1)
+	notebookResponse := model.NotebookResponse{ID: 2, Name: "project", ParentId: &parentId}
+	mockUsecase := newMockNotebookUsecase()
+	mockUsecase.(*mockNotebookUsecase).
+		On("CreateNotebook", mock.MatchedBy(func(notebook model.Notebook) bool {
+			return notebook.Name == "project" && *notebook.ParentId == parentId && notebook.UserId == 1
+		})).
+		Return(notebookResponse, nil)
+	controller := NewNotebookController(mockUsecase)
+
+	handle(mockContext, controller.CreateNotebook)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	mockUsecase.(*mockNotebookUsecase).AssertExpectations(t)
+}
+
+func TestDeleteNotebook(t *testing.T) {
+	req := httptest.NewRequest(http.MethodDelete, "/notebooks/2?on_delete=reparent", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockContext.SetPath("/notebooks/:notebookId")
+	mockContext.SetParamNames("notebookId")
+	mockContext.SetParamValues("2")
+	mockUsecase := newMockNotebookUsecase()
+	mockUsecase.(*mockNotebookUsecase).
+		On("DeleteNotebook", uint(1), uint(2), model.NotebookDeleteReparent).
+		Return(nil)
+	controller := NewNotebookController(mockUsecase)
+
+	handle(mockContext, controller.DeleteNotebook)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	mockUsecase.(*mockNotebookUsecase).AssertExpectations(t)
+}
+
+func TestDeleteNotebook_NotEmpty(t *testing.T) {
+	req := httptest.NewRequest(http.MethodDelete, "/notebooks/2", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockContext.SetPath("/notebooks/:notebookId")
+	mockContext.SetParamNames("notebookId")
+	mockContext.SetParamValues("2")
+	mockUsecase := newMockNotebookUsecase()
+	mockUsecase.(*mockNotebookUsecase).
+		On("DeleteNotebook", uint(1), uint(2), "").
+		Return(apperror.New(apperror.ErrConflict, "notebook is not empty"))
+	controller := NewNotebookController(mockUsecase)
+
+	handle(mockContext, controller.DeleteNotebook)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	mockUsecase.(*mockNotebookUsecase).AssertExpectations(t)
+}
@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"echo-rest-api/usecase"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const oidcStateCookie = "oidc_state"
+
+type IOIDCController interface {
+	Login(c echo.Context) error
+	Callback(c echo.Context) error
+}
+
+type oidcController struct {
+	ou usecase.IOIDCUsecase
+	tu usecase.ITokenUsecase
+	// successURL is where the browser goes after a login, or with mfa=required
+	// added when the login needs a second factor. Without it the callback
+	// answers like Login does.
+	successURL string
+	cc         CookieConfig
+}
+
+func NewOIDCController(ou usecase.IOIDCUsecase, tu usecase.ITokenUsecase, successURL string, cc CookieConfig) IOIDCController {
+	return &oidcController{ou, tu, successURL, cc}
+}
+
+func (oc *oidcController) Login(c echo.Context) error {
+	authorization, err := oc.ou.BeginLogin(c.Request().Context(), c.Param("provider"))
+	if err != nil {
+		return err
+	}
+	c.SetCookie(newAuthCookie(oc.cc, oidcStateCookie, authorization.StateToken, authorization.ExpiresAt))
+	return c.Redirect(http.StatusFound, authorization.AuthURL)
+}
+
+func (oc *oidcController) Callback(c echo.Context) error {
+	cookie, err := c.Cookie(oidcStateCookie)
+	// A state is good for one attempt.
+	c.SetCookie(newAuthCookie(oc.cc, oidcStateCookie, "", time.Now()))
+	if providerErr := c.QueryParam("error"); providerErr != "" {
+		return apperror.New(apperror.ErrUnauthorized, "identity provider returned "+providerErr)
+	}
+	if err != nil || cookie.Value == "" {
+		return apperror.New(apperror.ErrUnauthorized, "missing oidc state")
+	}
+	loginRes, err := oc.ou.CompleteLogin(c.Request().Context(), c.Param("provider"), c.QueryParam("code"), c.QueryParam("state"), cookie.Value)
+	if err != nil {
+		return err
+	}
+	if loginRes.MFARequired {
+		if oc.successURL == "" {
+			return c.JSON(http.StatusOK, loginRes)
+		}
+		return oc.redirectToMFA(c, loginRes)
+	}
+	if err := issueTokenCookies(c, oc.tu, oc.cc, loginRes.ID); err != nil {
+		return err
+	}
+	if oc.successURL != "" {
+		return c.Redirect(http.StatusSeeOther, oc.successURL)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// redirectToMFA sends the browser to the frontend to ask for the second
+// factor. The challenge goes along in an HttpOnly cookie that LoginMFA reads,
+// rather than in the URL, where it would end up in the history and in logs.
+func (oc *oidcController) redirectToMFA(c echo.Context, loginRes model.LoginResponse) error {
+	target, err := url.Parse(oc.successURL)
+	if err != nil {
+		return err
+	}
+	query := target.Query()
+	query.Set("mfa", "required")
+	target.RawQuery = query.Encode()
+	expiresAt := time.Time{}
+	if loginRes.MFAExpiresAt != nil {
+		expiresAt = *loginRes.MFAExpiresAt
+	}
+	c.SetCookie(newAuthCookie(oc.cc, mfaTokenCookie, loginRes.MFAToken, expiresAt))
+	return c.Redirect(http.StatusSeeOther, target.String())
+}
@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestOIDCLogin(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/oidc/mock/login", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockContext.SetParamNames("provider")
+	mockContext.SetParamValues("mock")
+	mockUsecase := newMockOIDCUsecase()
+	mockUsecase.(*mockOIDCUsecase).
+		On("BeginLogin", "mock").
+		Return(model.OIDCAuthorization{AuthURL: "https://idp.example.com/authorize?state=s", StateToken: "stateToken", ExpiresAt: time.Now().Add(time.Minute)}, nil)
+	controller := NewOIDCController(mockUsecase, nil, "", CookieConfig{})
+
+	handle(mockContext, controller.Login)
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "https://idp.example.com/authorize?state=s", rec.Header().Get("Location"))
+	assert.Contains(t, rec.Header().Get("Set-Cookie"), "oidc_state=stateToken")
+	assert.Contains(t, rec.Header().Get("Set-Cookie"), "HttpOnly")
+}
+
+func TestOIDCCallback(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/oidc/mock/callback?code=c&state=s", nil)
+	req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: "stateToken"})
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockContext.SetParamNames("provider")
+	mockContext.SetParamValues("mock")
+	mockUsecase := newMockOIDCUsecase()
+	mockUsecase.(*mockOIDCUsecase).
+		On("CompleteLogin", "mock", "c", "s", "stateToken").
+		Return(model.LoginResponse{UserResponse: model.UserResponse{ID: 1}}, nil)
+	tokenUsecase := newMockTokenUsecase()
+	tokenUsecase.(*mockTokenUsecase).
+		On("IssueTokens", uint(1), mock.AnythingOfType("model.SessionClient")).
+		Return(model.TokenPair{AccessToken: "testToken", RefreshToken: "testRefreshToken"}, nil)
+	controller := NewOIDCController(mockUsecase, tokenUsecase, "http://localhost:3000/", CookieConfig{})
+
+	handle(mockContext, controller.Callback)
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "http://localhost:3000/", rec.Header().Get("Location"))
+	cookies := rec.Header().Values("Set-Cookie")
+	assert.Equal(t, 3, len(cookies))
+	assert.Contains(t, cookies[0], "oidc_state=;")
+	assert.Contains(t, cookies[1], "token=testToken")
+	assert.Contains(t, cookies[2], "refresh_token=testRefreshToken")
+	tokenUsecase.(*mockTokenUsecase).AssertExpectations(t)
+}
+
+func TestOIDCCallback_MFARequired(t *testing.T) {
+	expiresAt := time.Now().Add(5 * time.Minute)
+	for _, successURL := range []string{"", "http://localhost:3000/"} {
+		req := httptest.NewRequest(http.MethodGet, "/oidc/mock/callback?code=c&state=s", nil)
+		req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: "stateToken"})
+		rec := httptest.NewRecorder()
+		mockContext := createMockContext(req, rec)
+		mockContext.SetParamNames("provider")
+		mockContext.SetParamValues("mock")
+		mockUsecase := newMockOIDCUsecase()
+		mockUsecase.(*mockOIDCUsecase).
+			On("CompleteLogin", "mock", "c", "s", "stateToken").
+			Return(model.LoginResponse{MFARequired: true, MFAToken: "challenge", MFAExpiresAt: &expiresAt}, nil)
+		tokenUsecase := newMockTokenUsecase()
+		controller := NewOIDCController(mockUsecase, tokenUsecase, successURL, CookieConfig{})
+
+		handle(mockContext, controller.Callback)
+		cookies := rec.Header().Values("Set-Cookie")
+		if successURL == "" {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Contains(t, rec.Body.String(), `"mfa_token":"challenge"`)
+			assert.Equal(t, 1, len(cookies))
+		} else {
+			// The challenge stays out of the URL and out of reach of scripts.
+			assert.Equal(t, http.StatusSeeOther, rec.Code)
+			assert.Equal(t, "http://localhost:3000/?mfa=required", rec.Header().Get("Location"))
+			assert.NotContains(t, rec.Body.String(), "challenge")
+			assert.Equal(t, 2, len(cookies))
+			assert.Contains(t, cookies[1], "mfa_token=challenge")
+			assert.Contains(t, cookies[1], "HttpOnly")
+		}
+		tokenUsecase.(*mockTokenUsecase).AssertNotCalled(t, "IssueTokens")
+	}
+}
+
+func TestOIDCCallback_Error(t *testing.T) {
+	mockUsecase := newMockOIDCUsecase()
+	mockUsecase.(*mockOIDCUsecase).
+		On("CompleteLogin", "mock", "c", "s", "stateToken").
+		Return(nil, apperror.New(apperror.ErrUnauthorized, "invalid oidc state"))
+	controller := NewOIDCController(mockUsecase, nil, "", CookieConfig{})
+
+	for _, target := range []string{"/oidc/mock/callback?code=c&state=s", "/oidc/mock/callback?error=access_denied"} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: "stateToken"})
+		rec := httptest.NewRecorder()
+		mockContext := createMockContext(req, rec)
+		mockContext.SetParamNames("provider")
+		mockContext.SetParamValues("mock")
+		handle(mockContext, controller.Callback)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, target)
+		assert.Contains(t, rec.Header().Get("Set-Cookie"), "oidc_state=;")
+	}
+	mockUsecase.(*mockOIDCUsecase).AssertNumberOfCalls(t, "CompleteLogin", 1)
+
+	req := httptest.NewRequest(http.MethodGet, "/oidc/mock/callback?code=c&state=s", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	handle(mockContext, controller.Callback)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), "missing oidc state")
+}
@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"echo-rest-api/model"
+	"echo-rest-api/usecase"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type IPasswordController interface {
+	ForgotPassword(c echo.Context) error
+	ResetPassword(c echo.Context) error
+}
+
+type passwordController struct {
+	pu usecase.IPasswordUsecase
+	cc CookieConfig
+}
+
+func NewPasswordController(pu usecase.IPasswordUsecase, cc CookieConfig) IPasswordController {
+	return &passwordController{pu, cc}
+}
+
+// ForgotPassword answers 202 for every well-formed request so that it cannot
+// be used to find out which emails are registered.
+func (pc *passwordController) ForgotPassword(c echo.Context) error {
+	req := model.PasswordForgotRequest{}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := pc.pu.ForgotPassword(c.Request().Context(), req.Email); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusAccepted)
+}
+
+func (pc *passwordController) ResetPassword(c echo.Context) error {
+	req := model.PasswordResetRequest{}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := pc.pu.ResetPassword(c.Request().Context(), req.Token, req.Password); err != nil {
+		return err
+	}
+	clearTokenCookies(c, pc.cc)
+	return c.NoContent(http.StatusNoContent)
+}
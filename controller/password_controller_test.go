@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"echo-rest-api/apperror"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForgotPassword(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/password/forgot", strings.NewReader(`{"email":"user@example.com"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockUsecase := newMockPasswordUsecase()
+	mockUsecase.(*mockPasswordUsecase).
+		On("ForgotPassword", "user@example.com").
+		Return(nil)
+	controller := NewPasswordController(mockUsecase, CookieConfig{})
+
+	handle(mockContext, controller.ForgotPassword)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Empty(t, rec.Body.String())
+	mockUsecase.(*mockPasswordUsecase).AssertExpectations(t)
+}
+
+func TestResetPassword(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/password/reset", strings.NewReader(`{"token":"reset","password":"new password"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockUsecase := newMockPasswordUsecase()
+	mockUsecase.(*mockPasswordUsecase).
+		On("ResetPassword", "reset", "new password").
+		Return(nil)
+	controller := NewPasswordController(mockUsecase, CookieConfig{})
+
+	handle(mockContext, controller.ResetPassword)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Contains(t, rec.Header().Values("Set-Cookie")[0], "token=;")
+	mockUsecase.(*mockPasswordUsecase).AssertExpectations(t)
+}
+
+func TestResetPassword_InvalidToken(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/password/reset", strings.NewReader(`{"token":"used","password":"new password"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockUsecase := newMockPasswordUsecase()
+	mockUsecase.(*mockPasswordUsecase).
+		On("ResetPassword", "used", "new password").
+		Return(apperror.New(apperror.ErrUnauthorized, "invalid password reset token"))
+	controller := NewPasswordController(mockUsecase, CookieConfig{})
+
+	handle(mockContext, controller.ResetPassword)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), "invalid password reset token")
+	mockUsecase.(*mockPasswordUsecase).AssertExpectations(t)
+}
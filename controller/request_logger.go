@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"echo-rest-api/logging"
+	"log/slog"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+)
+
+// RequestLogger gives every request a logger tagged with its request id,
+// which must be installed after the RequestID middleware, and writes an
+// access log entry once the request has been answered.
+func RequestLogger(logger *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			req := c.Request()
+			reqLogger := logger.With("request_id", c.Response().Header().Get(echo.HeaderXRequestID))
+			c.SetRequest(req.WithContext(logging.WithContext(req.Context(), reqLogger)))
+			if err := next(c); err != nil {
+				// Answer here so that the entry has the final status.
+				c.Error(err)
+			}
+			res := c.Response()
+			attrs := []any{
+				"method", req.Method,
+				"route", c.Path(),
+				"path", req.URL.Path,
+				"status", res.Status,
+				"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+				"bytes_out", res.Size,
+				"remote_ip", c.RealIP(),
+			}
+			if user, ok := c.Get("user").(*jwt.Token); ok {
+				if claims, ok := user.Claims.(jwt.MapClaims); ok {
+					if userId, ok := claims["user_id"].(float64); ok {
+						attrs = append(attrs, "user_id", uint(userId))
+					}
+				}
+			}
+			level := slog.LevelInfo
+			if res.Status >= 500 {
+				level = slog.LevelError
+			}
+			reqLogger.Log(req.Context(), level, "request", attrs...)
+			return nil
+		}
+	}
+}
+
+// requestLogger returns the logger RequestLogger scoped to the request.
+func requestLogger(c echo.Context) *slog.Logger {
+	return logging.FromContext(c.Request().Context())
+}
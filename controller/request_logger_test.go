@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"bytes"
+	"echo-rest-api/apperror"
+	"echo-rest-api/logging"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func newLoggedEcho(buf *bytes.Buffer) *echo.Echo {
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Use(middleware.RequestID())
+	e.Use(RequestLogger(logging.New(buf, slog.LevelInfo)))
+	return e
+}
+
+func lastRecord(t *testing.T, buf *bytes.Buffer) map[string]any {
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	record := map[string]any{}
+	assert.Nil(t, json.Unmarshal([]byte(lines[len(lines)-1]), &record))
+	return record
+}
+
+func TestRequestLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	e := newLoggedEcho(buf)
+	e.GET("/memos/:memoId", func(c echo.Context) error {
+		c.Set("user", &jwt.Token{Claims: jwt.MapClaims{"user_id": float64(7)}})
+		requestLogger(c).Info("handling")
+		return c.String(http.StatusOK, "ok")
+	})
+	req := httptest.NewRequest(http.MethodGet, "/memos/1", nil)
+	req.Header.Set(echo.HeaderXRequestID, "incoming-id")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, "incoming-id", rec.Header().Get(echo.HeaderXRequestID))
+	assert.Contains(t, buf.String(), `"msg":"handling","request_id":"incoming-id"`)
+	record := lastRecord(t, buf)
+	assert.Equal(t, "INFO", record["level"])
+	assert.Equal(t, "request", record["msg"])
+	assert.Equal(t, "incoming-id", record["request_id"])
+	assert.Equal(t, "GET", record["method"])
+	assert.Equal(t, "/memos/:memoId", record["route"])
+	assert.Equal(t, "/memos/1", record["path"])
+	assert.Equal(t, float64(http.StatusOK), record["status"])
+	assert.Equal(t, float64(7), record["user_id"])
+	assert.Contains(t, record, "latency_ms")
+}
+
+func TestRequestLogger_Error(t *testing.T) {
+	buf := &bytes.Buffer{}
+	e := newLoggedEcho(buf)
+	e.GET("/memos/:memoId", func(c echo.Context) error {
+		return apperror.New(apperror.ErrNotFound, "memo not found")
+	})
+	e.GET("/broken", func(c echo.Context) error {
+		return assert.AnError
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/memos/1", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	record := lastRecord(t, buf)
+	assert.Equal(t, "INFO", record["level"])
+	assert.Equal(t, float64(http.StatusNotFound), record["status"])
+	assert.NotEmpty(t, record["request_id"])
+	assert.NotContains(t, record, "user_id")
+
+	buf.Reset()
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/broken", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, buf.String(), `"msg":"request failed"`)
+	record = lastRecord(t, buf)
+	assert.Equal(t, "ERROR", record["level"])
+	assert.Equal(t, float64(http.StatusInternalServerError), record["status"])
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"echo-rest-api/usecase"
+	"net/http"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+)
+
+type ISessionController interface {
+	GetSessions(c echo.Context) error
+	RevokeSession(c echo.Context) error
+	LogoutAll(c echo.Context) error
+	RequireSession(next echo.HandlerFunc) echo.HandlerFunc
+}
+
+type sessionController struct {
+	su usecase.ISessionUsecase
+	cc CookieConfig
+}
+
+func NewSessionController(su usecase.ISessionUsecase, cc CookieConfig) ISessionController {
+	return &sessionController{su, cc}
+}
+
+func (sc *sessionController) GetSessions(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+	sessionId, _ := claims["jti"].(string)
+	sessionRes, err := sc.su.GetSessions(c.Request().Context(), uint(userId.(float64)), sessionId)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, sessionRes)
+}
+
+func (sc *sessionController) RevokeSession(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+	sessionId := c.Param("sessionId")
+
+	if err := sc.su.RevokeSession(c.Request().Context(), uint(userId.(float64)), sessionId); err != nil {
+		return err
+	}
+	if current, _ := claims["jti"].(string); current == sessionId {
+		clearTokenCookies(c, sc.cc)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (sc *sessionController) LogoutAll(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+
+	if err := sc.su.RevokeAllSessions(c.Request().Context(), uint(userId.(float64))); err != nil {
+		return err
+	}
+	clearTokenCookies(c, sc.cc)
+	return c.NoContent(http.StatusOK)
+}
+
+// RequireSession runs after the JWT middleware and rejects access tokens whose
+// session (the jti claim) has been revoked or has expired. Personal access
+// tokens have no session and are let through.
+func (sc *sessionController) RequireSession(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if AuthenticatedByAccessToken(c) {
+			return next(c)
+		}
+		user := c.Get("user").(*jwt.Token)
+		claims := user.Claims.(jwt.MapClaims)
+		userId := claims["user_id"]
+		sessionId, _ := claims["jti"].(string)
+		if err := sc.su.VerifySession(c.Request().Context(), uint(userId.(float64)), sessionId); err != nil {
+			return err
+		}
+		return next(c)
+	}
+}
@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSessions(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/sessions", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	sessionResponse := []model.SessionResponse{
+		{ID: "session1", UserAgent: "firefox", IP: "192.0.2.1", Current: true},
+	}
+	mockUsecase := newMockSessionUsecase()
+	mockUsecase.(*mockSessionUsecase).
+		On("GetSessions", uint(1), "session1").
+		Return(sessionResponse, nil)
+	controller := NewSessionController(mockUsecase, CookieConfig{})
+
+	handle(mockContext, controller.GetSessions)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	sessionJSON, err := json.Marshal(sessionResponse)
+	assert.Nil(t, err)
+	assert.JSONEq(t, string(sessionJSON), rec.Body.String())
+	mockUsecase.(*mockSessionUsecase).AssertExpectations(t)
+}
+
+func TestRevokeSession(t *testing.T) {
+	req := httptest.NewRequest(http.MethodDelete, "/sessions/session2", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockContext.SetPath("/sessions/:sessionId")
+	mockContext.SetParamNames("sessionId")
+	mockContext.SetParamValues("session2")
+	mockUsecase := newMockSessionUsecase()
+	mockUsecase.(*mockSessionUsecase).
+		On("RevokeSession", uint(1), "session2").
+		Return(nil)
+	controller := NewSessionController(mockUsecase, CookieConfig{})
+
+	handle(mockContext, controller.RevokeSession)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, rec.Header().Get("Set-Cookie"))
+	mockUsecase.(*mockSessionUsecase).AssertExpectations(t)
+}
+
+func TestLogoutAll(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/logout-all", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockUsecase := newMockSessionUsecase()
+	mockUsecase.(*mockSessionUsecase).
+		On("RevokeAllSessions", uint(1)).
+		Return(nil)
+	controller := NewSessionController(mockUsecase, CookieConfig{})
+
+	handle(mockContext, controller.LogoutAll)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Values("Set-Cookie")[0], "token=;")
+	mockUsecase.(*mockSessionUsecase).AssertExpectations(t)
+}
+
+func TestRequireSession(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/memos", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockUsecase := newMockSessionUsecase()
+	mockUsecase.(*mockSessionUsecase).
+		On("VerifySession", uint(1), "session1").
+		Return(apperror.New(apperror.ErrUnauthorized, "session has been revoked"))
+	controller := NewSessionController(mockUsecase, CookieConfig{})
+	called := false
+
+	handle(mockContext, controller.RequireSession(func(c echo.Context) error {
+		called = true
+		return nil
+	}))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.False(t, called)
+	mockUsecase.(*mockSessionUsecase).AssertExpectations(t)
+}
+
+func TestRequireSession_AccessToken(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/memos", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockContext.Set(accessTokenKey, model.AccessToken{ID: 1, UserId: 1})
+	mockUsecase := newMockSessionUsecase()
+	controller := NewSessionController(mockUsecase, CookieConfig{})
+	called := false
+
+	handle(mockContext, controller.RequireSession(func(c echo.Context) error {
+		called = true
+		return nil
+	}))
+	assert.True(t, called)
+	mockUsecase.(*mockSessionUsecase).AssertNotCalled(t, "VerifySession")
+}
@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"echo-rest-api/model"
+	"echo-rest-api/usecase"
+	"net/http"
+	"strconv"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+)
+
+type ITagController interface {
+	GetAllTags(c echo.Context) error
+	RenameTag(c echo.Context) error
+	MergeTags(c echo.Context) error
+}
+
+type tagController struct {
+	tu usecase.ITagUsecase
+}
+
+func NewTagController(tu usecase.ITagUsecase) ITagController {
+	return &tagController{tu}
+}
+
+func (tc *tagController) GetAllTags(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+	tagRes, err := tc.tu.GetAllTags(c.Request().Context(), uint(userId.(float64)))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, tagRes)
+}
+
+func (tc *tagController) RenameTag(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+	id := c.Param("tagId")
+	tagId, _ := strconv.Atoi(id)
+
+	tag := model.Tag{}
+	if err := c.Bind(&tag); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	tagRes, err := tc.tu.RenameTag(c.Request().Context(), tag, uint(userId.(float64)), uint(tagId))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, tagRes)
+}
+
+func (tc *tagController) MergeTags(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+	id := c.Param("tagId")
+	tagId, _ := strconv.Atoi(id)
+
+	merge := model.TagMerge{}
+	if err := c.Bind(&merge); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	tagRes, err := tc.tu.MergeTags(c.Request().Context(), uint(userId.(float64)), uint(tagId), merge.TargetId)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, tagRes)
+}
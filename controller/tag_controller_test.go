@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"bytes"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetAllTags(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/tags", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	tagResponse := []model.TagResponse{
+		{ID: 1, Name: "idea", MemoCount: 2},
+		{ID: 2, Name: "work", MemoCount: 1},
+	}
+	mockUsecase := newMockTagUsecase()
+	mockUsecase.(*mockTagUsecase).
+		On("GetAllTags", uint(1)).
+		Return(tagResponse, nil)
+	controller := NewTagController(mockUsecase)
+
+	handle(mockContext, controller.GetAllTags)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	tagJSON, err := json.Marshal(tagResponse)
+	assert.Nil(t, err)
+	assert.JSONEq(t, string(tagJSON), rec.Body.String())
+	mockUsecase.(*mockTagUsecase).AssertExpectations(t)
+}
+
+func TestGetAllTags_Error(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/tags", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockUsecase := newMockTagUsecase()
+	mockUsecase.(*mockTagUsecase).
+		On("GetAllTags", uint(1)).
+		Return(nil, errors.New("error"))
+	controller := NewTagController(mockUsecase)
+
+	handle(mockContext, controller.GetAllTags)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	mockUsecase.(*mockTagUsecase).AssertExpectations(t)
+}
+
+func TestRenameTag(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/tags/2", bytes.NewBufferString(`{"name":"renamed"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockContext.SetPath("/tags/:tagId")
+	mockContext.SetParamNames("tagId")
+	mockContext.SetParamValues("2")
+	tagResponse := model.TagResponse{ID: 2, Name: "renamed", MemoCount: 1}
+	mockUsecase := newMockTagUsecase()
+	mockUsecase.(*mockTagUsecase).
+		On("RenameTag", mock.MatchedBy(func(tag model.Tag) bool { return tag.Name == "renamed" }), uint(1), uint(2)).
+		Return(tagResponse, nil)
+	controller := NewTagController(mockUsecase)
+
+	handle(mockContext, controller.RenameTag)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	tagJSON, err := json.Marshal(tagResponse)
+	assert.Nil(t, err)
+	assert.JSONEq(t, string(tagJSON), rec.Body.String())
+	mockUsecase.(*mockTagUsecase).AssertExpectations(t)
+}
+
+func TestRenameTag_Conflict(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/tags/2", bytes.NewBufferString(`{"name":"work"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockContext.SetPath("/tags/:tagId")
+	mockContext.SetParamNames("tagId")
+	mockContext.SetParamValues("2")
+	mockUsecase := newMockTagUsecase()
+	mockUsecase.(*mockTagUsecase).
+		On("RenameTag", mock.Anything, uint(1), uint(2)).
+		Return(nil, apperror.New(apperror.ErrConflict, "tag name already exists"))
+	controller := NewTagController(mockUsecase)
+
+	handle(mockContext, controller.RenameTag)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	mockUsecase.(*mockTagUsecase).AssertExpectations(t)
+}
+
+func TestMergeTags(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/tags/2/merge", bytes.NewBufferString(`{"target_id":1}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockContext.SetPath("/tags/:tagId/merge")
+	mockContext.SetParamNames("tagId")
+	mockContext.SetParamValues("2")
+	tagResponse := model.TagResponse{ID: 1, Name: "work", MemoCount: 3}
+	mockUsecase := newMockTagUsecase()
+	mockUsecase.(*mockTagUsecase).
+		On("MergeTags", uint(1), uint(2), uint(1)).
+		Return(tagResponse, nil)
+	controller := NewTagController(mockUsecase)
+
+	handle(mockContext, controller.MergeTags)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	tagJSON, err := json.Marshal(tagResponse)
+	assert.Nil(t, err)
+	assert.JSONEq(t, string(tagJSON), rec.Body.String())
+	mockUsecase.(*mockTagUsecase).AssertExpectations(t)
+}
+
+func TestMergeTags_Same(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/tags/2/merge", bytes.NewBufferString(`{"target_id":2}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	mockContext.SetPath("/tags/:tagId/merge")
+	mockContext.SetParamNames("tagId")
+	mockContext.SetParamValues("2")
+	mockUsecase := newMockTagUsecase()
+	mockUsecase.(*mockTagUsecase).
+		On("MergeTags", uint(1), uint(2), uint(2)).
+		Return(nil, apperror.New(apperror.ErrValidation, "cannot merge a tag into itself"))
+	controller := NewTagController(mockUsecase)
+
+	handle(mockContext, controller.MergeTags)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	mockUsecase.(*mockTagUsecase).AssertExpectations(t)
+}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"context"
 	"echo-rest-api/model"
 	"echo-rest-api/usecase"
 	"net/http"
@@ -14,17 +15,25 @@ import (
 
 func createMockContext(req *http.Request, rec *httptest.ResponseRecorder) echo.Context {
 	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
 	mockContext := e.NewContext(req, rec)
 	mockContext.Set("csrf", "test_csrf_token")
 	mockContext.Set("user", &jwt.Token{
 		Claims: jwt.MapClaims{
 			"user_id": float64(1),
+			"jti":     "session1",
 			"exp":     time.Now().Add(time.Hour).Unix(),
 		},
 	})
 	return mockContext
 }
 
+func handle(c echo.Context, h echo.HandlerFunc) {
+	if err := h(c); err != nil {
+		c.Echo().HTTPErrorHandler(err, c)
+	}
+}
+
 type mockMemoUsecase struct {
 	mock.Mock
 }
@@ -33,15 +42,15 @@ func newMockMemoUsecase() usecase.IMemoUsecase {
 	return &mockMemoUsecase{}
 }
 
-func (m *mockMemoUsecase) GetAllMemos(userId uint) ([]model.MemoResponse, error) {
-	args := m.Called(userId)
-	if memoArg, ok := args.Get(0).([]model.MemoResponse); ok && memoArg != nil {
+func (m *mockMemoUsecase) GetAllMemos(ctx context.Context, userId uint, query model.MemoQuery) (model.MemoPageResponse, error) {
+	args := m.Called(userId, query)
+	if memoArg, ok := args.Get(0).(model.MemoPageResponse); ok {
 		return memoArg, nil
 	}
-	return nil, args.Error(1)
+	return model.MemoPageResponse{}, args.Error(1)
 }
 
-func (m *mockMemoUsecase) GetMemoById(userId uint, memoId uint) (model.MemoResponse, error) {
+func (m *mockMemoUsecase) GetMemoById(ctx context.Context, userId uint, memoId uint) (model.MemoResponse, error) {
 	args := m.Called(userId, memoId)
 	if memoArg, ok := args.Get(0).(model.MemoResponse); ok {
 		return memoArg, nil
@@ -49,7 +58,15 @@ func (m *mockMemoUsecase) GetMemoById(userId uint, memoId uint) (model.MemoRespo
 	return model.MemoResponse{}, args.Error(1)
 }
 
-func (m *mockMemoUsecase) CreateMemo(memo model.Memo) (model.MemoResponse, error) {
+func (m *mockMemoUsecase) SearchMemos(ctx context.Context, userId uint, query model.MemoSearchQuery) ([]model.MemoSearchResponse, error) {
+	args := m.Called(userId, query)
+	if memoArg, ok := args.Get(0).([]model.MemoSearchResponse); ok && memoArg != nil {
+		return memoArg, nil
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockMemoUsecase) CreateMemo(ctx context.Context, memo model.Memo) (model.MemoResponse, error) {
 	args := m.Called(memo)
 	if err, ok := args.Get(0).(error); ok && err != nil {
 		return model.MemoResponse{}, err
@@ -64,7 +81,7 @@ func (m *mockMemoUsecase) CreateMemo(memo model.Memo) (model.MemoResponse, error
 	return resMemo, nil
 }
 
-func (m *mockMemoUsecase) UpdateMemo(memo model.Memo, userId uint, memoId uint) (model.MemoResponse, error) {
+func (m *mockMemoUsecase) UpdateMemo(ctx context.Context, memo model.Memo, userId uint, memoId uint) (model.MemoResponse, error) {
 	args := m.Called(memo, userId, memoId)
 	if err, ok := args.Get(0).(error); ok && err != nil {
 		return model.MemoResponse{}, err
@@ -79,11 +96,77 @@ func (m *mockMemoUsecase) UpdateMemo(memo model.Memo, userId uint, memoId uint)
 	return resMemo, nil
 }
 
-func (m *mockMemoUsecase) DeleteMemo(userId uint, memoId uint) error {
+func (m *mockMemoUsecase) PatchMemo(ctx context.Context, patch model.MemoPatch, userId uint, memoId uint) (model.MemoResponse, error) {
+	args := m.Called(patch, userId, memoId)
+	if memoArg, ok := args.Get(0).(model.MemoResponse); ok {
+		return memoArg, nil
+	}
+	return model.MemoResponse{}, args.Error(1)
+}
+
+func (m *mockMemoUsecase) MoveMemo(ctx context.Context, userId uint, memoId uint, notebookId *uint) (model.MemoResponse, error) {
+	args := m.Called(userId, memoId, notebookId)
+	if memoArg, ok := args.Get(0).(model.MemoResponse); ok {
+		return memoArg, nil
+	}
+	return model.MemoResponse{}, args.Error(1)
+}
+
+func (m *mockMemoUsecase) DeleteMemo(ctx context.Context, userId uint, memoId uint) error {
 	args := m.Called(userId, memoId)
 	return args.Error(0)
 }
 
+func (m *mockMemoUsecase) GetTrashedMemos(ctx context.Context, userId uint) ([]model.MemoResponse, error) {
+	args := m.Called(userId)
+	if memoArg, ok := args.Get(0).([]model.MemoResponse); ok && memoArg != nil {
+		return memoArg, nil
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockMemoUsecase) RestoreMemo(ctx context.Context, userId uint, memoId uint) (model.MemoResponse, error) {
+	args := m.Called(userId, memoId)
+	if memoArg, ok := args.Get(0).(model.MemoResponse); ok {
+		return memoArg, nil
+	}
+	return model.MemoResponse{}, args.Error(1)
+}
+
+func (m *mockMemoUsecase) PurgeMemo(ctx context.Context, userId uint, memoId uint) error {
+	args := m.Called(userId, memoId)
+	return args.Error(0)
+}
+
+func (m *mockMemoUsecase) EmptyTrash(ctx context.Context, userId uint) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
+
+func (m *mockMemoUsecase) GetMemoRevisions(ctx context.Context, userId uint, memoId uint) ([]model.MemoRevisionResponse, error) {
+	args := m.Called(userId, memoId)
+	if revisionArg, ok := args.Get(0).([]model.MemoRevisionResponse); ok && revisionArg != nil {
+		return revisionArg, nil
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockMemoUsecase) GetMemoRevision(ctx context.Context, userId uint, memoId uint, rev uint) (model.MemoRevisionDiffResponse, error) {
+	args := m.Called(userId, memoId, rev)
+	if revisionArg, ok := args.Get(0).(model.MemoRevisionDiffResponse); ok {
+		return revisionArg, nil
+	}
+	return model.MemoRevisionDiffResponse{}, args.Error(1)
+}
+
+func (m *mockMemoUsecase) RestoreMemoRevision(ctx context.Context, userId uint, memoId uint, rev uint) (model.MemoResponse, error) {
+	args := m.Called(userId, memoId, rev)
+	if memoArg, ok := args.Get(0).(model.MemoResponse); ok {
+		return memoArg, nil
+	}
+	return model.MemoResponse{}, args.Error(1)
+}
+
 type mockUserUsecase struct {
 	mock.Mock
 }
@@ -92,15 +175,31 @@ func newMockUserUsecase() usecase.IUserUsecase {
 	return &mockUserUsecase{}
 }
 
-func (m *mockUserUsecase) Login(user model.User) (string, error) {
+func (m *mockUserUsecase) Login(ctx context.Context, user model.User) (model.LoginResponse, error) {
 	args := m.Called(user)
-	if tokenArg, ok := args.Get(0).(string); ok && tokenArg != "" {
-		return tokenArg, nil
+	if loginArg, ok := args.Get(0).(model.LoginResponse); ok {
+		return loginArg, nil
 	}
-	return "", args.Error(1)
+	return model.LoginResponse{}, args.Error(1)
 }
 
-func (m *mockUserUsecase) SignUp(user model.User) (model.UserResponse, error) {
+func (m *mockUserUsecase) ParseMFAChallenge(token string) (model.MFAChallenge, error) {
+	args := m.Called(token)
+	if challengeArg, ok := args.Get(0).(model.MFAChallenge); ok {
+		return challengeArg, nil
+	}
+	return model.MFAChallenge{}, args.Error(1)
+}
+
+func (m *mockUserUsecase) LoginMFA(ctx context.Context, token string, code string) (model.UserResponse, error) {
+	args := m.Called(token, code)
+	if userArg, ok := args.Get(0).(model.UserResponse); ok {
+		return userArg, nil
+	}
+	return model.UserResponse{}, args.Error(1)
+}
+
+func (m *mockUserUsecase) SignUp(ctx context.Context, user model.User) (model.UserResponse, error) {
 	args := m.Called(user)
 	if userArg, ok := args.Get(0).(model.UserResponse); ok {
 		resUser := model.UserResponse{
@@ -111,3 +210,371 @@ func (m *mockUserUsecase) SignUp(user model.User) (model.UserResponse, error) {
 	}
 	return model.UserResponse{}, args.Error(1)
 }
+
+type mockEmailVerificationUsecase struct {
+	mock.Mock
+}
+
+func newMockEmailVerificationUsecase() usecase.IEmailVerificationUsecase {
+	return &mockEmailVerificationUsecase{}
+}
+
+func (m *mockEmailVerificationUsecase) SendVerification(ctx context.Context, userId uint) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
+
+func (m *mockEmailVerificationUsecase) VerifyEmail(ctx context.Context, token string) (model.UserResponse, error) {
+	args := m.Called(token)
+	if userArg, ok := args.Get(0).(model.UserResponse); ok {
+		return userArg, nil
+	}
+	return model.UserResponse{}, args.Error(1)
+}
+
+func (m *mockEmailVerificationUsecase) CheckMemoWrite(ctx context.Context, userId uint, create bool) error {
+	args := m.Called(userId, create)
+	return args.Error(0)
+}
+
+type mockMFAUsecase struct {
+	mock.Mock
+}
+
+func newMockMFAUsecase() usecase.IMFAUsecase {
+	return &mockMFAUsecase{}
+}
+
+func (m *mockMFAUsecase) EnrollTOTP(ctx context.Context, userId uint) (model.TOTPEnrollmentResponse, error) {
+	args := m.Called(userId)
+	if enrollmentArg, ok := args.Get(0).(model.TOTPEnrollmentResponse); ok {
+		return enrollmentArg, nil
+	}
+	return model.TOTPEnrollmentResponse{}, args.Error(1)
+}
+
+func (m *mockMFAUsecase) ConfirmTOTP(ctx context.Context, userId uint, code string) (model.RecoveryCodesResponse, error) {
+	args := m.Called(userId, code)
+	if codesArg, ok := args.Get(0).(model.RecoveryCodesResponse); ok {
+		return codesArg, nil
+	}
+	return model.RecoveryCodesResponse{}, args.Error(1)
+}
+
+func (m *mockMFAUsecase) DisableTOTP(ctx context.Context, userId uint, password string) error {
+	args := m.Called(userId, password)
+	return args.Error(0)
+}
+
+type mockTokenUsecase struct {
+	mock.Mock
+}
+
+func newMockTokenUsecase() usecase.ITokenUsecase {
+	return &mockTokenUsecase{}
+}
+
+func (m *mockTokenUsecase) IssueTokens(ctx context.Context, userId uint, client model.SessionClient) (model.TokenPair, error) {
+	args := m.Called(userId, client)
+	if tokenArg, ok := args.Get(0).(model.TokenPair); ok {
+		return tokenArg, nil
+	}
+	return model.TokenPair{}, args.Error(1)
+}
+
+func (m *mockTokenUsecase) RefreshTokens(ctx context.Context, refreshToken string) (model.TokenPair, error) {
+	args := m.Called(refreshToken)
+	if tokenArg, ok := args.Get(0).(model.TokenPair); ok {
+		return tokenArg, nil
+	}
+	return model.TokenPair{}, args.Error(1)
+}
+
+func (m *mockTokenUsecase) RevokeTokens(ctx context.Context, refreshToken string) error {
+	args := m.Called(refreshToken)
+	return args.Error(0)
+}
+
+type mockSessionUsecase struct {
+	mock.Mock
+}
+
+func newMockSessionUsecase() usecase.ISessionUsecase {
+	return &mockSessionUsecase{}
+}
+
+func (m *mockSessionUsecase) GetSessions(ctx context.Context, userId uint, currentId string) ([]model.SessionResponse, error) {
+	args := m.Called(userId, currentId)
+	if sessionArg, ok := args.Get(0).([]model.SessionResponse); ok && sessionArg != nil {
+		return sessionArg, nil
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockSessionUsecase) VerifySession(ctx context.Context, userId uint, sessionId string) error {
+	args := m.Called(userId, sessionId)
+	return args.Error(0)
+}
+
+func (m *mockSessionUsecase) RevokeSession(ctx context.Context, userId uint, sessionId string) error {
+	args := m.Called(userId, sessionId)
+	return args.Error(0)
+}
+
+func (m *mockSessionUsecase) RevokeAllSessions(ctx context.Context, userId uint) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
+
+type mockTagUsecase struct {
+	mock.Mock
+}
+
+func newMockTagUsecase() usecase.ITagUsecase {
+	return &mockTagUsecase{}
+}
+
+func (m *mockTagUsecase) GetAllTags(ctx context.Context, userId uint) ([]model.TagResponse, error) {
+	args := m.Called(userId)
+	if tagArg, ok := args.Get(0).([]model.TagResponse); ok && tagArg != nil {
+		return tagArg, nil
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockTagUsecase) RenameTag(ctx context.Context, tag model.Tag, userId uint, tagId uint) (model.TagResponse, error) {
+	args := m.Called(tag, userId, tagId)
+	if tagArg, ok := args.Get(0).(model.TagResponse); ok {
+		return tagArg, nil
+	}
+	return model.TagResponse{}, args.Error(1)
+}
+
+func (m *mockTagUsecase) MergeTags(ctx context.Context, userId uint, sourceId uint, targetId uint) (model.TagResponse, error) {
+	args := m.Called(userId, sourceId, targetId)
+	if tagArg, ok := args.Get(0).(model.TagResponse); ok {
+		return tagArg, nil
+	}
+	return model.TagResponse{}, args.Error(1)
+}
+
+type mockNotebookUsecase struct {
+	mock.Mock
+}
+
+func newMockNotebookUsecase() usecase.INotebookUsecase {
+	return &mockNotebookUsecase{}
+}
+
+func (m *mockNotebookUsecase) GetAllNotebooks(ctx context.Context, userId uint) ([]model.NotebookResponse, error) {
+	args := m.Called(userId)
+	if notebookArg, ok := args.Get(0).([]model.NotebookResponse); ok && notebookArg != nil {
+		return notebookArg, nil
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockNotebookUsecase) GetNotebookById(ctx context.Context, userId uint, notebookId uint) (model.NotebookResponse, error) {
+	args := m.Called(userId, notebookId)
+	if notebookArg, ok := args.Get(0).(model.NotebookResponse); ok {
+		return notebookArg, nil
+	}
+	return model.NotebookResponse{}, args.Error(1)
+}
+
+func (m *mockNotebookUsecase) CreateNotebook(ctx context.Context, notebook model.Notebook) (model.NotebookResponse, error) {
+	args := m.Called(notebook)
+	if notebookArg, ok := args.Get(0).(model.NotebookResponse); ok {
+		return notebookArg, nil
+	}
+	return model.NotebookResponse{}, args.Error(1)
+}
+
+func (m *mockNotebookUsecase) UpdateNotebook(ctx context.Context, notebook model.Notebook, userId uint, notebookId uint) (model.NotebookResponse, error) {
+	args := m.Called(notebook, userId, notebookId)
+	if notebookArg, ok := args.Get(0).(model.NotebookResponse); ok {
+		return notebookArg, nil
+	}
+	return model.NotebookResponse{}, args.Error(1)
+}
+
+func (m *mockNotebookUsecase) DeleteNotebook(ctx context.Context, userId uint, notebookId uint, policy string) error {
+	args := m.Called(userId, notebookId, policy)
+	return args.Error(0)
+}
+
+type mockPasswordUsecase struct {
+	mock.Mock
+}
+
+func newMockPasswordUsecase() usecase.IPasswordUsecase {
+	return &mockPasswordUsecase{}
+}
+
+func (m *mockPasswordUsecase) ForgotPassword(ctx context.Context, email string) error {
+	args := m.Called(email)
+	return args.Error(0)
+}
+
+func (m *mockPasswordUsecase) ResetPassword(ctx context.Context, token string, password string) error {
+	args := m.Called(token, password)
+	return args.Error(0)
+}
+
+func (m *mockPasswordUsecase) Wait() {
+	m.Called()
+}
+
+type mockAccessTokenUsecase struct {
+	mock.Mock
+}
+
+func newMockAccessTokenUsecase() usecase.IAccessTokenUsecase {
+	return &mockAccessTokenUsecase{}
+}
+
+func (m *mockAccessTokenUsecase) GetAccessTokens(ctx context.Context, userId uint) ([]model.AccessTokenResponse, error) {
+	args := m.Called(userId)
+	if tokenArg, ok := args.Get(0).([]model.AccessTokenResponse); ok && tokenArg != nil {
+		return tokenArg, nil
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockAccessTokenUsecase) CreateAccessToken(ctx context.Context, req model.AccessTokenRequest, userId uint) (model.AccessTokenCreatedResponse, error) {
+	args := m.Called(req, userId)
+	if tokenArg, ok := args.Get(0).(model.AccessTokenCreatedResponse); ok {
+		return tokenArg, nil
+	}
+	return model.AccessTokenCreatedResponse{}, args.Error(1)
+}
+
+func (m *mockAccessTokenUsecase) RevokeAccessToken(ctx context.Context, userId uint, tokenId uint) error {
+	args := m.Called(userId, tokenId)
+	return args.Error(0)
+}
+
+func (m *mockAccessTokenUsecase) Authenticate(ctx context.Context, token string, scope string) (model.AccessToken, error) {
+	args := m.Called(token, scope)
+	if tokenArg, ok := args.Get(0).(model.AccessToken); ok {
+		return tokenArg, nil
+	}
+	return model.AccessToken{}, args.Error(1)
+}
+
+type mockOIDCUsecase struct {
+	mock.Mock
+}
+
+func newMockOIDCUsecase() usecase.IOIDCUsecase {
+	return &mockOIDCUsecase{}
+}
+
+func (m *mockOIDCUsecase) BeginLogin(ctx context.Context, provider string) (model.OIDCAuthorization, error) {
+	args := m.Called(provider)
+	if authorizationArg, ok := args.Get(0).(model.OIDCAuthorization); ok {
+		return authorizationArg, nil
+	}
+	return model.OIDCAuthorization{}, args.Error(1)
+}
+
+func (m *mockOIDCUsecase) CompleteLogin(ctx context.Context, provider string, code string, state string, stateToken string) (model.LoginResponse, error) {
+	args := m.Called(provider, code, state, stateToken)
+	if loginArg, ok := args.Get(0).(model.LoginResponse); ok {
+		return loginArg, nil
+	}
+	return model.LoginResponse{}, args.Error(1)
+}
+
+type mockLoginAttemptUsecase struct {
+	mock.Mock
+}
+
+func newMockLoginAttemptUsecase() usecase.ILoginAttemptUsecase {
+	return &mockLoginAttemptUsecase{}
+}
+
+func (m *mockLoginAttemptUsecase) CheckLogin(email string, ip string) error {
+	args := m.Called(email, ip)
+	return args.Error(0)
+}
+
+func (m *mockLoginAttemptUsecase) RecordFailure(email string, ip string) error {
+	args := m.Called(email, ip)
+	return args.Error(0)
+}
+
+func (m *mockLoginAttemptUsecase) RecordSuccess(email string, ip string) error {
+	args := m.Called(email, ip)
+	return args.Error(0)
+}
+
+func (m *mockLoginAttemptUsecase) CheckMFA(challenge model.MFAChallenge, ip string) error {
+	args := m.Called(challenge, ip)
+	return args.Error(0)
+}
+
+func (m *mockLoginAttemptUsecase) RecordMFAFailure(challenge model.MFAChallenge, ip string) error {
+	args := m.Called(challenge, ip)
+	return args.Error(0)
+}
+
+func (m *mockLoginAttemptUsecase) RecordMFASuccess(challenge model.MFAChallenge, ip string) error {
+	args := m.Called(challenge, ip)
+	return args.Error(0)
+}
+
+type mockAccountUsecase struct {
+	mock.Mock
+}
+
+func newMockAccountUsecase() usecase.IAccountUsecase {
+	return &mockAccountUsecase{}
+}
+
+func (m *mockAccountUsecase) ChangePassword(ctx context.Context, userId uint, sessionId string, currentPassword string, newPassword string) error {
+	args := m.Called(userId, sessionId, currentPassword, newPassword)
+	return args.Error(0)
+}
+
+func (m *mockAccountUsecase) ChangeEmail(ctx context.Context, userId uint, password string, email string) error {
+	args := m.Called(userId, password, email)
+	return args.Error(0)
+}
+
+func (m *mockAccountUsecase) DeleteAccount(ctx context.Context, userId uint, password string) (model.UserResponse, error) {
+	args := m.Called(userId, password)
+	if userArg, ok := args.Get(0).(model.UserResponse); ok {
+		return userArg, args.Error(1)
+	}
+	return model.UserResponse{}, args.Error(1)
+}
+
+func (m *mockAccountUsecase) CancelDeletion(ctx context.Context, userId uint) (model.UserResponse, error) {
+	args := m.Called(userId)
+	if userArg, ok := args.Get(0).(model.UserResponse); ok {
+		return userArg, args.Error(1)
+	}
+	return model.UserResponse{}, args.Error(1)
+}
+
+type mockHealthUsecase struct {
+	mock.Mock
+}
+
+func newMockHealthUsecase() usecase.IHealthUsecase {
+	return &mockHealthUsecase{}
+}
+
+func (m *mockHealthUsecase) Register(name string, check usecase.HealthChecker) {
+	m.Called(name, check)
+}
+
+func (m *mockHealthUsecase) Ready(ctx context.Context) model.HealthReport {
+	args := m.Called()
+	return args.Get(0).(model.HealthReport)
+}
+
+func (m *mockHealthUsecase) Drain() {
+	m.Called()
+}
@@ -1,10 +1,11 @@
 package controller
 
 import (
+	"echo-rest-api/apperror"
 	"echo-rest-api/model"
 	"echo-rest-api/usecase"
+	"errors"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -13,26 +14,37 @@ import (
 type IUserController interface {
 	SignUp(c echo.Context) error
 	Login(c echo.Context) error
+	LoginMFA(c echo.Context) error
 	Logout(c echo.Context) error
+	RefreshToken(c echo.Context) error
 	CsrfToken(c echo.Context) error
 }
 
 type userController struct {
 	uu usecase.IUserUsecase
+	tu usecase.ITokenUsecase
+	vu usecase.IEmailVerificationUsecase
+	au usecase.ILoginAttemptUsecase
+	cc CookieConfig
 }
 
-func NewUserController(uu usecase.IUserUsecase) IUserController {
-	return &userController{uu}
+func NewUserController(uu usecase.IUserUsecase, tu usecase.ITokenUsecase, vu usecase.IEmailVerificationUsecase, au usecase.ILoginAttemptUsecase, cc CookieConfig) IUserController {
+	return &userController{uu, tu, vu, au, cc}
 }
 
 func (uc *userController) SignUp(c echo.Context) error {
 	user := model.User{}
 	if err := c.Bind(&user); err != nil {
-		return c.JSON(http.StatusBadRequest, err.Error())
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
-	userResponse, err := uc.uu.SignUp(user)
+	userResponse, err := uc.uu.SignUp(c.Request().Context(), user)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
+	}
+	// The account exists at this point, so a failure to send the link is not
+	// reported to the client; it can ask for a new one.
+	if err := uc.vu.SendVerification(c.Request().Context(), userResponse.ID); err != nil {
+		requestLogger(c).Error("sending the verification link failed", "user_id", userResponse.ID, "error", err)
 	}
 	return c.JSON(http.StatusCreated, userResponse)
 }
@@ -40,44 +52,121 @@ func (uc *userController) SignUp(c echo.Context) error {
 func (uc *userController) Login(c echo.Context) error {
 	user := model.User{}
 	if err := c.Bind(&user); err != nil {
-		return c.JSON(http.StatusBadRequest, err.Error())
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := uc.au.CheckLogin(user.Email, c.RealIP()); err != nil {
+		return err
 	}
-	token, err := uc.uu.Login(user)
+	loginRes, err := uc.uu.Login(c.Request().Context(), user)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		uc.recordFailure(c, user.Email, err)
+		return err
 	}
-	cookie := new(http.Cookie)
-	cookie.Name = "token"
-	cookie.Value = token
-	cookie.Expires = time.Now().Add(24 * time.Hour)
-	cookie.Path = "/"
-	cookie.Domain = os.Getenv("API_DOMAIN")
-	cookie.HttpOnly = true
-	if os.Getenv("GO_ENV") == "production" {
-		cookie.Secure = true
-		cookie.SameSite = http.SameSiteNoneMode
-	} else {
-		cookie.SameSite = http.SameSiteDefaultMode
+	if err := uc.au.RecordSuccess(user.Email, c.RealIP()); err != nil {
+		requestLogger(c).Error("resetting login attempts failed", "error", err)
+	}
+	if loginRes.MFARequired {
+		return c.JSON(http.StatusOK, loginRes)
+	}
+	return uc.startSession(c, loginRes.ID)
+}
+
+func (uc *userController) LoginMFA(c echo.Context) error {
+	req := model.MFALoginRequest{}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	fromCookie := false
+	if cookie, err := c.Cookie(mfaTokenCookie); err == nil && req.Token == "" {
+		req.Token = cookie.Value
+		fromCookie = true
+	}
+	challenge, err := uc.uu.ParseMFAChallenge(req.Token)
+	if err != nil {
+		// A forged challenge counts against the IP address like a wrong
+		// password.
+		if err := uc.au.CheckLogin("", c.RealIP()); err != nil {
+			return err
+		}
+		uc.recordFailure(c, "", err)
+		return err
+	}
+	if err := uc.au.CheckMFA(challenge, c.RealIP()); err != nil {
+		return err
+	}
+	userRes, err := uc.uu.LoginMFA(c.Request().Context(), req.Token, req.Code)
+	if err != nil {
+		if errors.Is(err, apperror.ErrUnauthorized) {
+			if err := uc.au.RecordMFAFailure(challenge, c.RealIP()); err != nil {
+				requestLogger(c).Error("recording an MFA failure failed", "error", err)
+			}
+		}
+		return err
+	}
+	if err := uc.au.RecordMFASuccess(challenge, c.RealIP()); err != nil {
+		requestLogger(c).Error("resetting MFA attempts failed", "error", err)
+	}
+	if fromCookie {
+		c.SetCookie(newAuthCookie(uc.cc, mfaTokenCookie, "", time.Now()))
+	}
+	return uc.startSession(c, userRes.ID)
+}
+
+// recordFailure counts wrong credentials towards a lockout. Failing to count
+// them is logged rather than hiding the reason the login failed.
+func (uc *userController) recordFailure(c echo.Context, email string, err error) {
+	if !errors.Is(err, apperror.ErrUnauthorized) {
+		return
+	}
+	if err := uc.au.RecordFailure(email, c.RealIP()); err != nil {
+		requestLogger(c).Error("recording a login failure failed", "error", err)
+	}
+}
+
+func (uc *userController) startSession(c echo.Context, userId uint) error {
+	if err := issueTokenCookies(c, uc.tu, uc.cc, userId); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// issueTokenCookies starts a session for the client of c and sets the token
+// cookies, whichever way the user logged in.
+func issueTokenCookies(c echo.Context, tu usecase.ITokenUsecase, cc CookieConfig, userId uint) error {
+	tokens, err := tu.IssueTokens(c.Request().Context(), userId, model.SessionClient{
+		UserAgent: c.Request().UserAgent(),
+		IP:        c.RealIP(),
+	})
+	if err != nil {
+		return err
+	}
+	setTokenCookies(c, cc, tokens)
+	return nil
+}
+
+func (uc *userController) RefreshToken(c echo.Context) error {
+	cookie, err := c.Cookie(refreshTokenCookie)
+	if err != nil || cookie.Value == "" {
+		return apperror.New(apperror.ErrUnauthorized, "missing refresh token")
+	}
+	tokens, err := uc.tu.RefreshTokens(c.Request().Context(), cookie.Value)
+	if err != nil {
+		if errors.Is(err, apperror.ErrUnauthorized) {
+			clearTokenCookies(c, uc.cc)
+		}
+		return err
 	}
-	c.SetCookie(cookie)
+	setTokenCookies(c, uc.cc, tokens)
 	return c.NoContent(http.StatusOK)
 }
 
 func (uc *userController) Logout(c echo.Context) error {
-	cookie := new(http.Cookie)
-	cookie.Name = "token"
-	cookie.Value = ""
-	cookie.Expires = time.Now()
-	cookie.Path = "/"
-	cookie.Domain = os.Getenv("API_DOMAIN")
-	cookie.HttpOnly = true
-	if os.Getenv("GO_ENV") == "production" {
-		cookie.Secure = true
-		cookie.SameSite = http.SameSiteNoneMode
-	} else {
-		cookie.SameSite = http.SameSiteDefaultMode
+	if cookie, err := c.Cookie(refreshTokenCookie); err == nil && cookie.Value != "" {
+		if err := uc.tu.RevokeTokens(c.Request().Context(), cookie.Value); err != nil {
+			return err
+		}
 	}
-	c.SetCookie(cookie)
+	clearTokenCookies(c, uc.cc)
 	return c.NoContent(http.StatusOK)
 }
 
@@ -87,3 +176,46 @@ func (uc *userController) CsrfToken(c echo.Context) error {
 		"csrf_token": token,
 	})
 }
+
+const (
+	accessTokenCookie  = "token"
+	refreshTokenCookie = "refresh_token"
+	// mfaTokenCookie carries the MFA challenge of an OIDC login that ended
+	// on the frontend, which cannot read it.
+	mfaTokenCookie = "mfa_token"
+)
+
+// CookieConfig sets the attributes of the cookies the API issues.
+type CookieConfig struct {
+	Domain string
+	// Secure marks cookies Secure and SameSite=None so that a frontend on
+	// another site can send them.
+	Secure bool
+}
+
+func setTokenCookies(c echo.Context, cc CookieConfig, tokens model.TokenPair) {
+	c.SetCookie(newAuthCookie(cc, accessTokenCookie, tokens.AccessToken, tokens.AccessExpiresAt))
+	c.SetCookie(newAuthCookie(cc, refreshTokenCookie, tokens.RefreshToken, tokens.RefreshExpiresAt))
+}
+
+func clearTokenCookies(c echo.Context, cc CookieConfig) {
+	c.SetCookie(newAuthCookie(cc, accessTokenCookie, "", time.Now()))
+	c.SetCookie(newAuthCookie(cc, refreshTokenCookie, "", time.Now()))
+}
+
+func newAuthCookie(cc CookieConfig, name string, value string, expires time.Time) *http.Cookie {
+	cookie := new(http.Cookie)
+	cookie.Name = name
+	cookie.Value = value
+	cookie.Expires = expires
+	cookie.Path = "/"
+	cookie.Domain = cc.Domain
+	cookie.HttpOnly = true
+	if cc.Secure {
+		cookie.Secure = true
+		cookie.SameSite = http.SameSiteNoneMode
+	} else {
+		cookie.SameSite = http.SameSiteDefaultMode
+	}
+	return cookie
+}
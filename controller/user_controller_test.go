@@ -2,12 +2,15 @@ package controller
 
 import (
 	"bytes"
+	"echo-rest-api/apperror"
 	"echo-rest-api/model"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
@@ -33,14 +36,19 @@ func TestSignUp(t *testing.T) {
 	usecase.(*mockUserUsecase).
 		On("SignUp", mock.Anything).
 		Return(mockResponse, nil)
-	controller := NewUserController(usecase)
-	controller.SignUp(mockContext)
+	verificationUsecase := newMockEmailVerificationUsecase()
+	verificationUsecase.(*mockEmailVerificationUsecase).
+		On("SendVerification", uint(1)).
+		Return(nil)
+	controller := NewUserController(usecase, nil, verificationUsecase, nil, CookieConfig{})
+	handle(mockContext, controller.SignUp)
 	assert.Equal(t, http.StatusCreated, rec.Code)
 
 	userJSON, err := json.Marshal(mockResponse)
 	assert.Nil(t, err)
 	assert.JSONEq(t, string(userJSON), rec.Body.String())
 	usecase.(*mockUserUsecase).AssertExpectations(t)
+	verificationUsecase.(*mockEmailVerificationUsecase).AssertExpectations(t)
 }
 
 func TestSignUp_Error(t *testing.T) {
@@ -58,10 +66,10 @@ func TestSignUp_Error(t *testing.T) {
 	usecase.(*mockUserUsecase).
 		On("SignUp", mock.Anything).
 		Return(nil, errors.New("error"))
-	controller := NewUserController(usecase)
-	controller.SignUp(mockContext)
+	controller := NewUserController(usecase, nil, nil, nil, CookieConfig{})
+	handle(mockContext, controller.SignUp)
 	assert.Equal(t, http.StatusInternalServerError, rec.Code)
-	assert.Equal(t, "\"error\"\n", rec.Body.String())
+	assert.JSONEq(t, `{"type":"urn:problem-type:internal-error","title":"Internal Server Error","status":500,"instance":"/signup"}`, rec.Body.String())
 	usecase.(*mockUserUsecase).AssertExpectations(t)
 }
 
@@ -80,8 +88,8 @@ func TestSignUp_BadRequest(t *testing.T) {
 	usecase.(*mockUserUsecase).
 		On("SignUp", mock.Anything).
 		Return(nil, errors.New("error"))
-	controller := NewUserController(usecase)
-	controller.SignUp(mockContext)
+	controller := NewUserController(usecase, nil, nil, nil, CookieConfig{})
+	handle(mockContext, controller.SignUp)
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 	usecase.(*mockUserUsecase).AssertNotCalled(t, "SignUp")
 
@@ -99,16 +107,140 @@ func TestLogin(t *testing.T) {
 	rec := httptest.NewRecorder()
 	mockContext := createMockContext(req, rec)
 	usecase := newMockUserUsecase()
-	const token = "testToken"
 	usecase.(*mockUserUsecase).
 		On("Login", mock.Anything).
-		Return(token, nil)
-	controller := NewUserController(usecase)
-	controller.Login(mockContext)
+		Return(model.LoginResponse{UserResponse: model.UserResponse{ID: 1, Email: input.Email}}, nil)
+	tokenUsecase := newMockTokenUsecase()
+	tokenUsecase.(*mockTokenUsecase).
+		On("IssueTokens", uint(1), mock.AnythingOfType("model.SessionClient")).
+		Return(model.TokenPair{AccessToken: "testToken", RefreshToken: "testRefreshToken"}, nil)
+	attemptUsecase := newMockLoginAttemptUsecase()
+	attemptUsecase.(*mockLoginAttemptUsecase).On("CheckLogin", "testlogin@example.com", "192.0.2.1").Return(nil)
+	attemptUsecase.(*mockLoginAttemptUsecase).On("RecordSuccess", "testlogin@example.com", "192.0.2.1").Return(nil)
+	controller := NewUserController(usecase, tokenUsecase, nil, attemptUsecase, CookieConfig{})
+	handle(mockContext, controller.Login)
 	assert.Equal(t, http.StatusOK, rec.Code)
-	cookie := rec.Header().Get("Set-Cookie")
-	assert.Contains(t, cookie, "token="+token)
+	cookies := rec.Header().Values("Set-Cookie")
+	assert.Equal(t, 2, len(cookies))
+	assert.Contains(t, cookies[0], "token=testToken")
+	assert.Contains(t, cookies[1], "refresh_token=testRefreshToken")
 	usecase.(*mockUserUsecase).AssertExpectations(t)
+	tokenUsecase.(*mockTokenUsecase).AssertExpectations(t)
+	attemptUsecase.(*mockLoginAttemptUsecase).AssertExpectations(t)
+}
+
+func TestLogin_MFARequired(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"email":"testlogin@example.com","password":"testlogin"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	usecase := newMockUserUsecase()
+	usecase.(*mockUserUsecase).
+		On("Login", mock.Anything).
+		Return(model.LoginResponse{MFARequired: true, MFAToken: "challenge"}, nil)
+	tokenUsecase := newMockTokenUsecase()
+	attemptUsecase := newMockLoginAttemptUsecase()
+	attemptUsecase.(*mockLoginAttemptUsecase).On("CheckLogin", "testlogin@example.com", "192.0.2.1").Return(nil)
+	attemptUsecase.(*mockLoginAttemptUsecase).On("RecordSuccess", "testlogin@example.com", "192.0.2.1").Return(nil)
+	controller := NewUserController(usecase, tokenUsecase, nil, attemptUsecase, CookieConfig{})
+	handle(mockContext, controller.Login)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Values("Set-Cookie"))
+	assert.JSONEq(t, `{"mfa_required":true,"mfa_token":"challenge"}`, rec.Body.String())
+	tokenUsecase.(*mockTokenUsecase).AssertNotCalled(t, "IssueTokens")
+}
+
+func TestLoginMFA(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/login/mfa", strings.NewReader(`{"mfa_token":"challenge","code":"123456"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	challenge := model.MFAChallenge{ID: "c1", UserId: 1}
+	usecase := newMockUserUsecase()
+	usecase.(*mockUserUsecase).On("ParseMFAChallenge", "challenge").Return(challenge, nil)
+	usecase.(*mockUserUsecase).
+		On("LoginMFA", "challenge", "123456").
+		Return(model.UserResponse{ID: 1}, nil)
+	tokenUsecase := newMockTokenUsecase()
+	tokenUsecase.(*mockTokenUsecase).
+		On("IssueTokens", uint(1), mock.AnythingOfType("model.SessionClient")).
+		Return(model.TokenPair{AccessToken: "testToken", RefreshToken: "testRefreshToken"}, nil)
+	attemptUsecase := newMockLoginAttemptUsecase()
+	attemptUsecase.(*mockLoginAttemptUsecase).On("CheckMFA", challenge, "192.0.2.1").Return(nil)
+	attemptUsecase.(*mockLoginAttemptUsecase).On("RecordMFASuccess", challenge, "192.0.2.1").Return(nil)
+	controller := NewUserController(usecase, tokenUsecase, nil, attemptUsecase, CookieConfig{})
+	handle(mockContext, controller.LoginMFA)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 2, len(rec.Header().Values("Set-Cookie")))
+	usecase.(*mockUserUsecase).AssertExpectations(t)
+	tokenUsecase.(*mockTokenUsecase).AssertExpectations(t)
+	attemptUsecase.(*mockLoginAttemptUsecase).AssertExpectations(t)
+}
+
+func TestLoginMFA_Cookie(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/login/mfa", strings.NewReader(`{"code":"123456"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.AddCookie(&http.Cookie{Name: mfaTokenCookie, Value: "challenge"})
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	challenge := model.MFAChallenge{ID: "c1", UserId: 1}
+	usecase := newMockUserUsecase()
+	usecase.(*mockUserUsecase).On("ParseMFAChallenge", "challenge").Return(challenge, nil)
+	usecase.(*mockUserUsecase).On("LoginMFA", "challenge", "123456").Return(model.UserResponse{ID: 1}, nil)
+	tokenUsecase := newMockTokenUsecase()
+	tokenUsecase.(*mockTokenUsecase).
+		On("IssueTokens", uint(1), mock.AnythingOfType("model.SessionClient")).
+		Return(model.TokenPair{AccessToken: "testToken", RefreshToken: "testRefreshToken"}, nil)
+	attemptUsecase := newMockLoginAttemptUsecase()
+	attemptUsecase.(*mockLoginAttemptUsecase).On("CheckMFA", challenge, "192.0.2.1").Return(nil)
+	attemptUsecase.(*mockLoginAttemptUsecase).On("RecordMFASuccess", challenge, "192.0.2.1").Return(nil)
+	controller := NewUserController(usecase, tokenUsecase, nil, attemptUsecase, CookieConfig{})
+	handle(mockContext, controller.LoginMFA)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	cookies := rec.Header().Values("Set-Cookie")
+	assert.Equal(t, 3, len(cookies))
+	assert.Contains(t, cookies[0], "mfa_token=;")
+	usecase.(*mockUserUsecase).AssertExpectations(t)
+}
+
+func TestLoginMFA_WrongCode(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/login/mfa", strings.NewReader(`{"mfa_token":"challenge","code":"000000"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	challenge := model.MFAChallenge{ID: "c1", UserId: 1}
+	usecase := newMockUserUsecase()
+	usecase.(*mockUserUsecase).On("ParseMFAChallenge", "challenge").Return(challenge, nil)
+	usecase.(*mockUserUsecase).
+		On("LoginMFA", "challenge", "000000").
+		Return(nil, apperror.New(apperror.ErrUnauthorized, "invalid code"))
+	attemptUsecase := newMockLoginAttemptUsecase()
+	attemptUsecase.(*mockLoginAttemptUsecase).On("CheckMFA", challenge, "192.0.2.1").Return(nil)
+	attemptUsecase.(*mockLoginAttemptUsecase).On("RecordMFAFailure", challenge, "192.0.2.1").Return(nil)
+	controller := NewUserController(usecase, nil, nil, attemptUsecase, CookieConfig{})
+	handle(mockContext, controller.LoginMFA)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	attemptUsecase.(*mockLoginAttemptUsecase).AssertExpectations(t)
+	attemptUsecase.(*mockLoginAttemptUsecase).AssertNotCalled(t, "RecordMFASuccess", mock.Anything, mock.Anything)
+}
+
+func TestLoginMFA_InvalidChallenge(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/login/mfa", strings.NewReader(`{"mfa_token":"forged","code":"000000"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	usecase := newMockUserUsecase()
+	usecase.(*mockUserUsecase).
+		On("ParseMFAChallenge", "forged").
+		Return(nil, apperror.New(apperror.ErrUnauthorized, "invalid or expired MFA token"))
+	attemptUsecase := newMockLoginAttemptUsecase()
+	attemptUsecase.(*mockLoginAttemptUsecase).On("CheckLogin", "", "192.0.2.1").Return(nil)
+	attemptUsecase.(*mockLoginAttemptUsecase).On("RecordFailure", "", "192.0.2.1").Return(nil)
+	controller := NewUserController(usecase, nil, nil, attemptUsecase, CookieConfig{})
+	handle(mockContext, controller.LoginMFA)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	attemptUsecase.(*mockLoginAttemptUsecase).AssertExpectations(t)
+	usecase.(*mockUserUsecase).AssertNotCalled(t, "LoginMFA", mock.Anything, mock.Anything)
 }
 
 func TestLogin_Error(t *testing.T) {
@@ -126,11 +258,49 @@ func TestLogin_Error(t *testing.T) {
 	usecase.(*mockUserUsecase).
 		On("Login", mock.Anything).
 		Return(nil, errors.New("error"))
-	controller := NewUserController(usecase)
-	controller.Login(mockContext)
+	attemptUsecase := newMockLoginAttemptUsecase()
+	attemptUsecase.(*mockLoginAttemptUsecase).On("CheckLogin", "testlogin@example.com", "192.0.2.1").Return(nil)
+	controller := NewUserController(usecase, nil, nil, attemptUsecase, CookieConfig{})
+	handle(mockContext, controller.Login)
 	assert.Equal(t, http.StatusInternalServerError, rec.Code)
-	assert.Equal(t, "\"error\"\n", rec.Body.String())
+	assert.JSONEq(t, `{"type":"urn:problem-type:internal-error","title":"Internal Server Error","status":500,"instance":"/login"}`, rec.Body.String())
 	usecase.(*mockUserUsecase).AssertExpectations(t)
+	attemptUsecase.(*mockLoginAttemptUsecase).AssertNotCalled(t, "RecordFailure", mock.Anything, mock.Anything)
+}
+
+func TestLogin_Unauthorized(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"email":"testlogin@example.com","password":"wrongpassword"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	usecase := newMockUserUsecase()
+	usecase.(*mockUserUsecase).
+		On("Login", mock.Anything).
+		Return(nil, apperror.New(apperror.ErrUnauthorized, "invalid email or password"))
+	attemptUsecase := newMockLoginAttemptUsecase()
+	attemptUsecase.(*mockLoginAttemptUsecase).On("CheckLogin", "testlogin@example.com", "192.0.2.1").Return(nil)
+	attemptUsecase.(*mockLoginAttemptUsecase).On("RecordFailure", "testlogin@example.com", "192.0.2.1").Return(nil)
+	controller := NewUserController(usecase, nil, nil, attemptUsecase, CookieConfig{})
+	handle(mockContext, controller.Login)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	attemptUsecase.(*mockLoginAttemptUsecase).AssertExpectations(t)
+}
+
+func TestLogin_LockedOut(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"email":"testlogin@example.com","password":"testlogin"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	usecase := newMockUserUsecase()
+	attemptUsecase := newMockLoginAttemptUsecase()
+	attemptUsecase.(*mockLoginAttemptUsecase).
+		On("CheckLogin", "testlogin@example.com", "192.0.2.1").
+		Return(apperror.Throttled("too many failed logins, try again later", 30*time.Second))
+	controller := NewUserController(usecase, nil, nil, attemptUsecase, CookieConfig{})
+	handle(mockContext, controller.Login)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "30", rec.Header().Get(HeaderRetryAfter))
+	usecase.(*mockUserUsecase).AssertNotCalled(t, "Login", mock.Anything)
 }
 
 func TestLogin_BadRequest(t *testing.T) {
@@ -148,8 +318,8 @@ func TestLogin_BadRequest(t *testing.T) {
 	usecase.(*mockUserUsecase).
 		On("Login", mock.Anything).
 		Return(nil, errors.New("error"))
-	controller := NewUserController(usecase)
-	controller.Login(mockContext)
+	controller := NewUserController(usecase, nil, nil, nil, CookieConfig{})
+	handle(mockContext, controller.Login)
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 	usecase.(*mockUserUsecase).AssertNotCalled(t, "Login")
 }
@@ -158,19 +328,85 @@ func TestLogout(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPost, "/logout", nil)
 	rec := httptest.NewRecorder()
 	mockContext := createMockContext(req, rec)
-	userController := NewUserController(nil)
-	userController.Logout(mockContext)
+	userController := NewUserController(nil, nil, nil, nil, CookieConfig{})
+	handle(mockContext, userController.Logout)
 	assert.Equal(t, http.StatusOK, rec.Code)
 	token := rec.Header().Get("Set-Cookie")
 	assert.Contains(t, token, "token=")
 }
 
+func TestLogout_CookieConfig(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/logout", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	userController := NewUserController(nil, nil, nil, nil, CookieConfig{Domain: "api.example.com", Secure: true})
+	handle(mockContext, userController.Logout)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	token := rec.Header().Get("Set-Cookie")
+	assert.Contains(t, token, "Domain=api.example.com")
+	assert.Contains(t, token, "Secure")
+	assert.Contains(t, token, "SameSite=None")
+}
+
+func TestLogout_RevokesRefreshToken(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/logout", nil)
+	req.AddCookie(&http.Cookie{Name: "refresh_token", Value: "testRefreshToken"})
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	tokenUsecase := newMockTokenUsecase()
+	tokenUsecase.(*mockTokenUsecase).On("RevokeTokens", "testRefreshToken").Return(nil)
+	userController := NewUserController(nil, tokenUsecase, nil, nil, CookieConfig{})
+	handle(mockContext, userController.Logout)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	tokenUsecase.(*mockTokenUsecase).AssertExpectations(t)
+}
+
+func TestRefreshToken(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/token/refresh", nil)
+	req.AddCookie(&http.Cookie{Name: "refresh_token", Value: "oldRefreshToken"})
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	tokenUsecase := newMockTokenUsecase()
+	tokenUsecase.(*mockTokenUsecase).
+		On("RefreshTokens", "oldRefreshToken").
+		Return(model.TokenPair{AccessToken: "newToken", RefreshToken: "newRefreshToken"}, nil)
+	userController := NewUserController(nil, tokenUsecase, nil, nil, CookieConfig{})
+	handle(mockContext, userController.RefreshToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	cookies := rec.Header().Values("Set-Cookie")
+	assert.Contains(t, cookies[0], "token=newToken")
+	assert.Contains(t, cookies[1], "refresh_token=newRefreshToken")
+	tokenUsecase.(*mockTokenUsecase).AssertExpectations(t)
+}
+
+func TestRefreshToken_Unauthorized(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/token/refresh", nil)
+	rec := httptest.NewRecorder()
+	mockContext := createMockContext(req, rec)
+	tokenUsecase := newMockTokenUsecase()
+	userController := NewUserController(nil, tokenUsecase, nil, nil, CookieConfig{})
+	handle(mockContext, userController.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	tokenUsecase.(*mockTokenUsecase).AssertNotCalled(t, "RefreshTokens")
+
+	req = httptest.NewRequest(http.MethodPost, "/token/refresh", nil)
+	req.AddCookie(&http.Cookie{Name: "refresh_token", Value: "reusedRefreshToken"})
+	rec = httptest.NewRecorder()
+	mockContext = createMockContext(req, rec)
+	tokenUsecase.(*mockTokenUsecase).
+		On("RefreshTokens", "reusedRefreshToken").
+		Return(nil, apperror.New(apperror.ErrUnauthorized, "refresh token reused"))
+	handle(mockContext, userController.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Header().Values("Set-Cookie")[1], "refresh_token=;")
+}
+
 func TestCsrfToken(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/csrf", nil)
 	rec := httptest.NewRecorder()
 	mockContext := createMockContext(req, rec)
-	controller := NewUserController(nil)
-	controller.CsrfToken(mockContext)
+	controller := NewUserController(nil, nil, nil, nil, CookieConfig{})
+	handle(mockContext, controller.CsrfToken)
 	assert.Equal(t, http.StatusOK, rec.Code)
 	csrf, err := json.Marshal(echo.Map{"csrf_token": "test_csrf_token"})
 	assert.Nil(t, err)
@@ -1,46 +1,60 @@
 package db
 
 import (
+	"context"
+	"echo-rest-api/config"
+	"echo-rest-api/logging"
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
 
-	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-func SetupDB() *gorm.DB {
-	var (
-		db  *gorm.DB
-		err error
-	)
-	env := os.Getenv("GO_ENV")
-
-	if env == "test" {
-		db, err = gorm.Open(sqlite.Open(":memory"), &gorm.Config{})
-		fmt.Println("sqlite db")
-	} else {
-		if env == "dev" {
-			err = godotenv.Load()
-			if err != nil {
-				log.Fatalln(err)
-			}
+// SetupDB connects to Postgres, or to an in-memory SQLite database in the
+// test environment, logging queries through logger.
+func SetupDB(cfg config.Config, logger *slog.Logger) (*gorm.DB, error) {
+	gormConfig := &gorm.Config{
+		TranslateError: true,
+		Logger:         logging.NewGormLogger(logger, cfg.Postgres.SlowQueryThreshold),
+	}
+	if cfg.Env == config.EnvTest {
+		db, err := gorm.Open(sqlite.Open(":memory"), gormConfig)
+		if err != nil {
+			return nil, err
 		}
-		url := fmt.Sprintf("postgres://%s:%s@%s:%s/%s",
-			os.Getenv("POSTGRES_USER"),
-			os.Getenv("POSTGRES_PW"),
-			os.Getenv("POSTGRES_HOST"),
-			os.Getenv("POSTGRES_PORT"),
-			os.Getenv("POSTGRES_DB"))
-		db, err = gorm.Open(postgres.Open(url), &gorm.Config{})
-		fmt.Println("connected db")
+		logger.Debug("database connected", "dialect", "sqlite")
+		return db, nil
+	}
+	url := fmt.Sprintf("postgres://%s:%s@%s:%s/%s",
+		cfg.Postgres.User,
+		cfg.Postgres.Password,
+		cfg.Postgres.Host,
+		cfg.Postgres.Port,
+		cfg.Postgres.DB)
+	db, err := gorm.Open(postgres.Open(url), gormConfig)
+	if err != nil {
+		return nil, err
 	}
+	logger.Info("database connected", "dialect", "postgres", "host", cfg.Postgres.Host, "db", cfg.Postgres.DB)
+	return db, nil
+}
 
+// Ping checks that the database answers, for readiness probes.
+func Ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatalln(err)
+		return err
 	}
-	return db
+	return sqlDB.PingContext(ctx)
+}
 
+// Close closes the connection pool once the server no longer uses it.
+func Close(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
 }
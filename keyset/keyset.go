@@ -0,0 +1,184 @@
+package keyset
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const retiredSuffix = ".retired.pem"
+
+// minRSABits is the smallest RSA key accepted for signing.
+const minRSABits = 2048
+
+// Key is one signing key of a keyset. ID is sent as the kid header.
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	private interface{}
+	public  interface{}
+}
+
+// JWK is the public part of a key as published in a JSON Web Key Set.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+type IKeySet interface {
+	// Sign signs claims with the newest key.
+	Sign(claims jwt.Claims) (string, error)
+	// Keyfunc finds the key a token was signed with, for jwt.Parse.
+	Keyfunc(token *jwt.Token) (interface{}, error)
+	// JWKS returns the public keys that verify tokens.
+	JWKS() JWKS
+}
+
+type keySet struct {
+	// keys are sorted by ID, so the last one is the newest.
+	keys    []Key
+	retired map[string]bool
+}
+
+// NewHMACKeySet signs with a single shared secret. Its tokens carry no kid
+// and it publishes no keys.
+func NewHMACKeySet(secret []byte) IKeySet {
+	return &keySet{keys: []Key{{Method: jwt.SigningMethodHS256, private: secret, public: secret}}}
+}
+
+// LoadDir loads the PEM encoded private keys in dir, RSA or Ed25519, as
+// created by e.g. `openssl genpkey -algorithm ed25519 -out 2026-10-17.pem`.
+// The file name without .pem is the key id. The key whose id sorts last
+// signs, and all keys verify, except those named <id>.retired.pem. Rotating
+// is therefore adding a newer key and retiring the old one once the tokens
+// it signed have expired.
+func LoadDir(dir string) (IKeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	ks := &keySet{retired: map[string]bool{}}
+	for _, path := range paths {
+		name := filepath.Base(path)
+		if strings.HasSuffix(name, retiredSuffix) {
+			ks.retired[strings.TrimSuffix(name, retiredSuffix)] = true
+			continue
+		}
+		key, err := loadKey(path, strings.TrimSuffix(name, ".pem"))
+		if err != nil {
+			return nil, err
+		}
+		ks.keys = append(ks.keys, key)
+	}
+	if len(ks.keys) == 0 {
+		return nil, fmt.Errorf("keyset: no active keys in %s", dir)
+	}
+	sort.Slice(ks.keys, func(i, j int) bool {
+		return ks.keys[i].ID < ks.keys[j].ID
+	})
+	return ks, nil
+}
+
+func loadKey(path string, id string) (Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Key{}, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, fmt.Errorf("keyset: %s: no PEM data", path)
+	}
+	var private interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		err = fmt.Errorf("unsupported PEM type %q", block.Type)
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("keyset: %s: %w", path, err)
+	}
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < minRSABits {
+			return Key{}, fmt.Errorf("keyset: %s: rsa key shorter than %d bits", path, minRSABits)
+		}
+		return Key{ID: id, Method: jwt.SigningMethodRS256, private: k, public: &k.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return Key{ID: id, Method: jwt.SigningMethodEdDSA, private: k, public: k.Public()}, nil
+	default:
+		return Key{}, fmt.Errorf("keyset: %s: unsupported key type %T", path, private)
+	}
+}
+
+func (ks *keySet) Sign(claims jwt.Claims) (string, error) {
+	key := ks.keys[len(ks.keys)-1]
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString(key.private)
+}
+
+func (ks *keySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if ks.retired[kid] {
+		return nil, fmt.Errorf("keyset: key %q is retired", kid)
+	}
+	for _, key := range ks.keys {
+		if key.ID != kid {
+			continue
+		}
+		// Checking the algorithm keeps a public key from being used as an
+		// HMAC secret.
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("keyset: unexpected signing method %s", token.Method.Alg())
+		}
+		return key.public, nil
+	}
+	return nil, errors.New("keyset: unknown key id")
+}
+
+func (ks *keySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range ks.keys {
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
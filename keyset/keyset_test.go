@@ -0,0 +1,124 @@
+package keyset
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeKey(t *testing.T, dir string, name string, key interface{}) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.Nil(t, err)
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, name), data, 0o600))
+}
+
+func newClaims() jwt.MapClaims {
+	return jwt.MapClaims{"user_id": 1, "exp": time.Now().Add(time.Minute).Unix()}
+}
+
+func parse(ks IKeySet, token string) error {
+	_, err := jwt.Parse(token, ks.Keyfunc)
+	return err
+}
+
+func TestLoadDir_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	writeKey(t, dir, "2026-01.pem", rsaKey)
+
+	ks, err := LoadDir(dir)
+	assert.Nil(t, err)
+	oldToken, err := ks.Sign(newClaims())
+	assert.Nil(t, err)
+	assert.Nil(t, parse(ks, oldToken))
+
+	// A newer key signs, the older one still verifies.
+	writeKey(t, dir, "2026-02.pem", edKey)
+	ks, err = LoadDir(dir)
+	assert.Nil(t, err)
+	newToken, err := ks.Sign(newClaims())
+	assert.Nil(t, err)
+	token, err := jwt.Parse(newToken, ks.Keyfunc)
+	assert.Nil(t, err)
+	assert.Equal(t, "2026-02", token.Header["kid"])
+	assert.Equal(t, jwt.SigningMethodEdDSA.Alg(), token.Method.Alg())
+	assert.Nil(t, parse(ks, oldToken))
+	jwks := ks.JWKS()
+	assert.Equal(t, 2, len(jwks.Keys))
+	assert.Equal(t, JWK{Kty: "RSA", Kid: "2026-01", Use: "sig", Alg: "RS256", N: jwks.Keys[0].N, E: "AQAB"}, jwks.Keys[0])
+	assert.Equal(t, "OKP", jwks.Keys[1].Kty)
+	assert.Equal(t, "Ed25519", jwks.Keys[1].Crv)
+
+	assert.Nil(t, os.Rename(filepath.Join(dir, "2026-01.pem"), filepath.Join(dir, "2026-01.retired.pem")))
+	ks, err = LoadDir(dir)
+	assert.Nil(t, err)
+	assert.ErrorContains(t, parse(ks, oldToken), "retired")
+	assert.Nil(t, parse(ks, newToken))
+	assert.Equal(t, 1, len(ks.JWKS().Keys))
+}
+
+func TestLoadDir_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	_, err := LoadDir(dir)
+	assert.ErrorContains(t, err, "no active keys")
+
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.Nil(t, err)
+	writeKey(t, dir, "weak.pem", weak)
+	_, err = LoadDir(dir)
+	assert.ErrorContains(t, err, "shorter than")
+
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "weak.pem"), []byte("not a key"), 0o600))
+	_, err = LoadDir(dir)
+	assert.ErrorContains(t, err, "no PEM data")
+}
+
+func TestKeyfunc_Rejects(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	writeKey(t, dir, "key1.pem", rsaKey)
+	ks, err := LoadDir(dir)
+	assert.Nil(t, err)
+
+	// The public key of an RSA key must not verify an HMAC signature.
+	confused := jwt.NewWithClaims(jwt.SigningMethodHS256, newClaims())
+	confused.Header["kid"] = "key1"
+	signed, err := confused.SignedString(x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey))
+	assert.Nil(t, err)
+	assert.Error(t, parse(ks, signed))
+
+	unknown := jwt.NewWithClaims(jwt.SigningMethodRS256, newClaims())
+	unknown.Header["kid"] = "key2"
+	signed, err = unknown.SignedString(rsaKey)
+	assert.Nil(t, err)
+	assert.ErrorContains(t, parse(ks, signed), "unknown key id")
+
+	hmacToken, err := NewHMACKeySet([]byte("secret")).Sign(newClaims())
+	assert.Nil(t, err)
+	assert.Error(t, parse(ks, hmacToken))
+}
+
+func TestHMACKeySet(t *testing.T) {
+	ks := NewHMACKeySet([]byte("secret"))
+	signed, err := ks.Sign(newClaims())
+	assert.Nil(t, err)
+	token, err := jwt.Parse(signed, ks.Keyfunc)
+	assert.Nil(t, err)
+	assert.Nil(t, token.Header["kid"])
+	assert.Error(t, parse(NewHMACKeySet([]byte("other")), signed))
+	assert.Equal(t, JWKS{Keys: []JWK{}}, ks.JWKS())
+}
@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+type gormLogger struct {
+	logger        *slog.Logger
+	level         gormlogger.LogLevel
+	slowThreshold time.Duration
+}
+
+// NewGormLogger bridges GORM's logger to logger. Queries are written at
+// debug level when that is enabled, queries that take longer than
+// slowThreshold as warnings and failed queries as errors. A query whose
+// context carries a request logger is written to that one instead.
+func NewGormLogger(logger *slog.Logger, slowThreshold time.Duration) gormlogger.Interface {
+	level := gormlogger.Warn
+	if logger.Enabled(context.Background(), slog.LevelDebug) {
+		level = gormlogger.Info
+	}
+	return &gormLogger{logger, level, slowThreshold}
+}
+
+func (gl *gormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	copied := *gl
+	copied.level = level
+	return &copied
+}
+
+func (gl *gormLogger) from(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return gl.logger
+}
+
+// ParamsFilter keeps bound values, such as password hashes and tokens, out of
+// the SQL that Trace logs. GORM does not apply it to queries finished with
+// Scan, so the repositories finish theirs with Find.
+func (gl *gormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
+
+func (gl *gormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if gl.level >= gormlogger.Info {
+		gl.from(ctx).InfoContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (gl *gormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if gl.level >= gormlogger.Warn {
+		gl.from(ctx).WarnContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (gl *gormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if gl.level >= gormlogger.Error {
+		gl.from(ctx).ErrorContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (gl *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if gl.level <= gormlogger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	logger := gl.from(ctx)
+	switch {
+	// Missing records and cancelled requests are answered by the callers.
+	case err != nil && gl.level >= gormlogger.Error && !errors.Is(err, gorm.ErrRecordNotFound) && !errors.Is(err, context.Canceled):
+		sql, rows := fc()
+		logger.ErrorContext(ctx, "query failed", "error", err, "sql", sql, "rows", rows, "elapsed_ms", milliseconds(elapsed))
+	case gl.slowThreshold > 0 && elapsed > gl.slowThreshold && gl.level >= gormlogger.Warn:
+		sql, rows := fc()
+		logger.WarnContext(ctx, "slow query", "sql", sql, "rows", rows, "elapsed_ms", milliseconds(elapsed), "threshold_ms", milliseconds(gl.slowThreshold))
+	case gl.level >= gormlogger.Info:
+		sql, rows := fc()
+		logger.DebugContext(ctx, "query", "sql", sql, "rows", rows, "elapsed_ms", milliseconds(elapsed))
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,93 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func records(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	result := []map[string]any{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		record := map[string]any{}
+		assert.Nil(t, json.Unmarshal([]byte(line), &record))
+		result = append(result, record)
+	}
+	return result
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("WARN")
+	assert.Nil(t, err)
+	assert.Equal(t, slog.LevelWarn, level)
+	_, err = ParseLevel("verbose")
+	assert.Error(t, err)
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Equal(t, slog.Default(), FromContext(context.Background()))
+	logger := New(&bytes.Buffer{}, slog.LevelInfo)
+	assert.Equal(t, logger, FromContext(WithContext(context.Background(), logger)))
+}
+
+func TestGormLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	gl := NewGormLogger(New(buf, slog.LevelInfo), 50*time.Millisecond)
+	query := func() (string, int64) { return "SELECT * FROM memos", 2 }
+	ctx := context.Background()
+
+	gl.Trace(ctx, time.Now(), query, nil)
+	gl.Trace(ctx, time.Now(), query, gorm.ErrRecordNotFound)
+	assert.Empty(t, buf.String())
+
+	gl.Trace(ctx, time.Now().Add(-100*time.Millisecond), query, nil)
+	gl.Trace(ctx, time.Now(), query, errors.New("relation does not exist"))
+	logged := records(t, buf)
+	assert.Equal(t, 2, len(logged))
+	assert.Equal(t, "WARN", logged[0]["level"])
+	assert.Equal(t, "slow query", logged[0]["msg"])
+	assert.Equal(t, "SELECT * FROM memos", logged[0]["sql"])
+	assert.Equal(t, float64(50), logged[0]["threshold_ms"])
+	assert.Equal(t, "ERROR", logged[1]["level"])
+	assert.Equal(t, "relation does not exist", logged[1]["error"])
+}
+
+func TestGormLogger_RequestLogger(t *testing.T) {
+	base := &bytes.Buffer{}
+	buf := &bytes.Buffer{}
+	gl := NewGormLogger(New(base, slog.LevelInfo), time.Second)
+	ctx := WithContext(context.Background(), New(buf, slog.LevelDebug).With("request_id", "abc"))
+
+	gl.LogMode(gormlogger.Info).Trace(ctx, time.Now(), func() (string, int64) { return "SELECT 1", 1 }, nil)
+	assert.Empty(t, base.String())
+	logged := records(t, buf)
+	assert.Equal(t, 1, len(logged))
+	assert.Equal(t, "DEBUG", logged[0]["level"])
+	assert.Equal(t, "abc", logged[0]["request_id"])
+}
+
+func TestGormLogger_OmitsParams(t *testing.T) {
+	buf := &bytes.Buffer{}
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: NewGormLogger(New(buf, slog.LevelDebug), time.Second),
+	})
+	assert.Nil(t, err)
+
+	var names []struct{ Name string }
+	assert.Nil(t, db.Raw("SELECT name FROM sqlite_master WHERE name = ?", "secret-token").Find(&names).Error)
+	assert.NotContains(t, buf.String(), "secret-token")
+	assert.Contains(t, buf.String(), "name = ?")
+}
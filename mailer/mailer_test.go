@@ -0,0 +1,51 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageBytes(t *testing.T) {
+	msg := Message{To: "user@example.com", Subject: "Reset your password", Body: "line1\nline2"}
+	date := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	raw := string(msg.bytes("noreply@example.com", date))
+	assert.Equal(t, "From: noreply@example.com\r\n"+
+		"To: user@example.com\r\n"+
+		"Subject: Reset your password\r\n"+
+		"Date: Tue, 02 Jan 2024 03:04:05 +0000\r\n"+
+		"MIME-Version: 1.0\r\n"+
+		"Content-Type: text/plain; charset=utf-8\r\n"+
+		"\r\n"+
+		"line1\r\nline2", raw)
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	mailer := NewFileMailer(dir, "noreply@example.com")
+
+	assert.Nil(t, mailer.Send(Message{To: "user@example.com", Subject: "hello", Body: "body"}))
+	files, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(files))
+	assert.True(t, strings.HasSuffix(files[0].Name(), "-user@example.com.eml"))
+	raw, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	assert.Nil(t, err)
+	assert.Contains(t, string(raw), "To: user@example.com\r\n")
+	assert.True(t, strings.HasSuffix(string(raw), "\r\n\r\nbody"))
+}
+
+func TestMemoryMailer(t *testing.T) {
+	mailer := NewMemoryMailer()
+	assert.Nil(t, mailer.Send(Message{To: "a@example.com"}))
+	assert.Nil(t, mailer.Send(Message{To: "b@example.com"}))
+
+	messages := mailer.Messages()
+	assert.Equal(t, 2, len(messages))
+	assert.Equal(t, "b@example.com", messages[1].To)
+}
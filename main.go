@@ -1,24 +1,179 @@
 package main
 
 import (
+	"context"
+	"echo-rest-api/config"
 	"echo-rest-api/controller"
 	"echo-rest-api/db"
+	"echo-rest-api/keyset"
+	"echo-rest-api/logging"
+	"echo-rest-api/mailer"
+	"echo-rest-api/oidc"
 	"echo-rest-api/repository"
 	"echo-rest-api/router"
 	"echo-rest-api/usecase"
 	"echo-rest-api/validator"
+	"errors"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 )
 
 func main() {
-	db := db.SetupDB()
-	userRepository := repository.NewUserRepository(db)
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalln(err)
+	}
+	level, _ := logging.ParseLevel(cfg.LogLevel)
+	logger := logging.New(os.Stdout, level)
+	slog.SetDefault(logger)
+	logger.Info("effective config", "config", strings.Split(strings.TrimSpace(cfg.Redacted()), "\n"))
+	dbConnect, err := db.SetupDB(cfg, logger)
+	if err != nil {
+		logger.Error("connecting to the database failed", "error", err)
+		os.Exit(1)
+	}
+	cookies := controller.CookieConfig{Domain: cfg.APIDomain, Secure: cfg.IsProduction()}
+	secret := []byte(cfg.Secret)
+	var appMailer mailer.IMailer = mailer.NewFileMailer(cfg.Mail.Dir, cfg.Mail.From)
+	if smtp := cfg.Mail.SMTP; smtp.Host != "" {
+		appMailer = mailer.NewSMTPMailer(smtp.Host, smtp.Port, smtp.Username, smtp.Password, cfg.Mail.From)
+	}
+	memoRepository := repository.NewMemoRepository(dbConnect, cfg.Memo.MaxRevisions)
+	userRepository := repository.NewUserRepository(dbConnect)
 	userValidator := validator.NewUserValidator()
-	userUsecase := usecase.NewUserUsecase(userRepository, userValidator)
-	userController := controller.NewUserController(userUsecase)
-	memoRepository := repository.NewMemoRepository(db)
+	mfaRepository := repository.NewMFARepository(dbConnect)
+	userUsecase := usecase.NewUserUsecase(userRepository, userValidator, mfaRepository, secret)
+	mfaUsecase := usecase.NewMFAUsecase(userRepository, mfaRepository, cfg.TOTPIssuer)
+	mfaController := controller.NewMFAController(mfaUsecase)
+	emailVerificationUsecase := usecase.NewEmailVerificationUsecase(userRepository, memoRepository, appMailer, usecase.EmailVerificationOptions{
+		VerifyURL:      cfg.EmailVerification.URL,
+		TTL:            cfg.EmailVerification.TTL,
+		ResendInterval: cfg.EmailVerification.ResendInterval,
+		Policy:         cfg.EmailVerification.Policy,
+		MemoLimit:      cfg.EmailVerification.MemoLimit,
+		Secret:         secret,
+	})
+	emailVerificationController := controller.NewEmailVerificationController(emailVerificationUsecase)
+	refreshTokenRepository := repository.NewRefreshTokenRepository(dbConnect)
+	sessionRepository := repository.NewSessionRepository(dbConnect)
+	keys := keyset.NewHMACKeySet(secret)
+	if cfg.JWTKeysDir != "" {
+		loaded, err := keyset.LoadDir(cfg.JWTKeysDir)
+		if err != nil {
+			logger.Error("loading the signing keys failed", "error", err)
+			os.Exit(1)
+		}
+		keys = loaded
+	}
+	tokenUsecase := usecase.NewTokenUsecase(refreshTokenRepository, sessionRepository, keys, cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+	loginAttemptRepository := repository.NewMemoryLoginAttemptRepository()
+	if cfg.Login.AttemptStore == "db" {
+		loginAttemptRepository = repository.NewLoginAttemptRepository(dbConnect)
+	}
+	loginLockoutRepository := repository.NewLoginLockoutRepository(dbConnect)
+	loginAttemptUsecase := usecase.NewLoginAttemptUsecase(loginAttemptRepository, loginLockoutRepository, usecase.LoginAttemptOptions{
+		EmailThreshold:       cfg.Login.EmailThreshold,
+		IPThreshold:          cfg.Login.IPThreshold,
+		Lockout:              cfg.Login.Lockout,
+		MaxLockout:           cfg.Login.MaxLockout,
+		Window:               cfg.Login.AttemptWindow,
+		MFAChallengeAttempts: cfg.Login.MFAChallengeAttempts,
+	})
+	userController := controller.NewUserController(userUsecase, tokenUsecase, emailVerificationUsecase, loginAttemptUsecase, cookies)
+	sessionUsecase := usecase.NewSessionUsecase(sessionRepository)
+	sessionController := controller.NewSessionController(sessionUsecase, cookies)
+	passwordResetRepository := repository.NewPasswordResetRepository(dbConnect)
+	passwordUsecase := usecase.NewPasswordUsecase(userRepository, passwordResetRepository, sessionRepository, userValidator, appMailer, cfg.PasswordReset.URL, cfg.PasswordReset.TTL)
+	passwordController := controller.NewPasswordController(passwordUsecase, cookies)
+	accountUsecase := usecase.NewAccountUsecase(userRepository, sessionRepository, userValidator, appMailer, cfg.Account.DeletionGrace)
+	accountController := controller.NewAccountController(accountUsecase, emailVerificationUsecase)
+	healthUsecase := usecase.NewHealthUsecase(cfg.HealthTimeout)
+	healthUsecase.Register("database", func(ctx context.Context) error {
+		return db.Ping(ctx, dbConnect)
+	})
+	healthController := controller.NewHealthController(healthUsecase)
+	accountSweeper := usecase.NewAccountSweeper(userRepository, cfg.Account.SweepInterval)
+	identityRepository := repository.NewIdentityRepository(dbConnect)
+	oidcUsecase := usecase.NewOIDCUsecase(oidcProviders(cfg.OIDC), userRepository, identityRepository, mfaRepository, secret)
+	oidcController := controller.NewOIDCController(oidcUsecase, tokenUsecase, cfg.OIDC.SuccessURL, cookies)
+	accessTokenRepository := repository.NewAccessTokenRepository(dbConnect)
+	accessTokenValidator := validator.NewAccessTokenValidator()
+	accessTokenUsecase := usecase.NewAccessTokenUsecase(accessTokenRepository, accessTokenValidator)
+	accessTokenController := controller.NewAccessTokenController(accessTokenUsecase)
 	memoValidator := validator.NewMemoValidator()
 	memoUsecase := usecase.NewMemoUsecase(memoRepository, memoValidator)
 	memoController := controller.NewMemoController(memoUsecase)
-	e := router.NewRouter(userController, memoController)
-	e.Logger.Fatal((e.Start(":8080")))
+	trashSweeper := usecase.NewTrashSweeper(memoRepository, cfg.Trash.Retention, cfg.Trash.SweepInterval)
+	tagRepository := repository.NewTagRepository(dbConnect)
+	tagValidator := validator.NewTagValidator()
+	tagUsecase := usecase.NewTagUsecase(tagRepository, tagValidator)
+	tagController := controller.NewTagController(tagUsecase)
+	notebookRepository := repository.NewNotebookRepository(dbConnect)
+	notebookValidator := validator.NewNotebookValidator()
+	notebookUsecase := usecase.NewNotebookUsecase(notebookRepository, notebookValidator, cfg.Notebook.MaxDepth)
+	notebookController := controller.NewNotebookController(notebookUsecase)
+	e := router.NewRouter(cfg, logger, userController, memoController, tagController, notebookController, sessionController, passwordController, emailVerificationController, mfaController, accessTokenController, oidcController, accountController, healthController, keys)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var sweepers sync.WaitGroup
+	for _, sweeper := range []interface{ Run(context.Context) }{accountSweeper, trashSweeper} {
+		sweepers.Add(1)
+		go func() {
+			defer sweepers.Done()
+			sweeper.Run(ctx)
+		}()
+	}
+	e.HideBanner = true
+	e.HidePort = true
+	go func() {
+		logger.Info("http server started", "port", cfg.Port)
+		if err := e.Start(":" + cfg.Port); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("http server failed", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	<-ctx.Done()
+	logger.Info("shutting down", "drain_delay", cfg.DrainDelay.String(), "drain_timeout", cfg.ShutdownTimeout.String())
+	// Keep serving while load balancers notice that /readyz fails and stop
+	// sending new requests.
+	healthUsecase.Drain()
+	time.Sleep(cfg.DrainDelay)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		logger.Error("draining requests failed", "error", err)
+	}
+	sweepers.Wait()
+	passwordUsecase.Wait()
+	if err := db.Close(dbConnect); err != nil {
+		logger.Error("closing the database failed", "error", err)
+	}
+	logger.Info("shut down")
+}
+
+func oidcProviders(cfg config.OIDC) map[string]oidc.IProvider {
+	providers := map[string]oidc.IProvider{}
+	for name, p := range cfg.Providers {
+		providers[name] = oidc.NewProvider(oidc.Config{
+			Name:         name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		}, nil)
+	}
+	return providers
 }
@@ -1,24 +1,147 @@
 package main
 
 import (
+	"context"
+	"echo-rest-api/config"
 	"echo-rest-api/db"
-	"echo-rest-api/model"
+	"echo-rest-api/migration"
+	"flag"
 	"fmt"
 	"log"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
 
 	"gorm.io/gorm"
 )
 
+const usage = `usage: migrate [-dir DIR] COMMAND
+
+commands:
+  up              apply all pending migrations
+  down N          roll back the last N migrations
+  status          list the migrations and whether they are applied
+  create NAME     add empty up and down files for every dialect to DIR
+  force VERSION   record VERSION as the current version without running
+                  anything, after fixing a failed migration by hand
+`
+
 func main() {
-	dbConnect := db.SetupDB()
-	defer fmt.Println("Successfully Migrated")
+	dir := flag.String("dir", "migration", "directory of the migration files, for create")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+	}
+	flag.Parse()
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if args[0] == "create" {
+		if len(args) != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		paths, err := migration.Create(*dir, args[1])
+		if err != nil {
+			log.Fatalln(err)
+		}
+		for _, path := range paths {
+			fmt.Println(path)
+		}
+		return
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err := cfg.ValidateDB(); err != nil {
+		log.Fatalln(err)
+	}
+	dbConnect, err := db.SetupDB(cfg, slog.Default())
+	if err != nil {
+		log.Fatalln(err)
+	}
 	defer closeDB(dbConnect)
-	dbConnect.AutoMigrate(&model.User{}, &model.Memo{})
+	migrations, err := migration.Load(migration.Files, dbConnect.Dialector.Name())
+	if err != nil {
+		log.Fatalln(err)
+	}
+	sqlDB, err := dbConnect.DB()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	migrator, err := migration.NewMigrator(sqlDB, dbConnect.Dialector.Name(), migrations)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	ctx := context.Background()
+
+	switch {
+	case args[0] == "up" && len(args) == 1:
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Printf("Applied %d migrations\n", applied)
+	case args[0] == "down" && len(args) == 2:
+		n, err := strconv.Atoi(args[1])
+		if err != nil {
+			log.Fatalln("down: N must be a number")
+		}
+		rolledBack, err := migrator.Down(ctx, n)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Printf("Rolled back %d migrations\n", rolledBack)
+	case args[0] == "status" && len(args) == 1:
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		printStatus(statuses)
+	case args[0] == "force" && len(args) == 2:
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			log.Fatalln("force: VERSION must be a number")
+		}
+		if err := migrator.Force(ctx, version); err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Printf("Forced version %d\n", version)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func printStatus(statuses []migration.Status) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, status := range statuses {
+		state, appliedAt := "pending", ""
+		if status.AppliedAt != nil {
+			state, appliedAt = "applied", status.AppliedAt.Local().Format(time.DateTime)
+		}
+		switch {
+		case status.Dirty:
+			state = "dirty"
+		case status.Missing:
+			state = "missing"
+		case status.Modified:
+			state = "modified"
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+	w.Flush()
 }
 
-func closeDB(db *gorm.DB) {
-	sqlDB, _ := db.DB()
-	if err := sqlDB.Close(); err != nil {
+func closeDB(dbConnect *gorm.DB) {
+	if err := db.Close(dbConnect); err != nil {
 		log.Fatalln(err)
 	}
 }
@@ -0,0 +1,68 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// legacyColumn is a column 0001_init creates that the users and memos tables
+// of the first release lack. Those tables were created by AutoMigrate, and
+// 0001_init only creates tables that do not exist yet.
+type legacyColumn struct {
+	table      string
+	column     string
+	definition string
+	// constraint is run once 0001_init has created the tables it refers to.
+	constraint string
+}
+
+var legacyColumns = map[string][]legacyColumn{
+	"postgres": {
+		{table: "users", column: "email_verified_at", definition: "timestamptz"},
+		{table: "users", column: "verification_sent_at", definition: "timestamptz"},
+		{table: "users", column: "pending_email", definition: "text"},
+		{table: "users", column: "deletion_scheduled_at", definition: "timestamptz"},
+		{table: "memos", column: "notebook_id", definition: "bigint",
+			constraint: "ALTER TABLE memos ADD CONSTRAINT fk_memos_notebook FOREIGN KEY (notebook_id) REFERENCES notebooks (id) ON DELETE SET NULL"},
+		{table: "memos", column: "version", definition: "bigint NOT NULL DEFAULT 1"},
+	},
+	"sqlite": {
+		{table: "users", column: "email_verified_at", definition: "datetime"},
+		{table: "users", column: "verification_sent_at", definition: "datetime"},
+		{table: "users", column: "pending_email", definition: "text"},
+		{table: "users", column: "deletion_scheduled_at", definition: "datetime"},
+		// SQLite does not check that notebooks exists until the key is used.
+		{table: "memos", column: "notebook_id", definition: "integer REFERENCES notebooks (id) ON DELETE SET NULL"},
+		{table: "memos", column: "version", definition: "integer NOT NULL DEFAULT 1"},
+	},
+}
+
+// adopt adds the legacy columns to a database that AutoMigrate created
+// before versioned migrations, so that 0001_init applies to it. It returns
+// the constraints to add after 0001_init.
+func (m *migrator) adopt(ctx context.Context, conn *sql.Conn) ([]string, error) {
+	var constraints []string
+	for _, c := range legacyColumns[m.dialect.name] {
+		var tables, columns int
+		if err := conn.QueryRowContext(ctx, m.dialect.rebind(m.dialect.countTables), c.table).Scan(&tables); err != nil {
+			return nil, err
+		}
+		if tables == 0 {
+			continue
+		}
+		if err := conn.QueryRowContext(ctx, m.dialect.rebind(m.dialect.countColumns), c.table, c.column).Scan(&columns); err != nil {
+			return nil, err
+		}
+		if columns > 0 {
+			continue
+		}
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.column, c.definition)); err != nil {
+			return nil, fmt.Errorf("migration: adopting %s.%s: %w", c.table, c.column, err)
+		}
+		if c.constraint != "" {
+			constraints = append(constraints, c.constraint)
+		}
+	}
+	return constraints, nil
+}
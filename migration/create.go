@@ -0,0 +1,56 @@
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var nonNameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Create adds empty up and down files for name to the directory of every
+// dialect in dir, numbered one past the highest version in any of them, and
+// returns their paths.
+func Create(dir string, name string) ([]string, error) {
+	name = strings.Trim(nonNameChars.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return nil, errors.New("migration: invalid name")
+	}
+	var version int64
+	for _, d := range Dialects {
+		migrations, err := Load(os.DirFS(dir), d)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		for _, m := range migrations {
+			version = max(version, m.Version)
+		}
+	}
+	version++
+	paths := []string{}
+	for _, d := range Dialects {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0o755); err != nil {
+			return nil, err
+		}
+		for _, direction := range []string{"up", "down"} {
+			path := filepath.Join(dir, d, fmt.Sprintf("%04d_%s.%s.sql", version, name, direction))
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+			if err != nil {
+				return nil, err
+			}
+			_, err = fmt.Fprintf(f, "-- %s: %s %s\n", d, name, direction)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,130 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// advisoryLockKey identifies the migration lock among the Postgres advisory
+// locks of the database.
+const advisoryLockKey = 0x6d656d6f6d6967
+
+// LockTimeout is how long a migration waits for another one to finish.
+var LockTimeout = time.Minute
+
+// lockPollInterval is how often SQLite retries taking the lock.
+const lockPollInterval = 100 * time.Millisecond
+
+type dialect struct {
+	name        string
+	createTable string
+	// countTables and countColumns count the tables named by their first
+	// argument, and the columns of it named by the second.
+	countTables  string
+	countColumns string
+	// rebind replaces the ? placeholders of a query with the ones of the
+	// dialect.
+	rebind func(query string) string
+	// lock takes the migration lock on conn and returns the function that
+	// releases it.
+	lock func(ctx context.Context, conn *sql.Conn) (func(), error)
+}
+
+var dialects = map[string]dialect{
+	"postgres": {
+		name: "postgres",
+		createTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version bigint PRIMARY KEY,
+			name text NOT NULL,
+			checksum text NOT NULL,
+			dirty boolean NOT NULL DEFAULT false,
+			applied_at timestamptz NOT NULL
+		)`,
+		countTables:  "SELECT count(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?",
+		countColumns: "SELECT count(*) FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?",
+		rebind: func(query string) string {
+			var b strings.Builder
+			n := 0
+			for _, r := range query {
+				if r == '?' {
+					n++
+					fmt.Fprintf(&b, "$%d", n)
+					continue
+				}
+				b.WriteRune(r)
+			}
+			return b.String()
+		},
+		lock: lockPostgres,
+	},
+	"sqlite": {
+		name: "sqlite",
+		createTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version integer PRIMARY KEY,
+			name text NOT NULL,
+			checksum text NOT NULL,
+			dirty boolean NOT NULL DEFAULT false,
+			applied_at datetime NOT NULL
+		)`,
+		countTables:  "SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?",
+		countColumns: "SELECT count(*) FROM pragma_table_info(?) WHERE name = ?",
+		rebind:       func(query string) string { return query },
+		lock:         lockSQLite,
+	},
+}
+
+// lockPostgres takes a session level advisory lock, which Postgres releases
+// by itself should the process die while holding it.
+func lockPostgres(ctx context.Context, conn *sql.Conn) (func(), error) {
+	ctx, cancel := context.WithTimeout(ctx, LockTimeout)
+	defer cancel()
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", advisoryLockKey); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, errors.New("migration: timed out waiting for another migration to finish")
+		}
+		return nil, err
+	}
+	return func() {
+		conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockKey)
+	}, nil
+}
+
+// lockSQLite has no advisory locks to use, so it inserts the only row of
+// schema_migrations_lock and waits while another process holds it. A lock
+// left behind by a crashed process has to be deleted by hand.
+func lockSQLite(ctx context.Context, conn *sql.Conn) (func(), error) {
+	_, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations_lock (
+		id integer PRIMARY KEY CHECK (id = 1),
+		locked_at datetime NOT NULL
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(LockTimeout)
+	for {
+		result, err := conn.ExecContext(ctx, "INSERT OR IGNORE INTO schema_migrations_lock (id, locked_at) VALUES (1, ?)", time.Now().UTC())
+		if err != nil {
+			return nil, err
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return nil, err
+		} else if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.New("migration: timed out waiting for another migration to finish; if none is running, delete the row in schema_migrations_lock")
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+	return func() {
+		conn.ExecContext(context.Background(), "DELETE FROM schema_migrations_lock WHERE id = 1")
+	}, nil
+}
@@ -0,0 +1,417 @@
+package migration
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Files holds the migrations of every dialect, in a directory named after
+// the dialect: postgres/0001_init.up.sql and so on.
+//
+//go:embed postgres/*.sql sqlite/*.sql
+var Files embed.FS
+
+// Dialects are the databases migrations are written for, named like the
+// GORM dialectors.
+var Dialects = []string{"postgres", "sqlite"}
+
+// noTransaction on the first line of an up or down file runs it outside a
+// transaction, for statements like CREATE INDEX CONCURRENTLY. If such a
+// migration fails halfway its version is left dirty.
+const noTransaction = "-- migrate:no-transaction"
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+	// Checksum is the SHA-256 of Up. It is recorded when the migration is
+	// applied, so that editing an applied migration is noticed.
+	Checksum string
+}
+
+// Status is a migration as recorded in schema_migrations. Missing is set for
+// an applied version that has no files anymore, Modified when the up file
+// changed since it was applied.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+	Dirty     bool
+	Modified  bool
+	Missing   bool
+}
+
+// Load reads the migrations of dialect from fsys, ordered by version. Every
+// version needs an up and a down file.
+func Load(fsys fs.FS, dialect string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dialect)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	found := map[string]bool{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration: %s/%s: name is not VERSION_NAME.up.sql or VERSION_NAME.down.sql", dialect, entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("migration: %s/%s: invalid version", dialect, entry.Name())
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dialect, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration: %s: version %d is used by %s and %s", dialect, version, m.Name, match[2])
+		}
+		found[fmt.Sprintf("%d.%s", version, match[3])] = true
+		if match[3] == "up" {
+			m.Up = string(data)
+			sum := sha256.Sum256(data)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(data)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if !found[fmt.Sprintf("%d.up", m.Version)] || !found[fmt.Sprintf("%d.down", m.Version)] {
+			return nil, fmt.Errorf("migration: %s: %04d_%s needs both an up and a down file", dialect, m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+type IMigrator interface {
+	// Up applies all pending migrations and returns how many it applied.
+	Up(ctx context.Context) (int, error)
+	// Down rolls back the n most recently applied migrations, or all of them
+	// if there are fewer, and returns how many it rolled back.
+	Down(ctx context.Context, n int) (int, error)
+	Status(ctx context.Context) ([]Status, error)
+	// Force records version as the current version without running any
+	// migration: versions up to it count as applied, later ones as pending,
+	// and the dirty flag is cleared. It is how a failed migration is
+	// resolved after the schema has been fixed by hand.
+	Force(ctx context.Context, version int64) error
+}
+
+type migrator struct {
+	db         *sql.DB
+	dialect    dialect
+	migrations []Migration
+}
+
+// NewMigrator runs migrations against db. They have to be in version order,
+// as returned by Load.
+func NewMigrator(db *sql.DB, dialectName string, migrations []Migration) (IMigrator, error) {
+	d, ok := dialects[dialectName]
+	if !ok {
+		return nil, fmt.Errorf("migration: unsupported dialect %s", dialectName)
+	}
+	return &migrator{db, d, migrations}, nil
+}
+
+// record is a row of schema_migrations.
+type record struct {
+	version   int64
+	name      string
+	checksum  string
+	dirty     bool
+	appliedAt time.Time
+}
+
+func (m *migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		records, err := m.records(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.checkRecords(records); err != nil {
+			return err
+		}
+		var constraints []string
+		if len(records) == 0 {
+			if constraints, err = m.adopt(ctx, conn); err != nil {
+				return err
+			}
+		}
+		for _, migration := range m.migrations {
+			if _, ok := records[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration); err != nil {
+				return err
+			}
+			applied++
+			if migration.Version == 1 {
+				for _, constraint := range constraints {
+					if _, err := conn.ExecContext(ctx, constraint); err != nil {
+						return fmt.Errorf("migration: adopting: %w", err)
+					}
+				}
+			}
+		}
+		return nil
+	})
+	return applied, err
+}
+
+func (m *migrator) Down(ctx context.Context, n int) (int, error) {
+	if n < 1 {
+		return 0, errors.New("migration: nothing to roll back")
+	}
+	rolledBack := 0
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		records, err := m.records(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.checkRecords(records); err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && rolledBack < n; i-- {
+			migration := m.migrations[i]
+			if _, ok := records[migration.Version]; !ok {
+				continue
+			}
+			if err := m.revert(ctx, conn, migration); err != nil {
+				return err
+			}
+			rolledBack++
+		}
+		return nil
+	})
+	return rolledBack, err
+}
+
+func (m *migrator) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := m.createTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	records, err := m.records(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	statuses := []Status{}
+	for _, migration := range m.migrations {
+		status := Status{Migration: migration}
+		if r, ok := records[migration.Version]; ok {
+			appliedAt := r.appliedAt
+			status.AppliedAt = &appliedAt
+			status.Dirty = r.dirty
+			status.Modified = r.checksum != migration.Checksum
+			delete(records, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, r := range records {
+		appliedAt := r.appliedAt
+		statuses = append(statuses, Status{
+			Migration: Migration{Version: r.version, Name: r.name, Checksum: r.checksum},
+			AppliedAt: &appliedAt,
+			Dirty:     r.dirty,
+			Missing:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+func (m *migrator) Force(ctx context.Context, version int64) error {
+	known := version == 0
+	for _, migration := range m.migrations {
+		known = known || migration.Version == version
+	}
+	if !known {
+		return fmt.Errorf("migration: unknown version %d", version)
+	}
+	return m.locked(ctx, func(conn *sql.Conn) error {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if _, err := tx.ExecContext(ctx, m.dialect.rebind("DELETE FROM schema_migrations WHERE version > ?"), version); err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if migration.Version > version {
+				break
+			}
+			if _, err := tx.ExecContext(ctx, m.dialect.rebind("DELETE FROM schema_migrations WHERE version = ?"), migration.Version); err != nil {
+				return err
+			}
+			if err := m.insertRecord(ctx, tx, migration, false); err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	})
+}
+
+// checkRecords refuses to migrate a database whose history does not match
+// the migration files.
+func (m *migrator) checkRecords(records map[int64]record) error {
+	known := map[int64]Migration{}
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+	versions := make([]int64, 0, len(records))
+	for version := range records {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	for _, version := range versions {
+		r := records[version]
+		if r.dirty {
+			return fmt.Errorf("migration: %04d_%s failed halfway; fix the schema by hand, then run force with the version it is at", r.version, r.name)
+		}
+		migration, ok := known[version]
+		if !ok {
+			return fmt.Errorf("migration: %04d_%s is applied but its files are missing", r.version, r.name)
+		}
+		if migration.Checksum != r.checksum {
+			return fmt.Errorf("migration: %04d_%s was changed after it was applied", r.version, r.name)
+		}
+	}
+	return nil
+}
+
+func (m *migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	if strings.HasPrefix(migration.Up, noTransaction) {
+		if err := m.insertRecord(ctx, conn, migration, true); err != nil {
+			return err
+		}
+		if _, err := conn.ExecContext(ctx, migration.Up); err != nil {
+			return fmt.Errorf("migration: %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+		_, err := conn.ExecContext(ctx, m.dialect.rebind("UPDATE schema_migrations SET dirty = ? WHERE version = ?"), false, migration.Version)
+		return err
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+		return fmt.Errorf("migration: %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+	if err := m.insertRecord(ctx, tx, migration, false); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *migrator) revert(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	deleteRecord := m.dialect.rebind("DELETE FROM schema_migrations WHERE version = ?")
+	if strings.HasPrefix(migration.Down, noTransaction) {
+		if _, err := conn.ExecContext(ctx, m.dialect.rebind("UPDATE schema_migrations SET dirty = ? WHERE version = ?"), true, migration.Version); err != nil {
+			return err
+		}
+		if _, err := conn.ExecContext(ctx, migration.Down); err != nil {
+			return fmt.Errorf("migration: %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+		_, err := conn.ExecContext(ctx, deleteRecord, migration.Version)
+		return err
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+		return fmt.Errorf("migration: %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, deleteRecord, migration.Version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (m *migrator) insertRecord(ctx context.Context, db execer, migration Migration, dirty bool) error {
+	_, err := db.ExecContext(ctx,
+		m.dialect.rebind("INSERT INTO schema_migrations (version, name, checksum, dirty, applied_at) VALUES (?, ?, ?, ?, ?)"),
+		migration.Version, migration.Name, migration.Checksum, dirty, time.Now().UTC())
+	return err
+}
+
+func (m *migrator) records(ctx context.Context, conn *sql.Conn) (map[int64]record, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, checksum, dirty, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	records := map[int64]record{}
+	for rows.Next() {
+		r := record{}
+		if err := rows.Scan(&r.version, &r.name, &r.checksum, &r.dirty, &r.appliedAt); err != nil {
+			return nil, err
+		}
+		records[r.version] = r
+	}
+	return records, rows.Err()
+}
+
+func (m *migrator) createTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, m.dialect.createTable)
+	return err
+}
+
+// locked runs fn on a single connection while holding the migration lock, so
+// that instances started at the same time do not migrate concurrently.
+func (m *migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := m.createTable(ctx, conn); err != nil {
+		return err
+	}
+	unlock, err := m.dialect.lock(ctx, conn)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return fn(conn)
+}
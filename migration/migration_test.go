@@ -0,0 +1,269 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	conn, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	assert.Nil(t, err)
+	sqlDB, err := conn.DB()
+	assert.Nil(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+	return sqlDB
+}
+
+func hasTable(t *testing.T, db *sql.DB, name string) bool {
+	var count int
+	err := db.QueryRow("SELECT count(*) FROM sqlite_master WHERE name = ?", name).Scan(&count)
+	assert.Nil(t, err)
+	return count > 0
+}
+
+func testFiles(up2 string) fstest.MapFS {
+	return fstest.MapFS{
+		"sqlite/0001_notes.up.sql":     {Data: []byte("CREATE TABLE notes (id integer PRIMARY KEY, body text);")},
+		"sqlite/0001_notes.down.sql":   {Data: []byte("DROP TABLE notes;")},
+		"sqlite/0002_authors.up.sql":   {Data: []byte(up2)},
+		"sqlite/0002_authors.down.sql": {Data: []byte("DROP TABLE authors;")},
+	}
+}
+
+func TestLoad(t *testing.T) {
+	postgres, err := Load(Files, "postgres")
+	assert.Nil(t, err)
+	sqlite, err := Load(Files, "sqlite")
+	assert.Nil(t, err)
+	assert.Equal(t, len(postgres), len(sqlite))
+	for i := range postgres {
+		assert.Equal(t, postgres[i].Version, sqlite[i].Version)
+		assert.Equal(t, postgres[i].Name, sqlite[i].Name)
+	}
+	assert.Equal(t, int64(1), sqlite[0].Version)
+	assert.Equal(t, "init", sqlite[0].Name)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	_, err := Load(fstest.MapFS{"sqlite/0001_notes.up.sql": {Data: []byte("SELECT 1;")}}, "sqlite")
+	assert.ErrorContains(t, err, "needs both an up and a down file")
+	_, err = Load(fstest.MapFS{"sqlite/notes.up.sql": {Data: []byte("SELECT 1;")}}, "sqlite")
+	assert.ErrorContains(t, err, "name is not")
+	_, err = Load(fstest.MapFS{
+		"sqlite/0001_notes.up.sql":   {Data: []byte("SELECT 1;")},
+		"sqlite/0001_notes.down.sql": {Data: []byte("SELECT 1;")},
+		"sqlite/0001_other.up.sql":   {Data: []byte("SELECT 1;")},
+	}, "sqlite")
+	assert.ErrorContains(t, err, "version 1 is used by")
+}
+
+func TestMigrator(t *testing.T) {
+	db := openTestDB(t)
+	migrations, err := Load(Files, "sqlite")
+	assert.Nil(t, err)
+	migrator, err := NewMigrator(db, "sqlite", migrations)
+	assert.Nil(t, err)
+	ctx := context.Background()
+
+	applied, err := migrator.Up(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, len(migrations), applied)
+	assert.True(t, hasTable(t, db, "users"))
+	assert.True(t, hasTable(t, db, "memos_fts"))
+	applied, err = migrator.Up(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, applied)
+
+	rolledBack, err := migrator.Down(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, rolledBack)
+	assert.False(t, hasTable(t, db, "memos_fts"))
+	assert.True(t, hasTable(t, db, "memos"))
+	statuses, err := migrator.Status(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, len(migrations), len(statuses))
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.Nil(t, statuses[len(statuses)-1].AppliedAt)
+
+	rolledBack, err = migrator.Down(ctx, 100)
+	assert.Nil(t, err)
+	assert.Equal(t, len(migrations)-1, rolledBack)
+	assert.False(t, hasTable(t, db, "users"))
+	_, err = migrator.Down(ctx, 0)
+	assert.Error(t, err)
+}
+
+// legacyUser and legacyMemo are the models of the first release, whose
+// tables AutoMigrate created.
+type legacyUser struct {
+	gorm.Model
+	Email    string `gorm:"unique"`
+	Password string
+}
+
+func (legacyUser) TableName() string { return "users" }
+
+type legacyMemo struct {
+	gorm.Model
+	Title   string `gorm:"not null"`
+	Content string
+	User    legacyUser `gorm:"foreignKey:UserId; constraint:OnDelete:CASCADE"`
+	UserId  uint       `gorm:"not null"`
+}
+
+func (legacyMemo) TableName() string { return "memos" }
+
+func TestMigrator_AdoptAutoMigrate(t *testing.T) {
+	conn, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	assert.Nil(t, err)
+	assert.Nil(t, conn.AutoMigrate(&legacyUser{}, &legacyMemo{}))
+	user := legacyUser{Email: "user@example.com", Password: "hash"}
+	assert.Nil(t, conn.Create(&user).Error)
+	assert.Nil(t, conn.Create(&legacyMemo{Title: "old memo", UserId: user.ID}).Error)
+	db, err := conn.DB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	migrations, err := Load(Files, "sqlite")
+	assert.Nil(t, err)
+	migrator, err := NewMigrator(db, "sqlite", migrations)
+	assert.Nil(t, err)
+	applied, err := migrator.Up(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, len(migrations), applied)
+
+	var version int
+	var notebookId sql.NullInt64
+	err = db.QueryRow("SELECT version, notebook_id FROM memos WHERE title = 'old memo'").Scan(&version, &notebookId)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, version)
+	assert.False(t, notebookId.Valid)
+	_, err = db.Exec("UPDATE users SET email_verified_at = ?, verification_sent_at = ?, pending_email = ?, deletion_scheduled_at = ?",
+		time.Now(), time.Now(), "new@example.com", time.Now())
+	assert.Nil(t, err)
+	var hits int
+	assert.Nil(t, db.QueryRow("SELECT count(*) FROM memos_fts WHERE memos_fts MATCH 'old'").Scan(&hits))
+	assert.Equal(t, 1, hits)
+}
+
+func TestMigrator_Modified(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	migrations, err := Load(testFiles("CREATE TABLE authors (id integer PRIMARY KEY);"), "sqlite")
+	assert.Nil(t, err)
+	migrator, err := NewMigrator(db, "sqlite", migrations)
+	assert.Nil(t, err)
+	_, err = migrator.Up(ctx)
+	assert.Nil(t, err)
+
+	migrations, err = Load(testFiles("CREATE TABLE authors (id integer PRIMARY KEY, name text);"), "sqlite")
+	assert.Nil(t, err)
+	migrator, err = NewMigrator(db, "sqlite", migrations)
+	assert.Nil(t, err)
+	_, err = migrator.Up(ctx)
+	assert.ErrorContains(t, err, "0002_authors was changed after it was applied")
+	statuses, err := migrator.Status(ctx)
+	assert.Nil(t, err)
+	assert.False(t, statuses[0].Modified)
+	assert.True(t, statuses[1].Modified)
+
+	migrator, err = NewMigrator(db, "sqlite", migrations[:1])
+	assert.Nil(t, err)
+	_, err = migrator.Down(ctx, 1)
+	assert.ErrorContains(t, err, "0002_authors is applied but its files are missing")
+}
+
+func TestMigrator_DirtyAndForce(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	migrations, err := Load(testFiles(noTransaction+"\nCREATE TABLE authors (id integer PRIMARY KEY);\nSELECT * FROM missing;"), "sqlite")
+	assert.Nil(t, err)
+	migrator, err := NewMigrator(db, "sqlite", migrations)
+	assert.Nil(t, err)
+
+	applied, err := migrator.Up(ctx)
+	assert.ErrorContains(t, err, "0002_authors")
+	assert.Equal(t, 1, applied)
+	assert.True(t, hasTable(t, db, "authors"))
+	statuses, err := migrator.Status(ctx)
+	assert.Nil(t, err)
+	assert.True(t, statuses[1].Dirty)
+	_, err = migrator.Up(ctx)
+	assert.ErrorContains(t, err, "failed halfway")
+
+	// The table was created, so the schema is at version 2.
+	assert.ErrorContains(t, migrator.Force(ctx, 3), "unknown version")
+	assert.Nil(t, migrator.Force(ctx, 2))
+	statuses, err = migrator.Status(ctx)
+	assert.Nil(t, err)
+	assert.False(t, statuses[1].Dirty)
+	assert.NotNil(t, statuses[1].AppliedAt)
+
+	assert.Nil(t, migrator.Force(ctx, 0))
+	statuses, err = migrator.Status(ctx)
+	assert.Nil(t, err)
+	assert.Nil(t, statuses[0].AppliedAt)
+	assert.Nil(t, statuses[1].AppliedAt)
+}
+
+func TestMigrator_Lock(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	migrations, err := Load(testFiles("CREATE TABLE authors (id integer PRIMARY KEY);"), "sqlite")
+	assert.Nil(t, err)
+	migrator, err := NewMigrator(db, "sqlite", migrations)
+	assert.Nil(t, err)
+	timeout := LockTimeout
+	LockTimeout = 200 * time.Millisecond
+	defer func() { LockTimeout = timeout }()
+
+	conn, err := db.Conn(ctx)
+	assert.Nil(t, err)
+	unlock, err := lockSQLite(ctx, conn)
+	assert.Nil(t, err)
+	_, err = migrator.Up(ctx)
+	assert.ErrorContains(t, err, "timed out")
+	assert.False(t, hasTable(t, db, "notes"))
+
+	unlock()
+	conn.Close()
+	applied, err := migrator.Up(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, applied)
+}
+
+func TestRebind(t *testing.T) {
+	assert.Equal(t, "UPDATE t SET a = $1 WHERE b = $2", dialects["postgres"].rebind("UPDATE t SET a = ? WHERE b = ?"))
+	assert.Equal(t, "UPDATE t SET a = ?", dialects["sqlite"].rebind("UPDATE t SET a = ?"))
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	paths, err := Create(dir, "Add memo color")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "postgres", "0001_add_memo_color.up.sql"),
+		filepath.Join(dir, "postgres", "0001_add_memo_color.down.sql"),
+		filepath.Join(dir, "sqlite", "0001_add_memo_color.up.sql"),
+		filepath.Join(dir, "sqlite", "0001_add_memo_color.down.sql"),
+	}, paths)
+
+	paths, err = Create(dir, "second")
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "postgres", "0002_second.up.sql"), paths[0])
+	migrations, err := Load(os.DirFS(dir), "sqlite")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(migrations))
+
+	_, err = Create(dir, "--")
+	assert.Error(t, err)
+}
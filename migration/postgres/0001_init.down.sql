@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS login_lockouts;
+DROP TABLE IF EXISTS login_attempts;
+DROP TABLE IF EXISTS user_identities;
+DROP TABLE IF EXISTS access_tokens;
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS totp_credentials;
+DROP TABLE IF EXISTS password_reset_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS memo_revisions;
+DROP TABLE IF EXISTS memo_tags;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS memos;
+DROP TABLE IF EXISTS notebooks;
+DROP TABLE IF EXISTS users;
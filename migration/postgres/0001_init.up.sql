@@ -0,0 +1,184 @@
+-- The schema as created by GORM's AutoMigrate before versioned migrations.
+-- Every statement is conditional, so databases created by AutoMigrate adopt
+-- this migration without changes.
+
+CREATE TABLE IF NOT EXISTS users (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	email text,
+	password text,
+	email_verified_at timestamptz,
+	verification_sent_at timestamptz,
+	pending_email text,
+	deletion_scheduled_at timestamptz,
+	CONSTRAINT uni_users_email UNIQUE (email)
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_users_deletion_scheduled_at ON users (deletion_scheduled_at);
+
+CREATE TABLE IF NOT EXISTS notebooks (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	name text NOT NULL,
+	parent_id bigint,
+	user_id bigint NOT NULL,
+	CONSTRAINT fk_notebooks_parent FOREIGN KEY (parent_id) REFERENCES notebooks (id) ON DELETE CASCADE,
+	CONSTRAINT fk_notebooks_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_notebooks_deleted_at ON notebooks (deleted_at);
+
+CREATE TABLE IF NOT EXISTS memos (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	title text NOT NULL,
+	content text,
+	user_id bigint NOT NULL,
+	notebook_id bigint,
+	version bigint NOT NULL DEFAULT 1,
+	CONSTRAINT fk_memos_notebook FOREIGN KEY (notebook_id) REFERENCES notebooks (id) ON DELETE SET NULL,
+	CONSTRAINT fk_memos_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_memos_deleted_at ON memos (deleted_at);
+
+CREATE TABLE IF NOT EXISTS tags (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	name text NOT NULL,
+	user_id bigint NOT NULL,
+	CONSTRAINT fk_tags_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_user_id_name ON tags (name, user_id);
+CREATE INDEX IF NOT EXISTS idx_tags_deleted_at ON tags (deleted_at);
+
+CREATE TABLE IF NOT EXISTS memo_tags (
+	memo_id bigint,
+	tag_id bigint,
+	PRIMARY KEY (memo_id, tag_id),
+	CONSTRAINT fk_memo_tags_memo FOREIGN KEY (memo_id) REFERENCES memos (id) ON DELETE CASCADE,
+	CONSTRAINT fk_memo_tags_tag FOREIGN KEY (tag_id) REFERENCES tags (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS memo_revisions (
+	id bigserial PRIMARY KEY,
+	memo_id bigint NOT NULL,
+	revision bigint NOT NULL,
+	user_id bigint NOT NULL,
+	title text NOT NULL,
+	content text,
+	created_at timestamptz,
+	CONSTRAINT fk_memo_revisions_memo FOREIGN KEY (memo_id) REFERENCES memos (id) ON DELETE CASCADE,
+	CONSTRAINT fk_memo_revisions_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_memo_revisions_memo_id_revision ON memo_revisions (memo_id, revision);
+CREATE INDEX IF NOT EXISTS idx_memo_revisions_user_id ON memo_revisions (user_id);
+
+CREATE TABLE IF NOT EXISTS sessions (
+	id varchar(32) PRIMARY KEY,
+	user_id bigint NOT NULL,
+	user_agent text,
+	ip text,
+	created_at timestamptz,
+	last_seen_at timestamptz,
+	expires_at timestamptz NOT NULL,
+	revoked_at timestamptz,
+	CONSTRAINT fk_sessions_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+	id bigserial PRIMARY KEY,
+	session_id varchar(32) NOT NULL,
+	user_id bigint NOT NULL,
+	token_hash text NOT NULL,
+	expires_at timestamptz NOT NULL,
+	used_at timestamptz,
+	created_at timestamptz,
+	CONSTRAINT fk_refresh_tokens_session FOREIGN KEY (session_id) REFERENCES sessions (id) ON DELETE CASCADE,
+	CONSTRAINT fk_refresh_tokens_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session_id ON refresh_tokens (session_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+	id bigserial PRIMARY KEY,
+	user_id bigint NOT NULL,
+	token_hash text NOT NULL,
+	expires_at timestamptz NOT NULL,
+	used_at timestamptz,
+	created_at timestamptz,
+	CONSTRAINT fk_password_reset_tokens_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_password_reset_tokens_token_hash ON password_reset_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens (user_id);
+
+CREATE TABLE IF NOT EXISTS totp_credentials (
+	id bigserial PRIMARY KEY,
+	user_id bigint NOT NULL,
+	secret text NOT NULL,
+	confirmed_at timestamptz,
+	last_used_step bigint NOT NULL DEFAULT 0,
+	created_at timestamptz,
+	CONSTRAINT fk_totp_credentials_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_totp_credentials_user_id ON totp_credentials (user_id);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+	id bigserial PRIMARY KEY,
+	user_id bigint NOT NULL,
+	code_hash text NOT NULL,
+	used_at timestamptz,
+	CONSTRAINT fk_recovery_codes_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes (user_id);
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_code_hash ON recovery_codes (code_hash);
+
+CREATE TABLE IF NOT EXISTS access_tokens (
+	id bigserial PRIMARY KEY,
+	user_id bigint NOT NULL,
+	name text NOT NULL,
+	scopes text NOT NULL,
+	token_hash text NOT NULL,
+	expires_at timestamptz,
+	last_used_at timestamptz,
+	created_at timestamptz,
+	CONSTRAINT fk_access_tokens_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_access_tokens_token_hash ON access_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_access_tokens_user_id ON access_tokens (user_id);
+
+CREATE TABLE IF NOT EXISTS user_identities (
+	id bigserial PRIMARY KEY,
+	user_id bigint NOT NULL,
+	provider text NOT NULL,
+	subject text NOT NULL,
+	email text,
+	created_at timestamptz,
+	CONSTRAINT fk_user_identities_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_identities_provider_subject ON user_identities (provider, subject);
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities (user_id);
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+	key text PRIMARY KEY,
+	failures bigint NOT NULL,
+	last_failure_at timestamptz NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS login_lockouts (
+	id bigserial PRIMARY KEY,
+	key text NOT NULL,
+	ip text,
+	failures bigint,
+	locked_until timestamptz,
+	created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_login_lockouts_key ON login_lockouts (key);
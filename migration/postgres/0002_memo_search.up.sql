@@ -0,0 +1,9 @@
+-- A generated tsvector column with a GIN index for full-text search over
+-- memo titles and contents.
+
+ALTER TABLE memos ADD COLUMN IF NOT EXISTS search_vector tsvector
+	GENERATED ALWAYS AS (
+		setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+		setweight(to_tsvector('simple', coalesce(content, '')), 'B')
+	) STORED;
+CREATE INDEX IF NOT EXISTS idx_memos_search_vector ON memos USING GIN (search_vector);
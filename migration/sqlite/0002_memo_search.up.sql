@@ -0,0 +1,17 @@
+-- A memos_fts table kept in sync by triggers for full-text search over memo
+-- titles and contents. It is an FTS4 table because FTS4 is part of every
+-- build of the driver, while FTS5 needs the sqlite_fts5 build tag.
+
+CREATE VIRTUAL TABLE IF NOT EXISTS memos_fts USING fts4(title, content, tokenize=unicode61);
+CREATE TRIGGER IF NOT EXISTS memos_fts_insert AFTER INSERT ON memos BEGIN
+	INSERT INTO memos_fts(rowid, title, content) VALUES (new.id, new.title, new.content);
+END;
+CREATE TRIGGER IF NOT EXISTS memos_fts_update AFTER UPDATE OF title, content ON memos BEGIN
+	DELETE FROM memos_fts WHERE rowid = old.id;
+	INSERT INTO memos_fts(rowid, title, content) VALUES (new.id, new.title, new.content);
+END;
+CREATE TRIGGER IF NOT EXISTS memos_fts_delete AFTER DELETE ON memos BEGIN
+	DELETE FROM memos_fts WHERE rowid = old.id;
+END;
+DELETE FROM memos_fts;
+INSERT INTO memos_fts(rowid, title, content) SELECT id, title, content FROM memos;
@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+// Scopes of a personal access token. A write token may also read.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// AccessTokenPrefix starts every personal access token so that it can be
+// told apart from a JWT and found by secret scanners.
+const AccessTokenPrefix = "emp_"
+
+// AccessToken is a personal access token a user created for scripts and CLI
+// clients. Only the SHA-256 hash of the token is stored.
+type AccessToken struct {
+	ID         uint   `gorm:"primaryKey"`
+	User       User   `gorm:"foreignKey:UserId; constraint:OnDelete:CASCADE"`
+	UserId     uint   `gorm:"not null; index"`
+	Name       string `gorm:"not null"`
+	Scopes     string `gorm:"not null"`
+	TokenHash  string `gorm:"not null; uniqueIndex"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+type AccessTokenRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type AccessTokenResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// AccessTokenCreatedResponse is the only response that contains the token
+// itself.
+type AccessTokenCreatedResponse struct {
+	AccessTokenResponse
+	Token string `json:"token"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// UserIdentity links a user to the account with Subject at an OpenID Connect
+// provider.
+type UserIdentity struct {
+	ID        uint   `gorm:"primaryKey"`
+	User      User   `gorm:"foreignKey:UserId; constraint:OnDelete:CASCADE"`
+	UserId    uint   `gorm:"not null; index"`
+	Provider  string `gorm:"not null; uniqueIndex:idx_user_identities_provider_subject"`
+	Subject   string `gorm:"not null; uniqueIndex:idx_user_identities_provider_subject"`
+	Email     string
+	CreatedAt time.Time
+}
+
+// OIDCAuthorization starts a login at a provider. StateToken carries the
+// state, nonce and PKCE verifier to the callback and is kept in a cookie.
+type OIDCAuthorization struct {
+	AuthURL    string
+	StateToken string
+	ExpiresAt  time.Time
+}
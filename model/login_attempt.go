@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// LoginAttempt counts the failed logins for Key, which is an email address
+// or an IP address prefixed with its kind, e.g. "ip:192.0.2.1", together
+// with the ones still running. Failures are forgotten after a successful
+// login or a quiet period.
+type LoginAttempt struct {
+	Key           string    `gorm:"primaryKey"`
+	Failures      int       `gorm:"not null"`
+	LastFailureAt time.Time `gorm:"not null"`
+}
+
+// LoginLockout is the audit record of a key being locked out after a failed
+// login from IP.
+type LoginLockout struct {
+	ID          uint   `gorm:"primaryKey"`
+	Key         string `gorm:"not null; index"`
+	IP          string
+	Failures    int
+	LockedUntil time.Time
+	CreatedAt   time.Time
+}
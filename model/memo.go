@@ -12,12 +12,108 @@ type Memo struct {
 	Content string `json:"content"`
 	User    User   `json:"user" gorm:"foreignKey:UserId; constraint:OnDelete:CASCADE"`
 	UserId  uint   `json:"user_id" gorm:"not null"`
+	// NotebookId is nil for memos that are not filed in any notebook.
+	NotebookId *uint     `json:"notebook_id"`
+	Notebook   *Notebook `json:"-" gorm:"foreignKey:NotebookId; constraint:OnDelete:SET NULL"`
+	// TagNames carries the tag names sent by the client. nil leaves the tags
+	// of an existing memo untouched while an empty slice removes them all.
+	TagNames []string `json:"tags" gorm:"-"`
+	Tags     []Tag    `json:"-" gorm:"many2many:memo_tags; constraint:OnDelete:CASCADE"`
+	// Version is incremented on every write and exposed as the ETag. On
+	// update a non-zero Version is the version the client expects to replace.
+	Version uint `json:"-" gorm:"not null; default:1"`
+}
+
+// MemoPatch is a merge patch for a memo. Setting title or content to null
+// empties it, null tags remove all tags and a null notebook_id moves the memo
+// to the root.
+type MemoPatch struct {
+	Title      PatchField[string]   `json:"title"`
+	Content    PatchField[string]   `json:"content"`
+	Tags       PatchField[[]string] `json:"tags"`
+	NotebookId PatchField[uint]     `json:"notebook_id"`
+	// Version is taken from If-Match, see Memo.Version.
+	Version uint `json:"-"`
 }
 
 type MemoResponse struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	Title     string    `json:"title" gorm:"not null"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	Title      string     `json:"title" gorm:"not null"`
+	Content    string     `json:"content"`
+	Tags       []string   `json:"tags"`
+	NotebookId *uint      `json:"notebook_id"`
+	Version    uint       `json:"version"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+}
+
+const (
+	MemoSortCreatedAt = "created_at"
+	MemoSortUpdatedAt = "updated_at"
+	MemoSortTitle     = "title"
+
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+type MemoQuery struct {
+	Limit       int         `json:"limit"`
+	Cursor      string      `json:"cursor"`
+	SortBy      string      `json:"sort"`
+	Order       string      `json:"order"`
+	CreatedFrom time.Time   `json:"created_from"`
+	CreatedTo   time.Time   `json:"created_to"`
+	UpdatedFrom time.Time   `json:"updated_from"`
+	UpdatedTo   time.Time   `json:"updated_to"`
+	Tags        []string    `json:"tag"`
+	TagMatch    string      `json:"tag_match"`
+	NotebookId  *uint       `json:"notebook_id"`
+	Recursive   bool        `json:"recursive"`
+	After       *MemoCursor `json:"-"`
+}
+
+// MemoCursor is the decoded form of the opaque cursor handed out in
+// MemoPageResponse.NextCursor. It records the sort key of the last memo on
+// the page so the next page can continue strictly after it.
+type MemoCursor struct {
+	SortBy string    `json:"s"`
+	Order  string    `json:"o"`
+	Time   time.Time `json:"t,omitempty"`
+	Title  string    `json:"v,omitempty"`
+	ID     uint      `json:"id"`
+}
+
+type MemoPageResponse struct {
+	Memos      []MemoResponse `json:"memos"`
+	NextCursor string         `json:"next_cursor"`
+}
+
+type MemoSearchQuery struct {
+	Query string `json:"q"`
+	Limit int    `json:"limit"`
+}
+
+type MemoSearchResult struct {
+	ID             uint
+	Title          string
+	Content        string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Rank           float64
+	TitleHighlight string
+	Snippet        string
+}
+
+// MemoSearchResponse is a search hit. Matched terms in TitleHighlight and
+// Snippet are wrapped in <mark></mark>; the surrounding text is not escaped.
+type MemoSearchResponse struct {
+	ID             uint      `json:"id"`
+	Title          string    `json:"title"`
+	Content        string    `json:"content"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Rank           float64   `json:"rank"`
+	TitleHighlight string    `json:"title_highlight"`
+	Snippet        string    `json:"snippet"`
 }
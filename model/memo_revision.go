@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// MemoRevision is an immutable snapshot of a memo taken on every create and
+// update. Revisions are numbered per memo starting at 1.
+type MemoRevision struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Memo      Memo      `json:"-" gorm:"foreignKey:MemoId; constraint:OnDelete:CASCADE"`
+	MemoId    uint      `json:"memo_id" gorm:"not null; uniqueIndex:idx_memo_revisions_memo_id_revision"`
+	Revision  uint      `json:"revision" gorm:"not null; uniqueIndex:idx_memo_revisions_memo_id_revision"`
+	User      User      `json:"-" gorm:"foreignKey:UserId; constraint:OnDelete:CASCADE"`
+	UserId    uint      `json:"user_id" gorm:"not null; index"`
+	Title     string    `json:"title" gorm:"not null"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type MemoRevisionResponse struct {
+	Revision  uint      `json:"revision"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MemoRevisionDiffResponse is a revision together with a line-level unified
+// diff of its content against the current version of the memo.
+type MemoRevisionDiffResponse struct {
+	MemoRevisionResponse
+	Diff string `json:"diff"`
+}
@@ -0,0 +1,65 @@
+package model
+
+import "time"
+
+// TOTPCredential is the RFC 6238 secret of a user. It only takes part in
+// logins once ConfirmedAt is set. LastUsedStep is the time step of the last
+// accepted code, so a code cannot be replayed.
+type TOTPCredential struct {
+	ID           uint   `gorm:"primaryKey"`
+	User         User   `gorm:"foreignKey:UserId; constraint:OnDelete:CASCADE"`
+	UserId       uint   `gorm:"not null; uniqueIndex"`
+	Secret       string `gorm:"not null"`
+	ConfirmedAt  *time.Time
+	LastUsedStep int64 `gorm:"not null; default:0"`
+	CreatedAt    time.Time
+}
+
+// RecoveryCode is a one-time code that replaces a TOTP code when the
+// authenticator is lost. Only the SHA-256 hash of the code is stored.
+type RecoveryCode struct {
+	ID       uint   `gorm:"primaryKey"`
+	User     User   `gorm:"foreignKey:UserId; constraint:OnDelete:CASCADE"`
+	UserId   uint   `gorm:"not null; index"`
+	CodeHash string `gorm:"not null; index"`
+	UsedAt   *time.Time
+}
+
+type TOTPEnrollmentResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type RecoveryCodesResponse struct {
+	Codes []string `json:"recovery_codes"`
+}
+
+// LoginResponse is the outcome of the password step of a login. When
+// MFARequired is set no tokens are issued yet; MFAToken has to be exchanged
+// together with a code at POST /login/mfa.
+type LoginResponse struct {
+	UserResponse `json:"-"`
+	MFARequired  bool       `json:"mfa_required"`
+	MFAToken     string     `json:"mfa_token,omitempty"`
+	MFAExpiresAt *time.Time `json:"mfa_expires_at,omitempty"`
+}
+
+// MFAChallenge identifies the challenge token of a login, so that wrong codes
+// can be counted per account and per challenge.
+type MFAChallenge struct {
+	ID     string
+	UserId uint
+}
+
+type MFALoginRequest struct {
+	Token string `json:"mfa_token"`
+	Code  string `json:"code"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+type PasswordConfirmRequest struct {
+	Password string `json:"password"`
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Notebook struct {
+	gorm.Model
+	Name     string    `json:"name" gorm:"not null"`
+	ParentId *uint     `json:"parent_id"`
+	Parent   *Notebook `json:"-" gorm:"foreignKey:ParentId; constraint:OnDelete:CASCADE"`
+	User     User      `json:"user" gorm:"foreignKey:UserId; constraint:OnDelete:CASCADE"`
+	UserId   uint      `json:"user_id" gorm:"not null"`
+}
+
+type NotebookResponse struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name"`
+	ParentId  *uint     `json:"parent_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Policies for deleting a notebook that still contains memos or notebooks.
+const (
+	NotebookDeleteTrash    = "trash"
+	NotebookDeleteReparent = "reparent"
+)
+
+type MemoMove struct {
+	NotebookId *uint `json:"notebook_id"`
+}
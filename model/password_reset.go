@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// PasswordResetToken is a single-use token mailed to a user who forgot their
+// password. Only the SHA-256 hash of the token is stored.
+type PasswordResetToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	User      User      `gorm:"foreignKey:UserId; constraint:OnDelete:CASCADE"`
+	UserId    uint      `gorm:"not null; index"`
+	TokenHash string    `gorm:"not null; uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type PasswordForgotRequest struct {
+	Email string `json:"email"`
+}
+
+type PasswordResetRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
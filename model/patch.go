@@ -0,0 +1,20 @@
+package model
+
+import "encoding/json"
+
+// PatchField is a member of a JSON Merge Patch (RFC 7386) document. It tells
+// a member that was absent (Set is false) from one explicitly set to null.
+type PatchField[T any] struct {
+	Set   bool
+	Null  bool
+	Value T
+}
+
+func (f *PatchField[T]) UnmarshalJSON(b []byte) error {
+	f.Set = true
+	if string(b) == "null" {
+		f.Null = true
+		return nil
+	}
+	return json.Unmarshal(b, &f.Value)
+}
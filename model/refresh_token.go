@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// RefreshToken is a long-lived opaque token exchanged for a new access token.
+// Only the SHA-256 hash of the token is stored. Each refresh rotates the
+// token within its session; presenting a rotated token again revokes the
+// whole session.
+type RefreshToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	Session   Session   `gorm:"foreignKey:SessionId; constraint:OnDelete:CASCADE"`
+	SessionId string    `gorm:"not null; index; size:32"`
+	User      User      `gorm:"foreignKey:UserId; constraint:OnDelete:CASCADE"`
+	UserId    uint      `gorm:"not null; index"`
+	TokenHash string    `gorm:"not null; uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// TokenPair is the result of a login or refresh: a short-lived JWT access
+// token and the refresh token that replaces the one presented.
+type TokenPair struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
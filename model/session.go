@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// Session is one signed-in device. Its ID is carried as the jti claim of the
+// access tokens issued for it, so revoking the session invalidates them
+// before they expire.
+type Session struct {
+	ID         string `gorm:"primaryKey; size:32"`
+	User       User   `gorm:"foreignKey:UserId; constraint:OnDelete:CASCADE"`
+	UserId     uint   `gorm:"not null; index"`
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time `gorm:"not null"`
+	RevokedAt  *time.Time
+}
+
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
+
+// SessionClient describes the device a session is created for.
+type SessionClient struct {
+	UserAgent string
+	IP        string
+}
@@ -0,0 +1,25 @@
+package model
+
+import "gorm.io/gorm"
+
+type Tag struct {
+	gorm.Model
+	Name   string `json:"name" gorm:"not null; uniqueIndex:idx_tags_user_id_name"`
+	User   User   `json:"user" gorm:"foreignKey:UserId; constraint:OnDelete:CASCADE"`
+	UserId uint   `json:"user_id" gorm:"not null; uniqueIndex:idx_tags_user_id_name"`
+}
+
+type TagResponse struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	Name      string `json:"name"`
+	MemoCount int64  `json:"memo_count"`
+}
+
+type TagMerge struct {
+	TargetId uint `json:"target_id"`
+}
+
+const (
+	TagMatchAny = "any"
+	TagMatchAll = "all"
+)
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -8,9 +10,31 @@ type User struct {
 	gorm.Model
 	Email    string `json:"email" gorm:"unique"`
 	Password string `json:"password"`
+	// EmailVerifiedAt is set once the user opened the link mailed to Email.
+	EmailVerifiedAt    *time.Time `json:"-"`
+	VerificationSentAt *time.Time `json:"-"`
+	// PendingEmail is the address the user asked to change to. It replaces
+	// Email once the link mailed to it is opened.
+	PendingEmail *string `json:"-"`
+	// DeletionScheduledAt is when the account and everything in it will be
+	// deleted, unless the user cancels before then.
+	DeletionScheduledAt *time.Time `json:"-" gorm:"index"`
 }
 
 type UserResponse struct {
-	ID    uint   `json:"id" gorm:"primaryKey"`
-	Email string `json:"email" gorm:"unique"`
+	ID                  uint       `json:"id" gorm:"primaryKey"`
+	Email               string     `json:"email" gorm:"unique"`
+	EmailVerified       bool       `json:"email_verified"`
+	PendingEmail        *string    `json:"pending_email,omitempty"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+}
+
+type PasswordChangeRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type EmailChangeRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
 }
@@ -0,0 +1,282 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// DefaultScopes are requested when a provider is configured without scopes.
+var DefaultScopes = []string{"openid", "email", "profile"}
+
+// keysRefreshInterval limits how often an unknown key id makes the provider
+// fetch its JWKS again, so forged tokens cannot be used to hammer it.
+const keysRefreshInterval = time.Minute
+
+// maxResponseSize caps the documents read from a provider.
+const maxResponseSize = 1 << 20
+
+// Config describes an OpenID Connect provider. Its endpoints are discovered
+// from Issuer.
+type Config struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Claims are the verified claims of an ID token that matter for login.
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+// TokenError is an OAuth 2.0 error returned by the token endpoint, such as
+// an invalid or already used authorization code.
+type TokenError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *TokenError) Error() string {
+	if e.Description == "" {
+		return "oidc: token endpoint: " + e.Code
+	}
+	return "oidc: token endpoint: " + e.Code + ": " + e.Description
+}
+
+type IProvider interface {
+	AuthCodeURL(state string, nonce string, codeChallenge string) (string, error)
+	Exchange(code string, codeVerifier string) (string, error)
+	VerifyIDToken(rawIDToken string, nonce string) (Claims, error)
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type provider struct {
+	cfg    Config
+	client *http.Client
+
+	mu            sync.Mutex
+	meta          *metadata
+	keys          map[string]interface{}
+	keysFetchedAt time.Time
+}
+
+// NewProvider returns a provider that discovers its endpoints and keys on
+// first use. client defaults to an http.Client with a short timeout.
+func NewProvider(cfg Config, client *http.Client) IProvider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = DefaultScopes
+	}
+	return &provider{cfg: cfg, client: client}
+}
+
+func (p *provider) AuthCodeURL(state string, nonce string, codeChallenge string) (string, error) {
+	meta, err := p.metadata()
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", codeChallenge)
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// Exchange redeems an authorization code and returns the raw ID token, which
+// still has to be checked with VerifyIDToken.
+func (p *provider) Exchange(code string, codeVerifier string) (string, error) {
+	meta, err := p.metadata()
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+	form.Set("client_id", p.cfg.ClientID)
+	req, err := http.NewRequest(http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+	res, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	body := struct {
+		IDToken string `json:"id_token"`
+		TokenError
+	}{}
+	if err := json.NewDecoder(io.LimitReader(res.Body, maxResponseSize)).Decode(&body); err != nil {
+		return "", fmt.Errorf("oidc: token endpoint: %s: %w", res.Status, err)
+	}
+	if body.Code != "" {
+		return "", &body.TokenError
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("oidc: token endpoint: %s", res.Status)
+	}
+	if body.IDToken == "" {
+		return "", errors.New("oidc: token response has no id_token")
+	}
+	return body.IDToken, nil
+}
+
+// VerifyIDToken checks the signature of an ID token against the provider's
+// JWKS and validates its issuer, audience, expiry and nonce.
+func (p *provider) VerifyIDToken(rawIDToken string, nonce string) (Claims, error) {
+	meta, err := p.metadata()
+	if err != nil {
+		return Claims{}, err
+	}
+	claims := jwt.MapClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}))
+	if _, err := parser.ParseWithClaims(rawIDToken, claims, p.keyFunc); err != nil {
+		return Claims{}, fmt.Errorf("oidc: id token: %w", err)
+	}
+	if !claims.VerifyIssuer(meta.Issuer, true) {
+		return Claims{}, errors.New("oidc: id token: wrong issuer")
+	}
+	if !claims.VerifyAudience(p.cfg.ClientID, true) {
+		return Claims{}, errors.New("oidc: id token: wrong audience")
+	}
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return Claims{}, errors.New("oidc: id token: missing or past exp")
+	}
+	if azp, ok := claims["azp"].(string); ok && azp != p.cfg.ClientID {
+		return Claims{}, errors.New("oidc: id token: wrong authorized party")
+	}
+	tokenNonce, _ := claims["nonce"].(string)
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(tokenNonce), []byte(nonce)) != 1 {
+		return Claims{}, errors.New("oidc: id token: nonce mismatch")
+	}
+	res := Claims{}
+	res.Subject, _ = claims["sub"].(string)
+	if res.Subject == "" {
+		return Claims{}, errors.New("oidc: id token: missing sub")
+	}
+	res.Email, _ = claims["email"].(string)
+	// Some providers send email_verified as a string.
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		res.EmailVerified = v
+	case string:
+		res.EmailVerified = v == "true"
+	}
+	return res, nil
+}
+
+func (p *provider) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	if time.Since(p.keysFetchedAt) < keysRefreshInterval {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	keys, err := fetchKeys(p.client, p.meta.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+// lookupKey finds a key by id. A token without a key id is accepted only
+// when the provider publishes a single key.
+func (p *provider) lookupKey(kid string) interface{} {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key
+		}
+	}
+	return p.keys[kid]
+}
+
+func (p *provider) metadata() (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil {
+		return p.meta, nil
+	}
+	meta := metadata{}
+	wellKnown := strings.TrimSuffix(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := getJSON(p.client, wellKnown, &meta); err != nil {
+		return nil, err
+	}
+	if meta.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("oidc: discovery: issuer %q does not match %q", meta.Issuer, p.cfg.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, errors.New("oidc: discovery: missing endpoints")
+	}
+	p.meta = &meta
+	return p.meta, nil
+}
+
+func getJSON(client *http.Client, url string, v interface{}) error {
+	res, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: GET %s: %s", url, res.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, maxResponseSize)).Decode(v); err != nil {
+		return fmt.Errorf("oidc: GET %s: %w", url, err)
+	}
+	return nil
+}
+
+// CodeChallenge derives the S256 PKCE code challenge of a code verifier.
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
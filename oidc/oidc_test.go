@@ -0,0 +1,192 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"echo-rest-api/testHelpers"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+const testRedirectURL = "http://localhost:8080/oidc/mock/callback"
+
+func newTestProvider(m *testHelpers.MockOIDCProvider) IProvider {
+	return NewProvider(Config{
+		Name:         "mock",
+		Issuer:       m.Issuer,
+		ClientID:     m.ClientID,
+		ClientSecret: m.ClientSecret,
+		RedirectURL:  testRedirectURL,
+	}, nil)
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	m := testHelpers.NewMockOIDCProvider()
+	defer m.Close()
+	m.Claims = jwt.MapClaims{"sub": "alice", "email": "alice@example.com", "email_verified": true}
+	p := newTestProvider(m)
+
+	authURL, err := p.AuthCodeURL("state1", "nonce1", CodeChallenge("verifier1"))
+	assert.Nil(t, err)
+	u, err := url.Parse(authURL)
+	assert.Nil(t, err)
+	assert.Equal(t, m.Issuer+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+	assert.Equal(t, "openid email profile", u.Query().Get("scope"))
+	assert.Equal(t, testRedirectURL, u.Query().Get("redirect_uri"))
+	assert.Equal(t, "S256", u.Query().Get("code_challenge_method"))
+
+	code, state, err := m.Authorize(authURL)
+	assert.Nil(t, err)
+	assert.Equal(t, "state1", state)
+	rawIDToken, err := p.Exchange(code, "verifier1")
+	assert.Nil(t, err)
+	claims, err := p.VerifyIDToken(rawIDToken, "nonce1")
+	assert.Nil(t, err)
+	assert.Equal(t, Claims{Subject: "alice", Email: "alice@example.com", EmailVerified: true}, claims)
+
+	_, err = p.Exchange(code, "verifier1")
+	var tokenErr *TokenError
+	assert.True(t, errors.As(err, &tokenErr))
+	assert.Equal(t, "invalid_grant", tokenErr.Code)
+}
+
+func TestExchange_PKCE(t *testing.T) {
+	m := testHelpers.NewMockOIDCProvider()
+	defer m.Close()
+	p := newTestProvider(m)
+
+	authURL, err := p.AuthCodeURL("state1", "nonce1", CodeChallenge("verifier1"))
+	assert.Nil(t, err)
+	code, _, err := m.Authorize(authURL)
+	assert.Nil(t, err)
+	_, err = p.Exchange(code, "another verifier")
+	var tokenErr *TokenError
+	assert.True(t, errors.As(err, &tokenErr))
+	assert.Equal(t, "PKCE verification failed", tokenErr.Description)
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	m := testHelpers.NewMockOIDCProvider()
+	defer m.Close()
+	m.Claims = jwt.MapClaims{"sub": "alice", "nonce": "nonce1"}
+	p := newTestProvider(m)
+
+	_, err := p.VerifyIDToken(m.SignIDToken(nil), "nonce1")
+	assert.Nil(t, err)
+	claims, err := p.VerifyIDToken(m.SignIDToken(jwt.MapClaims{"email_verified": "true"}), "nonce1")
+	assert.Nil(t, err)
+	assert.True(t, claims.EmailVerified)
+
+	invalid := map[string]string{
+		"nonce":        m.SignIDToken(nil),
+		"issuer":       m.SignIDToken(jwt.MapClaims{"iss": "https://evil.example.com"}),
+		"audience":     m.SignIDToken(jwt.MapClaims{"aud": "another-client"}),
+		"azp":          m.SignIDToken(jwt.MapClaims{"aud": []string{m.ClientID, "another-client"}, "azp": "another-client"}),
+		"expired":      m.SignIDToken(jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}),
+		"no sub":       m.SignIDToken(jwt.MapClaims{"sub": ""}),
+		"garbage":      "not a jwt",
+		"unsigned":     unsignedToken(t),
+		"hmac":         hmacToken(t, m),
+		"other key":    otherKeyToken(t, m),
+		"unknown kid":  unknownKidToken(t, m),
+		"wrong kty ec": ecToken(t, m),
+	}
+	for name, token := range invalid {
+		nonce := "nonce1"
+		if name == "nonce" {
+			nonce = "nonce2"
+		}
+		_, err := p.VerifyIDToken(token, nonce)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestVerifyIDToken_KeyRotation(t *testing.T) {
+	m := testHelpers.NewMockOIDCProvider()
+	defer m.Close()
+	m.Claims = jwt.MapClaims{"sub": "alice", "nonce": "nonce1"}
+	p := newTestProvider(m)
+	_, err := p.VerifyIDToken(m.SignIDToken(nil), "nonce1")
+	assert.Nil(t, err)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	m.Key, m.KeyID = key, "key2"
+	_, err = p.VerifyIDToken(m.SignIDToken(nil), "nonce1")
+	assert.Error(t, err, "keys were fetched recently")
+
+	p.(*provider).keysFetchedAt = time.Now().Add(-keysRefreshInterval)
+	_, err = p.VerifyIDToken(m.SignIDToken(nil), "nonce1")
+	assert.Nil(t, err)
+}
+
+func TestDiscovery_IssuerMismatch(t *testing.T) {
+	m := testHelpers.NewMockOIDCProvider()
+	defer m.Close()
+	p := NewProvider(Config{Issuer: m.Issuer + "/", ClientID: m.ClientID}, nil)
+
+	_, err := p.AuthCodeURL("state1", "nonce1", "challenge")
+	assert.ErrorContains(t, err, "does not match")
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// RFC 7636 appendix B.
+	assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+}
+
+func unsignedToken(t *testing.T) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"sub": "alice"}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	assert.Nil(t, err)
+	return token
+}
+
+func hmacToken(t *testing.T, m *testHelpers.MockOIDCProvider) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss": m.Issuer, "aud": m.ClientID, "sub": "alice", "nonce": "nonce1", "exp": time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = m.KeyID
+	signed, err := token.SignedString(m.Key.N.Bytes())
+	assert.Nil(t, err)
+	return signed
+}
+
+func otherKeyToken(t *testing.T, m *testHelpers.MockOIDCProvider) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss": m.Issuer, "aud": m.ClientID, "sub": "alice", "nonce": "nonce1", "exp": time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = m.KeyID
+	signed, err := token.SignedString(key)
+	assert.Nil(t, err)
+	return signed
+}
+
+func unknownKidToken(t *testing.T, m *testHelpers.MockOIDCProvider) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss": m.Issuer, "aud": m.ClientID, "sub": "alice", "nonce": "nonce1", "exp": time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = "unknown"
+	signed, err := token.SignedString(m.Key)
+	assert.Nil(t, err)
+	return signed
+}
+
+func ecToken(t *testing.T, m *testHelpers.MockOIDCProvider) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": m.Issuer, "aud": m.ClientID, "sub": "alice", "nonce": "nonce1", "exp": time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = m.KeyID
+	signed, err := token.SignedString(key)
+	assert.Nil(t, err)
+	return signed
+}
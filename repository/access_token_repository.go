@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type IAccessTokenRepository interface {
+	GetAccessTokensByUser(ctx context.Context, tokens *[]model.AccessToken, userId uint) error
+	GetAccessTokenByHash(ctx context.Context, token *model.AccessToken, hash string) error
+	CreateAccessToken(ctx context.Context, token *model.AccessToken) error
+	TouchAccessToken(ctx context.Context, tokenId uint, usedAt time.Time) error
+	DeleteAccessToken(ctx context.Context, userId uint, tokenId uint) error
+}
+
+type accessTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewAccessTokenRepository(db *gorm.DB) IAccessTokenRepository {
+	return &accessTokenRepository{db}
+}
+
+func (ar *accessTokenRepository) GetAccessTokensByUser(ctx context.Context, tokens *[]model.AccessToken, userId uint) error {
+	if err := ar.db.WithContext(ctx).Where("user_id = ?", userId).Order("created_at DESC").Order("id DESC").Find(tokens).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (ar *accessTokenRepository) GetAccessTokenByHash(ctx context.Context, token *model.AccessToken, hash string) error {
+	if err := ar.db.WithContext(ctx).Where("token_hash = ?", hash).First(token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.Wrap(apperror.ErrNotFound, err)
+		}
+		return err
+	}
+	return nil
+}
+
+func (ar *accessTokenRepository) CreateAccessToken(ctx context.Context, token *model.AccessToken) error {
+	if err := ar.db.WithContext(ctx).Omit("User").Create(token).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (ar *accessTokenRepository) TouchAccessToken(ctx context.Context, tokenId uint, usedAt time.Time) error {
+	if err := ar.db.WithContext(ctx).Model(&model.AccessToken{}).Where("id = ?", tokenId).Update("last_used_at", usedAt).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (ar *accessTokenRepository) DeleteAccessToken(ctx context.Context, userId uint, tokenId uint) error {
+	result := ar.db.WithContext(ctx).Where("id = ? AND user_id = ?", tokenId, userId).Delete(&model.AccessToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected < 1 {
+		return apperror.New(apperror.ErrNotFound, "object does not exist")
+	}
+	return nil
+}
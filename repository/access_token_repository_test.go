@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"echo-rest-api/testHelpers"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessTokenRepository(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	repository := NewAccessTokenRepository(db)
+	first := model.AccessToken{UserId: 1, Name: "ci", Scopes: "read", TokenHash: "first"}
+	second := model.AccessToken{UserId: 1, Name: "cli", Scopes: "read write", TokenHash: "second"}
+	other := model.AccessToken{UserId: 2, Name: "other", Scopes: "read", TokenHash: "other"}
+	for _, token := range []*model.AccessToken{&first, &second, &other} {
+		assert.Nil(t, repository.CreateAccessToken(context.Background(), token))
+	}
+
+	tokens := []model.AccessToken{}
+	assert.Nil(t, repository.GetAccessTokensByUser(context.Background(), &tokens, 1))
+	assert.Equal(t, 2, len(tokens))
+
+	usedAt := time.Now()
+	assert.Nil(t, repository.TouchAccessToken(context.Background(), second.ID, usedAt))
+	stored := model.AccessToken{}
+	assert.Nil(t, repository.GetAccessTokenByHash(context.Background(), &stored, "second"))
+	assert.WithinDuration(t, usedAt, *stored.LastUsedAt, time.Second)
+
+	assert.ErrorIs(t, repository.DeleteAccessToken(context.Background(), 2, first.ID), apperror.ErrNotFound)
+	assert.Nil(t, repository.DeleteAccessToken(context.Background(), 1, first.ID))
+	err := repository.GetAccessTokenByHash(context.Background(), &model.AccessToken{}, "first")
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+}
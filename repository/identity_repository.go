@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type IIdentityRepository interface {
+	GetIdentity(ctx context.Context, identity *model.UserIdentity, provider string, subject string) error
+	CreateIdentity(ctx context.Context, identity *model.UserIdentity) error
+	CreateUserWithIdentity(ctx context.Context, user *model.User, identity *model.UserIdentity) error
+}
+
+type identityRepository struct {
+	db *gorm.DB
+}
+
+func NewIdentityRepository(db *gorm.DB) IIdentityRepository {
+	return &identityRepository{db}
+}
+
+func (ir *identityRepository) GetIdentity(ctx context.Context, identity *model.UserIdentity, provider string, subject string) error {
+	if err := ir.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(identity).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.Wrap(apperror.ErrNotFound, err)
+		}
+		return err
+	}
+	return nil
+}
+
+func (ir *identityRepository) CreateIdentity(ctx context.Context, identity *model.UserIdentity) error {
+	return createIdentity(ir.db.WithContext(ctx), identity)
+}
+
+// CreateUserWithIdentity creates a user that signs in through a provider
+// only, so that no user is left behind without its identity.
+func (ir *identityRepository) CreateUserWithIdentity(ctx context.Context, user *model.User, identity *model.UserIdentity) error {
+	return ir.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return apperror.New(apperror.ErrConflict, "email already registered")
+			}
+			return err
+		}
+		identity.UserId = user.ID
+		return createIdentity(tx, identity)
+	})
+}
+
+func createIdentity(db *gorm.DB, identity *model.UserIdentity) error {
+	if err := db.Omit("User").Create(identity).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return apperror.New(apperror.ErrConflict, "identity already linked")
+		}
+		return err
+	}
+	return nil
+}
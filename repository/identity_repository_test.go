@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"echo-rest-api/testHelpers"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateIdentity(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	repository := NewIdentityRepository(db)
+
+	identity := model.UserIdentity{}
+	assert.ErrorIs(t, repository.GetIdentity(context.Background(), &identity, "mock", "alice"), apperror.ErrNotFound)
+	assert.Nil(t, repository.CreateIdentity(context.Background(), &model.UserIdentity{UserId: 1, Provider: "mock", Subject: "alice"}))
+	assert.Nil(t, repository.CreateIdentity(context.Background(), &model.UserIdentity{UserId: 2, Provider: "other", Subject: "alice"}))
+	err := repository.CreateIdentity(context.Background(), &model.UserIdentity{UserId: 2, Provider: "mock", Subject: "alice"})
+	assert.ErrorIs(t, err, apperror.ErrConflict)
+
+	assert.Nil(t, repository.GetIdentity(context.Background(), &identity, "mock", "alice"))
+	assert.Equal(t, uint(1), identity.UserId)
+}
+
+func TestCreateUserWithIdentity(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	repository := NewIdentityRepository(db)
+
+	user := model.User{Email: "alice@example.com"}
+	identity := model.UserIdentity{Provider: "mock", Subject: "alice"}
+	assert.Nil(t, repository.CreateUserWithIdentity(context.Background(), &user, &identity))
+	assert.NotZero(t, user.ID)
+	assert.Equal(t, user.ID, identity.UserId)
+
+	// A taken subject rolls the new user back.
+	user = model.User{Email: "bob@example.com"}
+	err := repository.CreateUserWithIdentity(context.Background(), &user, &model.UserIdentity{Provider: "mock", Subject: "alice"})
+	assert.ErrorIs(t, err, apperror.ErrConflict)
+	var count int64
+	db.Model(&model.User{}).Where("email = ?", "bob@example.com").Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	user = model.User{Email: "testuser1@example.com"}
+	err = repository.CreateUserWithIdentity(context.Background(), &user, &model.UserIdentity{Provider: "mock", Subject: "carol"})
+	assert.ErrorIs(t, err, apperror.ErrConflict)
+}
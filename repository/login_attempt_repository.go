@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"echo-rest-api/model"
+	"slices"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ILoginAttemptRepository interface {
+	GetLoginAttempts(attempts *[]model.LoginAttempt, keys []string) error
+	// UpdateLoginAttempts hands the counters of keys, in that order, to
+	// update and stores what it leaves in them, unless it fails. The counters
+	// stay locked meanwhile, so that a check and the count it leads to are
+	// one step. A key without failures, or whose last failure was before
+	// since, has a counter at zero; a counter left at zero is deleted.
+	UpdateLoginAttempts(keys []string, since time.Time, update func(attempts []model.LoginAttempt) error) error
+	DeleteLoginAttempt(key string) error
+}
+
+type loginAttemptRepository struct {
+	db *gorm.DB
+}
+
+// NewLoginAttemptRepository keeps the counters in the database, so that all
+// instances of the API share them.
+func NewLoginAttemptRepository(db *gorm.DB) ILoginAttemptRepository {
+	return &loginAttemptRepository{db}
+}
+
+func (lr *loginAttemptRepository) GetLoginAttempts(attempts *[]model.LoginAttempt, keys []string) error {
+	if err := lr.db.Where("key IN ?", keys).Find(attempts).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (lr *loginAttemptRepository) UpdateLoginAttempts(keys []string, since time.Time, update func(attempts []model.LoginAttempt) error) error {
+	// Rows are created and locked in key order, so that two logins that
+	// share some keys cannot wait for each other.
+	sorted := slices.Sorted(slices.Values(keys))
+	return lr.db.Transaction(func(tx *gorm.DB) error {
+		missing := make([]model.LoginAttempt, len(sorted))
+		for i, key := range sorted {
+			missing[i] = model.LoginAttempt{Key: key}
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&missing).Error; err != nil {
+			return err
+		}
+		locked := []model.LoginAttempt{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key IN ?", sorted).Order("key").Find(&locked).Error; err != nil {
+			return err
+		}
+		byKey := map[string]model.LoginAttempt{}
+		for _, attempt := range locked {
+			if attempt.LastFailureAt.Before(since) {
+				attempt.Failures = 0
+			}
+			byKey[attempt.Key] = attempt
+		}
+		attempts := make([]model.LoginAttempt, len(keys))
+		for i, key := range keys {
+			attempts[i] = byKey[key]
+			attempts[i].Key = key
+		}
+		if err := update(attempts); err != nil {
+			return err
+		}
+		for _, attempt := range attempts {
+			if attempt.Failures <= 0 {
+				if err := tx.Where("key = ?", attempt.Key).Delete(&model.LoginAttempt{}).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if err := tx.Save(&attempt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (lr *loginAttemptRepository) DeleteLoginAttempt(key string) error {
+	if err := lr.db.Where("key = ?", key).Delete(&model.LoginAttempt{}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// memorySweepInterval is how often the memory repository drops counters
+// that would start over anyway.
+const memorySweepInterval = time.Minute
+
+type memoryLoginAttemptRepository struct {
+	mu       sync.Mutex
+	attempts map[string]model.LoginAttempt
+	sweptAt  time.Time
+}
+
+// NewMemoryLoginAttemptRepository keeps the counters in this process. It is
+// enough for a single instance and loses the counters on restart.
+func NewMemoryLoginAttemptRepository() ILoginAttemptRepository {
+	return &memoryLoginAttemptRepository{attempts: map[string]model.LoginAttempt{}}
+}
+
+func (mr *memoryLoginAttemptRepository) GetLoginAttempts(attempts *[]model.LoginAttempt, keys []string) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	*attempts = []model.LoginAttempt{}
+	for _, key := range keys {
+		if attempt, ok := mr.attempts[key]; ok {
+			*attempts = append(*attempts, attempt)
+		}
+	}
+	return nil
+}
+
+func (mr *memoryLoginAttemptRepository) UpdateLoginAttempts(keys []string, since time.Time, update func(attempts []model.LoginAttempt) error) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	if now := time.Now(); now.Sub(mr.sweptAt) >= memorySweepInterval {
+		for k, v := range mr.attempts {
+			if v.LastFailureAt.Before(since) {
+				delete(mr.attempts, k)
+			}
+		}
+		mr.sweptAt = now
+	}
+	attempts := make([]model.LoginAttempt, len(keys))
+	for i, key := range keys {
+		attempts[i] = mr.attempts[key]
+		if attempts[i].LastFailureAt.Before(since) {
+			attempts[i].Failures = 0
+		}
+		attempts[i].Key = key
+	}
+	if err := update(attempts); err != nil {
+		return err
+	}
+	for _, attempt := range attempts {
+		if attempt.Failures <= 0 {
+			delete(mr.attempts, attempt.Key)
+			continue
+		}
+		mr.attempts[attempt.Key] = attempt
+	}
+	return nil
+}
+
+func (mr *memoryLoginAttemptRepository) DeleteLoginAttempt(key string) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	delete(mr.attempts, key)
+	return nil
+}
+
+type ILoginLockoutRepository interface {
+	CreateLoginLockout(lockout *model.LoginLockout) error
+}
+
+type loginLockoutRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginLockoutRepository(db *gorm.DB) ILoginLockoutRepository {
+	return &loginLockoutRepository{db}
+}
+
+func (lr *loginLockoutRepository) CreateLoginLockout(lockout *model.LoginLockout) error {
+	if err := lr.db.Create(lockout).Error; err != nil {
+		return err
+	}
+	return nil
+}
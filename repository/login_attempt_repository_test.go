@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"echo-rest-api/model"
+	"echo-rest-api/testHelpers"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func countLoginFailure(at time.Time) func(attempts []model.LoginAttempt) error {
+	return func(attempts []model.LoginAttempt) error {
+		for i := range attempts {
+			attempts[i].Failures++
+			attempts[i].LastFailureAt = at
+		}
+		return nil
+	}
+}
+
+func testUpdateLoginAttempts(t *testing.T, repository ILoginAttemptRepository) {
+	now := time.Now()
+	assert.Nil(t, repository.UpdateLoginAttempts([]string{"ip:192.0.2.1"}, now.Add(-3*time.Hour), countLoginFailure(now.Add(-2*time.Hour))))
+	// The quiet period since the last failure starts the count over.
+	assert.Nil(t, repository.UpdateLoginAttempts([]string{"ip:192.0.2.1"}, now.Add(-time.Hour), countLoginFailure(now.Add(-time.Minute))))
+	assert.Nil(t, repository.UpdateLoginAttempts([]string{"ip:192.0.2.1", "email:a@example.com"}, now.Add(-time.Hour), countLoginFailure(now)))
+
+	attempts := []model.LoginAttempt{}
+	assert.Nil(t, repository.GetLoginAttempts(&attempts, []string{"ip:192.0.2.1", "email:b@example.com"}))
+	assert.Equal(t, 1, len(attempts))
+	assert.Equal(t, 2, attempts[0].Failures)
+	assert.WithinDuration(t, now, attempts[0].LastFailureAt, time.Millisecond)
+
+	// A failed update changes nothing, and a counter at zero is gone.
+	assert.Error(t, repository.UpdateLoginAttempts([]string{"ip:192.0.2.1", "email:b@example.com"}, now.Add(-time.Hour), func(attempts []model.LoginAttempt) error {
+		assert.Equal(t, "ip:192.0.2.1", attempts[0].Key)
+		assert.Equal(t, 2, attempts[0].Failures)
+		assert.Equal(t, "email:b@example.com", attempts[1].Key)
+		assert.Equal(t, 0, attempts[1].Failures)
+		attempts[0].Failures = 10
+		return errors.New("locked out")
+	}))
+	assert.Nil(t, repository.UpdateLoginAttempts([]string{"email:a@example.com"}, now.Add(-time.Hour), func(attempts []model.LoginAttempt) error {
+		attempts[0].Failures--
+		return nil
+	}))
+	assert.Nil(t, repository.GetLoginAttempts(&attempts, []string{"ip:192.0.2.1", "email:a@example.com", "email:b@example.com"}))
+	assert.Equal(t, 1, len(attempts))
+	assert.Equal(t, 2, attempts[0].Failures)
+
+	assert.Nil(t, repository.DeleteLoginAttempt("ip:192.0.2.1"))
+	assert.Nil(t, repository.GetLoginAttempts(&attempts, []string{"ip:192.0.2.1"}))
+	assert.Empty(t, attempts)
+}
+
+func TestUpdateLoginAttempts(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	testUpdateLoginAttempts(t, NewLoginAttemptRepository(db))
+}
+
+func TestUpdateLoginAttempts_Memory(t *testing.T) {
+	testUpdateLoginAttempts(t, NewMemoryLoginAttemptRepository())
+}
+
+func TestCreateLoginLockout(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	repository := NewLoginLockoutRepository(db)
+
+	lockout := model.LoginLockout{Key: "email:a@example.com", IP: "192.0.2.1", Failures: 5, LockedUntil: time.Now().Add(time.Minute)}
+	assert.Nil(t, repository.CreateLoginLockout(&lockout))
+	assert.NotZero(t, lockout.ID)
+}
@@ -1,71 +1,305 @@
 package repository
 
 import (
+	"context"
+	"echo-rest-api/apperror"
 	"echo-rest-api/model"
+	"errors"
 	"fmt"
+	"time"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
 type IMemoRepository interface {
-	GetAllMemos(memos *[]model.Memo, userId uint) error
-	GetMemoById(memo *model.Memo, userId uint, memoId uint) error
-	CreateMemo(memo *model.Memo) error
-	UpdateMemo(memo *model.Memo, userId uint, memoId uint) error
-	DeleteMemo(userId uint, memoId uint) error
+	GetAllMemos(ctx context.Context, memos *[]model.Memo, userId uint, query model.MemoQuery) error
+	GetMemoById(ctx context.Context, memo *model.Memo, userId uint, memoId uint) error
+	SearchMemos(ctx context.Context, results *[]model.MemoSearchResult, userId uint, query model.MemoSearchQuery) error
+	CountMemos(ctx context.Context, count *int64, userId uint) error
+	CreateMemo(ctx context.Context, memo *model.Memo) error
+	UpdateMemo(ctx context.Context, memo *model.Memo, userId uint, memoId uint) error
+	PatchMemo(ctx context.Context, memo *model.Memo, userId uint, memoId uint) error
+	MoveMemo(ctx context.Context, memo *model.Memo, userId uint, memoId uint, notebookId *uint) error
+	DeleteMemo(ctx context.Context, userId uint, memoId uint) error
+	GetTrashedMemos(ctx context.Context, memos *[]model.Memo, userId uint) error
+	RestoreMemo(ctx context.Context, memo *model.Memo, userId uint, memoId uint) error
+	PurgeMemo(ctx context.Context, userId uint, memoId uint) error
+	EmptyTrash(ctx context.Context, userId uint) error
+	PurgeMemosDeletedBefore(ctx context.Context, before time.Time) (int64, error)
+	GetMemoRevisions(ctx context.Context, revisions *[]model.MemoRevision, userId uint, memoId uint) error
+	GetMemoRevision(ctx context.Context, revision *model.MemoRevision, userId uint, memoId uint, rev uint) error
 }
 
 type memoRepository struct {
-	db *gorm.DB
+	db           *gorm.DB
+	maxRevisions int
 }
 
-func NewMemoRepository(db *gorm.DB) IMemoRepository {
-	return &memoRepository{db}
+// NewMemoRepository keeps at most maxRevisions memo revisions per user, or
+// DefaultMaxMemoRevisions when maxRevisions is not positive.
+func NewMemoRepository(db *gorm.DB, maxRevisions int) IMemoRepository {
+	if maxRevisions <= 0 {
+		maxRevisions = DefaultMaxMemoRevisions
+	}
+	return &memoRepository{db, maxRevisions}
+}
+
+var memoSortColumns = map[string]string{
+	model.MemoSortCreatedAt: "memos.created_at",
+	model.MemoSortUpdatedAt: "memos.updated_at",
+	model.MemoSortTitle:     "memos.title",
 }
 
-func (mr *memoRepository) GetAllMemos(memos *[]model.Memo, userId uint) error {
-	if err := mr.db.Joins("User").Where("user_id = ?", userId).Order("memos.created_at desc").Find(memos).Error; err != nil {
+func (mr *memoRepository) GetAllMemos(ctx context.Context, memos *[]model.Memo, userId uint, query model.MemoQuery) error {
+	column, ok := memoSortColumns[query.SortBy]
+	if !ok {
+		column = memoSortColumns[model.MemoSortCreatedAt]
+	}
+	order, op := model.SortDesc, "<"
+	if query.Order == model.SortAsc {
+		order, op = model.SortAsc, ">"
+	}
+
+	db := mr.db.WithContext(ctx).Joins("User").Where("user_id = ?", userId)
+	if !query.CreatedFrom.IsZero() {
+		db = db.Where("memos.created_at >= ?", query.CreatedFrom)
+	}
+	if !query.CreatedTo.IsZero() {
+		db = db.Where("memos.created_at < ?", query.CreatedTo)
+	}
+	if !query.UpdatedFrom.IsZero() {
+		db = db.Where("memos.updated_at >= ?", query.UpdatedFrom)
+	}
+	if !query.UpdatedTo.IsZero() {
+		db = db.Where("memos.updated_at < ?", query.UpdatedTo)
+	}
+	if query.NotebookId != nil {
+		if query.Recursive {
+			db = db.Where("memos.notebook_id IN (?)", notebookSubtree(mr.db.WithContext(ctx), userId, *query.NotebookId))
+		} else {
+			db = db.Where("memos.notebook_id = ?", *query.NotebookId)
+		}
+	}
+	if len(query.Tags) > 0 {
+		sub := mr.db.WithContext(ctx).Table("memo_tags").
+			Select("memo_tags.memo_id").
+			Joins("JOIN tags ON tags.id = memo_tags.tag_id").
+			Where("tags.user_id = ? AND tags.name IN ?", userId, query.Tags)
+		if query.TagMatch == model.TagMatchAll {
+			sub = sub.Group("memo_tags.memo_id").Having("COUNT(DISTINCT tags.id) = ?", len(uniqueNames(query.Tags)))
+		}
+		db = db.Where("memos.id IN (?)", sub)
+	}
+	if after := query.After; after != nil {
+		var value interface{} = after.Time
+		if query.SortBy == model.MemoSortTitle {
+			value = after.Title
+		}
+		db = db.Where(fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND memos.id %[2]s ?))", column, op), value, value, after.ID)
+	}
+	if query.Limit > 0 {
+		db = db.Limit(query.Limit)
+	}
+	if err := db.Preload("Tags", orderTagsByName).Order(column + " " + order).Order("memos.id " + order).Find(memos).Error; err != nil {
 		return err
 	}
 	return nil
 }
 
-func (mr *memoRepository) GetMemoById(memo *model.Memo, userId uint, memoId uint) error {
-	if err := mr.db.Joins("User").Where("user_id = ? AND memos.id = ?", userId, memoId).First(memo, memo).Error; err != nil {
+func (mr *memoRepository) GetMemoById(ctx context.Context, memo *model.Memo, userId uint, memoId uint) error {
+	if err := mr.db.WithContext(ctx).Joins("User").Preload("Tags", orderTagsByName).Where("user_id = ? AND memos.id = ?", userId, memoId).First(memo, memo).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.Wrap(apperror.ErrNotFound, err)
+		}
 		return err
 	}
 	return nil
 }
 
-func (mr *memoRepository) CreateMemo(memo *model.Memo) error {
-	if err := mr.db.Create(memo).Error; err != nil {
+func (mr *memoRepository) CountMemos(ctx context.Context, count *int64, userId uint) error {
+	if err := mr.db.WithContext(ctx).Model(&model.Memo{}).Where("user_id = ?", userId).Count(count).Error; err != nil {
 		return err
 	}
 	return nil
 }
 
-func (mr *memoRepository) UpdateMemo(memo *model.Memo, userId uint, memoId uint) error {
-	result := mr.db.Model(memo).
+func (mr *memoRepository) CreateMemo(ctx context.Context, memo *model.Memo) error {
+	return mr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkNotebook(tx, memo.UserId, memo.NotebookId); err != nil {
+			return err
+		}
+		memo.Version = 1
+		if err := tx.Omit("Tags").Create(memo).Error; err != nil {
+			return err
+		}
+		if err := mr.saveMemoRevision(tx, memo); err != nil {
+			return err
+		}
+		return saveMemoTags(tx, memo, memo.UserId)
+	})
+}
+
+// UpdateMemo replaces the title and content of a memo. When memo.Version is
+// set the update only applies if the stored version still matches it.
+func (mr *memoRepository) UpdateMemo(ctx context.Context, memo *model.Memo, userId uint, memoId uint) error {
+	return mr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return mr.writeMemo(tx, memo, userId, memoId, map[string]interface{}{
+			"title":   memo.Title,
+			"content": memo.Content,
+		})
+	})
+}
+
+// PatchMemo is UpdateMemo for a memo that had a merge patch applied, so it
+// also stores the notebook the memo is filed in.
+func (mr *memoRepository) PatchMemo(ctx context.Context, memo *model.Memo, userId uint, memoId uint) error {
+	return mr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkNotebook(tx, userId, memo.NotebookId); err != nil {
+			return err
+		}
+		return mr.writeMemo(tx, memo, userId, memoId, map[string]interface{}{
+			"title":       memo.Title,
+			"content":     memo.Content,
+			"notebook_id": memo.NotebookId,
+		})
+	})
+}
+
+func (mr *memoRepository) writeMemo(tx *gorm.DB, memo *model.Memo, userId uint, memoId uint, columns map[string]interface{}) error {
+	db := tx.Model(memo).
 		Clauses(clause.Returning{}).
-		Where("id = ? AND user_id = ?", memoId, userId).
-		Updates(model.Memo{Title: memo.Title, Content: memo.Content})
+		Where("id = ? AND user_id = ?", memoId, userId)
+	if memo.Version > 0 {
+		db = db.Where("version = ?", memo.Version)
+	}
+	columns["version"] = gorm.Expr("version + 1")
+	result := db.Updates(columns)
 	if result.Error != nil {
 		return result.Error
 	}
 	if result.RowsAffected < 1 {
-		return fmt.Errorf("object does not exist")
+		return memoNotUpdated(tx, userId, memoId, memo.Version)
 	}
-	return nil
+	if err := mr.saveMemoRevision(tx, memo); err != nil {
+		return err
+	}
+	return saveMemoTags(tx, memo, userId)
 }
 
-func (mr *memoRepository) DeleteMemo(userId uint, memoId uint) error {
-	result := mr.db.Where("id = ? AND user_id = ?", memoId, userId).Delete(&model.Memo{})
+// MoveMemo files a memo in another notebook. Like every write that bumps the
+// version, it records a revision for the new version.
+func (mr *memoRepository) MoveMemo(ctx context.Context, memo *model.Memo, userId uint, memoId uint, notebookId *uint) error {
+	return mr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkNotebook(tx, userId, notebookId); err != nil {
+			return err
+		}
+		result := tx.Model(memo).
+			Clauses(clause.Returning{}).
+			Where("id = ? AND user_id = ?", memoId, userId).
+			Updates(map[string]interface{}{
+				"notebook_id": notebookId,
+				"version":     gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected < 1 {
+			return apperror.New(apperror.ErrNotFound, "object does not exist")
+		}
+		if err := mr.saveMemoRevision(tx, memo); err != nil {
+			return err
+		}
+		return saveMemoTags(tx, memo, userId)
+	})
+}
+
+func (mr *memoRepository) DeleteMemo(ctx context.Context, userId uint, memoId uint) error {
+	result := mr.db.WithContext(ctx).Where("id = ? AND user_id = ?", memoId, userId).Delete(&model.Memo{})
 	if result.Error != nil {
 		return result.Error
 	}
 	if result.RowsAffected < 1 {
-		return fmt.Errorf("object does not exist")
+		return apperror.New(apperror.ErrNotFound, "object does not exist")
+	}
+	return nil
+}
+
+// memoNotUpdated tells apart a conditional update that lost against a newer
+// version from an update of a memo that does not exist.
+func memoNotUpdated(tx *gorm.DB, userId uint, memoId uint, version uint) error {
+	if version > 0 {
+		var count int64
+		if err := tx.Model(&model.Memo{}).Where("id = ? AND user_id = ?", memoId, userId).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return apperror.New(apperror.ErrPreconditionFailed, "memo has been modified")
+		}
+	}
+	return apperror.New(apperror.ErrNotFound, "object does not exist")
+}
+
+func orderTagsByName(db *gorm.DB) *gorm.DB {
+	return db.Order("tags.name")
+}
+
+// saveMemoTags replaces the tags of memo with memo.TagNames, creating any tag
+// the user does not have yet. When TagNames is nil the current tags are only
+// loaded into memo.Tags.
+func saveMemoTags(tx *gorm.DB, memo *model.Memo, userId uint) error {
+	if memo.TagNames == nil {
+		memo.Tags = []model.Tag{}
+		return tx.Model(memo).Order("tags.name").Association("Tags").Find(&memo.Tags)
+	}
+	tags := []model.Tag{}
+	names := uniqueNames(memo.TagNames)
+	if len(names) > 0 {
+		newTags := make([]model.Tag, 0, len(names))
+		for _, name := range names {
+			newTags = append(newTags, model.Tag{Name: name, UserId: userId})
+		}
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "name"}},
+			DoNothing: true,
+		}).Create(&newTags).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? AND name IN ?", userId, names).Order("name").Find(&tags).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Model(memo).Association("Tags").Replace(tags); err != nil {
+		return err
+	}
+	memo.Tags = tags
+	return nil
+}
+
+func uniqueNames(names []string) []string {
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+// checkNotebook makes sure a memo is only filed into a notebook of its owner.
+func checkNotebook(tx *gorm.DB, userId uint, notebookId *uint) error {
+	if notebookId == nil {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&model.Notebook{}).Where("id = ? AND user_id = ?", *notebookId, userId).Count(&count).Error; err != nil {
+		return err
+	}
+	if count < 1 {
+		return apperror.New(apperror.ErrNotFound, "notebook does not exist")
 	}
 	return nil
 }
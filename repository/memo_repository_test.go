@@ -1,9 +1,12 @@
 package repository
 
 import (
+	"context"
+	"echo-rest-api/apperror"
 	"echo-rest-api/model"
 	"echo-rest-api/testHelpers"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -11,10 +14,71 @@ import (
 func TestGetAllMemos(t *testing.T) {
 	db := testHelpers.SetupTestData()
 
-	repository := NewMemoRepository(db)
+	repository := NewMemoRepository(db, 0)
 	result := []model.Memo{}
 	const userId = uint(1)
-	err := repository.GetAllMemos(&result, userId)
+	err := repository.GetAllMemos(context.Background(), &result, userId, model.MemoQuery{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(result))
+}
+
+func TestGetAllMemos_Pagination(t *testing.T) {
+	db := testHelpers.SetupTestData()
+
+	repository := NewMemoRepository(db, 0)
+	const userId = uint(1)
+	first := []model.Memo{}
+	query := model.MemoQuery{Limit: 1, SortBy: model.MemoSortTitle, Order: model.SortAsc}
+	err := repository.GetAllMemos(context.Background(), &first, userId, query)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(first))
+	assert.Equal(t, "memo1 title", first[0].Title)
+
+	second := []model.Memo{}
+	query.After = &model.MemoCursor{Title: first[0].Title, ID: first[0].ID}
+	err = repository.GetAllMemos(context.Background(), &second, userId, query)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(second))
+	assert.Equal(t, "memo3 title", second[0].Title)
+
+	none := []model.Memo{}
+	query.After = &model.MemoCursor{Title: second[0].Title, ID: second[0].ID}
+	err = repository.GetAllMemos(context.Background(), &none, userId, query)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(none))
+}
+
+func TestGetAllMemos_PaginationByTime(t *testing.T) {
+	db := testHelpers.SetupTestData()
+
+	repository := NewMemoRepository(db, 0)
+	const userId = uint(1)
+	first := []model.Memo{}
+	query := model.MemoQuery{Limit: 1}
+	err := repository.GetAllMemos(context.Background(), &first, userId, query)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(first))
+	assert.Equal(t, uint(3), first[0].ID)
+
+	second := []model.Memo{}
+	query.After = &model.MemoCursor{Time: first[0].CreatedAt, ID: first[0].ID}
+	err = repository.GetAllMemos(context.Background(), &second, userId, query)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(second))
+	assert.Equal(t, uint(1), second[0].ID)
+}
+
+func TestGetAllMemos_Filter(t *testing.T) {
+	db := testHelpers.SetupTestData()
+
+	repository := NewMemoRepository(db, 0)
+	const userId = uint(1)
+	result := []model.Memo{}
+	err := repository.GetAllMemos(context.Background(), &result, userId, model.MemoQuery{CreatedFrom: time.Now().Add(time.Hour)})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(result))
+
+	err = repository.GetAllMemos(context.Background(), &result, userId, model.MemoQuery{CreatedTo: time.Now().Add(time.Hour)})
 	assert.Nil(t, err)
 	assert.Equal(t, 2, len(result))
 }
@@ -22,22 +86,31 @@ func TestGetAllMemos(t *testing.T) {
 func TestGetMemoById(t *testing.T) {
 	db := testHelpers.SetupTestData()
 
-	repository := NewMemoRepository(db)
+	repository := NewMemoRepository(db, 0)
 	result := model.Memo{}
 	const (
 		userId = uint(1)
 		memoId = uint(1)
 	)
-	err := repository.GetMemoById(&result, userId, memoId)
+	err := repository.GetMemoById(context.Background(), &result, userId, memoId)
 	assert.Nil(t, err)
 	assert.Equal(t, userId, (result.UserId))
 	assert.Equal(t, memoId, (result.ID))
 }
 
+func TestGetMemoById_NotFound(t *testing.T) {
+	db := testHelpers.SetupTestData()
+
+	repository := NewMemoRepository(db, 0)
+	result := model.Memo{}
+	err := repository.GetMemoById(context.Background(), &result, uint(2), uint(1))
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+}
+
 func TestCreateMemo(t *testing.T) {
 	db := testHelpers.SetupTestData()
 
-	repository := NewMemoRepository(db)
+	repository := NewMemoRepository(db, 0)
 	const (
 		userId = uint(2)
 		memoId = uint(4)
@@ -47,10 +120,10 @@ func TestCreateMemo(t *testing.T) {
 		Content: "created memo",
 		UserId:  userId,
 	}
-	err := repository.CreateMemo(&input)
+	err := repository.CreateMemo(context.Background(), &input)
 	assert.Equal(t, nil, err)
 	createdMemo := model.Memo{}
-	err = repository.GetMemoById(&createdMemo, userId, memoId)
+	err = repository.GetMemoById(context.Background(), &createdMemo, userId, memoId)
 	assert.Nil(t, err)
 	assert.Equal(t, memoId, createdMemo.ID)
 	assert.Equal(t, userId, createdMemo.UserId)
@@ -59,7 +132,7 @@ func TestCreateMemo(t *testing.T) {
 func TestUpdateMemo(t *testing.T) {
 	db := testHelpers.SetupTestData()
 
-	repository := NewMemoRepository(db)
+	repository := NewMemoRepository(db, 0)
 	const (
 		userId = uint(1)
 		memoId = uint(1)
@@ -68,25 +141,217 @@ func TestUpdateMemo(t *testing.T) {
 		Title:   "updated memo1 title",
 		Content: "updated memo1 content",
 	}
-	err := repository.UpdateMemo(&updateMemo, userId, memoId)
+	err := repository.UpdateMemo(context.Background(), &updateMemo, userId, memoId)
 	assert.Nil(t, err)
 	updatedMemo := model.Memo{}
-	err = repository.GetMemoById(&updatedMemo, userId, memoId)
+	err = repository.GetMemoById(context.Background(), &updatedMemo, userId, memoId)
 	assert.Nil(t, err)
 	assert.Equal(t, memoId, updatedMemo.ID)
 	assert.Equal(t, updateMemo.Title, "updated memo1 title")
 	assert.Equal(t, updateMemo.Content, "updated memo1 content")
 }
 
+func TestUpdateMemo_Version(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	repository := NewMemoRepository(db, 0)
+	memo := model.Memo{Title: "draft", UserId: 1}
+	assert.Nil(t, repository.CreateMemo(context.Background(), &memo))
+	assert.Equal(t, uint(1), memo.Version)
+
+	first := model.Memo{Title: "first tab", Version: 1}
+	assert.Nil(t, repository.UpdateMemo(context.Background(), &first, uint(1), memo.ID))
+	assert.Equal(t, uint(2), first.Version)
+
+	second := model.Memo{Title: "second tab", Version: 1}
+	err := repository.UpdateMemo(context.Background(), &second, uint(1), memo.ID)
+	assert.ErrorIs(t, err, apperror.ErrPreconditionFailed)
+
+	err = repository.UpdateMemo(context.Background(), &model.Memo{Title: "other user", Version: 2}, uint(2), memo.ID)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+
+	current := model.Memo{}
+	assert.Nil(t, repository.GetMemoById(context.Background(), &current, uint(1), memo.ID))
+	assert.Equal(t, "first tab", current.Title)
+	assert.Equal(t, uint(2), current.Version)
+}
+
+func TestPatchMemo(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	_, project, _ := setupNotebooks(t, db)
+	repository := NewMemoRepository(db, 0)
+
+	memo := model.Memo{Title: "memo1 title", Content: "", NotebookId: &project.ID, Version: 1}
+	err := repository.PatchMemo(context.Background(), &memo, uint(1), uint(1))
+	assert.Nil(t, err)
+	assert.Equal(t, uint(2), memo.Version)
+
+	patched := model.Memo{}
+	assert.Nil(t, repository.GetMemoById(context.Background(), &patched, uint(1), uint(1)))
+	assert.Equal(t, "", patched.Content)
+	assert.Equal(t, project.ID, *patched.NotebookId)
+
+	other := uint(999)
+	err = repository.PatchMemo(context.Background(), &model.Memo{Title: "memo1 title", NotebookId: &other}, uint(1), uint(1))
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+}
+
 func TestDeleteMemo(t *testing.T) {
 	db := testHelpers.SetupTestData()
-	repository := NewMemoRepository(db)
+	repository := NewMemoRepository(db, 0)
 	const (
 		userId = uint(1)
 		memoId = uint(1)
 	)
-	err := repository.DeleteMemo(userId, memoId)
+	err := repository.DeleteMemo(context.Background(), userId, memoId)
 	assert.Nil(t, err)
-	err = repository.DeleteMemo(userId, memoId)
+	err = repository.DeleteMemo(context.Background(), userId, memoId)
 	assert.Equal(t, "object does not exist", err.Error())
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+}
+
+func TestCountMemos(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	repository := NewMemoRepository(db, 0)
+	var count int64
+	assert.Nil(t, repository.CountMemos(context.Background(), &count, 1))
+	assert.Equal(t, int64(2), count)
+	assert.Nil(t, repository.DeleteMemo(context.Background(), 1, 1))
+	assert.Nil(t, repository.CountMemos(context.Background(), &count, 1))
+	assert.Equal(t, int64(1), count)
+}
+
+func TestSearchMemos(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	repository := NewMemoRepository(db, 0)
+	const userId = uint(1)
+	input := model.Memo{
+		Title:   "grocery list",
+		Content: "buy milk and fresh bread from the bakery",
+		UserId:  userId,
+	}
+	assert.Nil(t, repository.CreateMemo(context.Background(), &input))
+
+	results := []model.MemoSearchResult{}
+	err := repository.SearchMemos(context.Background(), &results, userId, model.MemoSearchQuery{Query: "bread", Limit: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, input.ID, results[0].ID)
+	assert.Contains(t, results[0].Snippet, "<mark>bread</mark>")
+
+	results = []model.MemoSearchResult{}
+	err = repository.SearchMemos(context.Background(), &results, userId, model.MemoSearchQuery{Query: `"fresh bread" -milk`, Limit: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(results))
+
+	results = []model.MemoSearchResult{}
+	err = repository.SearchMemos(context.Background(), &results, userId, model.MemoSearchQuery{Query: "-milk", Limit: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(results))
+
+	results = []model.MemoSearchResult{}
+	err = repository.SearchMemos(context.Background(), &results, userId, model.MemoSearchQuery{Query: "grocery OR memo3", Limit: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(results))
+
+	results = []model.MemoSearchResult{}
+	err = repository.SearchMemos(context.Background(), &results, uint(2), model.MemoSearchQuery{Query: "bread", Limit: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(results))
+
+	updateMemo := model.Memo{Title: "grocery list", Content: "buy eggs"}
+	assert.Nil(t, repository.UpdateMemo(context.Background(), &updateMemo, userId, input.ID))
+	results = []model.MemoSearchResult{}
+	err = repository.SearchMemos(context.Background(), &results, userId, model.MemoSearchQuery{Query: "bread", Limit: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(results))
+
+	assert.Nil(t, repository.DeleteMemo(context.Background(), userId, input.ID))
+	results = []model.MemoSearchResult{}
+	err = repository.SearchMemos(context.Background(), &results, userId, model.MemoSearchQuery{Query: "eggs", Limit: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(results))
+}
+
+func TestSearchMemos_Rank(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	repository := NewMemoRepository(db, 0)
+	const userId = uint(1)
+	titleHit := model.Memo{Title: "bakery", Content: "opening hours", UserId: userId}
+	assert.Nil(t, repository.CreateMemo(context.Background(), &titleHit))
+	bodyHit := model.Memo{Title: "errands", Content: "pick up bread at the bakery", UserId: userId}
+	assert.Nil(t, repository.CreateMemo(context.Background(), &bodyHit))
+
+	results := []model.MemoSearchResult{}
+	err := repository.SearchMemos(context.Background(), &results, userId, model.MemoSearchQuery{Query: "bakery", Limit: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(results))
+	assert.Equal(t, titleHit.ID, results[0].ID)
+	assert.Equal(t, bodyHit.ID, results[1].ID)
+	assert.Greater(t, results[0].Rank, results[1].Rank)
+	assert.Equal(t, "<mark>bakery</mark>", results[0].TitleHighlight)
+}
+
+func TestFtsMatchExpression(t *testing.T) {
+	assert.Equal(t, `(("milk" AND "bread"))`, ftsMatchExpression("milk bread"))
+	assert.Equal(t, `(("fresh bread")) NOT "milk"`, ftsMatchExpression(`"fresh bread" -milk`))
+	assert.Equal(t, `(("milk") OR ("bread" AND "eggs"))`, ftsMatchExpression("milk OR bread eggs"))
+	assert.Equal(t, `(("a""b"))`, ftsMatchExpression(`a"b`))
+	assert.Equal(t, "", ftsMatchExpression("-milk"))
+}
+
+func TestCreateMemo_Tags(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	repository := NewMemoRepository(db, 0)
+	const userId = uint(1)
+	input := model.Memo{
+		Title:    "tagged",
+		UserId:   userId,
+		TagNames: []string{"work", "idea", "work"},
+	}
+	err := repository.CreateMemo(context.Background(), &input)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(input.Tags))
+
+	createdMemo := model.Memo{}
+	err = repository.GetMemoById(context.Background(), &createdMemo, userId, input.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "idea", createdMemo.Tags[0].Name)
+	assert.Equal(t, "work", createdMemo.Tags[1].Name)
+
+	updateMemo := model.Memo{Title: "tagged", Content: "untouched tags"}
+	err = repository.UpdateMemo(context.Background(), &updateMemo, userId, input.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(updateMemo.Tags))
+
+	updateMemo = model.Memo{Title: "tagged", TagNames: []string{"idea", "home"}}
+	err = repository.UpdateMemo(context.Background(), &updateMemo, userId, input.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(updateMemo.Tags))
+	assert.Equal(t, "home", updateMemo.Tags[0].Name)
+
+	updateMemo = model.Memo{Title: "tagged", TagNames: []string{}}
+	err = repository.UpdateMemo(context.Background(), &updateMemo, userId, input.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(updateMemo.Tags))
+}
+
+func TestGetAllMemos_TagFilter(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	repository := NewMemoRepository(db, 0)
+	const userId = uint(1)
+	assert.Nil(t, repository.CreateMemo(context.Background(), &model.Memo{Title: "a", UserId: userId, TagNames: []string{"work"}}))
+	assert.Nil(t, repository.CreateMemo(context.Background(), &model.Memo{Title: "b", UserId: userId, TagNames: []string{"work", "urgent"}}))
+	assert.Nil(t, repository.CreateMemo(context.Background(), &model.Memo{Title: "c", UserId: uint(2), TagNames: []string{"work"}}))
+
+	result := []model.Memo{}
+	err := repository.GetAllMemos(context.Background(), &result, userId, model.MemoQuery{Tags: []string{"work", "urgent"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(result))
+
+	result = []model.Memo{}
+	err = repository.GetAllMemos(context.Background(), &result, userId, model.MemoQuery{Tags: []string{"work", "urgent"}, TagMatch: model.TagMatchAll})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, "b", result[0].Title)
+	assert.Equal(t, 2, len(result[0].Tags))
 }
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+const DefaultMaxMemoRevisions = 100
+
+func (mr *memoRepository) GetMemoRevisions(ctx context.Context, revisions *[]model.MemoRevision, userId uint, memoId uint) error {
+	if err := mr.db.WithContext(ctx).Where("id = ? AND user_id = ?", memoId, userId).First(&model.Memo{}).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.Wrap(apperror.ErrNotFound, err)
+		}
+		return err
+	}
+	if err := mr.db.WithContext(ctx).Where("memo_id = ?", memoId).Order("revision DESC").Find(revisions).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (mr *memoRepository) GetMemoRevision(ctx context.Context, revision *model.MemoRevision, userId uint, memoId uint, rev uint) error {
+	err := mr.db.WithContext(ctx).
+		Joins("JOIN memos ON memos.id = memo_revisions.memo_id AND memos.deleted_at IS NULL").
+		Where("memo_revisions.memo_id = ? AND memo_revisions.revision = ? AND memos.user_id = ?", memoId, rev, userId).
+		First(revision).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.New(apperror.ErrNotFound, "revision does not exist")
+		}
+		return err
+	}
+	return nil
+}
+
+// saveMemoRevision records the current title and content of memo as its next
+// revision and prunes the oldest revisions of the owner beyond the cap.
+func (mr *memoRepository) saveMemoRevision(tx *gorm.DB, memo *model.Memo) error {
+	var last uint
+	err := tx.Model(&model.MemoRevision{}).
+		Select("COALESCE(MAX(revision), 0)").
+		Where("memo_id = ?", memo.ID).
+		Find(&last).Error
+	if err != nil {
+		return err
+	}
+	revision := model.MemoRevision{
+		MemoId:   memo.ID,
+		Revision: last + 1,
+		UserId:   memo.UserId,
+		Title:    memo.Title,
+		Content:  memo.Content,
+	}
+	if err := tx.Omit("Memo", "User").Create(&revision).Error; err != nil {
+		return err
+	}
+	return pruneMemoRevisions(tx, memo.UserId, mr.maxRevisions)
+}
+
+// pruneMemoRevisions deletes the oldest revisions of a user until at most max
+// remain. The latest revision of every memo is always kept so that each memo
+// keeps a record of its current version.
+func pruneMemoRevisions(tx *gorm.DB, userId uint, max int) error {
+	var count int64
+	if err := tx.Model(&model.MemoRevision{}).Where("user_id = ?", userId).Count(&count).Error; err != nil {
+		return err
+	}
+	if count <= int64(max) {
+		return nil
+	}
+	var ids []uint
+	err := tx.Model(&model.MemoRevision{}).
+		Where("user_id = ?", userId).
+		Where("revision < (SELECT MAX(r.revision) FROM memo_revisions r WHERE r.memo_id = memo_revisions.memo_id)").
+		Order("id").
+		Limit(int(count)-max).
+		Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+	return tx.Delete(&model.MemoRevision{}, ids).Error
+}
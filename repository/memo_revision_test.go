@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"echo-rest-api/testHelpers"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoRevisions(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	repository := NewMemoRepository(db, 0)
+	memo := model.Memo{Title: "draft", Content: "first", UserId: 1}
+	assert.Nil(t, repository.CreateMemo(context.Background(), &memo))
+	assert.Nil(t, repository.UpdateMemo(context.Background(), &model.Memo{Title: "final", Content: "second"}, uint(1), memo.ID))
+
+	revisions := []model.MemoRevision{}
+	err := repository.GetMemoRevisions(context.Background(), &revisions, uint(1), memo.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(revisions))
+	assert.Equal(t, uint(2), revisions[0].Revision)
+	assert.Equal(t, "second", revisions[0].Content)
+	assert.Equal(t, uint(1), revisions[1].Revision)
+	assert.Equal(t, "draft", revisions[1].Title)
+
+	revision := model.MemoRevision{}
+	err = repository.GetMemoRevision(context.Background(), &revision, uint(1), memo.ID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "first", revision.Content)
+
+	err = repository.GetMemoRevision(context.Background(), &model.MemoRevision{}, uint(2), memo.ID, 1)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	err = repository.GetMemoRevisions(context.Background(), &revisions, uint(2), memo.ID)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+}
+
+func TestMemoRevisions_Prune(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	repository := NewMemoRepository(db, 3)
+	first := model.Memo{Title: "first", UserId: 1}
+	assert.Nil(t, repository.CreateMemo(context.Background(), &first))
+	second := model.Memo{Title: "second", UserId: 1}
+	assert.Nil(t, repository.CreateMemo(context.Background(), &second))
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, repository.UpdateMemo(context.Background(), &model.Memo{Title: fmt.Sprintf("second v%d", i+2)}, uint(1), second.ID))
+	}
+
+	revisions := []model.MemoRevision{}
+	assert.Nil(t, repository.GetMemoRevisions(context.Background(), &revisions, uint(1), first.ID))
+	assert.Equal(t, 1, len(revisions))
+
+	revisions = []model.MemoRevision{}
+	assert.Nil(t, repository.GetMemoRevisions(context.Background(), &revisions, uint(1), second.ID))
+	assert.Equal(t, 2, len(revisions))
+	assert.Equal(t, uint(4), revisions[0].Revision)
+	assert.Equal(t, uint(3), revisions[1].Revision)
+}
+
+func TestPurgeMemo_Revisions(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	repository := NewMemoRepository(db, 0)
+	memo := model.Memo{Title: "draft", UserId: 1}
+	assert.Nil(t, repository.CreateMemo(context.Background(), &memo))
+	assert.Nil(t, repository.DeleteMemo(context.Background(), uint(1), memo.ID))
+	assert.Nil(t, repository.PurgeMemo(context.Background(), uint(1), memo.ID))
+
+	var count int64
+	db.Model(&model.MemoRevision{}).Where("memo_id = ?", memo.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
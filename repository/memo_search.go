@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"echo-rest-api/model"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	highlightStart = "<mark>"
+	highlightEnd   = "</mark>"
+)
+
+func (mr *memoRepository) SearchMemos(ctx context.Context, results *[]model.MemoSearchResult, userId uint, query model.MemoSearchQuery) error {
+	// A query made only of exclusions has nothing to rank or highlight, so
+	// both backends answer it with no results.
+	match := ftsMatchExpression(query.Query)
+	if match == "" {
+		*results = []model.MemoSearchResult{}
+		return nil
+	}
+	var tx *gorm.DB
+	switch mr.db.Dialector.Name() {
+	case "postgres":
+		tx = mr.db.WithContext(ctx).Raw(`
+			SELECT memos.id, memos.title, memos.content, memos.created_at, memos.updated_at,
+				ts_rank(memos.search_vector, q) AS rank,
+				ts_headline('simple', memos.title, q, @titleOpts) AS title_highlight,
+				ts_headline('simple', memos.content, q, @snippetOpts) AS snippet
+			FROM memos, websearch_to_tsquery('simple', @q) AS q
+			WHERE memos.user_id = @user AND memos.deleted_at IS NULL AND memos.search_vector @@ q
+			ORDER BY rank DESC, memos.id DESC
+			LIMIT @limit`,
+			map[string]interface{}{
+				"q":           query.Query,
+				"user":        userId,
+				"limit":       query.Limit,
+				"titleOpts":   fmt.Sprintf("StartSel=%s, StopSel=%s, HighlightAll=true", highlightStart, highlightEnd),
+				"snippetOpts": fmt.Sprintf("StartSel=%s, StopSel=%s, MaxWords=30, MinWords=10", highlightStart, highlightEnd),
+			})
+	case "sqlite":
+		return mr.searchSQLite(ctx, results, userId, match, query.Limit)
+	default:
+		return fmt.Errorf("full-text search is not supported on %s", mr.db.Dialector.Name())
+	}
+	if err := tx.Find(results).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// ftsColumnWeights weigh title hits ten times as much as content hits, like
+// the A and B weights of the Postgres search vector.
+var ftsColumnWeights = []float64{10, 1}
+
+type ftsHit struct {
+	model.MemoSearchResult
+	MatchInfo []byte
+}
+
+// searchSQLite ranks the hits itself, because FTS4 has no ranking function,
+// only the match statistics of matchinfo.
+func (mr *memoRepository) searchSQLite(ctx context.Context, results *[]model.MemoSearchResult, userId uint, match string, limit int) error {
+	hits := []ftsHit{}
+	err := mr.db.WithContext(ctx).Raw(fmt.Sprintf(`
+		SELECT memos.id, memos.title, memos.content, memos.created_at, memos.updated_at,
+			snippet(memos_fts, '%[1]s', '%[2]s', '', 0, 64) AS title_highlight,
+			snippet(memos_fts, '%[1]s', '%[2]s', '…', 1, 16) AS snippet,
+			matchinfo(memos_fts, 'pcx') AS match_info
+		FROM memos_fts JOIN memos ON memos.id = memos_fts.rowid
+		WHERE memos_fts MATCH ? AND memos.user_id = ? AND memos.deleted_at IS NULL`, highlightStart, highlightEnd),
+		match, userId).Find(&hits).Error
+	if err != nil {
+		return err
+	}
+	for i := range hits {
+		hits[i].Rank = ftsRank(hits[i].MatchInfo)
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Rank != hits[j].Rank {
+			return hits[i].Rank > hits[j].Rank
+		}
+		return hits[i].ID > hits[j].ID
+	})
+	*results = make([]model.MemoSearchResult, 0, min(len(hits), limit))
+	for _, hit := range hits[:min(len(hits), limit)] {
+		*results = append(*results, hit.MemoSearchResult)
+	}
+	return nil
+}
+
+// ftsRank scores a row from matchinfo 'pcx': for every phrase and column,
+// its hits in the row as a share of its hits in all rows, weighted by
+// column.
+func ftsRank(info []byte) float64 {
+	values := make([]uint32, len(info)/4)
+	for i := range values {
+		values[i] = binary.NativeEndian.Uint32(info[i*4:])
+	}
+	if len(values) < 2 {
+		return 0
+	}
+	phrases, columns := int(values[0]), int(values[1])
+	rank := 0.0
+	for p := 0; p < phrases; p++ {
+		for c := 0; c < columns && c < len(ftsColumnWeights); c++ {
+			i := 2 + 3*(p*columns+c)
+			if i+1 >= len(values) || values[i+1] == 0 {
+				continue
+			}
+			rank += ftsColumnWeights[c] * float64(values[i]) / float64(values[i+1])
+		}
+	}
+	return rank
+}
+
+// ftsMatchExpression translates websearch_to_tsquery style input (quoted
+// phrases, -exclusion and OR) into an SQLite FTS MATCH expression. Every term
+// is quoted so FTS operators typed by the user are taken literally.
+func ftsMatchExpression(input string) string {
+	var groups [][]string
+	var excluded []string
+	current := []string{}
+	pendingOr := false
+
+	for _, tok := range websearchTokens(input) {
+		switch {
+		case tok.text == "OR" && !tok.quoted:
+			pendingOr = len(current) > 0
+		case tok.negated:
+			excluded = append(excluded, ftsQuote(tok.text))
+		default:
+			if pendingOr {
+				groups = append(groups, current)
+				current = []string{}
+				pendingOr = false
+			}
+			current = append(current, ftsQuote(tok.text))
+		}
+	}
+	if len(current) > 0 {
+		groups = append(groups, current)
+	}
+	if len(groups) == 0 {
+		return ""
+	}
+
+	alternatives := make([]string, 0, len(groups))
+	for _, g := range groups {
+		alternatives = append(alternatives, "("+strings.Join(g, " AND ")+")")
+	}
+	expr := "(" + strings.Join(alternatives, " OR ") + ")"
+	for _, ex := range excluded {
+		expr += " NOT " + ex
+	}
+	return expr
+}
+
+type websearchToken struct {
+	text    string
+	quoted  bool
+	negated bool
+}
+
+func websearchTokens(input string) []websearchToken {
+	var tokens []websearchToken
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		if runes[i] == ' ' || runes[i] == '\t' || runes[i] == '\n' {
+			i++
+			continue
+		}
+		tok := websearchToken{}
+		if runes[i] == '-' {
+			tok.negated = true
+			i++
+		}
+		if i < len(runes) && runes[i] == '"' {
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			tok.text = strings.TrimSpace(string(runes[i+1 : end]))
+			tok.quoted = true
+			i = end + 1
+		} else {
+			end := i
+			for end < len(runes) && runes[end] != ' ' && runes[end] != '\t' && runes[end] != '\n' {
+				end++
+			}
+			tok.text = string(runes[i:end])
+			i = end
+		}
+		if tok.text != "" {
+			tokens = append(tokens, tok)
+		}
+	}
+	return tokens
+}
+
+func ftsQuote(term string) string {
+	return `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+}
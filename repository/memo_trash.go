@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func (mr *memoRepository) GetTrashedMemos(ctx context.Context, memos *[]model.Memo, userId uint) error {
+	err := mr.db.WithContext(ctx).Unscoped().
+		Preload("Tags", orderTagsByName).
+		Where("user_id = ? AND deleted_at IS NOT NULL", userId).
+		Order("deleted_at DESC").Order("id DESC").
+		Find(memos).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// RestoreMemo takes a memo out of the trash. If the notebook it was filed in
+// has been deleted in the meantime the memo is restored to the root.
+func (mr *memoRepository) RestoreMemo(ctx context.Context, memo *model.Memo, userId uint, memoId uint) error {
+	return mr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", memoId, userId).First(memo).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperror.New(apperror.ErrNotFound, "object does not exist in trash")
+			}
+			return err
+		}
+		updates := map[string]interface{}{"deleted_at": nil}
+		if memo.NotebookId != nil {
+			if err := checkNotebook(tx, userId, memo.NotebookId); errors.Is(err, apperror.ErrNotFound) {
+				updates["notebook_id"] = nil
+			} else if err != nil {
+				return err
+			}
+		}
+		if err := tx.Unscoped().Model(memo).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.First(memo, memo.ID).Error; err != nil {
+			return err
+		}
+		return saveMemoTags(tx, memo, userId)
+	})
+}
+
+func (mr *memoRepository) PurgeMemo(ctx context.Context, userId uint, memoId uint) error {
+	return mr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		purged, err := purgeMemos(tx, "id = ? AND user_id = ?", memoId, userId)
+		if err != nil {
+			return err
+		}
+		if purged < 1 {
+			return apperror.New(apperror.ErrNotFound, "object does not exist in trash")
+		}
+		return nil
+	})
+}
+
+func (mr *memoRepository) EmptyTrash(ctx context.Context, userId uint) error {
+	return mr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		_, err := purgeMemos(tx, "user_id = ?", userId)
+		return err
+	})
+}
+
+func (mr *memoRepository) PurgeMemosDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	var purged int64
+	err := mr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		purged, err = purgeMemos(tx, "deleted_at < ?", before)
+		return err
+	})
+	return purged, err
+}
+
+// purgeMemos permanently deletes the trashed memos matching the given
+// conditions together with their tag associations and revisions.
+func purgeMemos(tx *gorm.DB, query string, args ...interface{}) (int64, error) {
+	trashed := tx.Unscoped().Model(&model.Memo{}).Select("id").Where("deleted_at IS NOT NULL").Where(query, args...)
+	if err := tx.Exec("DELETE FROM memo_tags WHERE memo_id IN (?)", trashed).Error; err != nil {
+		return 0, err
+	}
+	if err := tx.Where("memo_id IN (?)", trashed).Delete(&model.MemoRevision{}).Error; err != nil {
+		return 0, err
+	}
+	result := tx.Unscoped().Where("id IN (?)", trashed).Delete(&model.Memo{})
+	return result.RowsAffected, result.Error
+}
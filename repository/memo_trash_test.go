@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"echo-rest-api/testHelpers"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTrashedMemos(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	repository := NewMemoRepository(db, 0)
+	assert.Nil(t, repository.DeleteMemo(context.Background(), uint(1), uint(1)))
+
+	trashed := []model.Memo{}
+	err := repository.GetTrashedMemos(context.Background(), &trashed, uint(1))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(trashed))
+	assert.Equal(t, uint(1), trashed[0].ID)
+	assert.True(t, trashed[0].DeletedAt.Valid)
+
+	trashed = []model.Memo{}
+	err = repository.GetTrashedMemos(context.Background(), &trashed, uint(2))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(trashed))
+}
+
+func TestRestoreMemo(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	repository := NewMemoRepository(db, 0)
+	assert.Nil(t, repository.DeleteMemo(context.Background(), uint(1), uint(1)))
+
+	memo := model.Memo{}
+	err := repository.RestoreMemo(context.Background(), &memo, uint(1), uint(1))
+	assert.Nil(t, err)
+	assert.Equal(t, "memo1 title", memo.Title)
+	assert.False(t, memo.DeletedAt.Valid)
+	assert.Nil(t, repository.GetMemoById(context.Background(), &model.Memo{}, uint(1), uint(1)))
+
+	err = repository.RestoreMemo(context.Background(), &model.Memo{}, uint(1), uint(3))
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+}
+
+func TestRestoreMemo_DeletedNotebook(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	_, project, _ := setupNotebooks(t, db)
+	assert.Nil(t, NewNotebookRepository(db).DeleteNotebook(context.Background(), uint(1), project.ID, model.NotebookDeleteTrash))
+	repository := NewMemoRepository(db, 0)
+
+	trashed := []model.Memo{}
+	assert.Nil(t, repository.GetTrashedMemos(context.Background(), &trashed, uint(1)))
+	assert.Equal(t, 2, len(trashed))
+
+	memo := model.Memo{}
+	err := repository.RestoreMemo(context.Background(), &memo, uint(1), trashed[0].ID)
+	assert.Nil(t, err)
+	assert.Nil(t, memo.NotebookId)
+}
+
+func TestPurgeMemo(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	repository := NewMemoRepository(db, 0)
+
+	err := repository.PurgeMemo(context.Background(), uint(1), uint(1))
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+
+	assert.Nil(t, repository.DeleteMemo(context.Background(), uint(1), uint(1)))
+	assert.Nil(t, repository.PurgeMemo(context.Background(), uint(1), uint(1)))
+
+	var count int64
+	db.Unscoped().Model(&model.Memo{}).Where("id = ?", 1).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestEmptyTrash(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	repository := NewMemoRepository(db, 0)
+	assert.Nil(t, repository.DeleteMemo(context.Background(), uint(1), uint(1)))
+	assert.Nil(t, repository.DeleteMemo(context.Background(), uint(2), uint(2)))
+
+	assert.Nil(t, repository.EmptyTrash(context.Background(), uint(1)))
+
+	trashed := []model.Memo{}
+	assert.Nil(t, repository.GetTrashedMemos(context.Background(), &trashed, uint(1)))
+	assert.Equal(t, 0, len(trashed))
+	trashed = []model.Memo{}
+	assert.Nil(t, repository.GetTrashedMemos(context.Background(), &trashed, uint(2)))
+	assert.Equal(t, 1, len(trashed))
+	memos := []model.Memo{}
+	assert.Nil(t, repository.GetAllMemos(context.Background(), &memos, uint(1), model.MemoQuery{}))
+	assert.Equal(t, 1, len(memos))
+}
+
+func TestPurgeMemosDeletedBefore(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	repository := NewMemoRepository(db, 0)
+	assert.Nil(t, repository.DeleteMemo(context.Background(), uint(1), uint(1)))
+	assert.Nil(t, repository.DeleteMemo(context.Background(), uint(2), uint(2)))
+	db.Unscoped().Model(&model.Memo{}).Where("id = ?", 1).Update("deleted_at", time.Now().Add(-48*time.Hour))
+
+	purged, err := repository.PurgeMemosDeletedBefore(context.Background(), time.Now().Add(-24*time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	trashed := []model.Memo{}
+	assert.Nil(t, repository.GetTrashedMemos(context.Background(), &trashed, uint(2)))
+	assert.Equal(t, 1, len(trashed))
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type IMFARepository interface {
+	GetTOTPCredential(ctx context.Context, credential *model.TOTPCredential, userId uint) error
+	SaveTOTPCredential(ctx context.Context, credential *model.TOTPCredential) error
+	ConfirmTOTPCredential(ctx context.Context, userId uint, step int64, codeHashes []string) error
+	UseTOTPStep(ctx context.Context, userId uint, step int64) error
+	UseRecoveryCode(ctx context.Context, userId uint, codeHash string) error
+	DeleteTOTPCredential(ctx context.Context, userId uint) error
+}
+
+type mfaRepository struct {
+	db *gorm.DB
+}
+
+func NewMFARepository(db *gorm.DB) IMFARepository {
+	return &mfaRepository{db}
+}
+
+func (mr *mfaRepository) GetTOTPCredential(ctx context.Context, credential *model.TOTPCredential, userId uint) error {
+	if err := mr.db.WithContext(ctx).Where("user_id = ?", userId).First(credential).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.Wrap(apperror.ErrNotFound, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// SaveTOTPCredential replaces an unconfirmed credential of the user. It fails
+// with ErrConflict while a confirmed one exists.
+func (mr *mfaRepository) SaveTOTPCredential(ctx context.Context, credential *model.TOTPCredential) error {
+	return mr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&model.TOTPCredential{}).
+			Where("user_id = ? AND confirmed_at IS NOT NULL", credential.UserId).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return apperror.New(apperror.ErrConflict, "two-factor authentication already enabled")
+		}
+		if err := tx.Where("user_id = ?", credential.UserId).Delete(&model.TOTPCredential{}).Error; err != nil {
+			return err
+		}
+		return tx.Omit("User").Create(credential).Error
+	})
+}
+
+// ConfirmTOTPCredential enables the pending credential of the user, marking
+// step as used, and replaces the recovery codes with codeHashes.
+func (mr *mfaRepository) ConfirmTOTPCredential(ctx context.Context, userId uint, step int64, codeHashes []string) error {
+	return mr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.TOTPCredential{}).
+			Where("user_id = ? AND confirmed_at IS NULL", userId).
+			Updates(map[string]interface{}{
+				"confirmed_at":   time.Now(),
+				"last_used_step": step,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected < 1 {
+			return apperror.New(apperror.ErrNotFound, "two-factor enrollment not started")
+		}
+		if err := tx.Where("user_id = ?", userId).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codeHashes) == 0 {
+			return nil
+		}
+		codes := make([]model.RecoveryCode, 0, len(codeHashes))
+		for _, hash := range codeHashes {
+			codes = append(codes, model.RecoveryCode{UserId: userId, CodeHash: hash})
+		}
+		return tx.Omit("User").Create(&codes).Error
+	})
+}
+
+// UseTOTPStep records step as used. It fails with ErrConflict if a code of
+// the same or a later step was accepted before.
+func (mr *mfaRepository) UseTOTPStep(ctx context.Context, userId uint, step int64) error {
+	result := mr.db.WithContext(ctx).Model(&model.TOTPCredential{}).
+		Where("user_id = ? AND confirmed_at IS NOT NULL AND last_used_step < ?", userId, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected < 1 {
+		return apperror.New(apperror.ErrConflict, "code already used")
+	}
+	return nil
+}
+
+func (mr *mfaRepository) UseRecoveryCode(ctx context.Context, userId uint, codeHash string) error {
+	result := mr.db.WithContext(ctx).Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userId, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected < 1 {
+		return apperror.New(apperror.ErrNotFound, "recovery code does not exist")
+	}
+	return nil
+}
+
+func (mr *mfaRepository) DeleteTOTPCredential(ctx context.Context, userId uint) error {
+	return mr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userId).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("user_id = ?", userId).Delete(&model.TOTPCredential{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected < 1 {
+			return apperror.New(apperror.ErrNotFound, "two-factor authentication not enabled")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"echo-rest-api/testHelpers"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfirmTOTPCredential(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	repository := NewMFARepository(db)
+
+	err := repository.ConfirmTOTPCredential(context.Background(), 1, 100, []string{"a"})
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	assert.Nil(t, repository.SaveTOTPCredential(context.Background(), &model.TOTPCredential{UserId: 1, Secret: "first"}))
+	assert.Nil(t, repository.SaveTOTPCredential(context.Background(), &model.TOTPCredential{UserId: 1, Secret: "second"}))
+	assert.Nil(t, repository.ConfirmTOTPCredential(context.Background(), 1, 100, []string{"a", "b"}))
+
+	credential := model.TOTPCredential{}
+	assert.Nil(t, repository.GetTOTPCredential(context.Background(), &credential, 1))
+	assert.Equal(t, "second", credential.Secret)
+	assert.NotNil(t, credential.ConfirmedAt)
+	assert.Equal(t, int64(100), credential.LastUsedStep)
+	err = repository.SaveTOTPCredential(context.Background(), &model.TOTPCredential{UserId: 1, Secret: "third"})
+	assert.ErrorIs(t, err, apperror.ErrConflict)
+}
+
+func TestUseTOTPStep(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	repository := NewMFARepository(db)
+	assert.Nil(t, repository.SaveTOTPCredential(context.Background(), &model.TOTPCredential{UserId: 1, Secret: "secret"}))
+	assert.Nil(t, repository.ConfirmTOTPCredential(context.Background(), 1, 100, nil))
+
+	assert.ErrorIs(t, repository.UseTOTPStep(context.Background(), 1, 100), apperror.ErrConflict)
+	assert.Nil(t, repository.UseTOTPStep(context.Background(), 1, 101))
+	assert.ErrorIs(t, repository.UseTOTPStep(context.Background(), 1, 101), apperror.ErrConflict)
+	assert.ErrorIs(t, repository.UseTOTPStep(context.Background(), 2, 102), apperror.ErrConflict)
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	repository := NewMFARepository(db)
+	assert.Nil(t, repository.SaveTOTPCredential(context.Background(), &model.TOTPCredential{UserId: 1, Secret: "secret"}))
+	assert.Nil(t, repository.ConfirmTOTPCredential(context.Background(), 1, 100, []string{"a", "b"}))
+
+	assert.Nil(t, repository.UseRecoveryCode(context.Background(), 1, "a"))
+	assert.ErrorIs(t, repository.UseRecoveryCode(context.Background(), 1, "a"), apperror.ErrNotFound)
+	assert.ErrorIs(t, repository.UseRecoveryCode(context.Background(), 2, "b"), apperror.ErrNotFound)
+
+	assert.Nil(t, repository.DeleteTOTPCredential(context.Background(), 1))
+	assert.ErrorIs(t, repository.UseRecoveryCode(context.Background(), 1, "b"), apperror.ErrNotFound)
+	assert.ErrorIs(t, repository.DeleteTOTPCredential(context.Background(), 1), apperror.ErrNotFound)
+	err := repository.GetTOTPCredential(context.Background(), &model.TOTPCredential{}, 1)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type INotebookRepository interface {
+	GetAllNotebooks(ctx context.Context, notebooks *[]model.Notebook, userId uint) error
+	GetNotebookById(ctx context.Context, notebook *model.Notebook, userId uint, notebookId uint) error
+	GetNotebookPath(ctx context.Context, path *[]model.Notebook, userId uint, notebookId uint) error
+	GetSubtreeHeight(ctx context.Context, height *int, userId uint, notebookId uint) error
+	CreateNotebook(ctx context.Context, notebook *model.Notebook) error
+	UpdateNotebook(ctx context.Context, notebook *model.Notebook, userId uint, notebookId uint) error
+	DeleteNotebook(ctx context.Context, userId uint, notebookId uint, policy string) error
+}
+
+type notebookRepository struct {
+	db *gorm.DB
+}
+
+func NewNotebookRepository(db *gorm.DB) INotebookRepository {
+	return &notebookRepository{db}
+}
+
+// maxNotebookRecursion bounds the recursive queries below so that corrupt
+// parent links can never make them loop forever.
+const maxNotebookRecursion = 100
+
+// notebookSubtreeCTE defines subtree(id, depth) as notebook ? of user ? at
+// depth 1 and every notebook nested below it.
+const notebookSubtreeCTE = `WITH RECURSIVE subtree(id, depth) AS (
+	SELECT id, 1 FROM notebooks WHERE id = ? AND user_id = ? AND deleted_at IS NULL
+	UNION ALL
+	SELECT notebooks.id, subtree.depth + 1 FROM notebooks
+	JOIN subtree ON notebooks.parent_id = subtree.id
+	WHERE notebooks.deleted_at IS NULL AND subtree.depth < ?
+) `
+
+// notebookSubtree selects the ids of notebookId and all of its descendants.
+func notebookSubtree(db *gorm.DB, userId uint, notebookId uint) *gorm.DB {
+	return db.Raw(notebookSubtreeCTE+"SELECT id FROM subtree", notebookId, userId, maxNotebookRecursion)
+}
+
+func (nr *notebookRepository) GetAllNotebooks(ctx context.Context, notebooks *[]model.Notebook, userId uint) error {
+	if err := nr.db.WithContext(ctx).Where("user_id = ?", userId).Order("name").Order("id").Find(notebooks).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (nr *notebookRepository) GetNotebookById(ctx context.Context, notebook *model.Notebook, userId uint, notebookId uint) error {
+	if err := nr.db.WithContext(ctx).Where("user_id = ? AND id = ?", userId, notebookId).First(notebook).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.Wrap(apperror.ErrNotFound, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// GetNotebookPath loads the chain of notebooks from the top level notebook
+// down to notebookId itself.
+func (nr *notebookRepository) GetNotebookPath(ctx context.Context, path *[]model.Notebook, userId uint, notebookId uint) error {
+	*path = []model.Notebook{}
+	err := nr.db.WithContext(ctx).Raw(`WITH RECURSIVE ancestors(id, parent_id, depth) AS (
+			SELECT id, parent_id, 0 FROM notebooks WHERE id = ? AND user_id = ? AND deleted_at IS NULL
+			UNION ALL
+			SELECT notebooks.id, notebooks.parent_id, ancestors.depth + 1 FROM notebooks
+			JOIN ancestors ON notebooks.id = ancestors.parent_id
+			WHERE notebooks.deleted_at IS NULL AND ancestors.depth < ?
+		)
+		SELECT notebooks.* FROM notebooks JOIN ancestors ON ancestors.id = notebooks.id
+		ORDER BY ancestors.depth DESC`, notebookId, userId, maxNotebookRecursion).
+		Find(path).Error
+	if err != nil {
+		return err
+	}
+	if len(*path) == 0 {
+		return apperror.New(apperror.ErrNotFound, "notebook does not exist")
+	}
+	return nil
+}
+
+// GetSubtreeHeight counts the levels of the subtree rooted at notebookId,
+// which is 1 for a notebook without children.
+func (nr *notebookRepository) GetSubtreeHeight(ctx context.Context, height *int, userId uint, notebookId uint) error {
+	err := nr.db.WithContext(ctx).Raw(notebookSubtreeCTE+"SELECT COALESCE(MAX(depth), 0) FROM subtree", notebookId, userId, maxNotebookRecursion).
+		Find(height).Error
+	if err != nil {
+		return err
+	}
+	if *height == 0 {
+		return apperror.New(apperror.ErrNotFound, "object does not exist")
+	}
+	return nil
+}
+
+func (nr *notebookRepository) CreateNotebook(ctx context.Context, notebook *model.Notebook) error {
+	if err := nr.db.WithContext(ctx).Create(notebook).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (nr *notebookRepository) UpdateNotebook(ctx context.Context, notebook *model.Notebook, userId uint, notebookId uint) error {
+	result := nr.db.WithContext(ctx).Model(&model.Notebook{}).
+		Where("id = ? AND user_id = ?", notebookId, userId).
+		Updates(map[string]interface{}{"name": notebook.Name, "parent_id": notebook.ParentId})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected < 1 {
+		return apperror.New(apperror.ErrNotFound, "object does not exist")
+	}
+	return nr.GetNotebookById(ctx, notebook, userId, notebookId)
+}
+
+// DeleteNotebook removes an empty notebook. A notebook that still holds memos
+// or child notebooks is only removed when a policy is given: NotebookDeleteTrash
+// moves the whole subtree including its memos to the trash, while
+// NotebookDeleteReparent hands the contents over to the parent notebook.
+func (nr *notebookRepository) DeleteNotebook(ctx context.Context, userId uint, notebookId uint, policy string) error {
+	return nr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		notebook := model.Notebook{}
+		if err := tx.Where("id = ? AND user_id = ?", notebookId, userId).First(&notebook).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperror.New(apperror.ErrNotFound, "object does not exist")
+			}
+			return err
+		}
+		var children, memos int64
+		if err := tx.Model(&model.Notebook{}).Where("parent_id = ?", notebookId).Count(&children).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Memo{}).Where("notebook_id = ?", notebookId).Count(&memos).Error; err != nil {
+			return err
+		}
+
+		switch {
+		case children+memos == 0:
+		case policy == model.NotebookDeleteReparent:
+			if err := tx.Model(&model.Notebook{}).Where("parent_id = ?", notebookId).Update("parent_id", notebook.ParentId).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&model.Memo{}).Where("notebook_id = ?", notebookId).Update("notebook_id", notebook.ParentId).Error; err != nil {
+				return err
+			}
+		case policy == model.NotebookDeleteTrash:
+			subtree := notebookSubtree(tx, userId, notebookId)
+			if err := tx.Where("notebook_id IN (?)", subtree).Delete(&model.Memo{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("id IN (?)", subtree).Delete(&model.Notebook{}).Error; err != nil {
+				return err
+			}
+		default:
+			return apperror.New(apperror.ErrConflict, "notebook is not empty")
+		}
+		return tx.Delete(&notebook).Error
+	})
+}
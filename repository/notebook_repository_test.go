@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"echo-rest-api/testHelpers"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// setupNotebooks creates work > project > archive for user 1 with one memo
+// filed in project and one in archive.
+func setupNotebooks(t *testing.T, db *gorm.DB) (work, project, archive model.Notebook) {
+	repository := NewNotebookRepository(db)
+	work = model.Notebook{Name: "work", UserId: 1}
+	assert.Nil(t, repository.CreateNotebook(context.Background(), &work))
+	project = model.Notebook{Name: "project", UserId: 1, ParentId: &work.ID}
+	assert.Nil(t, repository.CreateNotebook(context.Background(), &project))
+	archive = model.Notebook{Name: "archive", UserId: 1, ParentId: &project.ID}
+	assert.Nil(t, repository.CreateNotebook(context.Background(), &archive))
+
+	memoRepository := NewMemoRepository(db, 0)
+	assert.Nil(t, memoRepository.CreateMemo(context.Background(), &model.Memo{Title: "in project", UserId: 1, NotebookId: &project.ID}))
+	assert.Nil(t, memoRepository.CreateMemo(context.Background(), &model.Memo{Title: "in archive", UserId: 1, NotebookId: &archive.ID}))
+	return work, project, archive
+}
+
+func TestGetAllNotebooks(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	setupNotebooks(t, db)
+	repository := NewNotebookRepository(db)
+
+	notebooks := []model.Notebook{}
+	err := repository.GetAllNotebooks(context.Background(), &notebooks, uint(1))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(notebooks))
+
+	notebooks = []model.Notebook{}
+	err = repository.GetAllNotebooks(context.Background(), &notebooks, uint(2))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(notebooks))
+}
+
+func TestGetNotebookPath(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	work, project, archive := setupNotebooks(t, db)
+	repository := NewNotebookRepository(db)
+
+	path := []model.Notebook{}
+	err := repository.GetNotebookPath(context.Background(), &path, uint(1), archive.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(path))
+	assert.Equal(t, work.ID, path[0].ID)
+	assert.Equal(t, project.ID, path[1].ID)
+	assert.Equal(t, archive.ID, path[2].ID)
+
+	err = repository.GetNotebookPath(context.Background(), &path, uint(2), archive.ID)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+}
+
+func TestGetSubtreeHeight(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	work, _, archive := setupNotebooks(t, db)
+	repository := NewNotebookRepository(db)
+
+	height := 0
+	assert.Nil(t, repository.GetSubtreeHeight(context.Background(), &height, uint(1), work.ID))
+	assert.Equal(t, 3, height)
+	assert.Nil(t, repository.GetSubtreeHeight(context.Background(), &height, uint(1), archive.ID))
+	assert.Equal(t, 1, height)
+}
+
+func TestUpdateNotebook(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	_, project, _ := setupNotebooks(t, db)
+	repository := NewNotebookRepository(db)
+
+	notebook := model.Notebook{Name: "renamed"}
+	err := repository.UpdateNotebook(context.Background(), &notebook, uint(1), project.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "renamed", notebook.Name)
+	assert.Nil(t, notebook.ParentId)
+
+	err = repository.UpdateNotebook(context.Background(), &model.Notebook{Name: "x"}, uint(2), project.ID)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+}
+
+func TestGetAllMemos_Notebook(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	work, project, _ := setupNotebooks(t, db)
+	repository := NewMemoRepository(db, 0)
+
+	memos := []model.Memo{}
+	err := repository.GetAllMemos(context.Background(), &memos, uint(1), model.MemoQuery{NotebookId: &work.ID})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(memos))
+
+	err = repository.GetAllMemos(context.Background(), &memos, uint(1), model.MemoQuery{NotebookId: &work.ID, Recursive: true})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(memos))
+
+	memos = []model.Memo{}
+	err = repository.GetAllMemos(context.Background(), &memos, uint(1), model.MemoQuery{NotebookId: &project.ID})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(memos))
+	assert.Equal(t, "in project", memos[0].Title)
+}
+
+func TestMoveMemo(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	work, _, _ := setupNotebooks(t, db)
+	repository := NewMemoRepository(db, 0)
+
+	var revisionsBefore int64
+	assert.Nil(t, db.Model(&model.MemoRevision{}).Where("memo_id = ?", 1).Count(&revisionsBefore).Error)
+	memo := model.Memo{}
+	err := repository.MoveMemo(context.Background(), &memo, uint(1), uint(1), &work.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, work.ID, *memo.NotebookId)
+	version := memo.Version
+
+	memo = model.Memo{}
+	err = repository.MoveMemo(context.Background(), &memo, uint(1), uint(1), nil)
+	assert.Nil(t, err)
+	assert.Nil(t, memo.NotebookId)
+	assert.Equal(t, version+1, memo.Version)
+
+	// Every move is a new version, so it leaves a revision like an edit.
+	revisions := []model.MemoRevision{}
+	assert.Nil(t, repository.GetMemoRevisions(context.Background(), &revisions, uint(1), uint(1)))
+	assert.Equal(t, int(revisionsBefore)+2, len(revisions))
+	assert.Equal(t, memo.Title, revisions[0].Title)
+	assert.Equal(t, memo.Content, revisions[0].Content)
+
+	err = repository.MoveMemo(context.Background(), &model.Memo{}, uint(2), uint(2), &work.ID)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	err = repository.CreateMemo(context.Background(), &model.Memo{Title: "foreign notebook", UserId: 2, NotebookId: &work.ID})
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+}
+
+func TestDeleteNotebook(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	work, project, archive := setupNotebooks(t, db)
+	repository := NewNotebookRepository(db)
+
+	err := repository.DeleteNotebook(context.Background(), uint(1), project.ID, "")
+	assert.ErrorIs(t, err, apperror.ErrConflict)
+
+	err = repository.DeleteNotebook(context.Background(), uint(1), project.ID, model.NotebookDeleteReparent)
+	assert.Nil(t, err)
+	moved := model.Notebook{}
+	assert.Nil(t, repository.GetNotebookById(context.Background(), &moved, uint(1), archive.ID))
+	assert.Equal(t, work.ID, *moved.ParentId)
+	memos := []model.Memo{}
+	assert.Nil(t, NewMemoRepository(db, 0).GetAllMemos(context.Background(), &memos, uint(1), model.MemoQuery{NotebookId: &work.ID}))
+	assert.Equal(t, 1, len(memos))
+
+	err = repository.DeleteNotebook(context.Background(), uint(1), work.ID, model.NotebookDeleteTrash)
+	assert.Nil(t, err)
+	assert.ErrorIs(t, repository.GetNotebookById(context.Background(), &moved, uint(1), archive.ID), apperror.ErrNotFound)
+	memos = []model.Memo{}
+	assert.Nil(t, NewMemoRepository(db, 0).GetAllMemos(context.Background(), &memos, uint(1), model.MemoQuery{}))
+	assert.Equal(t, 2, len(memos))
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type IPasswordResetRepository interface {
+	GetPasswordResetTokenByHash(ctx context.Context, token *model.PasswordResetToken, hash string) error
+	CreatePasswordResetToken(ctx context.Context, token *model.PasswordResetToken) error
+	ResetPassword(ctx context.Context, token *model.PasswordResetToken, passwordHash string) error
+}
+
+type passwordResetRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetRepository(db *gorm.DB) IPasswordResetRepository {
+	return &passwordResetRepository{db}
+}
+
+func (pr *passwordResetRepository) GetPasswordResetTokenByHash(ctx context.Context, token *model.PasswordResetToken, hash string) error {
+	if err := pr.db.WithContext(ctx).Where("token_hash = ?", hash).First(token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.Wrap(apperror.ErrNotFound, err)
+		}
+		return err
+	}
+	return nil
+}
+
+func (pr *passwordResetRepository) CreatePasswordResetToken(ctx context.Context, token *model.PasswordResetToken) error {
+	if err := pr.db.WithContext(ctx).Omit("User").Create(token).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// ResetPassword consumes token and stores the new password hash of its user.
+// Any other outstanding reset token of the user is consumed as well. It
+// fails with ErrConflict if the token was already used.
+func (pr *passwordResetRepository) ResetPassword(ctx context.Context, token *model.PasswordResetToken, passwordHash string) error {
+	return pr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&model.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", token.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected < 1 {
+			return apperror.New(apperror.ErrConflict, "password reset token already used")
+		}
+		token.UsedAt = &now
+		err := tx.Model(&model.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", token.UserId).
+			Update("used_at", now).Error
+		if err != nil {
+			return err
+		}
+		result = tx.Model(&model.User{}).Where("id = ?", token.UserId).Update("password", passwordHash)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected < 1 {
+			return apperror.New(apperror.ErrNotFound, "object does not exist")
+		}
+		return nil
+	})
+}
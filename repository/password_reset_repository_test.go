@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"echo-rest-api/testHelpers"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResetPassword(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	repository := NewPasswordResetRepository(db)
+	expires := time.Now().Add(time.Hour)
+	first := model.PasswordResetToken{UserId: 1, TokenHash: "first", ExpiresAt: expires}
+	second := model.PasswordResetToken{UserId: 1, TokenHash: "second", ExpiresAt: expires}
+	other := model.PasswordResetToken{UserId: 2, TokenHash: "other", ExpiresAt: expires}
+	for _, token := range []*model.PasswordResetToken{&first, &second, &other} {
+		assert.Nil(t, repository.CreatePasswordResetToken(context.Background(), token))
+	}
+
+	assert.Nil(t, repository.ResetPassword(context.Background(), &first, "new hash"))
+	assert.NotNil(t, first.UsedAt)
+	user := model.User{}
+	assert.Nil(t, NewUserRepository(db).GetUserByEmail(context.Background(), &user, "testuser1@example.com"))
+	assert.Equal(t, "new hash", user.Password)
+
+	err := repository.ResetPassword(context.Background(), &first, "another hash")
+	assert.ErrorIs(t, err, apperror.ErrConflict)
+	stored := model.PasswordResetToken{}
+	assert.Nil(t, repository.GetPasswordResetTokenByHash(context.Background(), &stored, "second"))
+	assert.NotNil(t, stored.UsedAt)
+	stored = model.PasswordResetToken{}
+	assert.Nil(t, repository.GetPasswordResetTokenByHash(context.Background(), &stored, "other"))
+	assert.Nil(t, stored.UsedAt)
+
+	err = repository.GetPasswordResetTokenByHash(context.Background(), &model.PasswordResetToken{}, "missing")
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+}
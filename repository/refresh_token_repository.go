@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type IRefreshTokenRepository interface {
+	GetRefreshTokenByHash(ctx context.Context, token *model.RefreshToken, hash string) error
+	CreateRefreshToken(ctx context.Context, token *model.RefreshToken) error
+	RotateRefreshToken(ctx context.Context, used *model.RefreshToken, next *model.RefreshToken) error
+}
+
+type refreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) IRefreshTokenRepository {
+	return &refreshTokenRepository{db}
+}
+
+func (rr *refreshTokenRepository) GetRefreshTokenByHash(ctx context.Context, token *model.RefreshToken, hash string) error {
+	if err := rr.db.WithContext(ctx).Where("token_hash = ?", hash).First(token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.Wrap(apperror.ErrNotFound, err)
+		}
+		return err
+	}
+	return nil
+}
+
+func (rr *refreshTokenRepository) CreateRefreshToken(ctx context.Context, token *model.RefreshToken) error {
+	if err := rr.db.WithContext(ctx).Omit("Session", "User").Create(token).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// RotateRefreshToken marks used as spent, stores next in its place and
+// extends the session to the expiry of next. It returns ErrConflict when used
+// was already spent, which happens when two requests race to refresh with
+// the same token.
+func (rr *refreshTokenRepository) RotateRefreshToken(ctx context.Context, used *model.RefreshToken, next *model.RefreshToken) error {
+	return rr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&model.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL", used.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected < 1 {
+			return apperror.New(apperror.ErrConflict, "refresh token already used")
+		}
+		if err := tx.Omit("Session", "User").Create(next).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Session{}).
+			Where("id = ?", next.SessionId).
+			Updates(map[string]interface{}{"last_seen_at": now, "expires_at": next.ExpiresAt}).Error
+	})
+}
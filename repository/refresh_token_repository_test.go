@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"echo-rest-api/testHelpers"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func createTestSession(t *testing.T, db *gorm.DB, id string, userId uint) {
+	session := model.Session{ID: id, UserId: userId, LastSeenAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+	assert.Nil(t, NewSessionRepository(db).CreateSession(context.Background(), &session))
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	createTestSession(t, db, "session", 1)
+	repository := NewRefreshTokenRepository(db)
+	expires := time.Now().Add(time.Hour)
+	first := model.RefreshToken{SessionId: "session", UserId: 1, TokenHash: "first", ExpiresAt: expires}
+	assert.Nil(t, repository.CreateRefreshToken(context.Background(), &first))
+
+	second := model.RefreshToken{SessionId: "session", UserId: 1, TokenHash: "second", ExpiresAt: expires.Add(time.Hour)}
+	assert.Nil(t, repository.RotateRefreshToken(context.Background(), &first, &second))
+
+	stored := model.RefreshToken{}
+	assert.Nil(t, repository.GetRefreshTokenByHash(context.Background(), &stored, "first"))
+	assert.NotNil(t, stored.UsedAt)
+	session := model.Session{}
+	assert.Nil(t, NewSessionRepository(db).GetSessionById(context.Background(), &session, 1, "session"))
+	assert.WithinDuration(t, second.ExpiresAt, session.ExpiresAt, time.Second)
+
+	third := model.RefreshToken{SessionId: "session", UserId: 1, TokenHash: "third", ExpiresAt: expires}
+	err := repository.RotateRefreshToken(context.Background(), &first, &third)
+	assert.ErrorIs(t, err, apperror.ErrConflict)
+	err = repository.GetRefreshTokenByHash(context.Background(), &model.RefreshToken{}, "third")
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ISessionRepository interface {
+	GetSessionsByUser(ctx context.Context, sessions *[]model.Session, userId uint) error
+	GetSessionById(ctx context.Context, session *model.Session, userId uint, sessionId string) error
+	CreateSession(ctx context.Context, session *model.Session) error
+	TouchSession(ctx context.Context, sessionId string, seenAt time.Time) error
+	RevokeSession(ctx context.Context, userId uint, sessionId string) error
+	RevokeUserSessions(ctx context.Context, userId uint) error
+	RevokeOtherSessions(ctx context.Context, userId uint, keepId string) error
+}
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) ISessionRepository {
+	return &sessionRepository{db}
+}
+
+// activeSessions limits a query to sessions that are neither revoked nor
+// expired.
+func activeSessions(db *gorm.DB) *gorm.DB {
+	return db.Where("revoked_at IS NULL AND expires_at > ?", time.Now())
+}
+
+func (sr *sessionRepository) GetSessionsByUser(ctx context.Context, sessions *[]model.Session, userId uint) error {
+	if err := sr.db.WithContext(ctx).Scopes(activeSessions).Where("user_id = ?", userId).Order("last_seen_at DESC").Find(sessions).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (sr *sessionRepository) GetSessionById(ctx context.Context, session *model.Session, userId uint, sessionId string) error {
+	if err := sr.db.WithContext(ctx).Scopes(activeSessions).Where("id = ? AND user_id = ?", sessionId, userId).First(session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.New(apperror.ErrNotFound, "session does not exist")
+		}
+		return err
+	}
+	return nil
+}
+
+func (sr *sessionRepository) CreateSession(ctx context.Context, session *model.Session) error {
+	if err := sr.db.WithContext(ctx).Omit("User").Create(session).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (sr *sessionRepository) TouchSession(ctx context.Context, sessionId string, seenAt time.Time) error {
+	if err := sr.db.WithContext(ctx).Model(&model.Session{}).Where("id = ?", sessionId).Update("last_seen_at", seenAt).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (sr *sessionRepository) RevokeSession(ctx context.Context, userId uint, sessionId string) error {
+	result := sr.db.WithContext(ctx).Model(&model.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionId, userId).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected < 1 {
+		return apperror.New(apperror.ErrNotFound, "session does not exist")
+	}
+	return nil
+}
+
+func (sr *sessionRepository) RevokeUserSessions(ctx context.Context, userId uint) error {
+	err := sr.db.WithContext(ctx).Model(&model.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (sr *sessionRepository) RevokeOtherSessions(ctx context.Context, userId uint, keepId string) error {
+	err := sr.db.WithContext(ctx).Model(&model.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userId, keepId).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"echo-rest-api/testHelpers"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSessionsByUser(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	createTestSession(t, db, "a", 1)
+	createTestSession(t, db, "b", 1)
+	createTestSession(t, db, "c", 2)
+	repository := NewSessionRepository(db)
+	expired := model.Session{ID: "d", UserId: 1, ExpiresAt: time.Now().Add(-time.Minute)}
+	assert.Nil(t, repository.CreateSession(context.Background(), &expired))
+
+	sessions := []model.Session{}
+	assert.Nil(t, repository.GetSessionsByUser(context.Background(), &sessions, 1))
+	assert.Equal(t, 2, len(sessions))
+
+	err := repository.GetSessionById(context.Background(), &model.Session{}, 1, "d")
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	err = repository.GetSessionById(context.Background(), &model.Session{}, 1, "c")
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+}
+
+func TestRevokeSession(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	createTestSession(t, db, "a", 1)
+	createTestSession(t, db, "b", 1)
+	createTestSession(t, db, "c", 2)
+	repository := NewSessionRepository(db)
+
+	err := repository.RevokeSession(context.Background(), 2, "a")
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	assert.Nil(t, repository.RevokeSession(context.Background(), 1, "a"))
+	err = repository.GetSessionById(context.Background(), &model.Session{}, 1, "a")
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	err = repository.RevokeSession(context.Background(), 1, "a")
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+
+	assert.Nil(t, repository.RevokeUserSessions(context.Background(), 1))
+	sessions := []model.Session{}
+	assert.Nil(t, repository.GetSessionsByUser(context.Background(), &sessions, 1))
+	assert.Equal(t, 0, len(sessions))
+	assert.Nil(t, repository.GetSessionById(context.Background(), &model.Session{}, 2, "c"))
+}
+
+func TestRevokeOtherSessions(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	createTestSession(t, db, "a", 1)
+	createTestSession(t, db, "b", 1)
+	createTestSession(t, db, "c", 2)
+	repository := NewSessionRepository(db)
+
+	assert.Nil(t, repository.RevokeOtherSessions(context.Background(), 1, "a"))
+	assert.Nil(t, repository.GetSessionById(context.Background(), &model.Session{}, 1, "a"))
+	err := repository.GetSessionById(context.Background(), &model.Session{}, 1, "b")
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	assert.Nil(t, repository.GetSessionById(context.Background(), &model.Session{}, 2, "c"))
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type ITagRepository interface {
+	GetAllTags(ctx context.Context, tags *[]model.TagResponse, userId uint) error
+	GetTagById(ctx context.Context, tag *model.TagResponse, userId uint, tagId uint) error
+	RenameTag(ctx context.Context, userId uint, tagId uint, name string) error
+	MergeTags(ctx context.Context, userId uint, sourceId uint, targetId uint) error
+}
+
+type tagRepository struct {
+	db *gorm.DB
+}
+
+func NewTagRepository(db *gorm.DB) ITagRepository {
+	return &tagRepository{db}
+}
+
+func (tr *tagRepository) tagsWithCount(ctx context.Context, userId uint) *gorm.DB {
+	return tr.db.WithContext(ctx).Table("tags").
+		Select("tags.id, tags.name, COUNT(memos.id) AS memo_count").
+		Joins("LEFT JOIN memo_tags ON memo_tags.tag_id = tags.id").
+		Joins("LEFT JOIN memos ON memos.id = memo_tags.memo_id AND memos.deleted_at IS NULL").
+		Where("tags.user_id = ? AND tags.deleted_at IS NULL", userId).
+		Group("tags.id, tags.name")
+}
+
+func (tr *tagRepository) GetAllTags(ctx context.Context, tags *[]model.TagResponse, userId uint) error {
+	if err := tr.tagsWithCount(ctx, userId).Order("tags.name").Find(tags).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (tr *tagRepository) GetTagById(ctx context.Context, tag *model.TagResponse, userId uint, tagId uint) error {
+	result := tr.tagsWithCount(ctx, userId).Where("tags.id = ?", tagId).Find(tag)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected < 1 {
+		return apperror.New(apperror.ErrNotFound, "object does not exist")
+	}
+	return nil
+}
+
+func (tr *tagRepository) RenameTag(ctx context.Context, userId uint, tagId uint, name string) error {
+	result := tr.db.WithContext(ctx).Model(&model.Tag{}).
+		Where("id = ? AND user_id = ?", tagId, userId).
+		Update("name", name)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+			return apperror.New(apperror.ErrConflict, "tag name already exists")
+		}
+		return result.Error
+	}
+	if result.RowsAffected < 1 {
+		return apperror.New(apperror.ErrNotFound, "object does not exist")
+	}
+	return nil
+}
+
+// MergeTags moves every memo tagged with sourceId over to targetId and then
+// removes the source tag.
+func (tr *tagRepository) MergeTags(ctx context.Context, userId uint, sourceId uint, targetId uint) error {
+	if sourceId == targetId {
+		return apperror.New(apperror.ErrValidation, "cannot merge a tag into itself")
+	}
+	return tr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&model.Tag{}).Where("id IN ? AND user_id = ?", []uint{sourceId, targetId}, userId).Count(&count).Error; err != nil {
+			return err
+		}
+		if count < 2 {
+			return apperror.New(apperror.ErrNotFound, "object does not exist")
+		}
+		err := tx.Exec(`INSERT INTO memo_tags (memo_id, tag_id)
+			SELECT memo_id, ? FROM memo_tags
+			WHERE tag_id = ? AND memo_id NOT IN (SELECT memo_id FROM memo_tags WHERE tag_id = ?)`,
+			targetId, sourceId, targetId).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM memo_tags WHERE tag_id = ?", sourceId).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&model.Tag{}, sourceId).Error
+	})
+}
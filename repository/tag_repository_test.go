@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"echo-rest-api/testHelpers"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// setupTaggedMemos tags memos of user 1 and 2 and returns the ids of user 1's
+// tags keyed by name.
+func setupTaggedMemos(t *testing.T, db *gorm.DB) map[string]uint {
+	repository := NewMemoRepository(db, 0)
+	assert.Nil(t, repository.CreateMemo(context.Background(), &model.Memo{Title: "a", UserId: 1, TagNames: []string{"work", "todo"}}))
+	assert.Nil(t, repository.CreateMemo(context.Background(), &model.Memo{Title: "b", UserId: 1, TagNames: []string{"work"}}))
+	assert.Nil(t, repository.CreateMemo(context.Background(), &model.Memo{Title: "c", UserId: 1, TagNames: []string{"job"}}))
+	assert.Nil(t, repository.CreateMemo(context.Background(), &model.Memo{Title: "d", UserId: 2, TagNames: []string{"work"}}))
+	tags := []model.Tag{}
+	assert.Nil(t, db.Where("user_id = ?", 1).Find(&tags).Error)
+	ids := map[string]uint{}
+	for _, tag := range tags {
+		ids[tag.Name] = tag.ID
+	}
+	return ids
+}
+
+func TestGetAllTags(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	ids := setupTaggedMemos(t, db)
+
+	repository := NewTagRepository(db)
+	tags := []model.TagResponse{}
+	err := repository.GetAllTags(context.Background(), &tags, uint(1))
+	assert.Nil(t, err)
+	assert.Equal(t, []model.TagResponse{
+		{ID: ids["job"], Name: "job", MemoCount: 1},
+		{ID: ids["todo"], Name: "todo", MemoCount: 1},
+		{ID: ids["work"], Name: "work", MemoCount: 2},
+	}, tags)
+}
+
+func TestRenameTag(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	ids := setupTaggedMemos(t, db)
+
+	repository := NewTagRepository(db)
+	err := repository.RenameTag(context.Background(), uint(1), ids["todo"], "later")
+	assert.Nil(t, err)
+	tag := model.TagResponse{}
+	err = repository.GetTagById(context.Background(), &tag, uint(1), ids["todo"])
+	assert.Nil(t, err)
+	assert.Equal(t, "later", tag.Name)
+
+	err = repository.RenameTag(context.Background(), uint(1), ids["todo"], "work")
+	assert.ErrorIs(t, err, apperror.ErrConflict)
+	err = repository.RenameTag(context.Background(), uint(2), ids["todo"], "other")
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+}
+
+func TestMergeTags(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	ids := setupTaggedMemos(t, db)
+
+	repository := NewTagRepository(db)
+	err := repository.MergeTags(context.Background(), uint(1), ids["job"], ids["work"])
+	assert.Nil(t, err)
+	tag := model.TagResponse{}
+	err = repository.GetTagById(context.Background(), &tag, uint(1), ids["work"])
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), tag.MemoCount)
+	err = repository.GetTagById(context.Background(), &tag, uint(1), ids["job"])
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+
+	otherTag := model.Tag{}
+	assert.Nil(t, db.Where("user_id = ?", 2).First(&otherTag).Error)
+	err = repository.MergeTags(context.Background(), uint(1), ids["todo"], otherTag.ID)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+
+	err = repository.MergeTags(context.Background(), uint(1), ids["work"], ids["work"])
+	assert.ErrorIs(t, err, apperror.ErrValidation)
+	err = repository.GetTagById(context.Background(), &tag, uint(1), ids["work"])
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), tag.MemoCount)
+}
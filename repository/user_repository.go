@@ -1,14 +1,27 @@
 package repository
 
 import (
+	"context"
+	"echo-rest-api/apperror"
 	"echo-rest-api/model"
+	"errors"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 type IUserRepository interface {
-	GetUserByEmail(user *model.User, email string) error
-	CreateUser(user *model.User) error
+	GetUserById(ctx context.Context, user *model.User, userId uint) error
+	GetUserByEmail(ctx context.Context, user *model.User, email string) error
+	CreateUser(ctx context.Context, user *model.User) error
+	MarkVerificationSent(ctx context.Context, userId uint, sentAt time.Time, notAfter time.Time) error
+	ClearVerificationSent(ctx context.Context, userId uint) error
+	VerifyEmail(ctx context.Context, userId uint, email string, verifiedAt time.Time) error
+	UpdatePassword(ctx context.Context, userId uint, hash string) error
+	SetPendingEmail(ctx context.Context, userId uint, email string) error
+	ScheduleDeletion(ctx context.Context, userId uint, at time.Time) error
+	CancelDeletion(ctx context.Context, userId uint) error
+	DeleteUsersScheduledBefore(ctx context.Context, before time.Time) (int64, error)
 }
 
 type userRepository struct {
@@ -19,16 +32,159 @@ func NewUserRepository(db *gorm.DB) IUserRepository {
 	return &userRepository{db}
 }
 
-func (ur *userRepository) GetUserByEmail(user *model.User, email string) error {
-	if err := ur.db.Where("email = ?", email).First(user).Error; err != nil {
+func (ur *userRepository) GetUserById(ctx context.Context, user *model.User, userId uint) error {
+	if err := ur.db.WithContext(ctx).First(user, userId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.Wrap(apperror.ErrNotFound, err)
+		}
 		return err
 	}
 	return nil
 }
 
-func (ur *userRepository) CreateUser(user *model.User) error {
-	if err := ur.db.Create(user).Error; err != nil {
+func (ur *userRepository) GetUserByEmail(ctx context.Context, user *model.User, email string) error {
+	if err := ur.db.WithContext(ctx).Where("email = ?", email).First(user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.Wrap(apperror.ErrNotFound, err)
+		}
 		return err
 	}
 	return nil
 }
+
+func (ur *userRepository) CreateUser(ctx context.Context, user *model.User) error {
+	if err := ur.db.WithContext(ctx).Create(user).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return apperror.New(apperror.ErrConflict, "email already registered")
+		}
+		return err
+	}
+	return nil
+}
+
+// MarkVerificationSent records that a verification email is being sent to an
+// unverified user or to the address a user is changing to, unless the
+// previous one was sent after notAfter.
+func (ur *userRepository) MarkVerificationSent(ctx context.Context, userId uint, sentAt time.Time, notAfter time.Time) error {
+	result := ur.db.WithContext(ctx).Model(&model.User{}).
+		Where("id = ? AND (email_verified_at IS NULL OR pending_email IS NOT NULL)", userId).
+		Where("verification_sent_at IS NULL OR verification_sent_at <= ?", notAfter).
+		Update("verification_sent_at", sentAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected < 1 {
+		return apperror.New(apperror.ErrTooManyRequests, "verification email sent recently")
+	}
+	return nil
+}
+
+// ClearVerificationSent takes back MarkVerificationSent when the email could
+// not be sent, so that the user can ask for another one right away.
+func (ur *userRepository) ClearVerificationSent(ctx context.Context, userId uint) error {
+	if err := ur.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userId).Update("verification_sent_at", nil).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// VerifyEmail marks email as verified if it is still the address of the user,
+// or makes it the address of the user if they are changing to it. Verifying
+// an address twice is not an error.
+func (ur *userRepository) VerifyEmail(ctx context.Context, userId uint, email string, verifiedAt time.Time) error {
+	result := ur.db.WithContext(ctx).Model(&model.User{}).
+		Where("id = ? AND email = ?", userId, email).
+		Where("email_verified_at IS NULL").
+		Update("email_verified_at", verifiedAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+	result = ur.db.WithContext(ctx).Model(&model.User{}).
+		Where("id = ? AND pending_email = ?", userId, email).
+		Updates(map[string]interface{}{
+			"email":             email,
+			"pending_email":     nil,
+			"email_verified_at": verifiedAt,
+		})
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+			return apperror.New(apperror.ErrConflict, "email already registered")
+		}
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+	var count int64
+	if err := ur.db.WithContext(ctx).Model(&model.User{}).Where("id = ? AND email = ?", userId, email).Count(&count).Error; err != nil {
+		return err
+	}
+	if count < 1 {
+		return apperror.New(apperror.ErrNotFound, "object does not exist")
+	}
+	return nil
+}
+
+func (ur *userRepository) UpdatePassword(ctx context.Context, userId uint, hash string) error {
+	result := ur.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userId).Update("password", hash)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected < 1 {
+		return apperror.New(apperror.ErrNotFound, "object does not exist")
+	}
+	return nil
+}
+
+// SetPendingEmail records the address a user is changing to and allows a
+// verification email to be sent to it right away.
+func (ur *userRepository) SetPendingEmail(ctx context.Context, userId uint, email string) error {
+	result := ur.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userId).Updates(map[string]interface{}{
+		"pending_email":        email,
+		"verification_sent_at": nil,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected < 1 {
+		return apperror.New(apperror.ErrNotFound, "object does not exist")
+	}
+	return nil
+}
+
+func (ur *userRepository) ScheduleDeletion(ctx context.Context, userId uint, at time.Time) error {
+	result := ur.db.WithContext(ctx).Model(&model.User{}).
+		Where("id = ? AND deletion_scheduled_at IS NULL", userId).
+		Update("deletion_scheduled_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected < 1 {
+		return apperror.New(apperror.ErrConflict, "account deletion is already scheduled")
+	}
+	return nil
+}
+
+func (ur *userRepository) CancelDeletion(ctx context.Context, userId uint) error {
+	result := ur.db.WithContext(ctx).Model(&model.User{}).
+		Where("id = ? AND deletion_scheduled_at IS NOT NULL", userId).
+		Update("deletion_scheduled_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected < 1 {
+		return apperror.New(apperror.ErrConflict, "account deletion is not scheduled")
+	}
+	return nil
+}
+
+// DeleteUsersScheduledBefore permanently deletes the users whose deletion was
+// due before the given time. Their memos, notebooks, tags, sessions and other
+// rows go with them through the ON DELETE CASCADE constraints.
+func (ur *userRepository) DeleteUsersScheduledBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := ur.db.WithContext(ctx).Unscoped().Where("deletion_scheduled_at <= ?", before).Delete(&model.User{})
+	return result.RowsAffected, result.Error
+}
@@ -1,36 +1,176 @@
 package repository
 
 import (
-	"echo-rest-api/db"
+	"context"
+	"echo-rest-api/apperror"
 	"echo-rest-api/model"
+	"echo-rest-api/testHelpers"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestGetUserByEmail(t *testing.T) {
-	db := db.SetupDB()
+	db := testHelpers.SetupTestDB()
 	repository := NewUserRepository(db)
 	user := model.User{}
 	const email = "testuser1@example.com"
-	err := repository.GetUserByEmail(&user, email)
+	err := repository.GetUserByEmail(context.Background(), &user, email)
 	assert.Nil(t, err)
 	assert.Equal(t, email, user.Email)
 }
 
 func TestCreateUser(t *testing.T) {
-	db := db.SetupDB()
+	db := testHelpers.SetupTestDB()
 	repository := NewUserRepository(db)
 	input := model.User{
 		Email:    "createuser@example.com",
 		Password: "createuser",
 	}
 
-	err := repository.CreateUser(&input)
+	err := repository.CreateUser(context.Background(), &input)
 	assert.Nil(t, err)
 
 	createdUser := model.User{}
-	err = repository.GetUserByEmail(&createdUser, input.Email)
+	err = repository.GetUserByEmail(context.Background(), &createdUser, input.Email)
 	assert.Nil(t, err)
 	assert.Equal(t, input.Email, createdUser.Email)
 }
+
+func TestCreateUser_Duplicate(t *testing.T) {
+	db := testHelpers.SetupTestDB()
+	repository := NewUserRepository(db)
+	input := model.User{
+		Email:    "testuser1@example.com",
+		Password: "duplicate",
+	}
+
+	err := repository.CreateUser(context.Background(), &input)
+	assert.ErrorIs(t, err, apperror.ErrConflict)
+}
+
+func TestGetUserByEmail_NotFound(t *testing.T) {
+	db := testHelpers.SetupTestDB()
+	repository := NewUserRepository(db)
+	user := model.User{}
+	err := repository.GetUserByEmail(context.Background(), &user, "nobody@example.com")
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+}
+
+func TestMarkVerificationSent(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	repository := NewUserRepository(db)
+	now := time.Now()
+
+	assert.Nil(t, repository.MarkVerificationSent(context.Background(), 1, now, now.Add(-time.Minute)))
+	err := repository.MarkVerificationSent(context.Background(), 1, now.Add(time.Second), now.Add(-time.Minute))
+	assert.ErrorIs(t, err, apperror.ErrTooManyRequests)
+	assert.Nil(t, repository.MarkVerificationSent(context.Background(), 1, now.Add(2*time.Minute), now.Add(time.Minute)))
+	assert.Nil(t, repository.ClearVerificationSent(context.Background(), 1))
+	assert.Nil(t, repository.MarkVerificationSent(context.Background(), 1, now.Add(3*time.Minute), now.Add(-time.Minute)))
+
+	assert.Nil(t, repository.VerifyEmail(context.Background(), 1, "testuser1@example.com", now))
+	err = repository.MarkVerificationSent(context.Background(), 1, now.Add(time.Hour), now.Add(time.Hour))
+	assert.ErrorIs(t, err, apperror.ErrTooManyRequests)
+}
+
+func TestVerifyEmail(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	repository := NewUserRepository(db)
+	now := time.Now()
+
+	err := repository.VerifyEmail(context.Background(), 1, "changed@example.com", now)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	assert.Nil(t, repository.VerifyEmail(context.Background(), 1, "testuser1@example.com", now))
+	assert.Nil(t, repository.VerifyEmail(context.Background(), 1, "testuser1@example.com", now.Add(time.Hour)))
+
+	user := model.User{}
+	assert.Nil(t, repository.GetUserById(context.Background(), &user, 1))
+	assert.WithinDuration(t, now, *user.EmailVerifiedAt, time.Second)
+	err = repository.GetUserById(context.Background(), &model.User{}, 99)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+}
+
+func TestVerifyEmail_PendingEmail(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	repository := NewUserRepository(db)
+	now := time.Now()
+
+	assert.Nil(t, repository.VerifyEmail(context.Background(), 1, "testuser1@example.com", now))
+	err := repository.MarkVerificationSent(context.Background(), 1, now, now)
+	assert.ErrorIs(t, err, apperror.ErrTooManyRequests)
+	// The pending address can be mailed even though the current one is
+	// verified.
+	assert.Nil(t, repository.SetPendingEmail(context.Background(), 1, "changed@example.com"))
+	assert.Nil(t, repository.MarkVerificationSent(context.Background(), 1, now, now.Add(-time.Minute)))
+
+	assert.Nil(t, repository.VerifyEmail(context.Background(), 1, "changed@example.com", now.Add(time.Minute)))
+	user := model.User{}
+	assert.Nil(t, repository.GetUserById(context.Background(), &user, 1))
+	assert.Equal(t, "changed@example.com", user.Email)
+	assert.Nil(t, user.PendingEmail)
+	assert.WithinDuration(t, now.Add(time.Minute), *user.EmailVerifiedAt, time.Second)
+	assert.Nil(t, repository.VerifyEmail(context.Background(), 1, "changed@example.com", now))
+	err = repository.VerifyEmail(context.Background(), 1, "testuser1@example.com", now)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+
+	assert.Nil(t, repository.SetPendingEmail(context.Background(), 2, "changed@example.com"))
+	err = repository.VerifyEmail(context.Background(), 2, "changed@example.com", now)
+	assert.ErrorIs(t, err, apperror.ErrConflict)
+}
+
+func TestUpdatePassword(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	repository := NewUserRepository(db)
+
+	assert.Nil(t, repository.UpdatePassword(context.Background(), 1, "newhash"))
+	user := model.User{}
+	assert.Nil(t, repository.GetUserById(context.Background(), &user, 1))
+	assert.Equal(t, "newhash", user.Password)
+	err := repository.UpdatePassword(context.Background(), 99, "newhash")
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+}
+
+func TestScheduleDeletion(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	repository := NewUserRepository(db)
+	at := time.Now().Add(time.Hour)
+
+	assert.Nil(t, repository.ScheduleDeletion(context.Background(), 1, at))
+	err := repository.ScheduleDeletion(context.Background(), 1, at)
+	assert.ErrorIs(t, err, apperror.ErrConflict)
+	user := model.User{}
+	assert.Nil(t, repository.GetUserById(context.Background(), &user, 1))
+	assert.WithinDuration(t, at, *user.DeletionScheduledAt, time.Second)
+
+	assert.Nil(t, repository.CancelDeletion(context.Background(), 1))
+	err = repository.CancelDeletion(context.Background(), 1)
+	assert.ErrorIs(t, err, apperror.ErrConflict)
+}
+
+func TestDeleteUsersScheduledBefore(t *testing.T) {
+	db := testHelpers.SetupTestData()
+	// SQLite only enforces the ON DELETE CASCADE constraints when foreign
+	// keys are enabled, which is per connection.
+	sqlDB, err := db.DB()
+	assert.Nil(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	assert.Nil(t, db.Exec("PRAGMA foreign_keys = ON").Error)
+	repository := NewUserRepository(db)
+	now := time.Now()
+	assert.Nil(t, repository.ScheduleDeletion(context.Background(), 1, now.Add(-time.Minute)))
+	assert.Nil(t, repository.ScheduleDeletion(context.Background(), 2, now.Add(time.Hour)))
+
+	deleted, err := repository.DeleteUsersScheduledBefore(context.Background(), now)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), deleted)
+	err = repository.GetUserById(context.Background(), &model.User{}, 1)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	assert.Nil(t, repository.GetUserById(context.Background(), &model.User{}, 2))
+	var memos int64
+	assert.Nil(t, db.Unscoped().Model(&model.Memo{}).Where("user_id = ?", 1).Count(&memos).Error)
+	assert.Equal(t, int64(0), memos)
+	assert.Nil(t, db.Unscoped().Model(&model.Memo{}).Where("user_id = ?", 2).Count(&memos).Error)
+	assert.Equal(t, int64(1), memos)
+}
@@ -1,53 +1,145 @@
 package router
 
 import (
+	"echo-rest-api/config"
 	"echo-rest-api/controller"
+	"echo-rest-api/keyset"
+	"log/slog"
 	"net/http"
-	"os"
 
 	echojwt "github.com/labstack/echo-jwt/v4"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
 
-func NewRouter(uc controller.IUserController, mc controller.IMemoController) *echo.Echo {
+func NewRouter(cfg config.Config, logger *slog.Logger, uc controller.IUserController, mc controller.IMemoController, tc controller.ITagController, nc controller.INotebookController, sc controller.ISessionController, pc controller.IPasswordController, vc controller.IEmailVerificationController, fc controller.IMFAController, ac controller.IAccessTokenController, oc controller.IOIDCController, acc controller.IAccountController, hc controller.IHealthController, keys keyset.IKeySet) *echo.Echo {
 	e := echo.New()
+	e.HTTPErrorHandler = controller.HTTPErrorHandler
+	e.IPExtractor = controller.IPExtractor(cfg.TrustedProxies)
+	e.Use(middleware.RequestID())
+	e.Use(controller.RequestLogger(logger))
+	if cfg.RequestTimeout > 0 {
+		// Cancels the queries of a request that takes too long, which is
+		// then answered with 503.
+		e.Use(middleware.ContextTimeout(cfg.RequestTimeout))
+	}
 	e.GET("/status", func(c echo.Context) error {
 		return c.JSON(http.StatusOK, "ok")
 	})
+	e.GET("/livez", hc.Livez)
+	e.GET("/readyz", hc.Readyz)
+	e.GET("/.well-known/jwks.json", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, keys.JWKS())
+	})
 
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins:     []string{"http://localhost:3000", os.Getenv("FE_URL")},
-		AllowHeaders:     []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAccessControlAllowHeaders, echo.HeaderXCSRFToken},
-		AllowMethods:     []string{"GET", "PUT", "POST", "DELETE"},
+		AllowOrigins:     []string{"http://localhost:3000", cfg.FEURL},
+		AllowHeaders:     []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAccessControlAllowHeaders, echo.HeaderXCSRFToken, echo.HeaderXRequestID, echo.HeaderAuthorization, controller.HeaderIfMatch},
+		AllowMethods:     []string{"GET", "PUT", "PATCH", "POST", "DELETE"},
+		ExposeHeaders:    []string{echo.HeaderXRequestID, controller.HeaderETag},
 		AllowCredentials: true,
 	}))
 
-	config := middleware.CSRFConfig{
+	// CSRF runs after authentication, so that only requests a personal access
+	// token has authenticated skip it; a browser never sends one on its own.
+	// Routes that only take the cookie check every request.
+	csrfConfig := middleware.CSRFConfig{
+		Skipper:        controller.AuthenticatedByAccessToken,
 		CookiePath:     "/",
-		CookieDomain:   os.Getenv("API_DOMAIN"),
+		CookieDomain:   cfg.APIDomain,
 		CookieHTTPOnly: true,
 		CookieSameSite: http.SameSiteNoneMode,
 	}
-	if os.Getenv("GO_ENV") != "production" {
-		config.CookieSameSite = http.SameSiteDefaultMode
+	if !cfg.IsProduction() {
+		csrfConfig.CookieSameSite = http.SameSiteDefaultMode
 	}
-	e.Use(middleware.CSRFWithConfig(config))
+	csrf := middleware.CSRFWithConfig(csrfConfig)
 
-	e.POST("/signup", uc.SignUp)
-	e.POST("/login", uc.Login)
-	e.POST("/logout", uc.Logout)
-	e.GET("/csrf", uc.CsrfToken)
+	e.POST("/signup", uc.SignUp, csrf)
+	e.POST("/login", uc.Login, csrf)
+	e.POST("/login/mfa", uc.LoginMFA, csrf)
+	e.GET("/oidc/:provider/login", oc.Login, csrf)
+	e.GET("/oidc/:provider/callback", oc.Callback, csrf)
+	e.POST("/logout", uc.Logout, csrf)
+	e.POST("/token/refresh", uc.RefreshToken, csrf)
+	e.POST("/password/forgot", pc.ForgotPassword, csrf)
+	e.POST("/password/reset", pc.ResetPassword, csrf)
+	e.GET("/verify-email", vc.VerifyEmail, csrf)
+	e.GET("/csrf", uc.CsrfToken, csrf)
 
-	t := e.Group("/memos")
-	t.Use(echojwt.WithConfig(echojwt.Config{
-		SigningKey:  []byte(os.Getenv("SECRET")),
+	jwtMiddleware := echojwt.WithConfig(echojwt.Config{
+		KeyFunc:     keys.Keyfunc,
 		TokenLookup: "cookie:token",
-	}))
+	})
+	// Resource routes also accept personal access tokens, which take the
+	// place of the JWT cookie when present.
+	tokenMiddleware := echojwt.WithConfig(echojwt.Config{
+		Skipper:     controller.AuthenticatedByAccessToken,
+		KeyFunc:     keys.Keyfunc,
+		TokenLookup: "cookie:token",
+	})
+
+	t := e.Group("/memos")
+	t.Use(ac.Authenticate, csrf, tokenMiddleware, sc.RequireSession, vc.RequireVerifiedEmail)
 	t.GET("", mc.GetAllMemos)
+	t.GET("/search", mc.SearchMemos)
+	t.GET("/trash", mc.GetTrashedMemos)
+	t.DELETE("/trash", mc.EmptyTrash)
 	t.GET("/:memoId", mc.GetMemoById)
 	t.POST("", mc.CreateMemo)
-	t.PUT("/:memoId", mc.UpdateMemo)
+	if cfg.Memo.RequireIfMatch {
+		t.PUT("/:memoId", mc.UpdateMemo, controller.RequireIfMatch)
+		t.PATCH("/:memoId", mc.PatchMemo, controller.RequireIfMatch)
+	} else {
+		t.PUT("/:memoId", mc.UpdateMemo)
+		t.PATCH("/:memoId", mc.PatchMemo)
+	}
+	t.PUT("/:memoId/notebook", mc.MoveMemo)
 	t.DELETE("/:memoId", mc.DeleteMemo)
+	t.POST("/:memoId/restore", mc.RestoreMemo)
+	t.DELETE("/:memoId/purge", mc.PurgeMemo)
+	t.GET("/:memoId/revisions", mc.GetMemoRevisions)
+	t.GET("/:memoId/revisions/:rev", mc.GetMemoRevision)
+	t.POST("/:memoId/revisions/:rev/restore", mc.RestoreMemoRevision)
+
+	tg := e.Group("/tags")
+	tg.Use(ac.Authenticate, csrf, tokenMiddleware, sc.RequireSession)
+	tg.GET("", tc.GetAllTags)
+	tg.PUT("/:tagId", tc.RenameTag)
+	tg.POST("/:tagId/merge", tc.MergeTags)
+
+	n := e.Group("/notebooks")
+	n.Use(ac.Authenticate, csrf, tokenMiddleware, sc.RequireSession)
+	n.GET("", nc.GetAllNotebooks)
+	n.GET("/:notebookId", nc.GetNotebookById)
+	n.POST("", nc.CreateNotebook)
+	n.PUT("/:notebookId", nc.UpdateNotebook)
+	n.DELETE("/:notebookId", nc.DeleteNotebook)
+
+	e.POST("/verify-email/resend", vc.ResendVerification, csrf, jwtMiddleware, sc.RequireSession)
+	f := e.Group("/mfa/totp")
+	f.Use(csrf, jwtMiddleware, sc.RequireSession)
+	f.POST("", fc.EnrollTOTP)
+	f.POST("/confirm", fc.ConfirmTOTP)
+	f.POST("/disable", fc.DisableTOTP)
+
+	at := e.Group("/tokens")
+	at.Use(csrf, jwtMiddleware, sc.RequireSession)
+	at.GET("", ac.GetAccessTokens)
+	at.POST("", ac.CreateAccessToken)
+	at.DELETE("/:tokenId", ac.RevokeAccessToken)
+
+	a := e.Group("/account")
+	a.Use(csrf, jwtMiddleware, sc.RequireSession)
+	a.PUT("/password", acc.ChangePassword)
+	a.PUT("/email", acc.ChangeEmail)
+	a.POST("/delete", acc.DeleteAccount)
+	a.POST("/delete/cancel", acc.CancelDeletion)
+
+	e.POST("/logout-all", sc.LogoutAll, csrf, jwtMiddleware, sc.RequireSession)
+	s := e.Group("/sessions")
+	s.Use(csrf, jwtMiddleware, sc.RequireSession)
+	s.GET("", sc.GetSessions)
+	s.DELETE("/:sessionId", sc.RevokeSession)
 	return e
 }
@@ -0,0 +1,97 @@
+package router
+
+import (
+	"context"
+	"echo-rest-api/config"
+	"echo-rest-api/controller"
+	"echo-rest-api/keyset"
+	"echo-rest-api/logging"
+	"echo-rest-api/model"
+	"echo-rest-api/usecase"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubAccessTokenUsecase struct {
+	usecase.IAccessTokenUsecase
+}
+
+func (s stubAccessTokenUsecase) Authenticate(ctx context.Context, token string, scope string) (model.AccessToken, error) {
+	if token != "emp_secret" {
+		return model.AccessToken{}, errors.New("unknown token")
+	}
+	return model.AccessToken{ID: 1, UserId: 1, Scopes: "read write"}, nil
+}
+
+type stubTagUsecase struct {
+	usecase.ITagUsecase
+}
+
+func (s stubTagUsecase) RenameTag(ctx context.Context, tag model.Tag, userId uint, tagId uint) (model.TagResponse, error) {
+	return model.TagResponse{ID: tagId, Name: tag.Name}, nil
+}
+
+func newTestRouter() *echo.Echo {
+	cc := controller.CookieConfig{}
+	return NewRouter(config.Config{Env: config.EnvTest}, logging.New(io.Discard, slog.LevelError),
+		controller.NewUserController(nil, nil, nil, nil, cc),
+		controller.NewMemoController(nil),
+		controller.NewTagController(stubTagUsecase{}),
+		controller.NewNotebookController(nil),
+		controller.NewSessionController(nil, cc),
+		controller.NewPasswordController(nil, cc),
+		controller.NewEmailVerificationController(nil),
+		controller.NewMFAController(nil),
+		controller.NewAccessTokenController(stubAccessTokenUsecase{}),
+		controller.NewOIDCController(nil, nil, "", cc),
+		controller.NewAccountController(nil, nil),
+		controller.NewHealthController(nil),
+		keyset.NewHMACKeySet([]byte("secret")),
+	)
+}
+
+func TestRouter_CSRF(t *testing.T) {
+	e := newTestRouter()
+	cases := []struct {
+		method string
+		path   string
+		status int
+	}{
+		// A personal access token authenticates the request on its own.
+		{http.MethodPut, "/tags/1", http.StatusOK},
+		// Routes that only take the cookie check CSRF whatever the header.
+		{http.MethodPut, "/account/password", http.StatusBadRequest},
+		{http.MethodPost, "/tokens", http.StatusBadRequest},
+		{http.MethodDelete, "/sessions/abc", http.StatusBadRequest},
+		{http.MethodPost, "/logout-all", http.StatusBadRequest},
+		{http.MethodPost, "/mfa/totp", http.StatusBadRequest},
+		{http.MethodPost, "/verify-email/resend", http.StatusBadRequest},
+		{http.MethodPost, "/login", http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(`{"name":"renamed"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer emp_secret")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, tc.status, rec.Code, "%s %s", tc.method, tc.path)
+	}
+}
+
+func TestRouter_CSRF_CookieOnTokenRoute(t *testing.T) {
+	e := newTestRouter()
+	req := httptest.NewRequest(http.MethodPut, "/tags/1", strings.NewReader(`{"name":"renamed"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.AddCookie(&http.Cookie{Name: "token", Value: "forged"})
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
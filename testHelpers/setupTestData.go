@@ -1,21 +1,28 @@
 package testHelpers
 
 import (
+	"context"
+	"echo-rest-api/config"
 	"echo-rest-api/db"
+	"echo-rest-api/migration"
 	"echo-rest-api/model"
+	"log/slog"
 
 	"gorm.io/gorm"
 )
 
-func SetupTestData() *gorm.DB {
-	db := db.SetupDB()
-	if db.Migrator().HasTable(&model.Memo{}) {
-		db.Migrator().DropTable(&model.Memo{})
-	}
-	if db.Migrator().HasTable(&model.User{}) {
-		db.Migrator().DropTable(&model.User{})
+// SetupTestDB connects to the test database.
+func SetupTestDB() *gorm.DB {
+	conn, err := db.SetupDB(config.Config{Env: config.EnvTest}, slog.Default())
+	if err != nil {
+		panic(err)
 	}
-	db.AutoMigrate(&model.Memo{}, &model.User{})
+	return conn
+}
+
+func SetupTestData() *gorm.DB {
+	db := SetupTestDB()
+	migrateTestDB(db)
 	memos := []model.Memo{
 		{Title: "memo1 title", Content: "memo1 content", UserId: 1},
 		{Title: "memo2 title", Content: "memo2 content", UserId: 2},
@@ -34,3 +41,26 @@ func SetupTestData() *gorm.DB {
 	}
 	return db
 }
+
+// migrateTestDB rolls back every migration and applies them again, which
+// leaves the test database empty.
+func migrateTestDB(conn *gorm.DB) {
+	migrations, err := migration.Load(migration.Files, conn.Dialector.Name())
+	if err != nil {
+		panic(err)
+	}
+	sqlDB, err := conn.DB()
+	if err != nil {
+		panic(err)
+	}
+	migrator, err := migration.NewMigrator(sqlDB, conn.Dialector.Name(), migrations)
+	if err != nil {
+		panic(err)
+	}
+	if _, err := migrator.Down(context.Background(), len(migrations)); err != nil {
+		panic(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		panic(err)
+	}
+}
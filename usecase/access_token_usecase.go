@@ -0,0 +1,113 @@
+package usecase
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"echo-rest-api/repository"
+	"echo-rest-api/validator"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+type IAccessTokenUsecase interface {
+	GetAccessTokens(ctx context.Context, userId uint) ([]model.AccessTokenResponse, error)
+	CreateAccessToken(ctx context.Context, req model.AccessTokenRequest, userId uint) (model.AccessTokenCreatedResponse, error)
+	RevokeAccessToken(ctx context.Context, userId uint, tokenId uint) error
+	Authenticate(ctx context.Context, token string, scope string) (model.AccessToken, error)
+}
+
+type accessTokenUsecase struct {
+	ar repository.IAccessTokenRepository
+	av validator.IAccessTokenValidator
+}
+
+func NewAccessTokenUsecase(ar repository.IAccessTokenRepository, av validator.IAccessTokenValidator) IAccessTokenUsecase {
+	return &accessTokenUsecase{ar, av}
+}
+
+func (au *accessTokenUsecase) GetAccessTokens(ctx context.Context, userId uint) ([]model.AccessTokenResponse, error) {
+	tokens := []model.AccessToken{}
+	if err := au.ar.GetAccessTokensByUser(ctx, &tokens, userId); err != nil {
+		return nil, err
+	}
+	resTokens := make([]model.AccessTokenResponse, 0, len(tokens))
+	for _, v := range tokens {
+		resTokens = append(resTokens, newAccessTokenResponse(v))
+	}
+	return resTokens, nil
+}
+
+func (au *accessTokenUsecase) CreateAccessToken(ctx context.Context, req model.AccessTokenRequest, userId uint) (model.AccessTokenCreatedResponse, error) {
+	if err := au.av.AccessTokenValidate(req); err != nil {
+		return model.AccessTokenCreatedResponse{}, apperror.Wrap(apperror.ErrValidation, err)
+	}
+	secret, err := randomToken()
+	if err != nil {
+		return model.AccessTokenCreatedResponse{}, err
+	}
+	plain := model.AccessTokenPrefix + secret
+	scopes := slices.Clone(req.Scopes)
+	slices.Sort(scopes)
+	token := model.AccessToken{
+		UserId:    userId,
+		Name:      req.Name,
+		Scopes:    strings.Join(slices.Compact(scopes), " "),
+		TokenHash: hashToken(plain),
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := au.ar.CreateAccessToken(ctx, &token); err != nil {
+		return model.AccessTokenCreatedResponse{}, err
+	}
+	return model.AccessTokenCreatedResponse{
+		AccessTokenResponse: newAccessTokenResponse(token),
+		Token:               plain,
+	}, nil
+}
+
+func (au *accessTokenUsecase) RevokeAccessToken(ctx context.Context, userId uint, tokenId uint) error {
+	if err := au.ar.DeleteAccessToken(ctx, userId, tokenId); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Authenticate resolves a personal access token that grants scope. A token
+// with the write scope may also read.
+func (au *accessTokenUsecase) Authenticate(ctx context.Context, token string, scope string) (model.AccessToken, error) {
+	stored := model.AccessToken{}
+	if err := au.ar.GetAccessTokenByHash(ctx, &stored, hashToken(token)); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return model.AccessToken{}, apperror.New(apperror.ErrUnauthorized, "invalid access token")
+		}
+		return model.AccessToken{}, err
+	}
+	now := time.Now()
+	if stored.ExpiresAt != nil && !now.Before(*stored.ExpiresAt) {
+		return model.AccessToken{}, apperror.New(apperror.ErrUnauthorized, "access token has expired")
+	}
+	scopes := strings.Fields(stored.Scopes)
+	if !slices.Contains(scopes, scope) && !slices.Contains(scopes, model.ScopeWrite) {
+		return model.AccessToken{}, apperror.New(apperror.ErrForbidden, "access token lacks the "+scope+" scope")
+	}
+	if stored.LastUsedAt == nil || now.Sub(*stored.LastUsedAt) >= sessionTouchInterval {
+		if err := au.ar.TouchAccessToken(ctx, stored.ID, now); err != nil {
+			return model.AccessToken{}, err
+		}
+		stored.LastUsedAt = &now
+	}
+	return stored, nil
+}
+
+func newAccessTokenResponse(token model.AccessToken) model.AccessTokenResponse {
+	return model.AccessTokenResponse{
+		ID:         token.ID,
+		Name:       token.Name,
+		Scopes:     strings.Fields(token.Scopes),
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+		CreatedAt:  token.CreatedAt,
+	}
+}
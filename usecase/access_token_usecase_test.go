@@ -0,0 +1,77 @@
+package usecase
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"echo-rest-api/validator"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateAccessToken(t *testing.T) {
+	var stored model.AccessToken
+	mockRepository := newMockAccessTokenRepository()
+	mockRepository.(*mockAccessTokenRepository).On("CreateAccessToken", mock.MatchedBy(func(token *model.AccessToken) bool {
+		stored = *token
+		return true
+	})).Return(nil)
+
+	usecase := NewAccessTokenUsecase(mockRepository, validator.NewAccessTokenValidator())
+	res, err := usecase.CreateAccessToken(context.Background(), model.AccessTokenRequest{Name: "ci", Scopes: []string{"write", "read", "write"}}, 1)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(res.Token, model.AccessTokenPrefix))
+	assert.Equal(t, []string{"read", "write"}, res.Scopes)
+	assert.Equal(t, uint(1), stored.UserId)
+	assert.Equal(t, hashToken(res.Token), stored.TokenHash)
+	assert.NotContains(t, stored.TokenHash, res.Token)
+	mockRepository.(*mockAccessTokenRepository).AssertExpectations(t)
+}
+
+func TestCreateAccessToken_Validate(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	mockRepository := newMockAccessTokenRepository()
+
+	usecase := NewAccessTokenUsecase(mockRepository, validator.NewAccessTokenValidator())
+	_, err := usecase.CreateAccessToken(context.Background(), model.AccessTokenRequest{Name: "ci"}, 1)
+	assert.ErrorIs(t, err, apperror.ErrValidation)
+	assert.Equal(t, "scopes: scopes are required.", err.Error())
+	_, err = usecase.CreateAccessToken(context.Background(), model.AccessTokenRequest{Name: "ci", Scopes: []string{"admin"}}, 1)
+	assert.Equal(t, "scopes: (0: must be read or write.).", err.Error())
+	_, err = usecase.CreateAccessToken(context.Background(), model.AccessTokenRequest{Name: "ci", Scopes: []string{"read"}, ExpiresAt: &past}, 1)
+	assert.Equal(t, "expires_at: must be in the future.", err.Error())
+	mockRepository.(*mockAccessTokenRepository).AssertNotCalled(t, "CreateAccessToken")
+}
+
+func TestAuthenticate(t *testing.T) {
+	expired := time.Now().Add(-time.Minute)
+	recent := time.Now()
+	mockRepository := newMockAccessTokenRepository()
+	mockRepository.(*mockAccessTokenRepository).On("GetAccessTokenByHash", hashToken("emp_read")).
+		Return(&model.AccessToken{ID: 1, UserId: 1, Scopes: "read"}, nil)
+	mockRepository.(*mockAccessTokenRepository).On("GetAccessTokenByHash", hashToken("emp_write")).
+		Return(&model.AccessToken{ID: 2, UserId: 1, Scopes: "write", LastUsedAt: &recent}, nil)
+	mockRepository.(*mockAccessTokenRepository).On("GetAccessTokenByHash", hashToken("emp_expired")).
+		Return(&model.AccessToken{ID: 3, UserId: 1, Scopes: "read", ExpiresAt: &expired}, nil)
+	mockRepository.(*mockAccessTokenRepository).On("GetAccessTokenByHash", hashToken("emp_unknown")).
+		Return(nil, apperror.New(apperror.ErrNotFound, "record not found"))
+	mockRepository.(*mockAccessTokenRepository).On("TouchAccessToken", uint(1), mock.Anything).Return(nil)
+
+	usecase := NewAccessTokenUsecase(mockRepository, validator.NewAccessTokenValidator())
+	token, err := usecase.Authenticate(context.Background(), "emp_read", model.ScopeRead)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), token.UserId)
+	_, err = usecase.Authenticate(context.Background(), "emp_read", model.ScopeWrite)
+	assert.ErrorIs(t, err, apperror.ErrForbidden)
+	_, err = usecase.Authenticate(context.Background(), "emp_write", model.ScopeRead)
+	assert.Nil(t, err)
+	_, err = usecase.Authenticate(context.Background(), "emp_expired", model.ScopeRead)
+	assert.ErrorIs(t, err, apperror.ErrUnauthorized)
+	_, err = usecase.Authenticate(context.Background(), "emp_unknown", model.ScopeRead)
+	assert.ErrorIs(t, err, apperror.ErrUnauthorized)
+	mockRepository.(*mockAccessTokenRepository).AssertNumberOfCalls(t, "TouchAccessToken", 1)
+}
@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"context"
+	"echo-rest-api/repository"
+	"log/slog"
+	"time"
+)
+
+const DefaultAccountSweepInterval = time.Hour
+
+type IAccountSweeper interface {
+	Run(ctx context.Context)
+	Sweep(ctx context.Context) (int64, error)
+}
+
+type accountSweeper struct {
+	ur       repository.IUserRepository
+	interval time.Duration
+	now      func() time.Time
+}
+
+// NewAccountSweeper returns a sweeper that permanently deletes the accounts
+// whose deletion is due, checking every interval. A non-positive interval
+// falls back to DefaultAccountSweepInterval.
+func NewAccountSweeper(ur repository.IUserRepository, interval time.Duration) IAccountSweeper {
+	if interval <= 0 {
+		interval = DefaultAccountSweepInterval
+	}
+	return &accountSweeper{ur, interval, time.Now}
+}
+
+// Run sweeps once immediately and then on every tick until ctx is done.
+func (as *accountSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(as.interval)
+	defer ticker.Stop()
+	for {
+		if deleted, err := as.Sweep(ctx); err != nil {
+			slog.Error("account sweeper: sweep failed", "error", err)
+		} else if deleted > 0 {
+			slog.Info("account sweeper: deleted accounts", "count", deleted)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (as *accountSweeper) Sweep(ctx context.Context) (int64, error) {
+	return as.ur.DeleteUsersScheduledBefore(ctx, as.now())
+}
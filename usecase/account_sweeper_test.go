@@ -0,0 +1,27 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountSweeper_Sweep(t *testing.T) {
+	now := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	userRepository := newMockUserRepository()
+	userRepository.(*mockUserRepository).On("DeleteUsersScheduledBefore", now).Return(int64(1), nil)
+
+	sweeper := NewAccountSweeper(userRepository, time.Minute).(*accountSweeper)
+	sweeper.now = func() time.Time { return now }
+	deleted, err := sweeper.Sweep(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), deleted)
+	userRepository.(*mockUserRepository).AssertExpectations(t)
+}
+
+func TestNewAccountSweeper_Defaults(t *testing.T) {
+	sweeper := NewAccountSweeper(nil, 0).(*accountSweeper)
+	assert.Equal(t, DefaultAccountSweepInterval, sweeper.interval)
+}
@@ -0,0 +1,128 @@
+package usecase
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/logging"
+	"echo-rest-api/mailer"
+	"echo-rest-api/model"
+	"echo-rest-api/repository"
+	"echo-rest-api/validator"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const DefaultAccountDeletionGrace = 7 * 24 * time.Hour
+
+type IAccountUsecase interface {
+	ChangePassword(ctx context.Context, userId uint, sessionId string, currentPassword string, newPassword string) error
+	ChangeEmail(ctx context.Context, userId uint, password string, email string) error
+	DeleteAccount(ctx context.Context, userId uint, password string) (model.UserResponse, error)
+	CancelDeletion(ctx context.Context, userId uint) (model.UserResponse, error)
+}
+
+type accountUsecase struct {
+	ur    repository.IUserRepository
+	sr    repository.ISessionRepository
+	uv    validator.IUserValidator
+	m     mailer.IMailer
+	grace time.Duration
+}
+
+// NewAccountUsecase deletes accounts grace after the user asked for it, or
+// after DefaultAccountDeletionGrace when grace is not positive.
+func NewAccountUsecase(ur repository.IUserRepository, sr repository.ISessionRepository, uv validator.IUserValidator, m mailer.IMailer, grace time.Duration) IAccountUsecase {
+	if grace <= 0 {
+		grace = DefaultAccountDeletionGrace
+	}
+	return &accountUsecase{ur, sr, uv, m, grace}
+}
+
+// ChangePassword sets a new password and signs the user out of every session
+// but sessionId, the one making the change.
+func (au *accountUsecase) ChangePassword(ctx context.Context, userId uint, sessionId string, currentPassword string, newPassword string) error {
+	if err := au.uv.PasswordValidate(newPassword); err != nil {
+		return apperror.Wrap(apperror.ErrValidation, err)
+	}
+	if _, err := au.checkPassword(ctx, userId, currentPassword); err != nil {
+		return err
+	}
+	hash, err := hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := au.ur.UpdatePassword(ctx, userId, hash); err != nil {
+		return err
+	}
+	return au.sr.RevokeOtherSessions(ctx, userId, sessionId)
+}
+
+// ChangeEmail records email as the pending address of the user. It only
+// replaces the current one once it is verified, see
+// IEmailVerificationUsecase.SendVerification.
+func (au *accountUsecase) ChangeEmail(ctx context.Context, userId uint, password string, email string) error {
+	if err := au.uv.EmailValidate(email); err != nil {
+		return apperror.Wrap(apperror.ErrValidation, err)
+	}
+	if _, err := au.checkPassword(ctx, userId, password); err != nil {
+		return err
+	}
+	if err := au.ur.GetUserByEmail(ctx, &model.User{}, email); err == nil {
+		return apperror.New(apperror.ErrConflict, "email already registered")
+	} else if !errors.Is(err, apperror.ErrNotFound) {
+		return err
+	}
+	return au.ur.SetPendingEmail(ctx, userId, email)
+}
+
+// DeleteAccount schedules the account for deletion once the grace period has
+// passed. Until then the user can still log in and cancel it.
+func (au *accountUsecase) DeleteAccount(ctx context.Context, userId uint, password string) (model.UserResponse, error) {
+	user, err := au.checkPassword(ctx, userId, password)
+	if err != nil {
+		return model.UserResponse{}, err
+	}
+	at := time.Now().Add(au.grace)
+	if err := au.ur.ScheduleDeletion(ctx, userId, at); err != nil {
+		return model.UserResponse{}, err
+	}
+	user.DeletionScheduledAt = &at
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Your account will be deleted",
+		Body: fmt.Sprintf("Your account and all of its memos will be deleted on %s.\n\n"+
+			"If you change your mind, log in and cancel the deletion before then.\n", at.UTC().Format(time.RFC1123)),
+	}
+	if err := au.m.Send(msg); err != nil {
+		logging.FromContext(ctx).Error("account deletion: sending mail failed", "user_id", user.ID, "error", err)
+	}
+	return newUserResponse(user), nil
+}
+
+func (au *accountUsecase) CancelDeletion(ctx context.Context, userId uint) (model.UserResponse, error) {
+	if err := au.ur.CancelDeletion(ctx, userId); err != nil {
+		return model.UserResponse{}, err
+	}
+	user := model.User{}
+	if err := au.ur.GetUserById(ctx, &user, userId); err != nil {
+		return model.UserResponse{}, err
+	}
+	return newUserResponse(user), nil
+}
+
+// checkPassword asks for the password again before an account is changed.
+// Users who only ever logged in with OIDC have none and have to set one with
+// a password reset first.
+func (au *accountUsecase) checkPassword(ctx context.Context, userId uint, password string) (model.User, error) {
+	user := model.User{}
+	if err := au.ur.GetUserById(ctx, &user, userId); err != nil {
+		return model.User{}, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return model.User{}, apperror.New(apperror.ErrUnauthorized, "invalid password")
+	}
+	return user, nil
+}
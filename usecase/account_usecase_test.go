@@ -0,0 +1,103 @@
+package usecase
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/mailer"
+	"echo-rest-api/model"
+	"echo-rest-api/validator"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newAccountUser(t *testing.T) *model.User {
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	assert.Nil(t, err)
+	user := model.User{Email: "user@example.com", Password: string(hash)}
+	user.ID = 1
+	return &user
+}
+
+func TestChangePassword(t *testing.T) {
+	userRepository := newMockUserRepository()
+	userRepository.(*mockUserRepository).On("GetUserById", uint(1)).Return(newAccountUser(t), nil)
+	userRepository.(*mockUserRepository).On("UpdatePassword", uint(1), mock.MatchedBy(func(hash string) bool {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte("new password")) == nil
+	})).Return(nil)
+	sessionRepository := newMockSessionRepository()
+	sessionRepository.(*mockSessionRepository).On("RevokeOtherSessions", uint(1), "current").Return(nil)
+
+	usecase := NewAccountUsecase(userRepository, sessionRepository, validator.NewUserValidator(), mailer.NewMemoryMailer(), 0)
+	assert.Nil(t, usecase.ChangePassword(context.Background(), 1, "current", "password", "new password"))
+	userRepository.(*mockUserRepository).AssertExpectations(t)
+	sessionRepository.(*mockSessionRepository).AssertExpectations(t)
+}
+
+func TestChangePassword_Invalid(t *testing.T) {
+	userRepository := newMockUserRepository()
+	userRepository.(*mockUserRepository).On("GetUserById", uint(1)).Return(newAccountUser(t), nil)
+	sessionRepository := newMockSessionRepository()
+
+	usecase := NewAccountUsecase(userRepository, sessionRepository, validator.NewUserValidator(), mailer.NewMemoryMailer(), 0)
+	err := usecase.ChangePassword(context.Background(), 1, "current", "wrong", "new password")
+	assert.ErrorIs(t, err, apperror.ErrUnauthorized)
+	err = usecase.ChangePassword(context.Background(), 1, "current", "password", "new")
+	assert.ErrorIs(t, err, apperror.ErrValidation)
+	userRepository.(*mockUserRepository).AssertNotCalled(t, "UpdatePassword")
+	sessionRepository.(*mockSessionRepository).AssertNotCalled(t, "RevokeOtherSessions")
+}
+
+func TestChangeEmail(t *testing.T) {
+	userRepository := newMockUserRepository()
+	userRepository.(*mockUserRepository).On("GetUserById", uint(1)).Return(newAccountUser(t), nil)
+	userRepository.(*mockUserRepository).On("GetUserByEmail", mock.Anything, "new@example.com").
+		Return(nil, apperror.New(apperror.ErrNotFound, "object does not exist"))
+	userRepository.(*mockUserRepository).On("GetUserByEmail", mock.Anything, "taken@example.com").
+		Return(&model.User{Email: "taken@example.com"}, nil)
+	userRepository.(*mockUserRepository).On("SetPendingEmail", uint(1), "new@example.com").Return(nil)
+
+	usecase := NewAccountUsecase(userRepository, newMockSessionRepository(), validator.NewUserValidator(), mailer.NewMemoryMailer(), 0)
+	assert.Nil(t, usecase.ChangeEmail(context.Background(), 1, "password", "new@example.com"))
+	err := usecase.ChangeEmail(context.Background(), 1, "password", "taken@example.com")
+	assert.ErrorIs(t, err, apperror.ErrConflict)
+	err = usecase.ChangeEmail(context.Background(), 1, "password", "not an email")
+	assert.ErrorIs(t, err, apperror.ErrValidation)
+	err = usecase.ChangeEmail(context.Background(), 1, "wrong", "new@example.com")
+	assert.ErrorIs(t, err, apperror.ErrUnauthorized)
+	userRepository.(*mockUserRepository).AssertNumberOfCalls(t, "SetPendingEmail", 1)
+}
+
+func TestDeleteAccount(t *testing.T) {
+	userRepository := newMockUserRepository()
+	userRepository.(*mockUserRepository).On("GetUserById", uint(1)).Return(newAccountUser(t), nil)
+	userRepository.(*mockUserRepository).On("ScheduleDeletion", uint(1), mock.MatchedBy(func(at time.Time) bool {
+		return time.Until(at) > 47*time.Hour && time.Until(at) <= 48*time.Hour
+	})).Return(nil)
+	mail := mailer.NewMemoryMailer()
+
+	usecase := NewAccountUsecase(userRepository, newMockSessionRepository(), validator.NewUserValidator(), mail, 48*time.Hour)
+	res, err := usecase.DeleteAccount(context.Background(), 1, "password")
+	assert.Nil(t, err)
+	assert.NotNil(t, res.DeletionScheduledAt)
+	assert.Equal(t, 1, len(mail.Messages()))
+	assert.Equal(t, "user@example.com", mail.Messages()[0].To)
+
+	_, err = usecase.DeleteAccount(context.Background(), 1, "wrong")
+	assert.ErrorIs(t, err, apperror.ErrUnauthorized)
+	userRepository.(*mockUserRepository).AssertNumberOfCalls(t, "ScheduleDeletion", 1)
+}
+
+func TestCancelDeletion(t *testing.T) {
+	userRepository := newMockUserRepository()
+	userRepository.(*mockUserRepository).On("CancelDeletion", uint(1)).Return(nil)
+	userRepository.(*mockUserRepository).On("GetUserById", uint(1)).Return(newAccountUser(t), nil)
+
+	usecase := NewAccountUsecase(userRepository, newMockSessionRepository(), validator.NewUserValidator(), mailer.NewMemoryMailer(), 0)
+	res, err := usecase.CancelDeletion(context.Background(), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, model.UserResponse{ID: 1, Email: "user@example.com"}, res)
+}
@@ -0,0 +1,193 @@
+package usecase
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"echo-rest-api/apperror"
+	"echo-rest-api/logging"
+	"echo-rest-api/mailer"
+	"echo-rest-api/model"
+	"echo-rest-api/repository"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Policies for memo writes by users who have not verified their email.
+const (
+	EmailPolicyOff   = "off"
+	EmailPolicyLimit = "limit"
+	EmailPolicyBlock = "block"
+)
+
+const (
+	DefaultEmailVerificationTTL    = 48 * time.Hour
+	DefaultEmailVerificationResend = 5 * time.Minute
+	DefaultEmailVerificationURL    = "http://localhost:8080/verify-email"
+	DefaultUnverifiedMemoLimit     = 10
+)
+
+type IEmailVerificationUsecase interface {
+	SendVerification(ctx context.Context, userId uint) error
+	VerifyEmail(ctx context.Context, token string) (model.UserResponse, error)
+	CheckMemoWrite(ctx context.Context, userId uint, create bool) error
+}
+
+// EmailVerificationOptions configures NewEmailVerificationUsecase. Zero values
+// fall back to the defaults and EmailPolicyOff.
+type EmailVerificationOptions struct {
+	VerifyURL      string
+	TTL            time.Duration
+	ResendInterval time.Duration
+	Policy         string
+	MemoLimit      int
+	// Secret keys the verification tokens.
+	Secret []byte
+}
+
+type emailVerificationUsecase struct {
+	ur   repository.IUserRepository
+	mr   repository.IMemoRepository
+	m    mailer.IMailer
+	opts EmailVerificationOptions
+}
+
+func NewEmailVerificationUsecase(ur repository.IUserRepository, mr repository.IMemoRepository, m mailer.IMailer, opts EmailVerificationOptions) IEmailVerificationUsecase {
+	if opts.VerifyURL == "" {
+		opts.VerifyURL = DefaultEmailVerificationURL
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultEmailVerificationTTL
+	}
+	if opts.ResendInterval <= 0 {
+		opts.ResendInterval = DefaultEmailVerificationResend
+	}
+	if opts.Policy == "" {
+		opts.Policy = EmailPolicyOff
+	}
+	if opts.MemoLimit <= 0 {
+		opts.MemoLimit = DefaultUnverifiedMemoLimit
+	}
+	return &emailVerificationUsecase{ur, mr, m, opts}
+}
+
+// SendVerification mails a signed verification link to an unverified user, or
+// to the address a user is changing to. A new link is only sent once
+// ResendInterval has passed since the last one, which does not count when it
+// could not be sent.
+func (eu *emailVerificationUsecase) SendVerification(ctx context.Context, userId uint) error {
+	user := model.User{}
+	if err := eu.ur.GetUserById(ctx, &user, userId); err != nil {
+		return err
+	}
+	email := user.Email
+	if user.PendingEmail != nil {
+		email = *user.PendingEmail
+	} else if user.EmailVerifiedAt != nil {
+		return apperror.New(apperror.ErrConflict, "email address already verified")
+	}
+	now := time.Now()
+	if err := eu.ur.MarkVerificationSent(ctx, user.ID, now, now.Add(-eu.opts.ResendInterval)); err != nil {
+		return err
+	}
+	if err := eu.sendLink(user.ID, email, now); err != nil {
+		if err := eu.ur.ClearVerificationSent(ctx, user.ID); err != nil {
+			logging.FromContext(ctx).Error("email verification: clearing the resend interval failed", "user_id", user.ID, "error", err)
+		}
+		return err
+	}
+	return nil
+}
+
+func (eu *emailVerificationUsecase) sendLink(userId uint, email string, now time.Time) error {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":   strconv.FormatUint(uint64(userId), 10),
+		"email": email,
+		"exp":   now.Add(eu.opts.TTL).Unix(),
+	}).SignedString(purposeKey(eu.opts.Secret, emailVerificationPurpose))
+	if err != nil {
+		return err
+	}
+	link, err := url.Parse(eu.opts.VerifyURL)
+	if err != nil {
+		return err
+	}
+	q := link.Query()
+	q.Set("token", token)
+	link.RawQuery = q.Encode()
+	msg := mailer.Message{
+		To:      email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Open the link below within %s to verify your email address:\n\n%s\n\n"+
+			"If you did not sign up or change your email address, you can ignore this email.\n", eu.opts.TTL, link),
+	}
+	if err := eu.m.Send(msg); err != nil {
+		return fmt.Errorf("sending verification mail: %w", err)
+	}
+	return nil
+}
+
+func (eu *emailVerificationUsecase) VerifyEmail(ctx context.Context, token string) (model.UserResponse, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return purposeKey(eu.opts.Secret, emailVerificationPurpose), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return model.UserResponse{}, apperror.New(apperror.ErrUnauthorized, "invalid verification token")
+	}
+	sub, _ := claims["sub"].(string)
+	email, _ := claims["email"].(string)
+	userId, err := strconv.ParseUint(sub, 10, 0)
+	if err != nil || email == "" {
+		return model.UserResponse{}, apperror.New(apperror.ErrUnauthorized, "invalid verification token")
+	}
+	if err := eu.ur.VerifyEmail(ctx, uint(userId), email, time.Now()); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return model.UserResponse{}, apperror.New(apperror.ErrUnauthorized, "invalid verification token")
+		}
+		return model.UserResponse{}, err
+	}
+	return model.UserResponse{ID: uint(userId), Email: email, EmailVerified: true}, nil
+}
+
+// CheckMemoWrite applies the unverified email policy to a memo write. With
+// EmailPolicyBlock unverified users cannot write at all, with EmailPolicyLimit
+// they cannot create more than MemoLimit memos.
+func (eu *emailVerificationUsecase) CheckMemoWrite(ctx context.Context, userId uint, create bool) error {
+	if eu.opts.Policy == EmailPolicyOff || (eu.opts.Policy == EmailPolicyLimit && !create) {
+		return nil
+	}
+	user := model.User{}
+	if err := eu.ur.GetUserById(ctx, &user, userId); err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+	if eu.opts.Policy == EmailPolicyBlock {
+		return apperror.New(apperror.ErrForbidden, "email address is not verified")
+	}
+	var count int64
+	if err := eu.mr.CountMemos(ctx, &count, userId); err != nil {
+		return err
+	}
+	if count >= int64(eu.opts.MemoLimit) {
+		return apperror.New(apperror.ErrForbidden, fmt.Sprintf("verify your email address to create more than %d memos", eu.opts.MemoLimit))
+	}
+	return nil
+}
+
+const emailVerificationPurpose = "email-verification"
+
+// purposeKey derives a signing key from secret for tokens that must not be
+// accepted anywhere else, in particular not as access tokens.
+func purposeKey(secret []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,155 @@
+package usecase
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/mailer"
+	"echo-rest-api/model"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSendVerification(t *testing.T) {
+	user := model.User{Email: "user@example.com"}
+	user.ID = 1
+	userRepository := newMockUserRepository()
+	userRepository.(*mockUserRepository).On("GetUserById", uint(1)).Return(&user, nil)
+	userRepository.(*mockUserRepository).On("MarkVerificationSent", uint(1), mock.Anything, mock.MatchedBy(func(notAfter time.Time) bool {
+		return time.Until(notAfter) < -4*time.Minute
+	})).Return(nil)
+	userRepository.(*mockUserRepository).On("VerifyEmail", uint(1), "user@example.com", mock.Anything).Return(nil)
+	mail := mailer.NewMemoryMailer()
+
+	usecase := NewEmailVerificationUsecase(userRepository, newMockMemoRepository(), mail, EmailVerificationOptions{Secret: testSecret})
+	assert.Nil(t, usecase.SendVerification(context.Background(), 1))
+
+	messages := mail.Messages()
+	assert.Equal(t, 1, len(messages))
+	assert.Equal(t, "user@example.com", messages[0].To)
+	link, err := url.Parse(regexp.MustCompile(`http://\S+`).FindString(messages[0].Body))
+	assert.Nil(t, err)
+	assert.Equal(t, "/verify-email", link.Path)
+
+	res, err := usecase.VerifyEmail(context.Background(), link.Query().Get("token"))
+	assert.Nil(t, err)
+	assert.Equal(t, model.UserResponse{ID: 1, Email: "user@example.com", EmailVerified: true}, res)
+	userRepository.(*mockUserRepository).AssertExpectations(t)
+}
+
+func TestSendVerification_Verified(t *testing.T) {
+	verifiedAt := time.Now()
+	userRepository := newMockUserRepository()
+	userRepository.(*mockUserRepository).On("GetUserById", uint(1)).
+		Return(&model.User{Email: "user@example.com", EmailVerifiedAt: &verifiedAt}, nil)
+	mail := mailer.NewMemoryMailer()
+
+	usecase := NewEmailVerificationUsecase(userRepository, newMockMemoRepository(), mail, EmailVerificationOptions{Secret: testSecret})
+	assert.ErrorIs(t, usecase.SendVerification(context.Background(), 1), apperror.ErrConflict)
+	assert.Equal(t, 0, len(mail.Messages()))
+}
+
+func TestSendVerification_PendingEmail(t *testing.T) {
+	verifiedAt := time.Now()
+	pending := "new@example.com"
+	user := model.User{Email: "user@example.com", EmailVerifiedAt: &verifiedAt, PendingEmail: &pending}
+	user.ID = 1
+	userRepository := newMockUserRepository()
+	userRepository.(*mockUserRepository).On("GetUserById", uint(1)).Return(&user, nil)
+	userRepository.(*mockUserRepository).On("MarkVerificationSent", uint(1), mock.Anything, mock.Anything).Return(nil)
+	userRepository.(*mockUserRepository).On("VerifyEmail", uint(1), "new@example.com", mock.Anything).Return(nil)
+	mail := mailer.NewMemoryMailer()
+
+	usecase := NewEmailVerificationUsecase(userRepository, newMockMemoRepository(), mail, EmailVerificationOptions{Secret: testSecret})
+	assert.Nil(t, usecase.SendVerification(context.Background(), 1))
+
+	messages := mail.Messages()
+	assert.Equal(t, 1, len(messages))
+	assert.Equal(t, "new@example.com", messages[0].To)
+	link, err := url.Parse(regexp.MustCompile(`http://\S+`).FindString(messages[0].Body))
+	assert.Nil(t, err)
+	res, err := usecase.VerifyEmail(context.Background(), link.Query().Get("token"))
+	assert.Nil(t, err)
+	assert.Equal(t, "new@example.com", res.Email)
+	userRepository.(*mockUserRepository).AssertExpectations(t)
+}
+
+func TestSendVerification_Throttled(t *testing.T) {
+	user := model.User{Email: "user@example.com"}
+	user.ID = 1
+	userRepository := newMockUserRepository()
+	userRepository.(*mockUserRepository).On("GetUserById", uint(1)).Return(&user, nil)
+	userRepository.(*mockUserRepository).On("MarkVerificationSent", uint(1), mock.Anything, mock.Anything).
+		Return(apperror.New(apperror.ErrTooManyRequests, "verification email sent recently"))
+	mail := mailer.NewMemoryMailer()
+
+	usecase := NewEmailVerificationUsecase(userRepository, newMockMemoRepository(), mail, EmailVerificationOptions{Secret: testSecret})
+	assert.ErrorIs(t, usecase.SendVerification(context.Background(), 1), apperror.ErrTooManyRequests)
+	assert.Equal(t, 0, len(mail.Messages()))
+}
+
+func TestSendVerification_MailError(t *testing.T) {
+	user := model.User{Email: "user@example.com"}
+	user.ID = 1
+	userRepository := newMockUserRepository()
+	userRepository.(*mockUserRepository).On("GetUserById", uint(1)).Return(&user, nil)
+	userRepository.(*mockUserRepository).On("MarkVerificationSent", uint(1), mock.Anything, mock.Anything).Return(nil)
+	userRepository.(*mockUserRepository).On("ClearVerificationSent", uint(1)).Return(nil)
+
+	// The failed attempt does not hold back a retry.
+	usecase := NewEmailVerificationUsecase(userRepository, newMockMemoRepository(), failingMailer{}, EmailVerificationOptions{Secret: testSecret})
+	assert.ErrorContains(t, usecase.SendVerification(context.Background(), 1), "smtp unavailable")
+	userRepository.(*mockUserRepository).AssertExpectations(t)
+}
+
+func TestVerifyEmail_InvalidToken(t *testing.T) {
+	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":   "1",
+		"email": "user@example.com",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}).SignedString(testSecret)
+	assert.Nil(t, err)
+	expired, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":   "1",
+		"email": "user@example.com",
+		"exp":   time.Now().Add(-time.Minute).Unix(),
+	}).SignedString(purposeKey(testSecret, emailVerificationPurpose))
+	assert.Nil(t, err)
+	userRepository := newMockUserRepository()
+
+	usecase := NewEmailVerificationUsecase(userRepository, newMockMemoRepository(), mailer.NewMemoryMailer(), EmailVerificationOptions{Secret: testSecret})
+	for _, token := range []string{"", "garbage", accessToken, expired} {
+		_, err := usecase.VerifyEmail(context.Background(), token)
+		assert.ErrorIs(t, err, apperror.ErrUnauthorized)
+	}
+	userRepository.(*mockUserRepository).AssertNotCalled(t, "VerifyEmail")
+}
+
+func TestCheckMemoWrite(t *testing.T) {
+	verifiedAt := time.Now()
+	userRepository := newMockUserRepository()
+	userRepository.(*mockUserRepository).On("GetUserById", uint(1)).Return(&model.User{}, nil)
+	userRepository.(*mockUserRepository).On("GetUserById", uint(2)).Return(&model.User{EmailVerifiedAt: &verifiedAt}, nil)
+	memoRepository := newMockMemoRepository()
+	memoRepository.(*mockMemoRepository).On("CountMemos", uint(1)).Return(2, nil)
+
+	off := NewEmailVerificationUsecase(userRepository, memoRepository, mailer.NewMemoryMailer(), EmailVerificationOptions{Secret: testSecret})
+	assert.Nil(t, off.CheckMemoWrite(context.Background(), 1, true))
+
+	block := NewEmailVerificationUsecase(userRepository, memoRepository, mailer.NewMemoryMailer(), EmailVerificationOptions{Secret: testSecret, Policy: EmailPolicyBlock})
+	assert.ErrorIs(t, block.CheckMemoWrite(context.Background(), 1, false), apperror.ErrForbidden)
+	assert.Nil(t, block.CheckMemoWrite(context.Background(), 2, false))
+
+	limit := NewEmailVerificationUsecase(userRepository, memoRepository, mailer.NewMemoryMailer(), EmailVerificationOptions{Secret: testSecret, Policy: EmailPolicyLimit, MemoLimit: 3})
+	assert.Nil(t, limit.CheckMemoWrite(context.Background(), 1, false))
+	assert.Nil(t, limit.CheckMemoWrite(context.Background(), 1, true))
+	limit = NewEmailVerificationUsecase(userRepository, memoRepository, mailer.NewMemoryMailer(), EmailVerificationOptions{Secret: testSecret, Policy: EmailPolicyLimit, MemoLimit: 2})
+	assert.ErrorIs(t, limit.CheckMemoWrite(context.Background(), 1, true), apperror.ErrForbidden)
+	assert.Nil(t, limit.CheckMemoWrite(context.Background(), 2, true))
+	memoRepository.(*mockMemoRepository).AssertNumberOfCalls(t, "CountMemos", 2)
+}
@@ -0,0 +1,111 @@
+package usecase
+
+import (
+	"context"
+	"echo-rest-api/model"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const DefaultHealthCheckTimeout = 2 * time.Second
+
+// HealthChecker reports whether a dependency can serve requests.
+type HealthChecker func(ctx context.Context) error
+
+type IHealthUsecase interface {
+	Register(name string, check HealthChecker)
+	Ready(ctx context.Context) model.HealthReport
+	// Drain makes every later readiness check fail, so that load balancers
+	// stop routing to an instance that is shutting down.
+	Drain()
+}
+
+type healthUsecase struct {
+	mu       sync.Mutex
+	checks   map[string]HealthChecker
+	timeout  time.Duration
+	draining atomic.Bool
+}
+
+// NewHealthUsecase returns a usecase whose checks each get timeout to answer.
+// A non-positive timeout falls back to the default.
+func NewHealthUsecase(timeout time.Duration) IHealthUsecase {
+	if timeout <= 0 {
+		timeout = DefaultHealthCheckTimeout
+	}
+	return &healthUsecase{checks: map[string]HealthChecker{}, timeout: timeout}
+}
+
+func (hu *healthUsecase) Register(name string, check HealthChecker) {
+	hu.mu.Lock()
+	defer hu.mu.Unlock()
+	hu.checks[name] = check
+}
+
+// Ready runs the registered checks concurrently.
+func (hu *healthUsecase) Ready(ctx context.Context) model.HealthReport {
+	hu.mu.Lock()
+	checks := make(map[string]HealthChecker, len(hu.checks))
+	for name, check := range hu.checks {
+		checks[name] = check
+	}
+	hu.mu.Unlock()
+
+	report := model.HealthReport{Status: model.HealthStatusOK, Checks: map[string]model.HealthCheck{}}
+	if hu.draining.Load() {
+		report.Status = model.HealthStatusUnavailable
+		report.Checks["shutdown"] = model.HealthCheck{Status: model.HealthStatusUnavailable, Error: "shutting down"}
+	}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := hu.run(ctx, check)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if result.Status != model.HealthStatusOK {
+				report.Status = model.HealthStatusUnavailable
+			}
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+func (hu *healthUsecase) run(ctx context.Context, check HealthChecker) model.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, hu.timeout)
+	defer cancel()
+	start := time.Now()
+	// A check that ignores ctx must not hold up the probe.
+	done := make(chan error, 1)
+	go func() { done <- check(ctx) }()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	result := model.HealthCheck{
+		Status:    model.HealthStatusOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = errors.New("timed out")
+		}
+		result.Status = model.HealthStatusUnavailable
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func (hu *healthUsecase) Drain() {
+	hu.draining.Store(true)
+}
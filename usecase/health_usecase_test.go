@@ -0,0 +1,52 @@
+package usecase
+
+import (
+	"context"
+	"echo-rest-api/model"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReady(t *testing.T) {
+	usecase := NewHealthUsecase(0)
+	report := usecase.Ready(context.Background())
+	assert.Equal(t, model.HealthStatusOK, report.Status)
+	assert.Empty(t, report.Checks)
+
+	usecase.Register("database", func(ctx context.Context) error { return nil })
+	usecase.Register("cache", func(ctx context.Context) error { return errors.New("connection refused") })
+	report = usecase.Ready(context.Background())
+	assert.Equal(t, model.HealthStatusUnavailable, report.Status)
+	assert.Equal(t, model.HealthStatusOK, report.Checks["database"].Status)
+	assert.Equal(t, model.HealthStatusUnavailable, report.Checks["cache"].Status)
+	assert.Equal(t, "connection refused", report.Checks["cache"].Error)
+}
+
+func TestReady_Timeout(t *testing.T) {
+	usecase := NewHealthUsecase(20 * time.Millisecond)
+	block := make(chan struct{})
+	defer close(block)
+	usecase.Register("stuck", func(ctx context.Context) error {
+		<-block
+		return nil
+	})
+	start := time.Now()
+	report := usecase.Ready(context.Background())
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, model.HealthStatusUnavailable, report.Status)
+	assert.Equal(t, "timed out", report.Checks["stuck"].Error)
+	assert.GreaterOrEqual(t, report.Checks["stuck"].LatencyMs, float64(20))
+}
+
+func TestReady_Drain(t *testing.T) {
+	usecase := NewHealthUsecase(0)
+	usecase.Register("database", func(ctx context.Context) error { return nil })
+	usecase.Drain()
+	report := usecase.Ready(context.Background())
+	assert.Equal(t, model.HealthStatusUnavailable, report.Status)
+	assert.Equal(t, "shutting down", report.Checks["shutdown"].Error)
+	assert.Equal(t, model.HealthStatusOK, report.Checks["database"].Status)
+}
@@ -0,0 +1,236 @@
+package usecase
+
+import (
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"echo-rest-api/repository"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultLoginEmailThreshold  = 5
+	DefaultLoginIPThreshold     = 20
+	DefaultLoginLockout         = 30 * time.Second
+	DefaultLoginMaxLockout      = 15 * time.Minute
+	DefaultLoginAttemptWindow   = time.Hour
+	DefaultMFAChallengeAttempts = 3
+)
+
+// LoginAttemptOptions configure the lockout after failed logins. Zero values
+// fall back to the defaults.
+type LoginAttemptOptions struct {
+	// EmailThreshold and IPThreshold are the number of failures that lock an
+	// email address or an IP address out. Users behind NAT share an IP
+	// address, so it gets more.
+	EmailThreshold int
+	IPThreshold    int
+	// Lockout is the first lockout. It doubles with every further failure,
+	// up to MaxLockout.
+	Lockout    time.Duration
+	MaxLockout time.Duration
+	// Window is the quiet period after which failures are forgotten.
+	Window time.Duration
+	// MFAChallengeAttempts is the number of wrong codes after which an MFA
+	// challenge is void and the login has to start over.
+	MFAChallengeAttempts int
+}
+
+// ILoginAttemptUsecase counts an attempt when it is checked, in the same step
+// as the check, so that concurrent attempts cannot all pass before any of
+// them is counted. A success gives the attempt back; one that is never
+// settled stays counted as a failure.
+type ILoginAttemptUsecase interface {
+	CheckLogin(email string, ip string) error
+	RecordFailure(email string, ip string) error
+	RecordSuccess(email string, ip string) error
+	// CheckMFA, RecordMFAFailure and RecordMFASuccess do the same for the
+	// second factor of a login, counting wrong codes per account as well as
+	// per challenge.
+	CheckMFA(challenge model.MFAChallenge, ip string) error
+	RecordMFAFailure(challenge model.MFAChallenge, ip string) error
+	RecordMFASuccess(challenge model.MFAChallenge, ip string) error
+}
+
+type loginAttemptUsecase struct {
+	ar   repository.ILoginAttemptRepository
+	lr   repository.ILoginLockoutRepository
+	opts LoginAttemptOptions
+}
+
+func NewLoginAttemptUsecase(ar repository.ILoginAttemptRepository, lr repository.ILoginLockoutRepository, opts LoginAttemptOptions) ILoginAttemptUsecase {
+	if opts.EmailThreshold <= 0 {
+		opts.EmailThreshold = DefaultLoginEmailThreshold
+	}
+	if opts.IPThreshold <= 0 {
+		opts.IPThreshold = DefaultLoginIPThreshold
+	}
+	if opts.Lockout <= 0 {
+		opts.Lockout = DefaultLoginLockout
+	}
+	if opts.MaxLockout <= 0 {
+		opts.MaxLockout = DefaultLoginMaxLockout
+	}
+	if opts.Window <= 0 {
+		opts.Window = DefaultLoginAttemptWindow
+	}
+	if opts.MFAChallengeAttempts <= 0 {
+		opts.MFAChallengeAttempts = DefaultMFAChallengeAttempts
+	}
+	return &loginAttemptUsecase{ar, lr, opts}
+}
+
+// CheckLogin rejects a login while its email address or IP address is
+// locked out, and counts it otherwise. email is empty when there is no
+// account to count against.
+func (lu *loginAttemptUsecase) CheckLogin(email string, ip string) error {
+	return lu.reserve(loginAttemptKeys(email, ip))
+}
+
+// reserve counts an attempt against keys unless one of them is locked out.
+func (lu *loginAttemptUsecase) reserve(keys []string) error {
+	now := time.Now()
+	return lu.ar.UpdateLoginAttempts(keys, now.Add(-lu.opts.Window), func(attempts []model.LoginAttempt) error {
+		var wait time.Duration
+		for _, attempt := range attempts {
+			if strings.HasPrefix(attempt.Key, mfaChallengeAttemptPrefix) {
+				if attempt.Failures >= lu.opts.MFAChallengeAttempts {
+					return apperror.New(apperror.ErrUnauthorized, "too many wrong codes, log in again")
+				}
+				continue
+			}
+			if d := lu.lockedUntil(attempt).Sub(now); d > wait {
+				wait = d
+			}
+		}
+		if wait > 0 {
+			return apperror.Throttled("too many failed logins, try again later", wait)
+		}
+		for i := range attempts {
+			attempts[i].Failures++
+			attempts[i].LastFailureAt = now
+		}
+		return nil
+	})
+}
+
+// release gives back the attempt that reserve counted against keys.
+func (lu *loginAttemptUsecase) release(keys []string) error {
+	return lu.ar.UpdateLoginAttempts(keys, time.Now().Add(-lu.opts.Window), func(attempts []model.LoginAttempt) error {
+		for i := range attempts {
+			attempts[i].Failures--
+		}
+		return nil
+	})
+}
+
+// RecordFailure keeps the attempt CheckLogin counted and records the keys it
+// has locked out.
+func (lu *loginAttemptUsecase) RecordFailure(email string, ip string) error {
+	return lu.recordLockouts(loginAttemptKeys(email, ip), ip)
+}
+
+func (lu *loginAttemptUsecase) recordLockouts(keys []string, ip string) error {
+	attempts := []model.LoginAttempt{}
+	if err := lu.ar.GetLoginAttempts(&attempts, keys); err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, attempt := range attempts {
+		lockedUntil := lu.lockedUntil(attempt)
+		if !lockedUntil.After(now) {
+			continue
+		}
+		lockout := model.LoginLockout{
+			Key:         attempt.Key,
+			IP:          ip,
+			Failures:    attempt.Failures,
+			LockedUntil: lockedUntil,
+		}
+		if err := lu.lr.CreateLoginLockout(&lockout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordSuccess forgets the failures of the email address and gives the
+// attempt back to the IP address. The earlier failures of the IP address
+// stay, or an attacker could clear them by logging in to an account of their
+// own between guesses.
+func (lu *loginAttemptUsecase) RecordSuccess(email string, ip string) error {
+	if err := lu.ar.DeleteLoginAttempt(emailAttemptKey(email)); err != nil {
+		return err
+	}
+	return lu.release([]string{ipAttemptPrefix + ip})
+}
+
+// CheckMFA rejects a code while the account or the IP address is locked out,
+// and for good once the challenge has seen too many wrong codes.
+func (lu *loginAttemptUsecase) CheckMFA(challenge model.MFAChallenge, ip string) error {
+	return lu.reserve(append([]string{mfaChallengeAttemptKey(challenge)}, mfaAttemptKeys(challenge, ip)...))
+}
+
+func (lu *loginAttemptUsecase) RecordMFAFailure(challenge model.MFAChallenge, ip string) error {
+	return lu.recordLockouts(mfaAttemptKeys(challenge, ip), ip)
+}
+
+func (lu *loginAttemptUsecase) RecordMFASuccess(challenge model.MFAChallenge, ip string) error {
+	if err := lu.ar.DeleteLoginAttempt(mfaChallengeAttemptKey(challenge)); err != nil {
+		return err
+	}
+	if err := lu.ar.DeleteLoginAttempt(mfaAccountAttemptKey(challenge)); err != nil {
+		return err
+	}
+	return lu.release([]string{ipAttemptPrefix + ip})
+}
+
+func (lu *loginAttemptUsecase) lockedUntil(attempt model.LoginAttempt) time.Time {
+	threshold := lu.opts.EmailThreshold
+	if strings.HasPrefix(attempt.Key, ipAttemptPrefix) {
+		threshold = lu.opts.IPThreshold
+	}
+	if attempt.Failures < threshold {
+		return time.Time{}
+	}
+	lockout := lu.opts.Lockout
+	for i := threshold; i < attempt.Failures && lockout < lu.opts.MaxLockout; i++ {
+		lockout *= 2
+	}
+	return attempt.LastFailureAt.Add(min(lockout, lu.opts.MaxLockout))
+}
+
+const (
+	emailAttemptPrefix        = "email:"
+	ipAttemptPrefix           = "ip:"
+	mfaAttemptPrefix          = "mfa:"
+	mfaChallengeAttemptPrefix = "mfa-challenge:"
+)
+
+func emailAttemptKey(email string) string {
+	return emailAttemptPrefix + strings.ToLower(strings.TrimSpace(email))
+}
+
+func loginAttemptKeys(email string, ip string) []string {
+	keys := []string{ipAttemptPrefix + ip}
+	if email != "" {
+		keys = append(keys, emailAttemptKey(email))
+	}
+	return keys
+}
+
+func mfaAttemptKeys(challenge model.MFAChallenge, ip string) []string {
+	return []string{ipAttemptPrefix + ip, mfaAccountAttemptKey(challenge)}
+}
+
+// mfaAccountAttemptKey counts wrong codes against the account with the same
+// threshold as an email address, since whoever answers the challenge already
+// knows the password.
+func mfaAccountAttemptKey(challenge model.MFAChallenge) string {
+	return mfaAttemptPrefix + strconv.FormatUint(uint64(challenge.UserId), 10)
+}
+
+func mfaChallengeAttemptKey(challenge model.MFAChallenge) string {
+	return mfaChallengeAttemptPrefix + challenge.ID
+}
@@ -0,0 +1,151 @@
+package usecase
+
+import (
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"echo-rest-api/repository"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// seedLoginFailures sets the counter of key as if it had failed failures
+// times just now.
+func seedLoginFailures(t *testing.T, ar repository.ILoginAttemptRepository, key string, failures int) {
+	assert.Nil(t, ar.UpdateLoginAttempts([]string{key}, time.Now().Add(-time.Hour), func(attempts []model.LoginAttempt) error {
+		attempts[0].Failures = failures
+		attempts[0].LastFailureAt = time.Now()
+		return nil
+	}))
+}
+
+func TestLoginAttempts_EmailLockout(t *testing.T) {
+	lockoutRepository := newMockLoginLockoutRepository()
+	lockoutRepository.(*mockLoginLockoutRepository).On("CreateLoginLockout", mock.AnythingOfType("*model.LoginLockout")).Return(nil)
+	attemptRepository := repository.NewMemoryLoginAttemptRepository()
+	usecase := NewLoginAttemptUsecase(attemptRepository, lockoutRepository, LoginAttemptOptions{EmailThreshold: 3})
+
+	for range 2 {
+		assert.Nil(t, usecase.CheckLogin("User@example.com", "192.0.2.1"))
+		assert.Nil(t, usecase.RecordFailure("User@example.com", "192.0.2.1"))
+	}
+	assert.Nil(t, usecase.CheckLogin("user@example.com", "192.0.2.1"))
+	lockoutRepository.(*mockLoginLockoutRepository).AssertNotCalled(t, "CreateLoginLockout", mock.Anything)
+
+	assert.Nil(t, usecase.RecordFailure("user@example.com", "192.0.2.1"))
+	err := usecase.CheckLogin("user@example.com", "198.51.100.1")
+	assert.ErrorIs(t, err, apperror.ErrTooManyRequests)
+	retryAfter, ok := apperror.RetryAfter(err)
+	assert.True(t, ok)
+	assert.InDelta(t, DefaultLoginLockout.Seconds(), retryAfter.Seconds(), 1)
+	assert.Nil(t, usecase.CheckLogin("other@example.com", "192.0.2.1"))
+	lockoutRepository.(*mockLoginLockoutRepository).AssertCalled(t, "CreateLoginLockout", mock.MatchedBy(func(lockout *model.LoginLockout) bool {
+		return lockout.Key == "email:user@example.com" && lockout.IP == "192.0.2.1" && lockout.Failures == 3
+	}))
+
+	// Every further failure doubles the lockout.
+	seedLoginFailures(t, attemptRepository, "email:user@example.com", 4)
+	retryAfter, _ = apperror.RetryAfter(usecase.CheckLogin("user@example.com", "192.0.2.1"))
+	assert.InDelta(t, 2*DefaultLoginLockout.Seconds(), retryAfter.Seconds(), 1)
+
+	assert.Nil(t, usecase.RecordSuccess("USER@example.com", "192.0.2.1"))
+	assert.Nil(t, usecase.CheckLogin("user@example.com", "192.0.2.1"))
+}
+
+func TestLoginAttempts_IPLockout(t *testing.T) {
+	lockoutRepository := newMockLoginLockoutRepository()
+	lockoutRepository.(*mockLoginLockoutRepository).On("CreateLoginLockout", mock.AnythingOfType("*model.LoginLockout")).Return(nil)
+	attemptRepository := repository.NewMemoryLoginAttemptRepository()
+	usecase := NewLoginAttemptUsecase(attemptRepository, lockoutRepository, LoginAttemptOptions{IPThreshold: 3, MaxLockout: time.Minute})
+
+	for _, email := range []string{"a@example.com", ""} {
+		assert.Nil(t, usecase.CheckLogin(email, "192.0.2.1"))
+		assert.Nil(t, usecase.RecordFailure(email, "192.0.2.1"))
+	}
+	// An attempt counts until it is settled.
+	assert.Nil(t, usecase.CheckLogin("b@example.com", "192.0.2.1"))
+	assert.ErrorIs(t, usecase.CheckLogin("c@example.com", "192.0.2.1"), apperror.ErrTooManyRequests)
+	assert.Nil(t, usecase.CheckLogin("c@example.com", "198.51.100.1"))
+
+	// A success gives its attempt back, but the failures of the IP address
+	// stay.
+	assert.Nil(t, usecase.RecordSuccess("b@example.com", "192.0.2.1"))
+	assert.Nil(t, usecase.CheckLogin("a@example.com", "192.0.2.1"))
+	assert.Nil(t, usecase.RecordFailure("a@example.com", "192.0.2.1"))
+	assert.ErrorIs(t, usecase.CheckLogin("", "192.0.2.1"), apperror.ErrTooManyRequests)
+
+	seedLoginFailures(t, attemptRepository, "ip:192.0.2.1", 13)
+	retryAfter, _ := apperror.RetryAfter(usecase.CheckLogin("", "192.0.2.1"))
+	assert.InDelta(t, time.Minute.Seconds(), retryAfter.Seconds(), 1)
+}
+
+func TestLoginAttempts_Concurrent(t *testing.T) {
+	lockoutRepository := newMockLoginLockoutRepository()
+	usecase := NewLoginAttemptUsecase(repository.NewMemoryLoginAttemptRepository(), lockoutRepository, LoginAttemptOptions{EmailThreshold: 3})
+
+	// Attempts that are still running count, so only as many pass the check
+	// as the threshold allows.
+	results := make(chan error, 10)
+	for i := range 10 {
+		go func() {
+			results <- usecase.CheckLogin("user@example.com", fmt.Sprintf("192.0.2.%d", i))
+		}()
+	}
+	passed := 0
+	for range 10 {
+		if err := <-results; err == nil {
+			passed++
+		} else {
+			assert.ErrorIs(t, err, apperror.ErrTooManyRequests)
+		}
+	}
+	assert.Equal(t, 3, passed)
+}
+
+func TestLoginAttempts_Window(t *testing.T) {
+	attemptRepository := repository.NewMemoryLoginAttemptRepository()
+	usecase := NewLoginAttemptUsecase(attemptRepository, newMockLoginLockoutRepository(), LoginAttemptOptions{EmailThreshold: 2})
+	assert.Nil(t, attemptRepository.UpdateLoginAttempts([]string{"email:user@example.com"}, time.Now().Add(-3*time.Hour), func(attempts []model.LoginAttempt) error {
+		attempts[0].Failures = 1
+		attempts[0].LastFailureAt = time.Now().Add(-2 * time.Hour)
+		return nil
+	}))
+
+	// The old failure is forgotten, so this one does not lock.
+	assert.Nil(t, usecase.CheckLogin("user@example.com", "192.0.2.1"))
+	assert.Nil(t, usecase.RecordFailure("user@example.com", "192.0.2.1"))
+	assert.Nil(t, usecase.CheckLogin("user@example.com", "192.0.2.1"))
+}
+
+func TestLoginAttempts_MFA(t *testing.T) {
+	lockoutRepository := newMockLoginLockoutRepository()
+	lockoutRepository.(*mockLoginLockoutRepository).On("CreateLoginLockout", mock.AnythingOfType("*model.LoginLockout")).Return(nil)
+	usecase := NewLoginAttemptUsecase(repository.NewMemoryLoginAttemptRepository(), lockoutRepository, LoginAttemptOptions{EmailThreshold: 4, MFAChallengeAttempts: 2})
+	first := model.MFAChallenge{ID: "first", UserId: 1}
+
+	// The challenge is void after two wrong codes, whichever IP sent them.
+	for _, ip := range []string{"192.0.2.1", "198.51.100.1"} {
+		assert.Nil(t, usecase.CheckMFA(first, ip))
+		assert.Nil(t, usecase.RecordMFAFailure(first, ip))
+	}
+	assert.ErrorIs(t, usecase.CheckMFA(first, "203.0.113.1"), apperror.ErrUnauthorized)
+
+	// A new challenge counts towards the same account.
+	second := model.MFAChallenge{ID: "second", UserId: 1}
+	for _, ip := range []string{"203.0.113.1", "203.0.113.2"} {
+		assert.Nil(t, usecase.CheckMFA(second, ip))
+		assert.Nil(t, usecase.RecordMFAFailure(second, ip))
+	}
+	third := model.MFAChallenge{ID: "third", UserId: 1}
+	assert.ErrorIs(t, usecase.CheckMFA(third, "203.0.113.3"), apperror.ErrTooManyRequests)
+	assert.Nil(t, usecase.CheckMFA(model.MFAChallenge{ID: "other", UserId: 2}, "203.0.113.3"))
+	lockoutRepository.(*mockLoginLockoutRepository).AssertCalled(t, "CreateLoginLockout", mock.MatchedBy(func(lockout *model.LoginLockout) bool {
+		return lockout.Key == "mfa:1" && lockout.Failures == 4
+	}))
+
+	assert.Nil(t, usecase.RecordMFASuccess(third, "203.0.113.3"))
+	assert.Nil(t, usecase.CheckMFA(third, "203.0.113.3"))
+}
@@ -0,0 +1,28 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	cases := []struct {
+		a, b string
+		diff string
+	}{
+		{"same\n", "same\n", ""},
+		{"", "new line", "--- a\n+++ b\n@@ -0,0 +1 @@\n+new line\n"},
+		{"old line", "", "--- a\n+++ b\n@@ -1 +0,0 @@\n-old line\n"},
+		{"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15",
+			"1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16",
+			"--- a\n+++ b\n" +
+				"@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n" +
+				"@@ -13,3 +13,4 @@\n 13\n 14\n 15\n+16\n"},
+		{"a\nb\nc", "a\nx\nc\nd",
+			"--- a\n+++ b\n@@ -1,3 +1,4 @@\n a\n-b\n+x\n c\n+d\n"},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.diff, unifiedDiff("a", "b", tc.a, tc.b))
+	}
+}
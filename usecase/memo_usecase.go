@@ -1,17 +1,39 @@
 package usecase
 
 import (
+	"context"
+	"echo-rest-api/apperror"
 	"echo-rest-api/model"
 	"echo-rest-api/repository"
 	"echo-rest-api/validator"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
 )
 
+const defaultMemoPageLimit = 20
+
 type IMemoUsecase interface {
-	GetAllMemos(userId uint) ([]model.MemoResponse, error)
-	GetMemoById(userId uint, memoId uint) (model.MemoResponse, error)
-	CreateMemo(memo model.Memo) (model.MemoResponse, error)
-	UpdateMemo(memo model.Memo, userId uint, memoId uint) (model.MemoResponse, error)
-	DeleteMemo(userId uint, memoId uint) error
+	GetAllMemos(ctx context.Context, userId uint, query model.MemoQuery) (model.MemoPageResponse, error)
+	GetMemoById(ctx context.Context, userId uint, memoId uint) (model.MemoResponse, error)
+	SearchMemos(ctx context.Context, userId uint, query model.MemoSearchQuery) ([]model.MemoSearchResponse, error)
+	CreateMemo(ctx context.Context, memo model.Memo) (model.MemoResponse, error)
+	UpdateMemo(ctx context.Context, memo model.Memo, userId uint, memoId uint) (model.MemoResponse, error)
+	PatchMemo(ctx context.Context, patch model.MemoPatch, userId uint, memoId uint) (model.MemoResponse, error)
+	MoveMemo(ctx context.Context, userId uint, memoId uint, notebookId *uint) (model.MemoResponse, error)
+	DeleteMemo(ctx context.Context, userId uint, memoId uint) error
+	GetTrashedMemos(ctx context.Context, userId uint) ([]model.MemoResponse, error)
+	RestoreMemo(ctx context.Context, userId uint, memoId uint) (model.MemoResponse, error)
+	PurgeMemo(ctx context.Context, userId uint, memoId uint) error
+	EmptyTrash(ctx context.Context, userId uint) error
+	GetMemoRevisions(ctx context.Context, userId uint, memoId uint) ([]model.MemoRevisionResponse, error)
+	GetMemoRevision(ctx context.Context, userId uint, memoId uint, rev uint) (model.MemoRevisionDiffResponse, error)
+	RestoreMemoRevision(ctx context.Context, userId uint, memoId uint, rev uint) (model.MemoResponse, error)
 }
 
 type memoUsecase struct {
@@ -23,78 +45,333 @@ func NewMemoUsecase(mr repository.IMemoRepository, mv validator.IMemoValidator)
 	return &memoUsecase{mr, mv}
 }
 
-func (mu *memoUsecase) GetAllMemos(userId uint) ([]model.MemoResponse, error) {
+func (mu *memoUsecase) GetAllMemos(ctx context.Context, userId uint, query model.MemoQuery) (model.MemoPageResponse, error) {
+	if err := mu.mv.MemoQueryValidate(query); err != nil {
+		return model.MemoPageResponse{}, apperror.Wrap(apperror.ErrValidation, err)
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultMemoPageLimit
+	}
+	if query.SortBy == "" {
+		query.SortBy = model.MemoSortCreatedAt
+	}
+	if query.Order == "" {
+		query.Order = model.SortDesc
+	}
+	if query.Cursor != "" {
+		cursor, err := decodeMemoCursor(query.Cursor)
+		if err != nil || cursor.SortBy != query.SortBy || cursor.Order != query.Order {
+			return model.MemoPageResponse{}, apperror.Wrap(apperror.ErrValidation, validation.Errors{
+				"cursor": validation.NewError("invalid_cursor", "invalid cursor for this sort order"),
+			})
+		}
+		query.After = &cursor
+	}
+
+	// fetch one extra row to find out whether another page follows
+	limit := query.Limit
+	query.Limit++
 	memos := []model.Memo{}
-	if err := mu.mr.GetAllMemos(&memos, userId); err != nil {
-		return nil, err
+	if err := mu.mr.GetAllMemos(ctx, &memos, userId, query); err != nil {
+		return model.MemoPageResponse{}, err
+	}
+	page := model.MemoPageResponse{Memos: []model.MemoResponse{}}
+	if len(memos) > limit {
+		memos = memos[:limit]
+		last := memos[limit-1]
+		page.NextCursor = encodeMemoCursor(model.MemoCursor{
+			SortBy: query.SortBy,
+			Order:  query.Order,
+			Time:   memoSortTime(last, query.SortBy),
+			Title:  memoSortTitle(last, query.SortBy),
+			ID:     last.ID,
+		})
 	}
-	resMemos := []model.MemoResponse{}
 	for _, memo := range memos {
-		t := model.MemoResponse{
-			ID:        memo.ID,
-			Title:     memo.Title,
-			Content:   memo.Content,
-			CreatedAt: memo.CreatedAt,
-			UpdatedAt: memo.UpdatedAt,
-		}
-		resMemos = append(resMemos, t)
+		page.Memos = append(page.Memos, newMemoResponse(memo))
 	}
-	return resMemos, nil
+	return page, nil
 }
 
-func (mu *memoUsecase) GetMemoById(userId uint, memoId uint) (model.MemoResponse, error) {
+func (mu *memoUsecase) GetMemoById(ctx context.Context, userId uint, memoId uint) (model.MemoResponse, error) {
 	memo := model.Memo{}
-	if err := mu.mr.GetMemoById(&memo, userId, memoId); err != nil {
+	if err := mu.mr.GetMemoById(ctx, &memo, userId, memoId); err != nil {
 		return model.MemoResponse{}, err
 	}
-	resMemo := model.MemoResponse{
-		ID:        memo.ID,
-		Title:     memo.Title,
-		Content:   memo.Content,
-		CreatedAt: memo.CreatedAt,
-		UpdatedAt: memo.UpdatedAt,
+	return newMemoResponse(memo), nil
+}
+
+func (mu *memoUsecase) SearchMemos(ctx context.Context, userId uint, query model.MemoSearchQuery) ([]model.MemoSearchResponse, error) {
+	if err := mu.mv.MemoSearchValidate(query); err != nil {
+		return nil, apperror.Wrap(apperror.ErrValidation, err)
 	}
-	return resMemo, nil
+	if query.Limit == 0 {
+		query.Limit = defaultMemoPageLimit
+	}
+	results := []model.MemoSearchResult{}
+	if err := mu.mr.SearchMemos(ctx, &results, userId, query); err != nil {
+		return nil, err
+	}
+	resMemos := []model.MemoSearchResponse{}
+	for _, r := range results {
+		resMemos = append(resMemos, model.MemoSearchResponse{
+			ID:             r.ID,
+			Title:          r.Title,
+			Content:        r.Content,
+			CreatedAt:      r.CreatedAt,
+			UpdatedAt:      r.UpdatedAt,
+			Rank:           r.Rank,
+			TitleHighlight: r.TitleHighlight,
+			Snippet:        r.Snippet,
+		})
+	}
+	return resMemos, nil
 }
 
-func (mu *memoUsecase) CreateMemo(memo model.Memo) (model.MemoResponse, error) {
+func (mu *memoUsecase) CreateMemo(ctx context.Context, memo model.Memo) (model.MemoResponse, error) {
+	memo.TagNames = trimTagNames(memo.TagNames)
 	if err := mu.mv.MemoValidate(memo); err != nil {
-		return model.MemoResponse{}, err
+		return model.MemoResponse{}, apperror.Wrap(apperror.ErrValidation, err)
 	}
-	if err := mu.mr.CreateMemo(&memo); err != nil {
+	if err := mu.mr.CreateMemo(ctx, &memo); err != nil {
 		return model.MemoResponse{}, err
 	}
 
-	resMemo := model.MemoResponse{
-		ID:        memo.ID,
-		Title:     memo.Title,
-		Content:   memo.Content,
-		CreatedAt: memo.CreatedAt,
-		UpdatedAt: memo.UpdatedAt,
+	return newMemoResponse(memo), nil
+}
+
+func (mu *memoUsecase) UpdateMemo(ctx context.Context, memo model.Memo, userId uint, memoId uint) (model.MemoResponse, error) {
+	memo.TagNames = trimTagNames(memo.TagNames)
+	if err := mu.mv.MemoValidate(memo); err != nil {
+		return model.MemoResponse{}, apperror.Wrap(apperror.ErrValidation, err)
+	}
+	if err := mu.mr.UpdateMemo(ctx, &memo, userId, memoId); err != nil {
+		if errors.Is(err, apperror.ErrPreconditionFailed) {
+			return model.MemoResponse{}, mu.staleMemo(ctx, err, userId, memoId)
+		}
+		return model.MemoResponse{}, err
 	}
-	return resMemo, nil
+	return newMemoResponse(memo), nil
 }
 
-func (mu *memoUsecase) UpdateMemo(memo model.Memo, userId uint, memoId uint) (model.MemoResponse, error) {
+// PatchMemo applies a merge patch to the stored memo and validates the result
+// as a whole, so fields left out of the patch keep their current values.
+func (mu *memoUsecase) PatchMemo(ctx context.Context, patch model.MemoPatch, userId uint, memoId uint) (model.MemoResponse, error) {
+	current := model.Memo{}
+	if err := mu.mr.GetMemoById(ctx, &current, userId, memoId); err != nil {
+		return model.MemoResponse{}, err
+	}
+	if patch.Version > 0 && patch.Version != current.Version {
+		return model.MemoResponse{}, apperror.Stale("memo has been modified", newMemoResponse(current))
+	}
+
+	// the write is conditional on the version read above, so a concurrent
+	// update is never overwritten by a patch applied to an older copy
+	memo := model.Memo{
+		Title:      current.Title,
+		Content:    current.Content,
+		NotebookId: current.NotebookId,
+		Version:    current.Version,
+	}
+	if patch.Title.Set {
+		memo.Title = patch.Title.Value
+	}
+	if patch.Content.Set {
+		memo.Content = patch.Content.Value
+	}
+	if patch.Tags.Set {
+		memo.TagNames = trimTagNames(patch.Tags.Value)
+		if memo.TagNames == nil {
+			memo.TagNames = []string{}
+		}
+	}
+	if patch.NotebookId.Set {
+		memo.NotebookId = nil
+		if !patch.NotebookId.Null {
+			memo.NotebookId = &patch.NotebookId.Value
+		}
+	}
 	if err := mu.mv.MemoValidate(memo); err != nil {
+		return model.MemoResponse{}, apperror.Wrap(apperror.ErrValidation, err)
+	}
+	if err := mu.mr.PatchMemo(ctx, &memo, userId, memoId); err != nil {
+		if errors.Is(err, apperror.ErrPreconditionFailed) {
+			return model.MemoResponse{}, mu.staleMemo(ctx, err, userId, memoId)
+		}
 		return model.MemoResponse{}, err
 	}
-	if err := mu.mr.UpdateMemo(&memo, userId, memoId); err != nil {
+	return newMemoResponse(memo), nil
+}
+
+// staleMemo attaches the current server copy to a failed conditional update.
+func (mu *memoUsecase) staleMemo(ctx context.Context, err error, userId uint, memoId uint) error {
+	current := model.Memo{}
+	if getErr := mu.mr.GetMemoById(ctx, &current, userId, memoId); getErr != nil {
+		return err
+	}
+	return apperror.Stale(err.Error(), newMemoResponse(current))
+}
+
+func (mu *memoUsecase) MoveMemo(ctx context.Context, userId uint, memoId uint, notebookId *uint) (model.MemoResponse, error) {
+	memo := model.Memo{}
+	if err := mu.mr.MoveMemo(ctx, &memo, userId, memoId, notebookId); err != nil {
+		return model.MemoResponse{}, err
+	}
+	return newMemoResponse(memo), nil
+}
+
+func (mu *memoUsecase) DeleteMemo(ctx context.Context, userId uint, memoId uint) error {
+	if err := mu.mr.DeleteMemo(ctx, userId, memoId); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (mu *memoUsecase) GetTrashedMemos(ctx context.Context, userId uint) ([]model.MemoResponse, error) {
+	memos := []model.Memo{}
+	if err := mu.mr.GetTrashedMemos(ctx, &memos, userId); err != nil {
+		return nil, err
+	}
+	resMemos := []model.MemoResponse{}
+	for _, memo := range memos {
+		resMemos = append(resMemos, newMemoResponse(memo))
+	}
+	return resMemos, nil
+}
+
+func (mu *memoUsecase) RestoreMemo(ctx context.Context, userId uint, memoId uint) (model.MemoResponse, error) {
+	memo := model.Memo{}
+	if err := mu.mr.RestoreMemo(ctx, &memo, userId, memoId); err != nil {
 		return model.MemoResponse{}, err
 	}
-	resMemo := model.MemoResponse{
-		ID:        memo.ID,
-		Title:     memo.Title,
-		Content:   memo.Content,
-		CreatedAt: memo.CreatedAt,
-		UpdatedAt: memo.UpdatedAt,
+	return newMemoResponse(memo), nil
+}
+
+func (mu *memoUsecase) PurgeMemo(ctx context.Context, userId uint, memoId uint) error {
+	if err := mu.mr.PurgeMemo(ctx, userId, memoId); err != nil {
+		return err
 	}
-	return resMemo, nil
+	return nil
 }
 
-func (mu *memoUsecase) DeleteMemo(userId uint, memoId uint) error {
-	if err := mu.mr.DeleteMemo(userId, memoId); err != nil {
+func (mu *memoUsecase) EmptyTrash(ctx context.Context, userId uint) error {
+	if err := mu.mr.EmptyTrash(ctx, userId); err != nil {
 		return err
 	}
 	return nil
 }
+
+func (mu *memoUsecase) GetMemoRevisions(ctx context.Context, userId uint, memoId uint) ([]model.MemoRevisionResponse, error) {
+	revisions := []model.MemoRevision{}
+	if err := mu.mr.GetMemoRevisions(ctx, &revisions, userId, memoId); err != nil {
+		return nil, err
+	}
+	resRevisions := []model.MemoRevisionResponse{}
+	for _, revision := range revisions {
+		resRevisions = append(resRevisions, newMemoRevisionResponse(revision))
+	}
+	return resRevisions, nil
+}
+
+func (mu *memoUsecase) GetMemoRevision(ctx context.Context, userId uint, memoId uint, rev uint) (model.MemoRevisionDiffResponse, error) {
+	revision := model.MemoRevision{}
+	if err := mu.mr.GetMemoRevision(ctx, &revision, userId, memoId, rev); err != nil {
+		return model.MemoRevisionDiffResponse{}, err
+	}
+	memo := model.Memo{}
+	if err := mu.mr.GetMemoById(ctx, &memo, userId, memoId); err != nil {
+		return model.MemoRevisionDiffResponse{}, err
+	}
+	return model.MemoRevisionDiffResponse{
+		MemoRevisionResponse: newMemoRevisionResponse(revision),
+		Diff:                 unifiedDiff(fmt.Sprintf("revision %d", revision.Revision), "current", revision.Content, memo.Content),
+	}, nil
+}
+
+// RestoreMemoRevision brings back the title and content of an old revision.
+// The restore is an ordinary update, so it is recorded as a new revision and
+// the history itself is never rewritten.
+func (mu *memoUsecase) RestoreMemoRevision(ctx context.Context, userId uint, memoId uint, rev uint) (model.MemoResponse, error) {
+	revision := model.MemoRevision{}
+	if err := mu.mr.GetMemoRevision(ctx, &revision, userId, memoId, rev); err != nil {
+		return model.MemoResponse{}, err
+	}
+	memo := model.Memo{Title: revision.Title, Content: revision.Content}
+	if err := mu.mr.UpdateMemo(ctx, &memo, userId, memoId); err != nil {
+		return model.MemoResponse{}, err
+	}
+	return newMemoResponse(memo), nil
+}
+
+func newMemoRevisionResponse(revision model.MemoRevision) model.MemoRevisionResponse {
+	return model.MemoRevisionResponse{
+		Revision:  revision.Revision,
+		Title:     revision.Title,
+		Content:   revision.Content,
+		CreatedAt: revision.CreatedAt,
+	}
+}
+
+func newMemoResponse(memo model.Memo) model.MemoResponse {
+	tags := []string{}
+	for _, tag := range memo.Tags {
+		tags = append(tags, tag.Name)
+	}
+	res := model.MemoResponse{
+		ID:         memo.ID,
+		Title:      memo.Title,
+		Content:    memo.Content,
+		Tags:       tags,
+		NotebookId: memo.NotebookId,
+		Version:    memo.Version,
+		CreatedAt:  memo.CreatedAt,
+		UpdatedAt:  memo.UpdatedAt,
+	}
+	if memo.DeletedAt.Valid {
+		res.DeletedAt = &memo.DeletedAt.Time
+	}
+	return res
+}
+
+func trimTagNames(names []string) []string {
+	if names == nil {
+		return nil
+	}
+	trimmed := make([]string, 0, len(names))
+	for _, name := range names {
+		trimmed = append(trimmed, strings.TrimSpace(name))
+	}
+	return trimmed
+}
+
+func memoSortTime(memo model.Memo, sortBy string) time.Time {
+	switch sortBy {
+	case model.MemoSortCreatedAt:
+		return memo.CreatedAt
+	case model.MemoSortUpdatedAt:
+		return memo.UpdatedAt
+	}
+	return time.Time{}
+}
+
+func memoSortTitle(memo model.Memo, sortBy string) string {
+	if sortBy == model.MemoSortTitle {
+		return memo.Title
+	}
+	return ""
+}
+
+func encodeMemoCursor(cursor model.MemoCursor) string {
+	b, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeMemoCursor(s string) (model.MemoCursor, error) {
+	cursor := model.MemoCursor{}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor, err
+	}
+	err = json.Unmarshal(b, &cursor)
+	return cursor, err
+}
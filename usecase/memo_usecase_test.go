@@ -1,10 +1,14 @@
 package usecase
 
 import (
+	"context"
+	"echo-rest-api/apperror"
 	"echo-rest-api/model"
 	"echo-rest-api/validator"
+	"encoding/json"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -18,24 +22,85 @@ func TestGetAllMemos(t *testing.T) {
 		{Title: "mock memo2 title", Content: "mock memo2 content", UserId: userId},
 	}
 	mockRepository := newMockMemoRepository()
-	mockRepository.(*mockMemoRepository).On("GetAllMemos", mock.Anything, userId).Return(&expectedMemos, nil)
+	mockRepository.(*mockMemoRepository).On("GetAllMemos", mock.Anything, userId, mock.Anything).Return(&expectedMemos, nil)
 
-	usecase := NewMemoUsecase(mockRepository, nil)
-	memos, err := usecase.GetAllMemos(userId)
+	usecase := NewMemoUsecase(mockRepository, validator.NewMemoValidator())
+	page, err := usecase.GetAllMemos(context.Background(), userId, model.MemoQuery{})
+	assert.Nil(t, err)
+	assert.Equal(t, len(expectedMemos), len(page.Memos))
+	assert.Empty(t, page.NextCursor)
+	mockRepository.(*mockMemoRepository).AssertExpectations(t)
+}
+
+func TestGetAllMemos_NextCursor(t *testing.T) {
+	const userId = uint(1)
+	expectedMemos := []model.Memo{
+		{Model: gorm.Model{ID: 3}, Title: "mock memo3 title", UserId: userId},
+		{Model: gorm.Model{ID: 2}, Title: "mock memo2 title", UserId: userId},
+		{Model: gorm.Model{ID: 1}, Title: "mock memo1 title", UserId: userId},
+	}
+	mockRepository := newMockMemoRepository()
+	mockRepository.(*mockMemoRepository).
+		On("GetAllMemos", mock.Anything, userId, mock.MatchedBy(func(q model.MemoQuery) bool {
+			return q.Limit == 3 && q.SortBy == model.MemoSortTitle && q.Order == model.SortDesc && q.After == nil
+		})).
+		Return(&expectedMemos, nil)
+
+	usecase := NewMemoUsecase(mockRepository, validator.NewMemoValidator())
+	page, err := usecase.GetAllMemos(context.Background(), userId, model.MemoQuery{Limit: 2, SortBy: model.MemoSortTitle})
 	assert.Nil(t, err)
-	assert.Equal(t, len(expectedMemos), len(memos))
+	assert.Equal(t, 2, len(page.Memos))
+	assert.NotEmpty(t, page.NextCursor)
+
+	cursor, err := decodeMemoCursor(page.NextCursor)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(2), cursor.ID)
+	assert.Equal(t, "mock memo2 title", cursor.Title)
 	mockRepository.(*mockMemoRepository).AssertExpectations(t)
 }
 
+func TestGetAllMemos_Cursor(t *testing.T) {
+	const userId = uint(1)
+	cursor := model.MemoCursor{SortBy: model.MemoSortCreatedAt, Order: model.SortDesc, ID: 5}
+	mockRepository := newMockMemoRepository()
+	mockRepository.(*mockMemoRepository).
+		On("GetAllMemos", mock.Anything, userId, mock.MatchedBy(func(q model.MemoQuery) bool {
+			return q.After != nil && q.After.ID == cursor.ID
+		})).
+		Return(&[]model.Memo{}, nil)
+
+	usecase := NewMemoUsecase(mockRepository, validator.NewMemoValidator())
+	page, err := usecase.GetAllMemos(context.Background(), userId, model.MemoQuery{Cursor: encodeMemoCursor(cursor)})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(page.Memos))
+	mockRepository.(*mockMemoRepository).AssertExpectations(t)
+
+	_, err = usecase.GetAllMemos(context.Background(), userId, model.MemoQuery{Cursor: encodeMemoCursor(cursor), SortBy: model.MemoSortTitle})
+	assert.ErrorIs(t, err, apperror.ErrValidation)
+	_, err = usecase.GetAllMemos(context.Background(), userId, model.MemoQuery{Cursor: "not a cursor"})
+	assert.ErrorIs(t, err, apperror.ErrValidation)
+}
+
+func TestGetAllMemos_Validate(t *testing.T) {
+	usecase := NewMemoUsecase(nil, validator.NewMemoValidator())
+
+	_, err := usecase.GetAllMemos(context.Background(), 1, model.MemoQuery{Limit: 101})
+	assert.Equal(t, "limit: must be between 1 and 100.", err.Error())
+	_, err = usecase.GetAllMemos(context.Background(), 1, model.MemoQuery{SortBy: "content"})
+	assert.Equal(t, "sort: must be one of created_at, updated_at, title.", err.Error())
+	_, err = usecase.GetAllMemos(context.Background(), 1, model.MemoQuery{Order: "up"})
+	assert.Equal(t, "order: must be asc or desc.", err.Error())
+}
+
 func TestGetAllMemos_Error(t *testing.T) {
 	const userId = uint(1)
 	mockRepository := newMockMemoRepository()
-	mockRepository.(*mockMemoRepository).On("GetAllMemos", mock.Anything, userId).Return(nil, errors.New("error"))
+	mockRepository.(*mockMemoRepository).On("GetAllMemos", mock.Anything, userId, mock.Anything).Return(nil, errors.New("error"))
 
-	usecase := NewMemoUsecase(mockRepository, nil)
-	memos, err := usecase.GetAllMemos(userId)
+	usecase := NewMemoUsecase(mockRepository, validator.NewMemoValidator())
+	page, err := usecase.GetAllMemos(context.Background(), userId, model.MemoQuery{})
 	assert.Error(t, err)
-	assert.Nil(t, memos)
+	assert.Nil(t, page.Memos)
 	mockRepository.(*mockMemoRepository).AssertExpectations(t)
 }
 
@@ -56,7 +121,7 @@ func TestGetMemoById(t *testing.T) {
 	mockRepository.(*mockMemoRepository).On("GetMemoById", mock.Anything, userId).Return(&expectedMemo, nil)
 
 	usecase := NewMemoUsecase(mockRepository, nil)
-	memo, err := usecase.GetMemoById(userId, memoId)
+	memo, err := usecase.GetMemoById(context.Background(), userId, memoId)
 	assert.Nil(t, err)
 	assert.Equal(t, expectedMemo.ID, memo.ID)
 	assert.Equal(t, expectedMemo.Title, memo.Title)
@@ -73,12 +138,38 @@ func TestGetMemoById_Error(t *testing.T) {
 	mockRepository.(*mockMemoRepository).On("GetMemoById", mock.Anything, userId).Return(nil, errors.New("error"))
 
 	usecase := NewMemoUsecase(mockRepository, nil)
-	memo, err := usecase.GetMemoById(userId, memoId)
+	memo, err := usecase.GetMemoById(context.Background(), userId, memoId)
 	assert.Equal(t, model.MemoResponse{}, memo)
 	assert.Error(t, err)
 	mockRepository.(*mockMemoRepository).AssertExpectations(t)
 }
 
+func TestSearchMemos(t *testing.T) {
+	const userId = uint(1)
+	expectedResults := []model.MemoSearchResult{
+		{ID: 1, Title: "grocery list", Content: "buy bread", Rank: 0.5, Snippet: "buy <mark>bread</mark>"},
+	}
+	mockRepository := newMockMemoRepository()
+	mockRepository.(*mockMemoRepository).
+		On("SearchMemos", mock.Anything, userId, model.MemoSearchQuery{Query: "bread", Limit: 20}).
+		Return(&expectedResults, nil)
+
+	usecase := NewMemoUsecase(mockRepository, validator.NewMemoValidator())
+	memos, err := usecase.SearchMemos(context.Background(), userId, model.MemoSearchQuery{Query: "bread"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(memos))
+	assert.Equal(t, "buy <mark>bread</mark>", memos[0].Snippet)
+	mockRepository.(*mockMemoRepository).AssertExpectations(t)
+}
+
+func TestSearchMemos_Validate(t *testing.T) {
+	usecase := NewMemoUsecase(nil, validator.NewMemoValidator())
+	memos, err := usecase.SearchMemos(context.Background(), 1, model.MemoSearchQuery{})
+	assert.Equal(t, "q: q is required.", err.Error())
+	assert.ErrorIs(t, err, apperror.ErrValidation)
+	assert.Nil(t, memos)
+}
+
 func TestCreateMemo(t *testing.T) {
 	mockMemo := model.Memo{
 		Title:   "mock memo1 title",
@@ -89,13 +180,33 @@ func TestCreateMemo(t *testing.T) {
 
 	validator := validator.NewMemoValidator()
 	usecase := NewMemoUsecase(mockRepository, validator)
-	memo, err := usecase.CreateMemo(mockMemo)
+	memo, err := usecase.CreateMemo(context.Background(), mockMemo)
 	assert.Nil(t, err)
 	assert.Equal(t, mockMemo.Title, memo.Title)
 	assert.Equal(t, mockMemo.Content, memo.Content)
 	mockRepository.(*mockMemoRepository).AssertExpectations(t)
 }
 
+func TestCreateMemo_Tags(t *testing.T) {
+	mockRepository := newMockMemoRepository()
+	mockRepository.(*mockMemoRepository).
+		On("CreateMemo", mock.MatchedBy(func(memo *model.Memo) bool {
+			memo.Tags = []model.Tag{{Name: "idea"}, {Name: "work"}}
+			return len(memo.TagNames) == 2 && memo.TagNames[0] == "work" && memo.TagNames[1] == "idea"
+		})).
+		Return(nil, nil)
+
+	validator := validator.NewMemoValidator()
+	usecase := NewMemoUsecase(mockRepository, validator)
+	memo, err := usecase.CreateMemo(context.Background(), model.Memo{Title: "tagged", TagNames: []string{" work", "idea "}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"idea", "work"}, memo.Tags)
+	mockRepository.(*mockMemoRepository).AssertExpectations(t)
+
+	_, err = usecase.CreateMemo(context.Background(), model.Memo{Title: "tagged", TagNames: []string{""}})
+	assert.Equal(t, "tags: (0: tag name is required.).", err.Error())
+}
+
 func TestCreateMemo_Error(t *testing.T) {
 	mockRepository := newMockMemoRepository()
 	mockRepository.(*mockMemoRepository).On("CreateMemo", mock.Anything).Return(nil, errors.New("error"))
@@ -106,7 +217,7 @@ func TestCreateMemo_Error(t *testing.T) {
 
 	validator := validator.NewMemoValidator()
 	usecase := NewMemoUsecase(mockRepository, validator)
-	memo, err := usecase.CreateMemo(mockMemo)
+	memo, err := usecase.CreateMemo(context.Background(), mockMemo)
 	assert.Equal(t, model.MemoResponse{}, memo)
 	assert.Error(t, err)
 	mockRepository.(*mockMemoRepository).AssertExpectations(t)
@@ -118,14 +229,14 @@ func TestCreateMemo_Validate(t *testing.T) {
 	mockMemo1 := model.Memo{
 		Title: "",
 	}
-	memo, err := usecase.CreateMemo(mockMemo1)
+	memo, err := usecase.CreateMemo(context.Background(), mockMemo1)
 	assert.Equal(t, "title: title is required.", err.Error())
 	assert.Equal(t, model.MemoResponse{}, memo)
 
 	mockMemo2 := model.Memo{
 		Title: "Too long title should be validated. Too long title should be validated. Too long title should be validated.",
 	}
-	memo, err = usecase.CreateMemo(mockMemo2)
+	memo, err = usecase.CreateMemo(context.Background(), mockMemo2)
 	assert.Equal(t, "title: limited max 50 length.", err.Error())
 	assert.Equal(t, model.MemoResponse{}, memo)
 }
@@ -148,7 +259,7 @@ func TestUpdateMemo(t *testing.T) {
 
 	validator := validator.NewMemoValidator()
 	usecase := NewMemoUsecase(mockRepository, validator)
-	memo, err := usecase.UpdateMemo(mockMemo, userId, memoId)
+	memo, err := usecase.UpdateMemo(context.Background(), mockMemo, userId, memoId)
 	assert.Nil(t, err)
 	assert.Equal(t, mockMemo.Title, memo.Title)
 	assert.Equal(t, mockMemo.Content, memo.Content)
@@ -173,12 +284,77 @@ func TestUpdateMemo_Error(t *testing.T) {
 
 	validator := validator.NewMemoValidator()
 	usecase := NewMemoUsecase(mockRepository, validator)
-	memo, err := usecase.UpdateMemo(mockMemo, userId, memoId)
+	memo, err := usecase.UpdateMemo(context.Background(), mockMemo, userId, memoId)
 	assert.Equal(t, model.MemoResponse{}, memo)
 	assert.Error(t, err)
 	mockRepository.(*mockMemoRepository).AssertExpectations(t)
 }
 
+func TestUpdateMemo_Stale(t *testing.T) {
+	current := model.Memo{Model: gorm.Model{ID: 1}, Title: "newer title", Version: 3}
+	mockRepository := newMockMemoRepository()
+	mockRepository.(*mockMemoRepository).On("UpdateMemo", mock.Anything, uint(1), uint(1)).
+		Return(nil, apperror.New(apperror.ErrPreconditionFailed, "memo has been modified"))
+	mockRepository.(*mockMemoRepository).On("GetMemoById", uint(1), uint(1)).Return(&current, nil)
+
+	usecase := NewMemoUsecase(mockRepository, validator.NewMemoValidator())
+	_, err := usecase.UpdateMemo(context.Background(), model.Memo{Title: "stale title", Version: 2}, 1, 1)
+	assert.ErrorIs(t, err, apperror.ErrPreconditionFailed)
+	serverCopy, ok := apperror.Current(err)
+	assert.True(t, ok)
+	assert.Equal(t, uint(3), serverCopy.(model.MemoResponse).Version)
+	assert.Equal(t, "newer title", serverCopy.(model.MemoResponse).Title)
+	mockRepository.(*mockMemoRepository).AssertExpectations(t)
+}
+
+func TestPatchMemo(t *testing.T) {
+	notebookId := uint(5)
+	current := model.Memo{
+		Model:      gorm.Model{ID: 1},
+		Title:      "memo1 title",
+		Content:    "memo1 content",
+		NotebookId: &notebookId,
+		Version:    2,
+	}
+	patch := model.MemoPatch{}
+	assert.Nil(t, json.Unmarshal([]byte(`{"content":null,"notebook_id":null}`), &patch))
+	mockRepository := newMockMemoRepository()
+	mockRepository.(*mockMemoRepository).On("GetMemoById", uint(1), uint(1)).Return(&current, nil)
+	mockRepository.(*mockMemoRepository).On("PatchMemo", mock.MatchedBy(func(memo *model.Memo) bool {
+		return memo.Title == "memo1 title" && memo.Content == "" && memo.NotebookId == nil &&
+			memo.TagNames == nil && memo.Version == 2
+	}), uint(1), uint(1)).Return(nil, nil)
+
+	usecase := NewMemoUsecase(mockRepository, validator.NewMemoValidator())
+	_, err := usecase.PatchMemo(context.Background(), patch, 1, 1)
+	assert.Nil(t, err)
+	mockRepository.(*mockMemoRepository).AssertExpectations(t)
+}
+
+func TestPatchMemo_Validate(t *testing.T) {
+	current := model.Memo{Model: gorm.Model{ID: 1}, Title: "memo1 title", Version: 1}
+	patch := model.MemoPatch{}
+	assert.Nil(t, json.Unmarshal([]byte(`{"title":null}`), &patch))
+	mockRepository := newMockMemoRepository()
+	mockRepository.(*mockMemoRepository).On("GetMemoById", uint(1), uint(1)).Return(&current, nil)
+
+	usecase := NewMemoUsecase(mockRepository, validator.NewMemoValidator())
+	_, err := usecase.PatchMemo(context.Background(), patch, 1, 1)
+	assert.Equal(t, "title: title is required.", err.Error())
+	mockRepository.(*mockMemoRepository).AssertNotCalled(t, "PatchMemo")
+}
+
+func TestPatchMemo_Stale(t *testing.T) {
+	current := model.Memo{Model: gorm.Model{ID: 1}, Title: "memo1 title", Version: 3}
+	mockRepository := newMockMemoRepository()
+	mockRepository.(*mockMemoRepository).On("GetMemoById", uint(1), uint(1)).Return(&current, nil)
+
+	usecase := NewMemoUsecase(mockRepository, validator.NewMemoValidator())
+	_, err := usecase.PatchMemo(context.Background(), model.MemoPatch{Version: 2}, 1, 1)
+	assert.ErrorIs(t, err, apperror.ErrPreconditionFailed)
+	mockRepository.(*mockMemoRepository).AssertNotCalled(t, "PatchMemo")
+}
+
 func TestUpdateMemo_Validate(t *testing.T) {
 	validator := validator.NewMemoValidator()
 	usecase := NewMemoUsecase(nil, validator)
@@ -186,14 +362,14 @@ func TestUpdateMemo_Validate(t *testing.T) {
 	mockMemo1 := model.Memo{
 		Title: "",
 	}
-	memo, err := usecase.CreateMemo(mockMemo1)
+	memo, err := usecase.CreateMemo(context.Background(), mockMemo1)
 	assert.Equal(t, "title: title is required.", err.Error())
 	assert.Equal(t, model.MemoResponse{}, memo)
 
 	mockMemo2 := model.Memo{
 		Title: "Too long title should be validated. Too long title should be validated. Too long title should be validated.",
 	}
-	memo, err = usecase.CreateMemo(mockMemo2)
+	memo, err = usecase.CreateMemo(context.Background(), mockMemo2)
 	assert.Equal(t, "title: limited max 50 length.", err.Error())
 	assert.Equal(t, model.MemoResponse{}, memo)
 }
@@ -207,7 +383,7 @@ func TestDeleteMemo(t *testing.T) {
 	mockRepository.(*mockMemoRepository).On("DeleteMemo", userId, memoId).Return(nil)
 	usecase := NewMemoUsecase(mockRepository, nil)
 
-	err := usecase.DeleteMemo(userId, memoId)
+	err := usecase.DeleteMemo(context.Background(), userId, memoId)
 	assert.Nil(t, err)
 	mockRepository.(*mockMemoRepository).AssertExpectations(t)
 }
@@ -217,7 +393,88 @@ func TestDeleteMemo_Error(t *testing.T) {
 	mockRepository.(*mockMemoRepository).On("DeleteMemo", uint(1), uint(1)).Return(errors.New("error"))
 
 	usecase := NewMemoUsecase(mockRepository, nil)
-	err := usecase.DeleteMemo(1, 1)
+	err := usecase.DeleteMemo(context.Background(), 1, 1)
 	assert.Error(t, err)
 	mockRepository.(*mockMemoRepository).AssertExpectations(t)
 }
+
+func TestGetTrashedMemos(t *testing.T) {
+	deletedAt := time.Now()
+	trashed := []model.Memo{
+		{Model: gorm.Model{ID: 1, DeletedAt: gorm.DeletedAt{Time: deletedAt, Valid: true}}, Title: "memo1 title", UserId: 1},
+	}
+	mockRepository := newMockMemoRepository()
+	mockRepository.(*mockMemoRepository).On("GetTrashedMemos", mock.Anything, uint(1)).Return(&trashed, nil)
+
+	usecase := NewMemoUsecase(mockRepository, nil)
+	memos, err := usecase.GetTrashedMemos(context.Background(), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(memos))
+	assert.Equal(t, &deletedAt, memos[0].DeletedAt)
+	mockRepository.(*mockMemoRepository).AssertExpectations(t)
+}
+
+func TestRestoreMemo(t *testing.T) {
+	restored := model.Memo{Model: gorm.Model{ID: 1}, Title: "memo1 title", UserId: 1}
+	mockRepository := newMockMemoRepository()
+	mockRepository.(*mockMemoRepository).On("RestoreMemo", uint(1), uint(1)).Return(&restored, nil)
+
+	usecase := NewMemoUsecase(mockRepository, nil)
+	memo, err := usecase.RestoreMemo(context.Background(), 1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "memo1 title", memo.Title)
+	assert.Nil(t, memo.DeletedAt)
+	mockRepository.(*mockMemoRepository).AssertExpectations(t)
+}
+
+func TestPurgeMemo_Error(t *testing.T) {
+	mockRepository := newMockMemoRepository()
+	mockRepository.(*mockMemoRepository).On("PurgeMemo", uint(1), uint(1)).Return(errors.New("error"))
+
+	usecase := NewMemoUsecase(mockRepository, nil)
+	err := usecase.PurgeMemo(context.Background(), 1, 1)
+	assert.Error(t, err)
+	mockRepository.(*mockMemoRepository).AssertExpectations(t)
+}
+
+func TestGetMemoRevision(t *testing.T) {
+	revision := model.MemoRevision{MemoId: 1, Revision: 1, Title: "memo1 title", Content: "line1\nline2"}
+	current := model.Memo{Model: gorm.Model{ID: 1}, Title: "memo1 title", Content: "line1\nline2 edited"}
+	mockRepository := newMockMemoRepository()
+	mockRepository.(*mockMemoRepository).On("GetMemoRevision", uint(1), uint(1), uint(1)).Return(&revision, nil)
+	mockRepository.(*mockMemoRepository).On("GetMemoById", uint(1), uint(1)).Return(&current, nil)
+
+	usecase := NewMemoUsecase(mockRepository, nil)
+	res, err := usecase.GetMemoRevision(context.Background(), 1, 1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), res.Revision)
+	assert.Equal(t, "--- revision 1\n+++ current\n@@ -1,2 +1,2 @@\n line1\n-line2\n+line2 edited\n", res.Diff)
+	mockRepository.(*mockMemoRepository).AssertExpectations(t)
+}
+
+func TestRestoreMemoRevision(t *testing.T) {
+	revision := model.MemoRevision{MemoId: 1, Revision: 1, Title: "old title", Content: "old content"}
+	restored := model.Memo{Model: gorm.Model{ID: 1}, Title: "old title", Content: "old content"}
+	mockRepository := newMockMemoRepository()
+	mockRepository.(*mockMemoRepository).On("GetMemoRevision", uint(1), uint(1), uint(1)).Return(&revision, nil)
+	mockRepository.(*mockMemoRepository).On("UpdateMemo", mock.MatchedBy(func(memo *model.Memo) bool {
+		return memo.Title == "old title" && memo.Content == "old content" && memo.TagNames == nil
+	}), uint(1), uint(1)).Return(&restored, nil)
+
+	usecase := NewMemoUsecase(mockRepository, nil)
+	memo, err := usecase.RestoreMemoRevision(context.Background(), 1, 1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "old title", memo.Title)
+	mockRepository.(*mockMemoRepository).AssertExpectations(t)
+}
+
+func TestRestoreMemoRevision_NotFound(t *testing.T) {
+	mockRepository := newMockMemoRepository()
+	mockRepository.(*mockMemoRepository).On("GetMemoRevision", uint(1), uint(1), uint(9)).
+		Return(nil, apperror.New(apperror.ErrNotFound, "revision does not exist"))
+
+	usecase := NewMemoUsecase(mockRepository, nil)
+	_, err := usecase.RestoreMemoRevision(context.Background(), 1, 1, 9)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	mockRepository.(*mockMemoRepository).AssertNotCalled(t, "UpdateMemo")
+}
@@ -0,0 +1,96 @@
+package usecase
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"echo-rest-api/repository"
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const DefaultTOTPIssuer = "echo-memo-api"
+
+type IMFAUsecase interface {
+	EnrollTOTP(ctx context.Context, userId uint) (model.TOTPEnrollmentResponse, error)
+	ConfirmTOTP(ctx context.Context, userId uint, code string) (model.RecoveryCodesResponse, error)
+	DisableTOTP(ctx context.Context, userId uint, password string) error
+}
+
+type mfaUsecase struct {
+	ur     repository.IUserRepository
+	mr     repository.IMFARepository
+	issuer string
+}
+
+// NewMFAUsecase labels enrolled authenticators with issuer, or
+// DefaultTOTPIssuer when it is empty.
+func NewMFAUsecase(ur repository.IUserRepository, mr repository.IMFARepository, issuer string) IMFAUsecase {
+	if issuer == "" {
+		issuer = DefaultTOTPIssuer
+	}
+	return &mfaUsecase{ur, mr, issuer}
+}
+
+// EnrollTOTP starts enrollment with a new secret. It only takes effect once
+// a code generated from it is confirmed.
+func (mu *mfaUsecase) EnrollTOTP(ctx context.Context, userId uint) (model.TOTPEnrollmentResponse, error) {
+	user := model.User{}
+	if err := mu.ur.GetUserById(ctx, &user, userId); err != nil {
+		return model.TOTPEnrollmentResponse{}, err
+	}
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return model.TOTPEnrollmentResponse{}, err
+	}
+	credential := model.TOTPCredential{UserId: userId, Secret: secret}
+	if err := mu.mr.SaveTOTPCredential(ctx, &credential); err != nil {
+		return model.TOTPEnrollmentResponse{}, err
+	}
+	return model.TOTPEnrollmentResponse{
+		Secret: secret,
+		URI:    otpauthURI(mu.issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables two-factor authentication and returns the recovery
+// codes, which are not shown again.
+func (mu *mfaUsecase) ConfirmTOTP(ctx context.Context, userId uint, code string) (model.RecoveryCodesResponse, error) {
+	credential := model.TOTPCredential{}
+	if err := mu.mr.GetTOTPCredential(ctx, &credential, userId); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return model.RecoveryCodesResponse{}, apperror.New(apperror.ErrNotFound, "two-factor enrollment not started")
+		}
+		return model.RecoveryCodesResponse{}, err
+	}
+	if credential.ConfirmedAt != nil {
+		return model.RecoveryCodesResponse{}, apperror.New(apperror.ErrConflict, "two-factor authentication already enabled")
+	}
+	step, ok := matchTOTP(credential.Secret, code, time.Now())
+	if !ok {
+		return model.RecoveryCodesResponse{}, apperror.New(apperror.ErrValidation, "invalid code")
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return model.RecoveryCodesResponse{}, err
+	}
+	if err := mu.mr.ConfirmTOTPCredential(ctx, userId, step, hashes); err != nil {
+		return model.RecoveryCodesResponse{}, err
+	}
+	return model.RecoveryCodesResponse{Codes: codes}, nil
+}
+
+// DisableTOTP removes the credential and recovery codes after checking the
+// password again.
+func (mu *mfaUsecase) DisableTOTP(ctx context.Context, userId uint, password string) error {
+	user := model.User{}
+	if err := mu.ur.GetUserById(ctx, &user, userId); err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return apperror.New(apperror.ErrUnauthorized, "invalid password")
+	}
+	return mu.mr.DeleteTOTPCredential(ctx, userId)
+}
@@ -0,0 +1,91 @@
+package usecase
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// currentTOTP returns the code an authenticator app shows for secret now.
+func currentTOTP(t *testing.T, secret string) (string, int64) {
+	key, err := totpEncoding.DecodeString(secret)
+	assert.Nil(t, err)
+	step := time.Now().Unix() / totpPeriod
+	return totpCode(key, step), step
+}
+
+func TestEnrollTOTP(t *testing.T) {
+	userRepository := newMockUserRepository()
+	userRepository.(*mockUserRepository).On("GetUserById", uint(1)).Return(&model.User{Email: "user@example.com"}, nil)
+	mfaRepository := newMockMFARepository()
+	mfaRepository.(*mockMFARepository).On("SaveTOTPCredential", mock.MatchedBy(func(credential *model.TOTPCredential) bool {
+		return credential.UserId == 1 && credential.ConfirmedAt == nil
+	})).Return(nil)
+
+	usecase := NewMFAUsecase(userRepository, mfaRepository, "")
+	res, err := usecase.EnrollTOTP(context.Background(), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 32, len(res.Secret))
+	assert.True(t, strings.HasPrefix(res.URI, "otpauth://totp/echo-memo-api:user@example.com?"))
+	assert.Contains(t, res.URI, "secret="+res.Secret)
+	mfaRepository.(*mockMFARepository).AssertExpectations(t)
+}
+
+func TestConfirmTOTP(t *testing.T) {
+	secret, err := newTOTPSecret()
+	assert.Nil(t, err)
+	code, step := currentTOTP(t, secret)
+	mfaRepository := newMockMFARepository()
+	mfaRepository.(*mockMFARepository).On("GetTOTPCredential", uint(1)).Return(&model.TOTPCredential{UserId: 1, Secret: secret}, nil)
+	mfaRepository.(*mockMFARepository).On("ConfirmTOTPCredential", uint(1), step, mock.MatchedBy(func(hashes []string) bool {
+		return len(hashes) == recoveryCodeCount
+	})).Return(nil)
+
+	usecase := NewMFAUsecase(newMockUserRepository(), mfaRepository, "")
+	_, err = usecase.ConfirmTOTP(context.Background(), 1, "000000")
+	if code != "000000" {
+		assert.ErrorIs(t, err, apperror.ErrValidation)
+	}
+	res, err := usecase.ConfirmTOTP(context.Background(), 1, code)
+	assert.Nil(t, err)
+	assert.Equal(t, recoveryCodeCount, len(res.Codes))
+	mfaRepository.(*mockMFARepository).AssertExpectations(t)
+}
+
+func TestConfirmTOTP_Enabled(t *testing.T) {
+	confirmedAt := time.Now()
+	mfaRepository := newMockMFARepository()
+	mfaRepository.(*mockMFARepository).On("GetTOTPCredential", uint(1)).
+		Return(&model.TOTPCredential{UserId: 1, Secret: "JBSWY3DPEHPK3PXP", ConfirmedAt: &confirmedAt}, nil)
+	mfaRepository.(*mockMFARepository).On("GetTOTPCredential", uint(2)).
+		Return(nil, apperror.New(apperror.ErrNotFound, "record not found"))
+
+	usecase := NewMFAUsecase(newMockUserRepository(), mfaRepository, "")
+	_, err := usecase.ConfirmTOTP(context.Background(), 1, "123456")
+	assert.ErrorIs(t, err, apperror.ErrConflict)
+	_, err = usecase.ConfirmTOTP(context.Background(), 2, "123456")
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	mfaRepository.(*mockMFARepository).AssertNotCalled(t, "ConfirmTOTPCredential")
+}
+
+func TestDisableTOTP(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	assert.Nil(t, err)
+	userRepository := newMockUserRepository()
+	userRepository.(*mockUserRepository).On("GetUserById", uint(1)).Return(&model.User{Password: string(hash)}, nil)
+	mfaRepository := newMockMFARepository()
+	mfaRepository.(*mockMFARepository).On("DeleteTOTPCredential", uint(1)).Return(nil)
+
+	usecase := NewMFAUsecase(userRepository, mfaRepository, "")
+	assert.ErrorIs(t, usecase.DisableTOTP(context.Background(), 1, "wrong password"), apperror.ErrUnauthorized)
+	mfaRepository.(*mockMFARepository).AssertNotCalled(t, "DeleteTOTPCredential")
+	assert.Nil(t, usecase.DisableTOTP(context.Background(), 1, "password"))
+	mfaRepository.(*mockMFARepository).AssertExpectations(t)
+}
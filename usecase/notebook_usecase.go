@@ -0,0 +1,137 @@
+package usecase
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"echo-rest-api/repository"
+	"echo-rest-api/validator"
+	"fmt"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// DefaultNotebookMaxDepth is how deep notebooks may be nested when no other
+// limit is configured. A top level notebook has depth 1.
+const DefaultNotebookMaxDepth = 5
+
+type INotebookUsecase interface {
+	GetAllNotebooks(ctx context.Context, userId uint) ([]model.NotebookResponse, error)
+	GetNotebookById(ctx context.Context, userId uint, notebookId uint) (model.NotebookResponse, error)
+	CreateNotebook(ctx context.Context, notebook model.Notebook) (model.NotebookResponse, error)
+	UpdateNotebook(ctx context.Context, notebook model.Notebook, userId uint, notebookId uint) (model.NotebookResponse, error)
+	DeleteNotebook(ctx context.Context, userId uint, notebookId uint, policy string) error
+}
+
+type notebookUsecase struct {
+	nr       repository.INotebookRepository
+	nv       validator.INotebookValidator
+	maxDepth int
+}
+
+func NewNotebookUsecase(nr repository.INotebookRepository, nv validator.INotebookValidator, maxDepth int) INotebookUsecase {
+	if maxDepth < 1 {
+		maxDepth = DefaultNotebookMaxDepth
+	}
+	return &notebookUsecase{nr, nv, maxDepth}
+}
+
+func (nu *notebookUsecase) GetAllNotebooks(ctx context.Context, userId uint) ([]model.NotebookResponse, error) {
+	notebooks := []model.Notebook{}
+	if err := nu.nr.GetAllNotebooks(ctx, &notebooks, userId); err != nil {
+		return nil, err
+	}
+	resNotebooks := []model.NotebookResponse{}
+	for _, notebook := range notebooks {
+		resNotebooks = append(resNotebooks, newNotebookResponse(notebook))
+	}
+	return resNotebooks, nil
+}
+
+func (nu *notebookUsecase) GetNotebookById(ctx context.Context, userId uint, notebookId uint) (model.NotebookResponse, error) {
+	notebook := model.Notebook{}
+	if err := nu.nr.GetNotebookById(ctx, &notebook, userId, notebookId); err != nil {
+		return model.NotebookResponse{}, err
+	}
+	return newNotebookResponse(notebook), nil
+}
+
+func (nu *notebookUsecase) CreateNotebook(ctx context.Context, notebook model.Notebook) (model.NotebookResponse, error) {
+	if err := nu.nv.NotebookValidate(notebook); err != nil {
+		return model.NotebookResponse{}, apperror.Wrap(apperror.ErrValidation, err)
+	}
+	if err := nu.checkParent(ctx, notebook.UserId, 0, notebook.ParentId, 1); err != nil {
+		return model.NotebookResponse{}, err
+	}
+	if err := nu.nr.CreateNotebook(ctx, &notebook); err != nil {
+		return model.NotebookResponse{}, err
+	}
+	return newNotebookResponse(notebook), nil
+}
+
+func (nu *notebookUsecase) UpdateNotebook(ctx context.Context, notebook model.Notebook, userId uint, notebookId uint) (model.NotebookResponse, error) {
+	if err := nu.nv.NotebookValidate(notebook); err != nil {
+		return model.NotebookResponse{}, apperror.Wrap(apperror.ErrValidation, err)
+	}
+	if notebook.ParentId != nil {
+		height := 0
+		if err := nu.nr.GetSubtreeHeight(ctx, &height, userId, notebookId); err != nil {
+			return model.NotebookResponse{}, err
+		}
+		if err := nu.checkParent(ctx, userId, notebookId, notebook.ParentId, height); err != nil {
+			return model.NotebookResponse{}, err
+		}
+	}
+	if err := nu.nr.UpdateNotebook(ctx, &notebook, userId, notebookId); err != nil {
+		return model.NotebookResponse{}, err
+	}
+	return newNotebookResponse(notebook), nil
+}
+
+func (nu *notebookUsecase) DeleteNotebook(ctx context.Context, userId uint, notebookId uint, policy string) error {
+	err := validation.Validate(policy,
+		validation.In(model.NotebookDeleteTrash, model.NotebookDeleteReparent).Error("must be trash or reparent"),
+	)
+	if err != nil {
+		return apperror.Wrap(apperror.ErrValidation, validation.Errors{"on_delete": err})
+	}
+	if err := nu.nr.DeleteNotebook(ctx, userId, notebookId, policy); err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkParent verifies that a subtree of the given height can be placed
+// below parentId without creating a cycle or exceeding the maximum depth.
+func (nu *notebookUsecase) checkParent(ctx context.Context, userId uint, notebookId uint, parentId *uint, height int) error {
+	if parentId == nil {
+		return nil
+	}
+	path := []model.Notebook{}
+	if err := nu.nr.GetNotebookPath(ctx, &path, userId, *parentId); err != nil {
+		return err
+	}
+	for _, ancestor := range path {
+		if ancestor.ID == notebookId {
+			return apperror.Wrap(apperror.ErrValidation, validation.Errors{
+				"parent_id": validation.NewError("cycle", "cannot move a notebook into itself or its descendants"),
+			})
+		}
+	}
+	if len(path)+height > nu.maxDepth {
+		return apperror.Wrap(apperror.ErrValidation, validation.Errors{
+			"parent_id": validation.NewError("too_deep", fmt.Sprintf("notebooks can be nested at most %d levels deep", nu.maxDepth)),
+		})
+	}
+	return nil
+}
+
+func newNotebookResponse(notebook model.Notebook) model.NotebookResponse {
+	return model.NotebookResponse{
+		ID:        notebook.ID,
+		Name:      notebook.Name,
+		ParentId:  notebook.ParentId,
+		CreatedAt: notebook.CreatedAt,
+		UpdatedAt: notebook.UpdatedAt,
+	}
+}
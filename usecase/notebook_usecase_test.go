@@ -0,0 +1,119 @@
+package usecase
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"echo-rest-api/validator"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func notebookPath(ids ...uint) *[]model.Notebook {
+	path := []model.Notebook{}
+	for _, id := range ids {
+		path = append(path, model.Notebook{Model: gorm.Model{ID: id}})
+	}
+	return &path
+}
+
+func TestGetAllNotebooks(t *testing.T) {
+	const userId = uint(1)
+	expectedNotebooks := []model.Notebook{
+		{Model: gorm.Model{ID: 1}, Name: "work", UserId: userId},
+		{Model: gorm.Model{ID: 2}, Name: "project", UserId: userId},
+	}
+	mockRepository := newMockNotebookRepository()
+	mockRepository.(*mockNotebookRepository).On("GetAllNotebooks", mock.Anything, userId).Return(&expectedNotebooks, nil)
+
+	usecase := NewNotebookUsecase(mockRepository, nil, 0)
+	notebooks, err := usecase.GetAllNotebooks(context.Background(), userId)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(notebooks))
+	mockRepository.(*mockNotebookRepository).AssertExpectations(t)
+}
+
+func TestGetNotebookById_Error(t *testing.T) {
+	mockRepository := newMockNotebookRepository()
+	mockRepository.(*mockNotebookRepository).On("GetNotebookById", uint(1), uint(1)).Return(nil, errors.New("error"))
+
+	usecase := NewNotebookUsecase(mockRepository, nil, 0)
+	notebook, err := usecase.GetNotebookById(context.Background(), 1, 1)
+	assert.Error(t, err)
+	assert.Equal(t, model.NotebookResponse{}, notebook)
+	mockRepository.(*mockNotebookRepository).AssertExpectations(t)
+}
+
+func TestCreateNotebook(t *testing.T) {
+	parentId := uint(2)
+	mockRepository := newMockNotebookRepository()
+	mockRepository.(*mockNotebookRepository).On("GetNotebookPath", uint(1), parentId).Return(notebookPath(1, 2), nil)
+	mockRepository.(*mockNotebookRepository).On("CreateNotebook", mock.Anything).Return(nil)
+
+	usecase := NewNotebookUsecase(mockRepository, validator.NewNotebookValidator(), 3)
+	notebook, err := usecase.CreateNotebook(context.Background(), model.Notebook{Name: "nested", UserId: 1, ParentId: &parentId})
+	assert.Nil(t, err)
+	assert.Equal(t, "nested", notebook.Name)
+	assert.Equal(t, &parentId, notebook.ParentId)
+	mockRepository.(*mockNotebookRepository).AssertExpectations(t)
+}
+
+func TestCreateNotebook_TooDeep(t *testing.T) {
+	parentId := uint(3)
+	mockRepository := newMockNotebookRepository()
+	mockRepository.(*mockNotebookRepository).On("GetNotebookPath", uint(1), parentId).Return(notebookPath(1, 2, 3), nil)
+
+	usecase := NewNotebookUsecase(mockRepository, validator.NewNotebookValidator(), 3)
+	_, err := usecase.CreateNotebook(context.Background(), model.Notebook{Name: "nested", UserId: 1, ParentId: &parentId})
+	assert.Equal(t, "parent_id: notebooks can be nested at most 3 levels deep.", err.Error())
+	assert.ErrorIs(t, err, apperror.ErrValidation)
+	mockRepository.(*mockNotebookRepository).AssertNotCalled(t, "CreateNotebook")
+}
+
+func TestCreateNotebook_Validate(t *testing.T) {
+	usecase := NewNotebookUsecase(nil, validator.NewNotebookValidator(), 0)
+	_, err := usecase.CreateNotebook(context.Background(), model.Notebook{Name: ""})
+	assert.Equal(t, "name: name is required.", err.Error())
+}
+
+func TestUpdateNotebook(t *testing.T) {
+	parentId := uint(1)
+	mockRepository := newMockNotebookRepository()
+	mockRepository.(*mockNotebookRepository).On("GetSubtreeHeight", uint(1), uint(3)).Return(2, nil)
+	mockRepository.(*mockNotebookRepository).On("GetNotebookPath", uint(1), parentId).Return(notebookPath(1), nil)
+	mockRepository.(*mockNotebookRepository).On("UpdateNotebook", mock.Anything, uint(1), uint(3)).Return(nil)
+
+	usecase := NewNotebookUsecase(mockRepository, validator.NewNotebookValidator(), 3)
+	_, err := usecase.UpdateNotebook(context.Background(), model.Notebook{Name: "moved", ParentId: &parentId}, 1, 3)
+	assert.Nil(t, err)
+	mockRepository.(*mockNotebookRepository).AssertExpectations(t)
+}
+
+func TestUpdateNotebook_Cycle(t *testing.T) {
+	parentId := uint(4)
+	mockRepository := newMockNotebookRepository()
+	mockRepository.(*mockNotebookRepository).On("GetSubtreeHeight", uint(1), uint(2)).Return(3, nil)
+	mockRepository.(*mockNotebookRepository).On("GetNotebookPath", uint(1), parentId).Return(notebookPath(1, 2, 4), nil)
+
+	usecase := NewNotebookUsecase(mockRepository, validator.NewNotebookValidator(), 10)
+	_, err := usecase.UpdateNotebook(context.Background(), model.Notebook{Name: "moved", ParentId: &parentId}, 1, 2)
+	assert.Equal(t, "parent_id: cannot move a notebook into itself or its descendants.", err.Error())
+	mockRepository.(*mockNotebookRepository).AssertNotCalled(t, "UpdateNotebook")
+}
+
+func TestDeleteNotebook(t *testing.T) {
+	mockRepository := newMockNotebookRepository()
+	mockRepository.(*mockNotebookRepository).On("DeleteNotebook", uint(1), uint(2), model.NotebookDeleteTrash).Return(nil)
+
+	usecase := NewNotebookUsecase(mockRepository, nil, 0)
+	err := usecase.DeleteNotebook(context.Background(), 1, 2, model.NotebookDeleteTrash)
+	assert.Nil(t, err)
+	mockRepository.(*mockNotebookRepository).AssertExpectations(t)
+
+	err = usecase.DeleteNotebook(context.Background(), 1, 2, "shred")
+	assert.Equal(t, "on_delete: must be trash or reparent.", err.Error())
+}
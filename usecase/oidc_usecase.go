@@ -0,0 +1,160 @@
+package usecase
+
+import (
+	"context"
+	"crypto/subtle"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"echo-rest-api/oidc"
+	"echo-rest-api/repository"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// oidcStateTTL is how long a user has to sign in at the provider.
+const oidcStateTTL = 10 * time.Minute
+
+const oidcStatePurpose = "oidc-state"
+
+type IOIDCUsecase interface {
+	BeginLogin(ctx context.Context, provider string) (model.OIDCAuthorization, error)
+	CompleteLogin(ctx context.Context, provider string, code string, state string, stateToken string) (model.LoginResponse, error)
+}
+
+type oidcUsecase struct {
+	providers map[string]oidc.IProvider
+	ur        repository.IUserRepository
+	ir        repository.IIdentityRepository
+	mr        repository.IMFARepository
+	secret    []byte
+}
+
+func NewOIDCUsecase(providers map[string]oidc.IProvider, ur repository.IUserRepository, ir repository.IIdentityRepository, mr repository.IMFARepository, secret []byte) IOIDCUsecase {
+	return &oidcUsecase{providers, ur, ir, mr, secret}
+}
+
+func (ou *oidcUsecase) BeginLogin(ctx context.Context, provider string) (model.OIDCAuthorization, error) {
+	p, ok := ou.providers[provider]
+	if !ok {
+		return model.OIDCAuthorization{}, apperror.New(apperror.ErrNotFound, "unknown identity provider")
+	}
+	state, err := randomToken()
+	if err != nil {
+		return model.OIDCAuthorization{}, err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return model.OIDCAuthorization{}, err
+	}
+	verifier, err := randomToken()
+	if err != nil {
+		return model.OIDCAuthorization{}, err
+	}
+	authURL, err := p.AuthCodeURL(state, nonce, oidc.CodeChallenge(verifier))
+	if err != nil {
+		return model.OIDCAuthorization{}, err
+	}
+	expiresAt := time.Now().Add(oidcStateTTL)
+	stateToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"provider":      provider,
+		"state":         state,
+		"nonce":         nonce,
+		"code_verifier": verifier,
+		"exp":           expiresAt.Unix(),
+	}).SignedString(purposeKey(ou.secret, oidcStatePurpose))
+	if err != nil {
+		return model.OIDCAuthorization{}, err
+	}
+	return model.OIDCAuthorization{
+		AuthURL:    authURL,
+		StateToken: stateToken,
+		ExpiresAt:  expiresAt,
+	}, nil
+}
+
+// CompleteLogin handles the redirect back from the provider. stateToken is
+// the one BeginLogin returned to the same browser.
+func (ou *oidcUsecase) CompleteLogin(ctx context.Context, provider string, code string, state string, stateToken string) (model.LoginResponse, error) {
+	p, ok := ou.providers[provider]
+	if !ok {
+		return model.LoginResponse{}, apperror.New(apperror.ErrNotFound, "unknown identity provider")
+	}
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(stateToken, claims, func(t *jwt.Token) (interface{}, error) {
+		return purposeKey(ou.secret, oidcStatePurpose), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return model.LoginResponse{}, apperror.New(apperror.ErrUnauthorized, "invalid oidc state")
+	}
+	expectedState, _ := claims["state"].(string)
+	nonce, _ := claims["nonce"].(string)
+	verifier, _ := claims["code_verifier"].(string)
+	if claims["provider"] != provider || expectedState == "" || subtle.ConstantTimeCompare([]byte(state), []byte(expectedState)) != 1 {
+		return model.LoginResponse{}, apperror.New(apperror.ErrUnauthorized, "invalid oidc state")
+	}
+	rawIDToken, err := p.Exchange(code, verifier)
+	if err != nil {
+		var tokenErr *oidc.TokenError
+		if errors.As(err, &tokenErr) {
+			return model.LoginResponse{}, apperror.Wrap(apperror.ErrUnauthorized, err)
+		}
+		return model.LoginResponse{}, err
+	}
+	idClaims, err := p.VerifyIDToken(rawIDToken, nonce)
+	if err != nil {
+		return model.LoginResponse{}, apperror.Wrap(apperror.ErrUnauthorized, err)
+	}
+	user, err := ou.findOrCreateUser(ctx, provider, idClaims)
+	if err != nil {
+		return model.LoginResponse{}, err
+	}
+	return newLoginResponse(ctx, ou.mr, ou.secret, user)
+}
+
+// findOrCreateUser returns the user linked to the identity. An unknown
+// identity is linked to the user with the same email address, or to a new
+// user, but only if the provider verified the address. Linking also requires
+// that the existing user verified it, as anybody could have signed up with
+// an address they do not own.
+func (ou *oidcUsecase) findOrCreateUser(ctx context.Context, provider string, claims oidc.Claims) (model.User, error) {
+	user := model.User{}
+	identity := model.UserIdentity{}
+	err := ou.ir.GetIdentity(ctx, &identity, provider, claims.Subject)
+	if err == nil {
+		if err := ou.ur.GetUserById(ctx, &user, identity.UserId); err != nil {
+			return model.User{}, err
+		}
+		return user, nil
+	}
+	if !errors.Is(err, apperror.ErrNotFound) {
+		return model.User{}, err
+	}
+	if claims.Email == "" || !claims.EmailVerified {
+		return model.User{}, apperror.New(apperror.ErrUnauthorized, "identity provider did not return a verified email address")
+	}
+	identity = model.UserIdentity{Provider: provider, Subject: claims.Subject, Email: claims.Email}
+	err = ou.ur.GetUserByEmail(ctx, &user, claims.Email)
+	switch {
+	case err == nil:
+		if user.EmailVerifiedAt == nil {
+			return model.User{}, apperror.New(apperror.ErrConflict, "an account with this email address exists; log in with its password and verify the address to link it")
+		}
+		identity.UserId = user.ID
+		if err := ou.ir.CreateIdentity(ctx, &identity); err != nil {
+			return model.User{}, err
+		}
+		return user, nil
+	case errors.Is(err, apperror.ErrNotFound):
+		// The new user has no password until it sets one with a reset.
+		verifiedAt := time.Now()
+		user = model.User{Email: claims.Email, EmailVerifiedAt: &verifiedAt}
+		if err := ou.ir.CreateUserWithIdentity(ctx, &user, &identity); err != nil {
+			return model.User{}, err
+		}
+		return user, nil
+	default:
+		return model.User{}, err
+	}
+}
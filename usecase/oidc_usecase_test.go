@@ -0,0 +1,176 @@
+package usecase
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"echo-rest-api/oidc"
+	"echo-rest-api/testHelpers"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func newTestOIDCUsecase(m *testHelpers.MockOIDCProvider, repositories repositoryMocks) IOIDCUsecase {
+	providers := map[string]oidc.IProvider{
+		"mock": oidc.NewProvider(oidc.Config{
+			Name:         "mock",
+			Issuer:       m.Issuer,
+			ClientID:     m.ClientID,
+			ClientSecret: m.ClientSecret,
+			RedirectURL:  "http://localhost:8080/oidc/mock/callback",
+		}, nil),
+	}
+	return NewOIDCUsecase(providers, repositories.users, repositories.identities, repositories.mfa, testSecret)
+}
+
+type repositoryMocks struct {
+	users      *mockUserRepository
+	identities *mockIdentityRepository
+	mfa        *mockMFARepository
+}
+
+func newRepositoryMocks() repositoryMocks {
+	mfaRepository := newMockMFARepository().(*mockMFARepository)
+	mfaRepository.On("GetTOTPCredential", mock.Anything).Return(nil, apperror.New(apperror.ErrNotFound, "record not found"))
+	return repositoryMocks{
+		users:      newMockUserRepository().(*mockUserRepository),
+		identities: newMockIdentityRepository().(*mockIdentityRepository),
+		mfa:        mfaRepository,
+	}
+}
+
+// signIn runs the browser part of the flow against the mock provider.
+func signIn(t *testing.T, usecase IOIDCUsecase, m *testHelpers.MockOIDCProvider) (model.LoginResponse, error) {
+	authorization, err := usecase.BeginLogin(context.Background(), "mock")
+	assert.Nil(t, err)
+	code, state, err := m.Authorize(authorization.AuthURL)
+	assert.Nil(t, err)
+	return usecase.CompleteLogin(context.Background(), "mock", code, state, authorization.StateToken)
+}
+
+func TestOIDCLogin_NewUser(t *testing.T) {
+	m := testHelpers.NewMockOIDCProvider()
+	defer m.Close()
+	m.Claims = jwt.MapClaims{"sub": "alice", "email": "alice@example.com", "email_verified": true}
+	repositories := newRepositoryMocks()
+	repositories.identities.On("GetIdentity", "mock", "alice").Return(nil, apperror.New(apperror.ErrNotFound, "record not found"))
+	repositories.users.On("GetUserByEmail", mock.AnythingOfType("*model.User"), "alice@example.com").Return(nil, apperror.New(apperror.ErrNotFound, "record not found"))
+	repositories.identities.On("CreateUserWithIdentity", mock.MatchedBy(func(user *model.User) bool {
+		return user.Email == "alice@example.com" && user.EmailVerifiedAt != nil && user.Password == ""
+	}), mock.MatchedBy(func(identity *model.UserIdentity) bool {
+		return identity.Provider == "mock" && identity.Subject == "alice"
+	})).Return(nil)
+	usecase := newTestOIDCUsecase(m, repositories)
+
+	loginRes, err := signIn(t, usecase, m)
+	assert.Nil(t, err)
+	assert.False(t, loginRes.MFARequired)
+	assert.Equal(t, uint(10), loginRes.ID)
+	assert.True(t, loginRes.EmailVerified)
+	repositories.identities.AssertExpectations(t)
+}
+
+func TestOIDCLogin_LinkedUser(t *testing.T) {
+	m := testHelpers.NewMockOIDCProvider()
+	defer m.Close()
+	m.Claims = jwt.MapClaims{"sub": "alice"}
+	repositories := newRepositoryMocks()
+	repositories.identities.On("GetIdentity", "mock", "alice").Return(&model.UserIdentity{UserId: 1}, nil)
+	repositories.users.On("GetUserById", uint(1)).Return(&model.User{Model: gorm.Model{ID: 1}, Email: "testuser1@example.com"}, nil)
+	usecase := newTestOIDCUsecase(m, repositories)
+
+	loginRes, err := signIn(t, usecase, m)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), loginRes.ID)
+	repositories.users.AssertNotCalled(t, "GetUserByEmail", mock.Anything, mock.Anything)
+}
+
+func TestOIDCLogin_LinkByEmail(t *testing.T) {
+	m := testHelpers.NewMockOIDCProvider()
+	defer m.Close()
+	m.Claims = jwt.MapClaims{"sub": "alice", "email": "testuser1@example.com", "email_verified": true}
+	verifiedAt := time.Now()
+	existing := model.User{Model: gorm.Model{ID: 1}, Email: "testuser1@example.com", EmailVerifiedAt: &verifiedAt}
+	repositories := newRepositoryMocks()
+	repositories.identities.On("GetIdentity", "mock", "alice").Return(nil, apperror.New(apperror.ErrNotFound, "record not found"))
+	repositories.users.On("GetUserByEmail", mock.AnythingOfType("*model.User"), "testuser1@example.com").Return(&existing, nil)
+	repositories.identities.On("CreateIdentity", mock.MatchedBy(func(identity *model.UserIdentity) bool {
+		return identity.UserId == 1 && identity.Subject == "alice"
+	})).Return(nil)
+	usecase := newTestOIDCUsecase(m, repositories)
+
+	loginRes, err := signIn(t, usecase, m)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), loginRes.ID)
+	repositories.identities.AssertExpectations(t)
+
+	// A user who never verified the address may not own it.
+	existing.EmailVerifiedAt = nil
+	_, err = signIn(t, usecase, m)
+	assert.ErrorIs(t, err, apperror.ErrConflict)
+	repositories.identities.AssertNumberOfCalls(t, "CreateIdentity", 1)
+}
+
+func TestOIDCLogin_UnverifiedEmail(t *testing.T) {
+	m := testHelpers.NewMockOIDCProvider()
+	defer m.Close()
+	m.Claims = jwt.MapClaims{"sub": "alice", "email": "testuser1@example.com", "email_verified": false}
+	repositories := newRepositoryMocks()
+	repositories.identities.On("GetIdentity", "mock", "alice").Return(nil, apperror.New(apperror.ErrNotFound, "record not found"))
+	usecase := newTestOIDCUsecase(m, repositories)
+
+	_, err := signIn(t, usecase, m)
+	assert.ErrorIs(t, err, apperror.ErrUnauthorized)
+	repositories.users.AssertNotCalled(t, "GetUserByEmail", mock.Anything, mock.Anything)
+}
+
+func TestOIDCLogin_MFA(t *testing.T) {
+	m := testHelpers.NewMockOIDCProvider()
+	defer m.Close()
+	m.Claims = jwt.MapClaims{"sub": "alice"}
+	confirmedAt := time.Now()
+	repositories := newRepositoryMocks()
+	repositories.mfa = newMockMFARepository().(*mockMFARepository)
+	repositories.mfa.On("GetTOTPCredential", uint(1)).Return(&model.TOTPCredential{UserId: 1, ConfirmedAt: &confirmedAt}, nil)
+	repositories.identities.On("GetIdentity", "mock", "alice").Return(&model.UserIdentity{UserId: 1}, nil)
+	repositories.users.On("GetUserById", uint(1)).Return(&model.User{Model: gorm.Model{ID: 1}}, nil)
+	usecase := newTestOIDCUsecase(m, repositories)
+
+	loginRes, err := signIn(t, usecase, m)
+	assert.Nil(t, err)
+	assert.True(t, loginRes.MFARequired)
+	assert.NotEmpty(t, loginRes.MFAToken)
+	assert.Equal(t, uint(0), loginRes.ID)
+}
+
+func TestOIDCLogin_InvalidState(t *testing.T) {
+	m := testHelpers.NewMockOIDCProvider()
+	defer m.Close()
+	repositories := newRepositoryMocks()
+	usecase := newTestOIDCUsecase(m, repositories)
+
+	_, err := usecase.BeginLogin(context.Background(), "unknown")
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+
+	authorization, err := usecase.BeginLogin(context.Background(), "mock")
+	assert.Nil(t, err)
+	code, state, err := m.Authorize(authorization.AuthURL)
+	assert.Nil(t, err)
+	other, err := usecase.BeginLogin(context.Background(), "mock")
+	assert.Nil(t, err)
+	_, err = usecase.CompleteLogin(context.Background(), "mock", code, state, other.StateToken)
+	assert.ErrorIs(t, err, apperror.ErrUnauthorized)
+	_, err = usecase.CompleteLogin(context.Background(), "mock", code, state, "")
+	assert.ErrorIs(t, err, apperror.ErrUnauthorized)
+	_, err = usecase.CompleteLogin(context.Background(), "unknown", code, state, authorization.StateToken)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	// The provider rejects a code it did not issue.
+	_, err = usecase.CompleteLogin(context.Background(), "mock", "forged", state, authorization.StateToken)
+	assert.ErrorIs(t, err, apperror.ErrUnauthorized)
+	repositories.identities.AssertNotCalled(t, "GetIdentity", mock.Anything, mock.Anything)
+}
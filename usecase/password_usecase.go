@@ -0,0 +1,150 @@
+package usecase
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/logging"
+	"echo-rest-api/mailer"
+	"echo-rest-api/model"
+	"echo-rest-api/repository"
+	"echo-rest-api/validator"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultPasswordResetTTL = time.Hour
+	DefaultPasswordResetURL = "http://localhost:3000/password/reset"
+)
+
+type IPasswordUsecase interface {
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token string, password string) error
+	// Wait blocks until the reset links still being mailed are sent, for
+	// shutdown.
+	Wait()
+}
+
+type passwordUsecase struct {
+	ur       repository.IUserRepository
+	pr       repository.IPasswordResetRepository
+	sr       repository.ISessionRepository
+	uv       validator.IUserValidator
+	m        mailer.IMailer
+	resetURL string
+	ttl      time.Duration
+	pending  sync.WaitGroup
+}
+
+// NewPasswordUsecase mails reset links pointing at resetURL with the token in
+// the token query parameter, valid for ttl. Empty or non-positive values fall
+// back to the defaults.
+func NewPasswordUsecase(ur repository.IUserRepository, pr repository.IPasswordResetRepository, sr repository.ISessionRepository, uv validator.IUserValidator, m mailer.IMailer, resetURL string, ttl time.Duration) IPasswordUsecase {
+	if resetURL == "" {
+		resetURL = DefaultPasswordResetURL
+	}
+	if ttl <= 0 {
+		ttl = DefaultPasswordResetTTL
+	}
+	return &passwordUsecase{ur: ur, pr: pr, sr: sr, uv: uv, m: m, resetURL: resetURL, ttl: ttl}
+}
+
+// ForgotPassword mails a reset link to email. It succeeds whether or not the
+// email belongs to an account. The link is created and mailed after it
+// returns and failures are only logged, so neither the result nor the time
+// it takes tells a caller who is registered.
+func (pu *passwordUsecase) ForgotPassword(ctx context.Context, email string) error {
+	user := model.User{}
+	if err := pu.ur.GetUserByEmail(ctx, &user, email); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	logger := logging.FromContext(ctx)
+	// The link is sent after the request has been answered and its context
+	// cancelled.
+	ctx = context.WithoutCancel(ctx)
+	pu.pending.Add(1)
+	go func() {
+		defer pu.pending.Done()
+		if err := pu.sendResetLink(ctx, user); err != nil {
+			logger.Error("password reset: sending mail failed", "user_id", user.ID, "error", err)
+		}
+	}()
+	return nil
+}
+
+func (pu *passwordUsecase) Wait() {
+	pu.pending.Wait()
+}
+
+func (pu *passwordUsecase) sendResetLink(ctx context.Context, user model.User) error {
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+	reset := model.PasswordResetToken{
+		UserId:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(pu.ttl),
+	}
+	if err := pu.pr.CreatePasswordResetToken(ctx, &reset); err != nil {
+		return err
+	}
+	link, err := pu.resetLink(token)
+	if err != nil {
+		return err
+	}
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Someone asked to reset the password of your account.\n\n"+
+			"Open the link below within %s to choose a new password:\n\n%s\n\n"+
+			"If this was not you, you can ignore this email.\n", pu.ttl, link),
+	}
+	return pu.m.Send(msg)
+}
+
+// ResetPassword sets a new password for the owner of a reset token and signs
+// them out of every session.
+func (pu *passwordUsecase) ResetPassword(ctx context.Context, token string, password string) error {
+	if err := pu.uv.PasswordValidate(password); err != nil {
+		return apperror.Wrap(apperror.ErrValidation, err)
+	}
+	stored := model.PasswordResetToken{}
+	if err := pu.pr.GetPasswordResetTokenByHash(ctx, &stored, hashToken(token)); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return apperror.New(apperror.ErrUnauthorized, "invalid password reset token")
+		}
+		return err
+	}
+	if stored.UsedAt != nil || !time.Now().Before(stored.ExpiresAt) {
+		return apperror.New(apperror.ErrUnauthorized, "invalid password reset token")
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := pu.pr.ResetPassword(ctx, &stored, hash); err != nil {
+		if errors.Is(err, apperror.ErrConflict) {
+			return apperror.New(apperror.ErrUnauthorized, "invalid password reset token")
+		}
+		return err
+	}
+	return pu.sr.RevokeUserSessions(ctx, stored.UserId)
+}
+
+func (pu *passwordUsecase) resetLink(token string) (string, error) {
+	u, err := url.Parse(pu.resetURL)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
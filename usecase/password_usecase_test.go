@@ -0,0 +1,154 @@
+package usecase
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/mailer"
+	"echo-rest-api/model"
+	"echo-rest-api/validator"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type failingMailer struct{}
+
+func (failingMailer) Send(msg mailer.Message) error {
+	return errors.New("smtp unavailable")
+}
+
+// blockingMailer holds every message until release is closed.
+type blockingMailer struct {
+	release chan struct{}
+	mailer.MemoryMailer
+}
+
+func (m *blockingMailer) Send(msg mailer.Message) error {
+	<-m.release
+	return m.MemoryMailer.Send(msg)
+}
+
+func TestForgotPassword(t *testing.T) {
+	userRepository := newMockUserRepository()
+	userRepository.(*mockUserRepository).On("GetUserByEmail", mock.Anything, "user@example.com").
+		Return(&model.User{Email: "user@example.com"}, nil)
+	var tokenHash string
+	resetRepository := newMockPasswordResetRepository()
+	resetRepository.(*mockPasswordResetRepository).On("CreatePasswordResetToken", mock.MatchedBy(func(token *model.PasswordResetToken) bool {
+		tokenHash = token.TokenHash
+		return time.Until(token.ExpiresAt) > 29*time.Minute && time.Until(token.ExpiresAt) <= 30*time.Minute
+	})).Return(nil)
+	mail := mailer.NewMemoryMailer()
+
+	usecase := NewPasswordUsecase(userRepository, resetRepository, newMockSessionRepository(), validator.NewUserValidator(), mail, "https://app.example.com/reset?lang=en", 30*time.Minute)
+	assert.Nil(t, usecase.ForgotPassword(context.Background(), "user@example.com"))
+	usecase.Wait()
+
+	messages := mail.Messages()
+	assert.Equal(t, 1, len(messages))
+	assert.Equal(t, "user@example.com", messages[0].To)
+	link := regexp.MustCompile(`https://\S+`).FindString(messages[0].Body)
+	u, err := url.Parse(link)
+	assert.Nil(t, err)
+	assert.Equal(t, "en", u.Query().Get("lang"))
+	assert.Equal(t, tokenHash, hashToken(u.Query().Get("token")))
+	resetRepository.(*mockPasswordResetRepository).AssertExpectations(t)
+}
+
+func TestForgotPassword_Unknown(t *testing.T) {
+	userRepository := newMockUserRepository()
+	userRepository.(*mockUserRepository).On("GetUserByEmail", mock.Anything, "nobody@example.com").
+		Return(nil, apperror.New(apperror.ErrNotFound, "object does not exist"))
+	resetRepository := newMockPasswordResetRepository()
+	mail := mailer.NewMemoryMailer()
+
+	usecase := NewPasswordUsecase(userRepository, resetRepository, newMockSessionRepository(), validator.NewUserValidator(), mail, "", 0)
+	assert.Nil(t, usecase.ForgotPassword(context.Background(), "nobody@example.com"))
+	usecase.Wait()
+	assert.Equal(t, 0, len(mail.Messages()))
+	resetRepository.(*mockPasswordResetRepository).AssertNotCalled(t, "CreatePasswordResetToken")
+}
+
+func TestForgotPassword_MailError(t *testing.T) {
+	userRepository := newMockUserRepository()
+	userRepository.(*mockUserRepository).On("GetUserByEmail", mock.Anything, "user@example.com").
+		Return(&model.User{Email: "user@example.com"}, nil)
+	resetRepository := newMockPasswordResetRepository()
+	resetRepository.(*mockPasswordResetRepository).On("CreatePasswordResetToken", mock.Anything).Return(nil)
+
+	usecase := NewPasswordUsecase(userRepository, resetRepository, newMockSessionRepository(), validator.NewUserValidator(), failingMailer{}, "", 0)
+	assert.Nil(t, usecase.ForgotPassword(context.Background(), "user@example.com"))
+	usecase.Wait()
+	resetRepository.(*mockPasswordResetRepository).AssertExpectations(t)
+}
+
+func TestForgotPassword_Background(t *testing.T) {
+	userRepository := newMockUserRepository()
+	userRepository.(*mockUserRepository).On("GetUserByEmail", mock.Anything, "user@example.com").
+		Return(&model.User{Email: "user@example.com"}, nil)
+	resetRepository := newMockPasswordResetRepository()
+	resetRepository.(*mockPasswordResetRepository).On("CreatePasswordResetToken", mock.Anything).Return(nil)
+	mail := &blockingMailer{release: make(chan struct{})}
+
+	// ForgotPassword answers before the mail is sent, as fast as for an
+	// unknown address.
+	usecase := NewPasswordUsecase(userRepository, resetRepository, newMockSessionRepository(), validator.NewUserValidator(), mail, "", 0)
+	assert.Nil(t, usecase.ForgotPassword(context.Background(), "user@example.com"))
+	assert.Equal(t, 0, len(mail.Messages()))
+
+	close(mail.release)
+	usecase.Wait()
+	assert.Equal(t, 1, len(mail.Messages()))
+}
+
+func TestResetPassword(t *testing.T) {
+	stored := model.PasswordResetToken{ID: 1, UserId: 1, ExpiresAt: time.Now().Add(time.Hour)}
+	resetRepository := newMockPasswordResetRepository()
+	resetRepository.(*mockPasswordResetRepository).On("GetPasswordResetTokenByHash", hashToken("reset")).Return(&stored, nil)
+	resetRepository.(*mockPasswordResetRepository).On("ResetPassword", mock.Anything, mock.MatchedBy(func(hash string) bool {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte("new password")) == nil
+	})).Return(nil)
+	sessionRepository := newMockSessionRepository()
+	sessionRepository.(*mockSessionRepository).On("RevokeUserSessions", uint(1)).Return(nil)
+
+	usecase := NewPasswordUsecase(newMockUserRepository(), resetRepository, sessionRepository, validator.NewUserValidator(), mailer.NewMemoryMailer(), "", 0)
+	assert.Nil(t, usecase.ResetPassword(context.Background(), "reset", "new password"))
+	resetRepository.(*mockPasswordResetRepository).AssertExpectations(t)
+	sessionRepository.(*mockSessionRepository).AssertExpectations(t)
+}
+
+func TestResetPassword_InvalidToken(t *testing.T) {
+	usedAt := time.Now()
+	resetRepository := newMockPasswordResetRepository()
+	resetRepository.(*mockPasswordResetRepository).On("GetPasswordResetTokenByHash", hashToken("missing")).
+		Return(nil, apperror.New(apperror.ErrNotFound, "object does not exist"))
+	resetRepository.(*mockPasswordResetRepository).On("GetPasswordResetTokenByHash", hashToken("expired")).
+		Return(&model.PasswordResetToken{ID: 1, UserId: 1, ExpiresAt: time.Now().Add(-time.Minute)}, nil)
+	resetRepository.(*mockPasswordResetRepository).On("GetPasswordResetTokenByHash", hashToken("used")).
+		Return(&model.PasswordResetToken{ID: 2, UserId: 1, ExpiresAt: time.Now().Add(time.Hour), UsedAt: &usedAt}, nil)
+	sessionRepository := newMockSessionRepository()
+
+	usecase := NewPasswordUsecase(newMockUserRepository(), resetRepository, sessionRepository, validator.NewUserValidator(), mailer.NewMemoryMailer(), "", 0)
+	for _, token := range []string{"missing", "expired", "used"} {
+		err := usecase.ResetPassword(context.Background(), token, "new password")
+		assert.ErrorIs(t, err, apperror.ErrUnauthorized)
+	}
+	resetRepository.(*mockPasswordResetRepository).AssertNotCalled(t, "ResetPassword")
+	sessionRepository.(*mockSessionRepository).AssertNotCalled(t, "RevokeUserSessions")
+}
+
+func TestResetPassword_Validate(t *testing.T) {
+	resetRepository := newMockPasswordResetRepository()
+
+	usecase := NewPasswordUsecase(newMockUserRepository(), resetRepository, newMockSessionRepository(), validator.NewUserValidator(), mailer.NewMemoryMailer(), "", 0)
+	err := usecase.ResetPassword(context.Background(), "reset", "12345")
+	assert.ErrorIs(t, err, apperror.ErrValidation)
+	assert.Equal(t, "password: limited min 6 max 30 char.", err.Error())
+	resetRepository.(*mockPasswordResetRepository).AssertNotCalled(t, "GetPasswordResetTokenByHash")
+}
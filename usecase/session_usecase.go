@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"echo-rest-api/repository"
+	"errors"
+	"time"
+)
+
+// sessionTouchInterval limits how often LastSeenAt is written while a
+// session is in use.
+const sessionTouchInterval = time.Minute
+
+type ISessionUsecase interface {
+	GetSessions(ctx context.Context, userId uint, currentId string) ([]model.SessionResponse, error)
+	VerifySession(ctx context.Context, userId uint, sessionId string) error
+	RevokeSession(ctx context.Context, userId uint, sessionId string) error
+	RevokeAllSessions(ctx context.Context, userId uint) error
+}
+
+type sessionUsecase struct {
+	sr repository.ISessionRepository
+}
+
+func NewSessionUsecase(sr repository.ISessionRepository) ISessionUsecase {
+	return &sessionUsecase{sr}
+}
+
+func (su *sessionUsecase) GetSessions(ctx context.Context, userId uint, currentId string) ([]model.SessionResponse, error) {
+	sessions := []model.Session{}
+	if err := su.sr.GetSessionsByUser(ctx, &sessions, userId); err != nil {
+		return nil, err
+	}
+	resSessions := []model.SessionResponse{}
+	for _, session := range sessions {
+		resSessions = append(resSessions, model.SessionResponse{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			Current:    session.ID == currentId,
+		})
+	}
+	return resSessions, nil
+}
+
+// VerifySession makes sure the session an access token was issued for is
+// still active and records that it has been seen.
+func (su *sessionUsecase) VerifySession(ctx context.Context, userId uint, sessionId string) error {
+	if sessionId == "" {
+		return apperror.New(apperror.ErrUnauthorized, "session has been revoked")
+	}
+	session := model.Session{}
+	if err := su.sr.GetSessionById(ctx, &session, userId, sessionId); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return apperror.New(apperror.ErrUnauthorized, "session has been revoked")
+		}
+		return err
+	}
+	if now := time.Now(); now.Sub(session.LastSeenAt) > sessionTouchInterval {
+		return su.sr.TouchSession(ctx, sessionId, now)
+	}
+	return nil
+}
+
+func (su *sessionUsecase) RevokeSession(ctx context.Context, userId uint, sessionId string) error {
+	if err := su.sr.RevokeSession(ctx, userId, sessionId); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (su *sessionUsecase) RevokeAllSessions(ctx context.Context, userId uint) error {
+	if err := su.sr.RevokeUserSessions(ctx, userId); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package usecase
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetSessions(t *testing.T) {
+	sessions := []model.Session{
+		{ID: "a", UserId: 1, UserAgent: "firefox"},
+		{ID: "b", UserId: 1, UserAgent: "curl"},
+	}
+	mockRepository := newMockSessionRepository()
+	mockRepository.(*mockSessionRepository).On("GetSessionsByUser", mock.Anything, uint(1)).Return(&sessions, nil)
+
+	usecase := NewSessionUsecase(mockRepository)
+	res, err := usecase.GetSessions(context.Background(), 1, "b")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(res))
+	assert.False(t, res[0].Current)
+	assert.True(t, res[1].Current)
+	mockRepository.(*mockSessionRepository).AssertExpectations(t)
+}
+
+func TestVerifySession(t *testing.T) {
+	mockRepository := newMockSessionRepository()
+	mockRepository.(*mockSessionRepository).On("GetSessionById", uint(1), "fresh").
+		Return(&model.Session{ID: "fresh", LastSeenAt: time.Now()}, nil)
+	mockRepository.(*mockSessionRepository).On("GetSessionById", uint(1), "idle").
+		Return(&model.Session{ID: "idle", LastSeenAt: time.Now().Add(-time.Hour)}, nil)
+	mockRepository.(*mockSessionRepository).On("TouchSession", "idle", mock.Anything).Return(nil)
+	mockRepository.(*mockSessionRepository).On("GetSessionById", uint(1), "revoked").
+		Return(nil, apperror.New(apperror.ErrNotFound, "session does not exist"))
+
+	usecase := NewSessionUsecase(mockRepository)
+	assert.Nil(t, usecase.VerifySession(context.Background(), 1, "fresh"))
+	assert.Nil(t, usecase.VerifySession(context.Background(), 1, "idle"))
+	assert.ErrorIs(t, usecase.VerifySession(context.Background(), 1, "revoked"), apperror.ErrUnauthorized)
+	assert.ErrorIs(t, usecase.VerifySession(context.Background(), 1, ""), apperror.ErrUnauthorized)
+	mockRepository.(*mockSessionRepository).AssertExpectations(t)
+	mockRepository.(*mockSessionRepository).AssertNumberOfCalls(t, "TouchSession", 1)
+}
@@ -0,0 +1,66 @@
+package usecase
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"echo-rest-api/repository"
+	"echo-rest-api/validator"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type ITagUsecase interface {
+	GetAllTags(ctx context.Context, userId uint) ([]model.TagResponse, error)
+	RenameTag(ctx context.Context, tag model.Tag, userId uint, tagId uint) (model.TagResponse, error)
+	MergeTags(ctx context.Context, userId uint, sourceId uint, targetId uint) (model.TagResponse, error)
+}
+
+type tagUsecase struct {
+	tr repository.ITagRepository
+	tv validator.ITagValidator
+}
+
+func NewTagUsecase(tr repository.ITagRepository, tv validator.ITagValidator) ITagUsecase {
+	return &tagUsecase{tr, tv}
+}
+
+func (tu *tagUsecase) GetAllTags(ctx context.Context, userId uint) ([]model.TagResponse, error) {
+	tags := []model.TagResponse{}
+	if err := tu.tr.GetAllTags(ctx, &tags, userId); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (tu *tagUsecase) RenameTag(ctx context.Context, tag model.Tag, userId uint, tagId uint) (model.TagResponse, error) {
+	tag.Name = strings.TrimSpace(tag.Name)
+	if err := tu.tv.TagValidate(tag); err != nil {
+		return model.TagResponse{}, apperror.Wrap(apperror.ErrValidation, err)
+	}
+	if err := tu.tr.RenameTag(ctx, userId, tagId, tag.Name); err != nil {
+		return model.TagResponse{}, err
+	}
+	resTag := model.TagResponse{}
+	if err := tu.tr.GetTagById(ctx, &resTag, userId, tagId); err != nil {
+		return model.TagResponse{}, err
+	}
+	return resTag, nil
+}
+
+func (tu *tagUsecase) MergeTags(ctx context.Context, userId uint, sourceId uint, targetId uint) (model.TagResponse, error) {
+	if sourceId == targetId {
+		return model.TagResponse{}, apperror.Wrap(apperror.ErrValidation, validation.Errors{
+			"target_id": validation.NewError("same_tag", "cannot merge a tag into itself"),
+		})
+	}
+	if err := tu.tr.MergeTags(ctx, userId, sourceId, targetId); err != nil {
+		return model.TagResponse{}, err
+	}
+	resTag := model.TagResponse{}
+	if err := tu.tr.GetTagById(ctx, &resTag, userId, targetId); err != nil {
+		return model.TagResponse{}, err
+	}
+	return resTag, nil
+}
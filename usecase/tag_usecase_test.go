@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/model"
+	"echo-rest-api/validator"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetAllTags(t *testing.T) {
+	const userId = uint(1)
+	expectedTags := []model.TagResponse{
+		{ID: 1, Name: "idea", MemoCount: 2},
+		{ID: 2, Name: "work", MemoCount: 1},
+	}
+	mockRepository := newMockTagRepository()
+	mockRepository.(*mockTagRepository).On("GetAllTags", mock.Anything, userId).Return(&expectedTags, nil)
+
+	usecase := NewTagUsecase(mockRepository, nil)
+	tags, err := usecase.GetAllTags(context.Background(), userId)
+	assert.Nil(t, err)
+	assert.Equal(t, expectedTags, tags)
+	mockRepository.(*mockTagRepository).AssertExpectations(t)
+}
+
+func TestGetAllTags_Error(t *testing.T) {
+	mockRepository := newMockTagRepository()
+	mockRepository.(*mockTagRepository).On("GetAllTags", mock.Anything, uint(1)).Return(nil, errors.New("error"))
+
+	usecase := NewTagUsecase(mockRepository, nil)
+	tags, err := usecase.GetAllTags(context.Background(), 1)
+	assert.Error(t, err)
+	assert.Nil(t, tags)
+	mockRepository.(*mockTagRepository).AssertExpectations(t)
+}
+
+func TestRenameTag(t *testing.T) {
+	const (
+		userId = uint(1)
+		tagId  = uint(2)
+	)
+	expectedTag := model.TagResponse{ID: tagId, Name: "renamed", MemoCount: 3}
+	mockRepository := newMockTagRepository()
+	mockRepository.(*mockTagRepository).On("RenameTag", userId, tagId, "renamed").Return(nil)
+	mockRepository.(*mockTagRepository).On("GetTagById", userId, tagId).Return(&expectedTag, nil)
+
+	usecase := NewTagUsecase(mockRepository, validator.NewTagValidator())
+	tag, err := usecase.RenameTag(context.Background(), model.Tag{Name: "  renamed "}, userId, tagId)
+	assert.Nil(t, err)
+	assert.Equal(t, expectedTag, tag)
+	mockRepository.(*mockTagRepository).AssertExpectations(t)
+}
+
+func TestRenameTag_Validate(t *testing.T) {
+	usecase := NewTagUsecase(nil, validator.NewTagValidator())
+	tag, err := usecase.RenameTag(context.Background(), model.Tag{Name: " "}, 1, 1)
+	assert.Equal(t, "name: tag name is required.", err.Error())
+	assert.ErrorIs(t, err, apperror.ErrValidation)
+	assert.Equal(t, model.TagResponse{}, tag)
+}
+
+func TestMergeTags(t *testing.T) {
+	const userId = uint(1)
+	expectedTag := model.TagResponse{ID: 1, Name: "work", MemoCount: 3}
+	mockRepository := newMockTagRepository()
+	mockRepository.(*mockTagRepository).On("MergeTags", userId, uint(2), uint(1)).Return(nil)
+	mockRepository.(*mockTagRepository).On("GetTagById", userId, uint(1)).Return(&expectedTag, nil)
+
+	usecase := NewTagUsecase(mockRepository, nil)
+	tag, err := usecase.MergeTags(context.Background(), userId, 2, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, expectedTag, tag)
+	mockRepository.(*mockTagRepository).AssertExpectations(t)
+}
+
+func TestMergeTags_Same(t *testing.T) {
+	mockRepository := newMockTagRepository()
+	usecase := NewTagUsecase(mockRepository, nil)
+	_, err := usecase.MergeTags(context.Background(), 1, 2, 2)
+	assert.ErrorIs(t, err, apperror.ErrValidation)
+	mockRepository.(*mockTagRepository).AssertNotCalled(t, "MergeTags")
+}
@@ -1,13 +1,18 @@
 package usecase
 
 import (
+	"context"
 	"echo-rest-api/model"
 	"echo-rest-api/repository"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// testSecret stands in for SECRET in usecases that sign purpose tokens.
+var testSecret = []byte("test-secret")
+
 type mockMemoRepository struct {
 	mock.Mock
 }
@@ -16,15 +21,15 @@ func newMockMemoRepository() repository.IMemoRepository {
 	return &mockMemoRepository{}
 }
 
-func (m *mockMemoRepository) GetAllMemos(memos *[]model.Memo, userId uint) error {
-	args := m.Called(memos, userId)
+func (m *mockMemoRepository) GetAllMemos(ctx context.Context, memos *[]model.Memo, userId uint, query model.MemoQuery) error {
+	args := m.Called(memos, userId, query)
 	if memoArg, ok := args.Get(0).(*[]model.Memo); ok && memoArg != nil {
 		*memos = *memoArg
 	}
 	return args.Error(1)
 }
 
-func (m *mockMemoRepository) GetMemoById(memo *model.Memo, userId uint, memoId uint) error {
+func (m *mockMemoRepository) GetMemoById(ctx context.Context, memo *model.Memo, userId uint, memoId uint) error {
 	args := m.Called(userId, memoId)
 	if memoArg, ok := args.Get(0).(*model.Memo); ok && memoArg != nil {
 		*memo = *memoArg
@@ -33,7 +38,21 @@ func (m *mockMemoRepository) GetMemoById(memo *model.Memo, userId uint, memoId u
 
 }
 
-func (m *mockMemoRepository) CreateMemo(memo *model.Memo) error {
+func (m *mockMemoRepository) SearchMemos(ctx context.Context, results *[]model.MemoSearchResult, userId uint, query model.MemoSearchQuery) error {
+	args := m.Called(results, userId, query)
+	if resultArg, ok := args.Get(0).(*[]model.MemoSearchResult); ok && resultArg != nil {
+		*results = *resultArg
+	}
+	return args.Error(1)
+}
+
+func (m *mockMemoRepository) CountMemos(ctx context.Context, count *int64, userId uint) error {
+	args := m.Called(userId)
+	*count = int64(args.Int(0))
+	return args.Error(1)
+}
+
+func (m *mockMemoRepository) CreateMemo(ctx context.Context, memo *model.Memo) error {
 	args := m.Called(memo)
 	if memoArg, ok := args.Get(0).(*model.Memo); ok && memoArg != nil {
 		*memo = *memoArg
@@ -41,7 +60,7 @@ func (m *mockMemoRepository) CreateMemo(memo *model.Memo) error {
 	return args.Error(1)
 }
 
-func (m *mockMemoRepository) UpdateMemo(memo *model.Memo, userId uint, memoId uint) error {
+func (m *mockMemoRepository) UpdateMemo(ctx context.Context, memo *model.Memo, userId uint, memoId uint) error {
 	args := m.Called(memo, userId, memoId)
 	if memoArg, ok := args.Get(0).(*model.Memo); ok && memoArg != nil {
 		*memo = *memoArg
@@ -49,11 +68,74 @@ func (m *mockMemoRepository) UpdateMemo(memo *model.Memo, userId uint, memoId ui
 	return args.Error(1)
 }
 
-func (m *mockMemoRepository) DeleteMemo(userId uint, memoId uint) error {
+func (m *mockMemoRepository) PatchMemo(ctx context.Context, memo *model.Memo, userId uint, memoId uint) error {
+	args := m.Called(memo, userId, memoId)
+	if memoArg, ok := args.Get(0).(*model.Memo); ok && memoArg != nil {
+		*memo = *memoArg
+	}
+	return args.Error(1)
+}
+
+func (m *mockMemoRepository) MoveMemo(ctx context.Context, memo *model.Memo, userId uint, memoId uint, notebookId *uint) error {
+	args := m.Called(userId, memoId, notebookId)
+	if memoArg, ok := args.Get(0).(*model.Memo); ok && memoArg != nil {
+		*memo = *memoArg
+	}
+	return args.Error(1)
+}
+
+func (m *mockMemoRepository) DeleteMemo(ctx context.Context, userId uint, memoId uint) error {
 	args := m.Called(userId, memoId)
 	return args.Error(0)
 }
 
+func (m *mockMemoRepository) GetTrashedMemos(ctx context.Context, memos *[]model.Memo, userId uint) error {
+	args := m.Called(memos, userId)
+	if memoArg, ok := args.Get(0).(*[]model.Memo); ok && memoArg != nil {
+		*memos = *memoArg
+	}
+	return args.Error(1)
+}
+
+func (m *mockMemoRepository) RestoreMemo(ctx context.Context, memo *model.Memo, userId uint, memoId uint) error {
+	args := m.Called(userId, memoId)
+	if memoArg, ok := args.Get(0).(*model.Memo); ok && memoArg != nil {
+		*memo = *memoArg
+	}
+	return args.Error(1)
+}
+
+func (m *mockMemoRepository) PurgeMemo(ctx context.Context, userId uint, memoId uint) error {
+	args := m.Called(userId, memoId)
+	return args.Error(0)
+}
+
+func (m *mockMemoRepository) EmptyTrash(ctx context.Context, userId uint) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
+
+func (m *mockMemoRepository) PurgeMemosDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockMemoRepository) GetMemoRevisions(ctx context.Context, revisions *[]model.MemoRevision, userId uint, memoId uint) error {
+	args := m.Called(revisions, userId, memoId)
+	if revisionArg, ok := args.Get(0).(*[]model.MemoRevision); ok && revisionArg != nil {
+		*revisions = *revisionArg
+	}
+	return args.Error(1)
+}
+
+func (m *mockMemoRepository) GetMemoRevision(ctx context.Context, revision *model.MemoRevision, userId uint, memoId uint, rev uint) error {
+	args := m.Called(userId, memoId, rev)
+	if revisionArg, ok := args.Get(0).(*model.MemoRevision); ok && revisionArg != nil {
+		*revision = *revisionArg
+	}
+	return args.Error(1)
+}
+
 type mockUserRepository struct {
 	mock.Mock
 }
@@ -62,12 +144,60 @@ func newMockUserRepository() repository.IUserRepository {
 	return &mockUserRepository{}
 }
 
-func (m *mockUserRepository) CreateUser(user *model.User) error {
+func (m *mockUserRepository) CreateUser(ctx context.Context, user *model.User) error {
 	args := m.Called(user)
 	return args.Error(0)
 }
 
-func (m *mockUserRepository) GetUserByEmail(user *model.User, email string) error {
+func (m *mockUserRepository) GetUserById(ctx context.Context, user *model.User, userId uint) error {
+	args := m.Called(userId)
+	if userArg, ok := args.Get(0).(*model.User); ok && userArg != nil {
+		*user = *userArg
+	}
+	return args.Error(1)
+}
+
+func (m *mockUserRepository) MarkVerificationSent(ctx context.Context, userId uint, sentAt time.Time, notAfter time.Time) error {
+	args := m.Called(userId, sentAt, notAfter)
+	return args.Error(0)
+}
+
+func (m *mockUserRepository) ClearVerificationSent(ctx context.Context, userId uint) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
+
+func (m *mockUserRepository) VerifyEmail(ctx context.Context, userId uint, email string, verifiedAt time.Time) error {
+	args := m.Called(userId, email, verifiedAt)
+	return args.Error(0)
+}
+
+func (m *mockUserRepository) UpdatePassword(ctx context.Context, userId uint, hash string) error {
+	args := m.Called(userId, hash)
+	return args.Error(0)
+}
+
+func (m *mockUserRepository) SetPendingEmail(ctx context.Context, userId uint, email string) error {
+	args := m.Called(userId, email)
+	return args.Error(0)
+}
+
+func (m *mockUserRepository) ScheduleDeletion(ctx context.Context, userId uint, at time.Time) error {
+	args := m.Called(userId, at)
+	return args.Error(0)
+}
+
+func (m *mockUserRepository) CancelDeletion(ctx context.Context, userId uint) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
+
+func (m *mockUserRepository) DeleteUsersScheduledBefore(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockUserRepository) GetUserByEmail(ctx context.Context, user *model.User, email string) error {
 	args := m.Called(user, email)
 	if userArg, ok := args.Get(0).(*model.User); ok && userArg != nil {
 		*user = *userArg
@@ -76,3 +206,315 @@ func (m *mockUserRepository) GetUserByEmail(user *model.User, email string) erro
 	}
 	return args.Error(1)
 }
+
+type mockTagRepository struct {
+	mock.Mock
+}
+
+func newMockTagRepository() repository.ITagRepository {
+	return &mockTagRepository{}
+}
+
+func (m *mockTagRepository) GetAllTags(ctx context.Context, tags *[]model.TagResponse, userId uint) error {
+	args := m.Called(tags, userId)
+	if tagArg, ok := args.Get(0).(*[]model.TagResponse); ok && tagArg != nil {
+		*tags = *tagArg
+	}
+	return args.Error(1)
+}
+
+func (m *mockTagRepository) GetTagById(ctx context.Context, tag *model.TagResponse, userId uint, tagId uint) error {
+	args := m.Called(userId, tagId)
+	if tagArg, ok := args.Get(0).(*model.TagResponse); ok && tagArg != nil {
+		*tag = *tagArg
+	}
+	return args.Error(1)
+}
+
+func (m *mockTagRepository) RenameTag(ctx context.Context, userId uint, tagId uint, name string) error {
+	args := m.Called(userId, tagId, name)
+	return args.Error(0)
+}
+
+func (m *mockTagRepository) MergeTags(ctx context.Context, userId uint, sourceId uint, targetId uint) error {
+	args := m.Called(userId, sourceId, targetId)
+	return args.Error(0)
+}
+
+type mockNotebookRepository struct {
+	mock.Mock
+}
+
+func newMockNotebookRepository() repository.INotebookRepository {
+	return &mockNotebookRepository{}
+}
+
+func (m *mockNotebookRepository) GetAllNotebooks(ctx context.Context, notebooks *[]model.Notebook, userId uint) error {
+	args := m.Called(notebooks, userId)
+	if notebookArg, ok := args.Get(0).(*[]model.Notebook); ok && notebookArg != nil {
+		*notebooks = *notebookArg
+	}
+	return args.Error(1)
+}
+
+func (m *mockNotebookRepository) GetNotebookById(ctx context.Context, notebook *model.Notebook, userId uint, notebookId uint) error {
+	args := m.Called(userId, notebookId)
+	if notebookArg, ok := args.Get(0).(*model.Notebook); ok && notebookArg != nil {
+		*notebook = *notebookArg
+	}
+	return args.Error(1)
+}
+
+func (m *mockNotebookRepository) GetNotebookPath(ctx context.Context, path *[]model.Notebook, userId uint, notebookId uint) error {
+	args := m.Called(userId, notebookId)
+	if pathArg, ok := args.Get(0).(*[]model.Notebook); ok && pathArg != nil {
+		*path = *pathArg
+	}
+	return args.Error(1)
+}
+
+func (m *mockNotebookRepository) GetSubtreeHeight(ctx context.Context, height *int, userId uint, notebookId uint) error {
+	args := m.Called(userId, notebookId)
+	*height = args.Int(0)
+	return args.Error(1)
+}
+
+func (m *mockNotebookRepository) CreateNotebook(ctx context.Context, notebook *model.Notebook) error {
+	args := m.Called(notebook)
+	return args.Error(0)
+}
+
+func (m *mockNotebookRepository) UpdateNotebook(ctx context.Context, notebook *model.Notebook, userId uint, notebookId uint) error {
+	args := m.Called(notebook, userId, notebookId)
+	return args.Error(0)
+}
+
+func (m *mockNotebookRepository) DeleteNotebook(ctx context.Context, userId uint, notebookId uint, policy string) error {
+	args := m.Called(userId, notebookId, policy)
+	return args.Error(0)
+}
+
+type mockRefreshTokenRepository struct {
+	mock.Mock
+}
+
+func newMockRefreshTokenRepository() repository.IRefreshTokenRepository {
+	return &mockRefreshTokenRepository{}
+}
+
+func (m *mockRefreshTokenRepository) GetRefreshTokenByHash(ctx context.Context, token *model.RefreshToken, hash string) error {
+	args := m.Called(hash)
+	if tokenArg, ok := args.Get(0).(*model.RefreshToken); ok && tokenArg != nil {
+		*token = *tokenArg
+	}
+	return args.Error(1)
+}
+
+func (m *mockRefreshTokenRepository) CreateRefreshToken(ctx context.Context, token *model.RefreshToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *mockRefreshTokenRepository) RotateRefreshToken(ctx context.Context, used *model.RefreshToken, next *model.RefreshToken) error {
+	args := m.Called(used, next)
+	return args.Error(0)
+}
+
+type mockSessionRepository struct {
+	mock.Mock
+}
+
+func newMockSessionRepository() repository.ISessionRepository {
+	return &mockSessionRepository{}
+}
+
+func (m *mockSessionRepository) GetSessionsByUser(ctx context.Context, sessions *[]model.Session, userId uint) error {
+	args := m.Called(sessions, userId)
+	if sessionArg, ok := args.Get(0).(*[]model.Session); ok && sessionArg != nil {
+		*sessions = *sessionArg
+	}
+	return args.Error(1)
+}
+
+func (m *mockSessionRepository) GetSessionById(ctx context.Context, session *model.Session, userId uint, sessionId string) error {
+	args := m.Called(userId, sessionId)
+	if sessionArg, ok := args.Get(0).(*model.Session); ok && sessionArg != nil {
+		*session = *sessionArg
+	}
+	return args.Error(1)
+}
+
+func (m *mockSessionRepository) CreateSession(ctx context.Context, session *model.Session) error {
+	args := m.Called(session)
+	return args.Error(0)
+}
+
+func (m *mockSessionRepository) TouchSession(ctx context.Context, sessionId string, seenAt time.Time) error {
+	args := m.Called(sessionId, seenAt)
+	return args.Error(0)
+}
+
+func (m *mockSessionRepository) RevokeSession(ctx context.Context, userId uint, sessionId string) error {
+	args := m.Called(userId, sessionId)
+	return args.Error(0)
+}
+
+func (m *mockSessionRepository) RevokeUserSessions(ctx context.Context, userId uint) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
+
+func (m *mockSessionRepository) RevokeOtherSessions(ctx context.Context, userId uint, keepId string) error {
+	args := m.Called(userId, keepId)
+	return args.Error(0)
+}
+
+type mockPasswordResetRepository struct {
+	mock.Mock
+}
+
+func newMockPasswordResetRepository() repository.IPasswordResetRepository {
+	return &mockPasswordResetRepository{}
+}
+
+func (m *mockPasswordResetRepository) GetPasswordResetTokenByHash(ctx context.Context, token *model.PasswordResetToken, hash string) error {
+	args := m.Called(hash)
+	if tokenArg, ok := args.Get(0).(*model.PasswordResetToken); ok && tokenArg != nil {
+		*token = *tokenArg
+	}
+	return args.Error(1)
+}
+
+func (m *mockPasswordResetRepository) CreatePasswordResetToken(ctx context.Context, token *model.PasswordResetToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *mockPasswordResetRepository) ResetPassword(ctx context.Context, token *model.PasswordResetToken, passwordHash string) error {
+	args := m.Called(token, passwordHash)
+	return args.Error(0)
+}
+
+type mockMFARepository struct {
+	mock.Mock
+}
+
+func newMockMFARepository() repository.IMFARepository {
+	return &mockMFARepository{}
+}
+
+func (m *mockMFARepository) GetTOTPCredential(ctx context.Context, credential *model.TOTPCredential, userId uint) error {
+	args := m.Called(userId)
+	if credentialArg, ok := args.Get(0).(*model.TOTPCredential); ok && credentialArg != nil {
+		*credential = *credentialArg
+	}
+	return args.Error(1)
+}
+
+func (m *mockMFARepository) SaveTOTPCredential(ctx context.Context, credential *model.TOTPCredential) error {
+	args := m.Called(credential)
+	return args.Error(0)
+}
+
+func (m *mockMFARepository) ConfirmTOTPCredential(ctx context.Context, userId uint, step int64, codeHashes []string) error {
+	args := m.Called(userId, step, codeHashes)
+	return args.Error(0)
+}
+
+func (m *mockMFARepository) UseTOTPStep(ctx context.Context, userId uint, step int64) error {
+	args := m.Called(userId, step)
+	return args.Error(0)
+}
+
+func (m *mockMFARepository) UseRecoveryCode(ctx context.Context, userId uint, codeHash string) error {
+	args := m.Called(userId, codeHash)
+	return args.Error(0)
+}
+
+func (m *mockMFARepository) DeleteTOTPCredential(ctx context.Context, userId uint) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
+
+type mockAccessTokenRepository struct {
+	mock.Mock
+}
+
+func newMockAccessTokenRepository() repository.IAccessTokenRepository {
+	return &mockAccessTokenRepository{}
+}
+
+func (m *mockAccessTokenRepository) GetAccessTokensByUser(ctx context.Context, tokens *[]model.AccessToken, userId uint) error {
+	args := m.Called(tokens, userId)
+	if tokenArg, ok := args.Get(0).(*[]model.AccessToken); ok && tokenArg != nil {
+		*tokens = *tokenArg
+	}
+	return args.Error(1)
+}
+
+func (m *mockAccessTokenRepository) GetAccessTokenByHash(ctx context.Context, token *model.AccessToken, hash string) error {
+	args := m.Called(hash)
+	if tokenArg, ok := args.Get(0).(*model.AccessToken); ok && tokenArg != nil {
+		*token = *tokenArg
+	}
+	return args.Error(1)
+}
+
+func (m *mockAccessTokenRepository) CreateAccessToken(ctx context.Context, token *model.AccessToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *mockAccessTokenRepository) TouchAccessToken(ctx context.Context, tokenId uint, usedAt time.Time) error {
+	args := m.Called(tokenId, usedAt)
+	return args.Error(0)
+}
+
+func (m *mockAccessTokenRepository) DeleteAccessToken(ctx context.Context, userId uint, tokenId uint) error {
+	args := m.Called(userId, tokenId)
+	return args.Error(0)
+}
+
+type mockIdentityRepository struct {
+	mock.Mock
+}
+
+func newMockIdentityRepository() repository.IIdentityRepository {
+	return &mockIdentityRepository{}
+}
+
+func (m *mockIdentityRepository) GetIdentity(ctx context.Context, identity *model.UserIdentity, provider string, subject string) error {
+	args := m.Called(provider, subject)
+	if identityArg, ok := args.Get(0).(*model.UserIdentity); ok && identityArg != nil {
+		*identity = *identityArg
+	}
+	return args.Error(1)
+}
+
+func (m *mockIdentityRepository) CreateIdentity(ctx context.Context, identity *model.UserIdentity) error {
+	args := m.Called(identity)
+	return args.Error(0)
+}
+
+func (m *mockIdentityRepository) CreateUserWithIdentity(ctx context.Context, user *model.User, identity *model.UserIdentity) error {
+	args := m.Called(user, identity)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	user.ID = 10
+	identity.UserId = user.ID
+	return nil
+}
+
+type mockLoginLockoutRepository struct {
+	mock.Mock
+}
+
+func newMockLoginLockoutRepository() repository.ILoginLockoutRepository {
+	return &mockLoginLockoutRepository{}
+}
+
+func (m *mockLoginLockoutRepository) CreateLoginLockout(lockout *model.LoginLockout) error {
+	args := m.Called(lockout)
+	return args.Error(0)
+}
@@ -0,0 +1,184 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"echo-rest-api/apperror"
+	"echo-rest-api/keyset"
+	"echo-rest-api/model"
+	"echo-rest-api/repository"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	DefaultAccessTokenTTL  = 15 * time.Minute
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+type ITokenUsecase interface {
+	IssueTokens(ctx context.Context, userId uint, client model.SessionClient) (model.TokenPair, error)
+	RefreshTokens(ctx context.Context, refreshToken string) (model.TokenPair, error)
+	RevokeTokens(ctx context.Context, refreshToken string) error
+}
+
+type tokenUsecase struct {
+	rr         repository.IRefreshTokenRepository
+	sr         repository.ISessionRepository
+	keys       keyset.IKeySet
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+// NewTokenUsecase issues access tokens signed by keys and valid for
+// accessTTL, and refresh tokens valid for refreshTTL. Non-positive values fall
+// back to the defaults.
+func NewTokenUsecase(rr repository.IRefreshTokenRepository, sr repository.ISessionRepository, keys keyset.IKeySet, accessTTL time.Duration, refreshTTL time.Duration) ITokenUsecase {
+	if accessTTL <= 0 {
+		accessTTL = DefaultAccessTokenTTL
+	}
+	if refreshTTL <= 0 {
+		refreshTTL = DefaultRefreshTokenTTL
+	}
+	return &tokenUsecase{rr, sr, keys, accessTTL, refreshTTL}
+}
+
+// IssueTokens starts a new session for a user that just logged in.
+func (tu *tokenUsecase) IssueTokens(ctx context.Context, userId uint, client model.SessionClient) (model.TokenPair, error) {
+	sessionId, err := randomSessionId()
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+	pair, refresh, err := tu.newTokenPair(userId, sessionId)
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+	session := model.Session{
+		ID:         sessionId,
+		UserId:     userId,
+		UserAgent:  client.UserAgent,
+		IP:         client.IP,
+		LastSeenAt: time.Now(),
+		ExpiresAt:  pair.RefreshExpiresAt,
+	}
+	if err := tu.sr.CreateSession(ctx, &session); err != nil {
+		return model.TokenPair{}, err
+	}
+	if err := tu.rr.CreateRefreshToken(ctx, &refresh); err != nil {
+		return model.TokenPair{}, err
+	}
+	return pair, nil
+}
+
+// RefreshTokens exchanges a refresh token for a new pair. Presenting a token
+// that was already rotated means it has leaked, so the whole session is
+// revoked and the legitimate holder has to log in again.
+func (tu *tokenUsecase) RefreshTokens(ctx context.Context, refreshToken string) (model.TokenPair, error) {
+	stored := model.RefreshToken{}
+	if err := tu.rr.GetRefreshTokenByHash(ctx, &stored, hashToken(refreshToken)); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return model.TokenPair{}, apperror.New(apperror.ErrUnauthorized, "invalid refresh token")
+		}
+		return model.TokenPair{}, err
+	}
+	if stored.UsedAt != nil {
+		return model.TokenPair{}, tu.revokeReused(ctx, stored)
+	}
+	if !time.Now().Before(stored.ExpiresAt) {
+		return model.TokenPair{}, apperror.New(apperror.ErrUnauthorized, "invalid refresh token")
+	}
+	if err := tu.sr.GetSessionById(ctx, &model.Session{}, stored.UserId, stored.SessionId); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return model.TokenPair{}, apperror.New(apperror.ErrUnauthorized, "session has been revoked")
+		}
+		return model.TokenPair{}, err
+	}
+
+	pair, next, err := tu.newTokenPair(stored.UserId, stored.SessionId)
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+	if err := tu.rr.RotateRefreshToken(ctx, &stored, &next); err != nil {
+		if errors.Is(err, apperror.ErrConflict) {
+			return model.TokenPair{}, tu.revokeReused(ctx, stored)
+		}
+		return model.TokenPair{}, err
+	}
+	return pair, nil
+}
+
+func (tu *tokenUsecase) RevokeTokens(ctx context.Context, refreshToken string) error {
+	stored := model.RefreshToken{}
+	if err := tu.rr.GetRefreshTokenByHash(ctx, &stored, hashToken(refreshToken)); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	if err := tu.sr.RevokeSession(ctx, stored.UserId, stored.SessionId); err != nil && !errors.Is(err, apperror.ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (tu *tokenUsecase) revokeReused(ctx context.Context, stored model.RefreshToken) error {
+	if err := tu.sr.RevokeSession(ctx, stored.UserId, stored.SessionId); err != nil && !errors.Is(err, apperror.ErrNotFound) {
+		return err
+	}
+	return apperror.New(apperror.ErrUnauthorized, "refresh token reused")
+}
+
+func (tu *tokenUsecase) newTokenPair(userId uint, sessionId string) (model.TokenPair, model.RefreshToken, error) {
+	now := time.Now()
+	pair := model.TokenPair{
+		AccessExpiresAt:  now.Add(tu.accessTTL),
+		RefreshExpiresAt: now.Add(tu.refreshTTL),
+	}
+	var err error
+	pair.AccessToken, err = tu.keys.Sign(jwt.MapClaims{
+		"user_id": userId,
+		"jti":     sessionId,
+		"exp":     pair.AccessExpiresAt.Unix(),
+	})
+	if err != nil {
+		return model.TokenPair{}, model.RefreshToken{}, err
+	}
+	pair.RefreshToken, err = randomToken()
+	if err != nil {
+		return model.TokenPair{}, model.RefreshToken{}, err
+	}
+	refresh := model.RefreshToken{
+		SessionId: sessionId,
+		UserId:    userId,
+		TokenHash: hashToken(pair.RefreshToken),
+		ExpiresAt: pair.RefreshExpiresAt,
+	}
+	return pair, refresh, nil
+}
+
+func randomSessionId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// randomToken returns 256 random bits encoded for use in cookies and URLs.
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,117 @@
+package usecase
+
+import (
+	"context"
+	"echo-rest-api/apperror"
+	"echo-rest-api/keyset"
+	"echo-rest-api/model"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestIssueTokens(t *testing.T) {
+	var sessionId string
+	refreshRepository := newMockRefreshTokenRepository()
+	sessionRepository := newMockSessionRepository()
+	sessionRepository.(*mockSessionRepository).On("CreateSession", mock.MatchedBy(func(session *model.Session) bool {
+		sessionId = session.ID
+		return session.UserId == 1 && session.UserAgent == "test-agent" && session.IP == "192.0.2.1"
+	})).Return(nil)
+	refreshRepository.(*mockRefreshTokenRepository).On("CreateRefreshToken", mock.MatchedBy(func(token *model.RefreshToken) bool {
+		return token.UserId == 1 && token.SessionId == sessionId && token.TokenHash != ""
+	})).Return(nil)
+
+	keys := keyset.NewHMACKeySet([]byte("secret"))
+	usecase := NewTokenUsecase(refreshRepository, sessionRepository, keys, time.Minute, time.Hour)
+	pair, err := usecase.IssueTokens(context.Background(), 1, model.SessionClient{UserAgent: "test-agent", IP: "192.0.2.1"})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, pair.RefreshToken)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), pair.AccessExpiresAt, time.Second)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), pair.RefreshExpiresAt, time.Second)
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(pair.AccessToken, claims, keys.Keyfunc)
+	assert.Nil(t, err)
+	assert.Equal(t, sessionId, claims["jti"])
+	refreshRepository.(*mockRefreshTokenRepository).AssertExpectations(t)
+	sessionRepository.(*mockSessionRepository).AssertExpectations(t)
+}
+
+func TestRefreshTokens(t *testing.T) {
+	stored := model.RefreshToken{ID: 1, SessionId: "session", UserId: 1, ExpiresAt: time.Now().Add(time.Hour)}
+	refreshRepository := newMockRefreshTokenRepository()
+	refreshRepository.(*mockRefreshTokenRepository).On("GetRefreshTokenByHash", hashToken("refresh")).Return(&stored, nil)
+	refreshRepository.(*mockRefreshTokenRepository).On("RotateRefreshToken", mock.Anything, mock.MatchedBy(func(next *model.RefreshToken) bool {
+		return next.SessionId == "session" && next.TokenHash != hashToken("refresh")
+	})).Return(nil)
+	sessionRepository := newMockSessionRepository()
+	sessionRepository.(*mockSessionRepository).On("GetSessionById", uint(1), "session").Return(&model.Session{ID: "session"}, nil)
+
+	usecase := NewTokenUsecase(refreshRepository, sessionRepository, keyset.NewHMACKeySet([]byte("secret")), 0, 0)
+	pair, err := usecase.RefreshTokens(context.Background(), "refresh")
+	assert.Nil(t, err)
+	assert.NotEqual(t, "refresh", pair.RefreshToken)
+	refreshRepository.(*mockRefreshTokenRepository).AssertExpectations(t)
+	sessionRepository.(*mockSessionRepository).AssertExpectations(t)
+}
+
+func TestRefreshTokens_Reused(t *testing.T) {
+	usedAt := time.Now().Add(-time.Minute)
+	stored := model.RefreshToken{ID: 1, SessionId: "session", UserId: 1, ExpiresAt: time.Now().Add(time.Hour), UsedAt: &usedAt}
+	refreshRepository := newMockRefreshTokenRepository()
+	refreshRepository.(*mockRefreshTokenRepository).On("GetRefreshTokenByHash", hashToken("refresh")).Return(&stored, nil)
+	sessionRepository := newMockSessionRepository()
+	sessionRepository.(*mockSessionRepository).On("RevokeSession", uint(1), "session").Return(nil)
+
+	usecase := NewTokenUsecase(refreshRepository, sessionRepository, keyset.NewHMACKeySet([]byte("secret")), 0, 0)
+	_, err := usecase.RefreshTokens(context.Background(), "refresh")
+	assert.ErrorIs(t, err, apperror.ErrUnauthorized)
+	sessionRepository.(*mockSessionRepository).AssertExpectations(t)
+	refreshRepository.(*mockRefreshTokenRepository).AssertNotCalled(t, "RotateRefreshToken")
+}
+
+func TestRefreshTokens_Race(t *testing.T) {
+	stored := model.RefreshToken{ID: 1, SessionId: "session", UserId: 1, ExpiresAt: time.Now().Add(time.Hour)}
+	refreshRepository := newMockRefreshTokenRepository()
+	refreshRepository.(*mockRefreshTokenRepository).On("GetRefreshTokenByHash", hashToken("refresh")).Return(&stored, nil)
+	refreshRepository.(*mockRefreshTokenRepository).On("RotateRefreshToken", mock.Anything, mock.Anything).
+		Return(apperror.New(apperror.ErrConflict, "refresh token already used"))
+	sessionRepository := newMockSessionRepository()
+	sessionRepository.(*mockSessionRepository).On("GetSessionById", uint(1), "session").Return(&model.Session{ID: "session"}, nil)
+	sessionRepository.(*mockSessionRepository).On("RevokeSession", uint(1), "session").Return(nil)
+
+	usecase := NewTokenUsecase(refreshRepository, sessionRepository, keyset.NewHMACKeySet([]byte("secret")), 0, 0)
+	_, err := usecase.RefreshTokens(context.Background(), "refresh")
+	assert.ErrorIs(t, err, apperror.ErrUnauthorized)
+	sessionRepository.(*mockSessionRepository).AssertExpectations(t)
+}
+
+func TestRefreshTokens_RevokedSession(t *testing.T) {
+	stored := model.RefreshToken{ID: 1, SessionId: "session", UserId: 1, ExpiresAt: time.Now().Add(time.Hour)}
+	refreshRepository := newMockRefreshTokenRepository()
+	refreshRepository.(*mockRefreshTokenRepository).On("GetRefreshTokenByHash", hashToken("refresh")).Return(&stored, nil)
+	sessionRepository := newMockSessionRepository()
+	sessionRepository.(*mockSessionRepository).On("GetSessionById", uint(1), "session").
+		Return(nil, apperror.New(apperror.ErrNotFound, "session does not exist"))
+
+	usecase := NewTokenUsecase(refreshRepository, sessionRepository, keyset.NewHMACKeySet([]byte("secret")), 0, 0)
+	_, err := usecase.RefreshTokens(context.Background(), "refresh")
+	assert.ErrorIs(t, err, apperror.ErrUnauthorized)
+	refreshRepository.(*mockRefreshTokenRepository).AssertNotCalled(t, "RotateRefreshToken")
+}
+
+func TestRefreshTokens_Expired(t *testing.T) {
+	stored := model.RefreshToken{ID: 1, SessionId: "session", UserId: 1, ExpiresAt: time.Now().Add(-time.Hour)}
+	refreshRepository := newMockRefreshTokenRepository()
+	refreshRepository.(*mockRefreshTokenRepository).On("GetRefreshTokenByHash", hashToken("refresh")).Return(&stored, nil)
+	sessionRepository := newMockSessionRepository()
+
+	usecase := NewTokenUsecase(refreshRepository, sessionRepository, keyset.NewHMACKeySet([]byte("secret")), 0, 0)
+	_, err := usecase.RefreshTokens(context.Background(), "refresh")
+	assert.ErrorIs(t, err, apperror.ErrUnauthorized)
+	sessionRepository.(*mockSessionRepository).AssertNotCalled(t, "RevokeSession")
+}
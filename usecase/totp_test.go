@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B, SHA1, truncated to six digits.
+	secret := []byte("12345678901234567890")
+	cases := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, code := range cases {
+		assert.Equal(t, code, totpCode(secret, unix/totpPeriod))
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111111, 0)
+
+	step, ok := matchTOTP(secret, "050471", now)
+	assert.True(t, ok)
+	assert.Equal(t, int64(1111111111/totpPeriod), step)
+	_, ok = matchTOTP(secret, "050471", now.Add(totpPeriod*time.Second))
+	assert.True(t, ok)
+	_, ok = matchTOTP(secret, "050471", now.Add(2*totpPeriod*time.Second))
+	assert.False(t, ok)
+	_, ok = matchTOTP(secret, "50471", now)
+	assert.False(t, ok)
+	_, ok = matchTOTP("not base32!", "050471", now)
+	assert.False(t, ok)
+}
+
+func TestOtpauthURI(t *testing.T) {
+	uri, err := url.Parse(otpauthURI("echo memo", "user@example.com", "JBSWY3DPEHPK3PXP"))
+	assert.Nil(t, err)
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/echo memo:user@example.com", uri.Path)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", uri.Query().Get("secret"))
+	assert.Equal(t, "echo memo", uri.Query().Get("issuer"))
+}
+
+func TestNewRecoveryCodes(t *testing.T) {
+	codes, hashes, err := newRecoveryCodes()
+	assert.Nil(t, err)
+	assert.Equal(t, recoveryCodeCount, len(codes))
+	assert.Regexp(t, `^[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}$`, codes[0])
+	assert.Equal(t, hashes[0], hashToken(normalizeRecoveryCode(codes[0])))
+	assert.Equal(t, hashes[1], hashToken(normalizeRecoveryCode(" "+codes[1]+" ")))
+}
@@ -0,0 +1,60 @@
+package usecase
+
+import (
+	"context"
+	"echo-rest-api/repository"
+	"log/slog"
+	"time"
+)
+
+const (
+	DefaultTrashRetention     = 30 * 24 * time.Hour
+	DefaultTrashSweepInterval = time.Hour
+)
+
+type ITrashSweeper interface {
+	Run(ctx context.Context)
+	Sweep(ctx context.Context) (int64, error)
+}
+
+type trashSweeper struct {
+	mr        repository.IMemoRepository
+	retention time.Duration
+	interval  time.Duration
+	now       func() time.Time
+}
+
+// NewTrashSweeper returns a sweeper that permanently deletes memos which have
+// been in the trash for longer than retention, checking every interval.
+// Non-positive values fall back to the defaults.
+func NewTrashSweeper(mr repository.IMemoRepository, retention time.Duration, interval time.Duration) ITrashSweeper {
+	if retention <= 0 {
+		retention = DefaultTrashRetention
+	}
+	if interval <= 0 {
+		interval = DefaultTrashSweepInterval
+	}
+	return &trashSweeper{mr, retention, interval, time.Now}
+}
+
+// Run sweeps once immediately and then on every tick until ctx is done.
+func (ts *trashSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(ts.interval)
+	defer ticker.Stop()
+	for {
+		if purged, err := ts.Sweep(ctx); err != nil {
+			slog.Error("trash sweeper: sweep failed", "error", err)
+		} else if purged > 0 {
+			slog.Info("trash sweeper: purged memos", "count", purged)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (ts *trashSweeper) Sweep(ctx context.Context) (int64, error) {
+	return ts.mr.PurgeMemosDeletedBefore(ctx, ts.now().Add(-ts.retention))
+}
@@ -0,0 +1,28 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrashSweeper_Sweep(t *testing.T) {
+	now := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	mockRepository := newMockMemoRepository()
+	mockRepository.(*mockMemoRepository).On("PurgeMemosDeletedBefore", now.Add(-24*time.Hour)).Return(int64(2), nil)
+
+	sweeper := NewTrashSweeper(mockRepository, 24*time.Hour, time.Minute).(*trashSweeper)
+	sweeper.now = func() time.Time { return now }
+	purged, err := sweeper.Sweep(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), purged)
+	mockRepository.(*mockMemoRepository).AssertExpectations(t)
+}
+
+func TestNewTrashSweeper_Defaults(t *testing.T) {
+	sweeper := NewTrashSweeper(nil, 0, 0).(*trashSweeper)
+	assert.Equal(t, DefaultTrashRetention, sweeper.retention)
+	assert.Equal(t, DefaultTrashSweepInterval, sweeper.interval)
+}
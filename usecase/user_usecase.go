@@ -1,40 +1,54 @@
 package usecase
 
 import (
+	"context"
+	"echo-rest-api/apperror"
 	"echo-rest-api/model"
 	"echo-rest-api/repository"
 	"echo-rest-api/validator"
-	"os"
+	"errors"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// mfaChallengeTTL is how long a user has to enter the second factor after
+// the password step of a login.
+const mfaChallengeTTL = 5 * time.Minute
+
+const mfaChallengePurpose = "mfa-challenge"
+
 type IUserUsecase interface {
-	SignUp(user model.User) (model.UserResponse, error)
-	Login(user model.User) (string, error)
+	SignUp(ctx context.Context, user model.User) (model.UserResponse, error)
+	Login(ctx context.Context, user model.User) (model.LoginResponse, error)
+	LoginMFA(ctx context.Context, token string, code string) (model.UserResponse, error)
+	ParseMFAChallenge(token string) (model.MFAChallenge, error)
 }
 
 type userUsecase struct {
 	ur repository.IUserRepository
 	uv validator.IUserValidator
+	mr repository.IMFARepository
+	// secret keys the MFA challenge tokens.
+	secret []byte
 }
 
-func NewUserUsecase(ur repository.IUserRepository, uv validator.IUserValidator) IUserUsecase {
-	return &userUsecase{ur, uv}
+func NewUserUsecase(ur repository.IUserRepository, uv validator.IUserValidator, mr repository.IMFARepository, secret []byte) IUserUsecase {
+	return &userUsecase{ur, uv, mr, secret}
 }
 
-func (uu *userUsecase) SignUp(user model.User) (model.UserResponse, error) {
+func (uu *userUsecase) SignUp(ctx context.Context, user model.User) (model.UserResponse, error) {
 	if err := uu.uv.UserValidate(user); err != nil {
-		return model.UserResponse{}, err
+		return model.UserResponse{}, apperror.Wrap(apperror.ErrValidation, err)
 	}
-	hash, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	hash, err := hashPassword(user.Password)
 	if err != nil {
 		return model.UserResponse{}, err
 	}
-	newUser := model.User{Email: user.Email, Password: string(hash)}
-	if err := uu.ur.CreateUser(&newUser); err != nil {
+	newUser := model.User{Email: user.Email, Password: hash}
+	if err := uu.ur.CreateUser(ctx, &newUser); err != nil {
 		return model.UserResponse{}, err
 	}
 
@@ -45,25 +59,120 @@ func (uu *userUsecase) SignUp(user model.User) (model.UserResponse, error) {
 	return resUser, nil
 }
 
-func (uu *userUsecase) Login(user model.User) (string, error) {
+// Login checks the credentials. Tokens are issued separately by
+// ITokenUsecase, and only once the MFA challenge is answered when the
+// response says MFARequired.
+func (uu *userUsecase) Login(ctx context.Context, user model.User) (model.LoginResponse, error) {
 	if err := uu.uv.UserValidate(user); err != nil {
-		return "", err
+		return model.LoginResponse{}, apperror.Wrap(apperror.ErrValidation, err)
 	}
 	storedUser := model.User{}
-	if err := uu.ur.GetUserByEmail(&storedUser, user.Email); err != nil {
-		return "", err
+	if err := uu.ur.GetUserByEmail(ctx, &storedUser, user.Email); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return model.LoginResponse{}, apperror.New(apperror.ErrUnauthorized, "invalid email or password")
+		}
+		return model.LoginResponse{}, err
 	}
 	err := bcrypt.CompareHashAndPassword([]byte(storedUser.Password), []byte(user.Password))
 	if err != nil {
-		return "", err
+		return model.LoginResponse{}, apperror.New(apperror.ErrUnauthorized, "invalid email or password")
+	}
+	return newLoginResponse(ctx, uu.mr, uu.secret, storedUser)
+}
+
+// LoginMFA completes a login that returned an MFA challenge, accepting a
+// TOTP code or a recovery code.
+func (uu *userUsecase) LoginMFA(ctx context.Context, token string, code string) (model.UserResponse, error) {
+	challenge, err := uu.ParseMFAChallenge(token)
+	if err != nil {
+		return model.UserResponse{}, err
+	}
+	credential := model.TOTPCredential{}
+	if err := uu.mr.GetTOTPCredential(ctx, &credential, challenge.UserId); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return model.UserResponse{}, apperror.New(apperror.ErrUnauthorized, "invalid mfa token")
+		}
+		return model.UserResponse{}, err
+	}
+	if credential.ConfirmedAt == nil {
+		return model.UserResponse{}, apperror.New(apperror.ErrUnauthorized, "invalid mfa token")
+	}
+	if err := verifySecondFactor(ctx, uu.mr, credential, code); err != nil {
+		return model.UserResponse{}, err
+	}
+	storedUser := model.User{}
+	if err := uu.ur.GetUserById(ctx, &storedUser, challenge.UserId); err != nil {
+		return model.UserResponse{}, err
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": storedUser.ID,
-		"exp":     time.Now().Add(time.Hour * 12).Unix(),
-	})
-	tokenString, err := token.SignedString([]byte(os.Getenv("SECRET")))
+	return newUserResponse(storedUser), nil
+}
+
+// ParseMFAChallenge checks an MFA challenge token without answering it.
+func (uu *userUsecase) ParseMFAChallenge(token string) (model.MFAChallenge, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return purposeKey(uu.secret, mfaChallengePurpose), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return model.MFAChallenge{}, apperror.New(apperror.ErrUnauthorized, "invalid mfa token")
+	}
+	sub, _ := claims["sub"].(string)
+	userId, err := strconv.ParseUint(sub, 10, 0)
+	id, _ := claims["jti"].(string)
+	if err != nil || id == "" {
+		return model.MFAChallenge{}, apperror.New(apperror.ErrUnauthorized, "invalid mfa token")
+	}
+	return model.MFAChallenge{ID: id, UserId: uint(userId)}, nil
+}
+
+// newLoginResponse answers a successful first login step. Users with a
+// confirmed TOTP credential get an MFA challenge instead of their details.
+func newLoginResponse(ctx context.Context, mr repository.IMFARepository, secret []byte, user model.User) (model.LoginResponse, error) {
+	res := model.LoginResponse{UserResponse: newUserResponse(user)}
+	credential := model.TOTPCredential{}
+	if err := mr.GetTOTPCredential(ctx, &credential, user.ID); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return res, nil
+		}
+		return model.LoginResponse{}, err
+	}
+	if credential.ConfirmedAt == nil {
+		return res, nil
+	}
+	id, err := randomSessionId()
+	if err != nil {
+		return model.LoginResponse{}, err
+	}
+	expiresAt := time.Now().Add(mfaChallengeTTL)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"jti": id,
+		"sub": strconv.FormatUint(uint64(user.ID), 10),
+		"exp": expiresAt.Unix(),
+	}).SignedString(purposeKey(secret, mfaChallengePurpose))
+	if err != nil {
+		return model.LoginResponse{}, err
+	}
+	return model.LoginResponse{
+		MFARequired:  true,
+		MFAToken:     token,
+		MFAExpiresAt: &expiresAt,
+	}, nil
+}
+
+func newUserResponse(user model.User) model.UserResponse {
+	return model.UserResponse{
+		ID:                  user.ID,
+		Email:               user.Email,
+		EmailVerified:       user.EmailVerifiedAt != nil,
+		PendingEmail:        user.PendingEmail,
+		DeletionScheduledAt: user.DeletionScheduledAt,
+	}
+}
+
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return "", err
 	}
-	return tokenString, nil
+	return string(hash), nil
 }
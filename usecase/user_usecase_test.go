@@ -1,11 +1,15 @@
 package usecase
 
 import (
+	"context"
+	"echo-rest-api/apperror"
 	"echo-rest-api/model"
 	"echo-rest-api/validator"
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"gorm.io/gorm"
@@ -19,9 +23,9 @@ func TestSignUp(t *testing.T) {
 	mockRepository := newMockUserRepository()
 	mockRepository.(*mockUserRepository).On("CreateUser", mock.AnythingOfType("*model.User")).Return(nil)
 	validator := validator.NewUserValidator()
-	usecase := NewUserUsecase(mockRepository, validator)
+	usecase := NewUserUsecase(mockRepository, validator, nil, testSecret)
 
-	user, err := usecase.SignUp(mockUser)
+	user, err := usecase.SignUp(context.Background(), mockUser)
 	assert.Nil(t, err)
 	assert.Equal(t, user.Email, mockUser.Email)
 	mockRepository.(*mockUserRepository).AssertExpectations(t)
@@ -35,9 +39,9 @@ func TestSignUp_Error(t *testing.T) {
 	mockRepository := newMockUserRepository()
 	mockRepository.(*mockUserRepository).On("CreateUser", mock.AnythingOfType("*model.User")).Return(errors.New("error"))
 	validator := validator.NewUserValidator()
-	usecase := NewUserUsecase(mockRepository, validator)
+	usecase := NewUserUsecase(mockRepository, validator, nil, testSecret)
 
-	user, err := usecase.SignUp(mockUser)
+	user, err := usecase.SignUp(context.Background(), mockUser)
 	assert.Error(t, err)
 	assert.Equal(t, model.UserResponse{}, user)
 	mockRepository.(*mockUserRepository).AssertExpectations(t)
@@ -47,13 +51,13 @@ func TestSignUp_Validate(t *testing.T) {
 	mockRepository := newMockUserRepository()
 	mockRepository.(*mockUserRepository).On("CreateUser", mock.AnythingOfType("*model.User")).Return(errors.New("error"))
 	validator := validator.NewUserValidator()
-	usecase := NewUserUsecase(mockRepository, validator)
+	usecase := NewUserUsecase(mockRepository, validator, nil, testSecret)
 
 	mockUser := model.User{
 		Email:    "",
 		Password: "testsignup",
 	}
-	user, err := usecase.SignUp(mockUser)
+	user, err := usecase.SignUp(context.Background(), mockUser)
 	assert.Equal(t, "email: email is required.", err.Error())
 	assert.Equal(t, model.UserResponse{}, user)
 
@@ -61,7 +65,7 @@ func TestSignUp_Validate(t *testing.T) {
 		Email:    "thisistoolongemail@toolongemail.com",
 		Password: "testsignup",
 	}
-	user, err = usecase.SignUp(mockUser)
+	user, err = usecase.SignUp(context.Background(), mockUser)
 	assert.Equal(t, "email: limited max 30 char.", err.Error())
 	assert.Equal(t, model.UserResponse{}, user)
 
@@ -69,7 +73,7 @@ func TestSignUp_Validate(t *testing.T) {
 		Email:    "testsignup",
 		Password: "testsignup",
 	}
-	user, err = usecase.SignUp(mockUser)
+	user, err = usecase.SignUp(context.Background(), mockUser)
 	assert.Equal(t, "email: invalid email format.", err.Error())
 	assert.Equal(t, model.UserResponse{}, user)
 
@@ -77,7 +81,7 @@ func TestSignUp_Validate(t *testing.T) {
 		Email:    "testsignup@example.com",
 		Password: "",
 	}
-	user, err = usecase.SignUp(mockUser)
+	user, err = usecase.SignUp(context.Background(), mockUser)
 	assert.Equal(t, "password: password is required.", err.Error())
 	assert.Equal(t, model.UserResponse{}, user)
 
@@ -85,7 +89,7 @@ func TestSignUp_Validate(t *testing.T) {
 		Email:    "testsignup@example.com",
 		Password: "12345",
 	}
-	user, err = usecase.SignUp(mockUser)
+	user, err = usecase.SignUp(context.Background(), mockUser)
 	assert.Equal(t, "password: limited min 6 max 30 char.", err.Error())
 	assert.Equal(t, model.UserResponse{}, user)
 	mockRepository.(*mockUserRepository).AssertNotCalled(t, "CreateUser")
@@ -101,13 +105,18 @@ func TestLogin(t *testing.T) {
 	}
 	mockRepository := newMockUserRepository()
 	mockRepository.(*mockUserRepository).On("GetUserByEmail", mock.AnythingOfType("*model.User"), mock.Anything).Return(&mockUser, nil)
+	mfaRepository := newMockMFARepository()
+	mfaRepository.(*mockMFARepository).On("GetTOTPCredential", uint(1)).Return(nil, apperror.New(apperror.ErrNotFound, "record not found"))
 	validator := validator.NewUserValidator()
-	usecase := NewUserUsecase(mockRepository, validator)
+	usecase := NewUserUsecase(mockRepository, validator, mfaRepository, testSecret)
 
-	token, err := usecase.Login(mockUser)
-	assert.NotEmpty(t, token)
+	userRes, err := usecase.Login(context.Background(), mockUser)
+	assert.NotEmpty(t, userRes)
+	assert.Equal(t, uint(1), userRes.ID)
+	assert.False(t, userRes.MFARequired)
 	assert.Nil(t, err)
 	mockRepository.(*mockUserRepository).AssertExpectations(t)
+	mfaRepository.(*mockMFARepository).AssertExpectations(t)
 }
 
 func TestLogin_Error(t *testing.T) {
@@ -119,54 +128,141 @@ func TestLogin_Error(t *testing.T) {
 	mockRepository.(*mockUserRepository).On("GetUserByEmail", mock.AnythingOfType("*model.User"), mock.Anything).Return(nil, errors.New("error"))
 
 	validator := validator.NewUserValidator()
-	usecase := NewUserUsecase(mockRepository, validator)
-	token, err := usecase.Login(mockUser)
-	assert.Empty(t, token)
+	usecase := NewUserUsecase(mockRepository, validator, nil, testSecret)
+	userRes, err := usecase.Login(context.Background(), mockUser)
+	assert.Empty(t, userRes)
 	assert.Error(t, err)
 	mockRepository.(*mockUserRepository).AssertExpectations(t)
 }
 
+func TestLogin_Unauthorized(t *testing.T) {
+	storedUser := model.User{
+		Email:    "testlogin@example.com",
+		Password: "testlogin",
+	}
+	mockRepository := newMockUserRepository()
+	mockRepository.(*mockUserRepository).On("GetUserByEmail", mock.AnythingOfType("*model.User"), mock.Anything).Return(&storedUser, nil)
+	validator := validator.NewUserValidator()
+	usecase := NewUserUsecase(mockRepository, validator, nil, testSecret)
+
+	userRes, err := usecase.Login(context.Background(), model.User{Email: "testlogin@example.com", Password: "wrongpassword"})
+	assert.Empty(t, userRes)
+	assert.ErrorIs(t, err, apperror.ErrUnauthorized)
+
+	mockRepository = newMockUserRepository()
+	mockRepository.(*mockUserRepository).On("GetUserByEmail", mock.AnythingOfType("*model.User"), mock.Anything).Return(nil, apperror.New(apperror.ErrNotFound, "record not found"))
+	usecase = NewUserUsecase(mockRepository, validator, nil, testSecret)
+	userRes, err = usecase.Login(context.Background(), model.User{Email: "nobody@example.com", Password: "testlogin"})
+	assert.Empty(t, userRes)
+	assert.ErrorIs(t, err, apperror.ErrUnauthorized)
+}
+
 func TestLogin_Validate(t *testing.T) {
 	validator := validator.NewUserValidator()
-	usecase := NewUserUsecase(nil, validator)
+	usecase := NewUserUsecase(nil, validator, nil, testSecret)
 
 	mockUser := model.User{
 		Email:    "",
 		Password: "testsignup",
 	}
-	token, err := usecase.Login(mockUser)
+	userRes, err := usecase.Login(context.Background(), mockUser)
 	assert.Equal(t, "email: email is required.", err.Error())
-	assert.Empty(t, token)
+	assert.Empty(t, userRes)
 
 	mockUser = model.User{
 		Email:    "thisistoolongemail@toolongemail.com",
 		Password: "testsignup",
 	}
-	token, err = usecase.Login(mockUser)
+	userRes, err = usecase.Login(context.Background(), mockUser)
 	assert.Equal(t, "email: limited max 30 char.", err.Error())
-	assert.Empty(t, token)
+	assert.Empty(t, userRes)
 
 	mockUser = model.User{
 		Email:    "testsignup",
 		Password: "testsignup",
 	}
-	token, err = usecase.Login(mockUser)
+	userRes, err = usecase.Login(context.Background(), mockUser)
 	assert.Equal(t, "email: invalid email format.", err.Error())
-	assert.Empty(t, token)
+	assert.Empty(t, userRes)
 
 	mockUser = model.User{
 		Email:    "testsignup@example.com",
 		Password: "",
 	}
-	token, err = usecase.Login(mockUser)
+	userRes, err = usecase.Login(context.Background(), mockUser)
 	assert.Equal(t, "password: password is required.", err.Error())
-	assert.Empty(t, token)
+	assert.Empty(t, userRes)
 
 	mockUser = model.User{
 		Email:    "testsignup@example.com",
 		Password: "12345",
 	}
-	token, err = usecase.Login(mockUser)
+	userRes, err = usecase.Login(context.Background(), mockUser)
 	assert.Equal(t, "password: limited min 6 max 30 char.", err.Error())
-	assert.Empty(t, token)
+	assert.ErrorIs(t, err, apperror.ErrValidation)
+	assert.Empty(t, userRes)
+}
+
+func TestLogin_MFA(t *testing.T) {
+	secret, err := newTOTPSecret()
+	assert.Nil(t, err)
+	confirmedAt := time.Now()
+	credential := model.TOTPCredential{UserId: 1, Secret: secret, ConfirmedAt: &confirmedAt}
+	mockUser := model.User{
+		Model:    gorm.Model{ID: 1},
+		Email:    "testlogin@example.com",
+		Password: "testlogin",
+	}
+	mockRepository := newMockUserRepository()
+	mockRepository.(*mockUserRepository).On("GetUserByEmail", mock.AnythingOfType("*model.User"), mock.Anything).Return(&mockUser, nil)
+	mockRepository.(*mockUserRepository).On("GetUserById", uint(1)).Return(&mockUser, nil)
+	mfaRepository := newMockMFARepository()
+	mfaRepository.(*mockMFARepository).On("GetTOTPCredential", uint(1)).Return(&credential, nil)
+	code, step := currentTOTP(t, secret)
+	mfaRepository.(*mockMFARepository).On("UseTOTPStep", uint(1), step).Return(nil)
+	usecase := NewUserUsecase(mockRepository, validator.NewUserValidator(), mfaRepository, testSecret)
+
+	loginRes, err := usecase.Login(context.Background(), mockUser)
+	assert.Nil(t, err)
+	assert.True(t, loginRes.MFARequired)
+	assert.Equal(t, uint(0), loginRes.ID)
+	assert.NotEmpty(t, loginRes.MFAToken)
+	challenge, err := usecase.ParseMFAChallenge(loginRes.MFAToken)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), challenge.UserId)
+	assert.NotEmpty(t, challenge.ID)
+
+	_, err = usecase.LoginMFA(context.Background(), "forged", code)
+	assert.ErrorIs(t, err, apperror.ErrUnauthorized)
+	userRes, err := usecase.LoginMFA(context.Background(), loginRes.MFAToken, code)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), userRes.ID)
+	mfaRepository.(*mockMFARepository).AssertExpectations(t)
+}
+
+func TestLoginMFA_Replay(t *testing.T) {
+	secret, err := newTOTPSecret()
+	assert.Nil(t, err)
+	code, step := currentTOTP(t, secret)
+	confirmedAt := time.Now()
+	credential := model.TOTPCredential{UserId: 1, Secret: secret, ConfirmedAt: &confirmedAt, LastUsedStep: step}
+	mfaRepository := newMockMFARepository()
+	mfaRepository.(*mockMFARepository).On("GetTOTPCredential", uint(1)).Return(&credential, nil)
+	mfaRepository.(*mockMFARepository).On("UseRecoveryCode", uint(1), hashToken("abcdabcdabcdabcd")).Return(nil)
+	mockRepository := newMockUserRepository()
+	mockRepository.(*mockUserRepository).On("GetUserById", uint(1)).Return(&model.User{Model: gorm.Model{ID: 1}}, nil)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"jti": "challenge",
+		"sub": "1",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}).SignedString(purposeKey(testSecret, mfaChallengePurpose))
+	assert.Nil(t, err)
+	usecase := NewUserUsecase(mockRepository, validator.NewUserValidator(), mfaRepository, testSecret)
+
+	_, err = usecase.LoginMFA(context.Background(), token, code)
+	assert.ErrorIs(t, err, apperror.ErrUnauthorized)
+	mfaRepository.(*mockMFARepository).AssertNotCalled(t, "UseTOTPStep")
+	userRes, err := usecase.LoginMFA(context.Background(), token, "ABCD-abcd-ABCD-abcd")
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), userRes.ID)
 }
@@ -0,0 +1,43 @@
+package validator
+
+import (
+	"echo-rest-api/model"
+	"errors"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type IAccessTokenValidator interface {
+	AccessTokenValidate(req model.AccessTokenRequest) error
+}
+
+type accessTokenValidator struct{}
+
+func NewAccessTokenValidator() IAccessTokenValidator {
+	return &accessTokenValidator{}
+}
+
+func (av *accessTokenValidator) AccessTokenValidate(req model.AccessTokenRequest) error {
+	return validation.ValidateStruct(&req,
+		validation.Field(
+			&req.Name,
+			validation.Required.Error("name is required"),
+			validation.RuneLength(1, 50).Error("limited max 50 length"),
+		),
+		validation.Field(
+			&req.Scopes,
+			validation.Required.Error("scopes are required"),
+			validation.Each(validation.In(model.ScopeRead, model.ScopeWrite).Error("must be read or write")),
+		),
+		validation.Field(
+			&req.ExpiresAt,
+			validation.By(func(value interface{}) error {
+				if expiresAt, _ := value.(*time.Time); expiresAt != nil && !expiresAt.After(time.Now()) {
+					return errors.New("must be in the future")
+				}
+				return nil
+			}),
+		),
+	)
+}
@@ -8,6 +8,8 @@ import (
 
 type IMemoValidator interface {
 	MemoValidate(memo model.Memo) error
+	MemoQueryValidate(query model.MemoQuery) error
+	MemoSearchValidate(query model.MemoSearchQuery) error
 }
 
 type memoValidator struct{}
@@ -23,5 +25,47 @@ func (tv *memoValidator) MemoValidate(memo model.Memo) error {
 			validation.Required.Error("title is required"),
 			validation.RuneLength(1, 50).Error("limited max 50 length"),
 		),
+		validation.Field(
+			&memo.TagNames,
+			validation.Length(0, 20).Error("limited max 20 tags"),
+			validation.Each(tagNameRules...),
+		),
+	)
+}
+
+func (tv *memoValidator) MemoQueryValidate(query model.MemoQuery) error {
+	return validation.ValidateStruct(&query,
+		validation.Field(
+			&query.Limit,
+			validation.Min(0).Error("must be between 1 and 100"),
+			validation.Max(100).Error("must be between 1 and 100"),
+		),
+		validation.Field(
+			&query.SortBy,
+			validation.In(model.MemoSortCreatedAt, model.MemoSortUpdatedAt, model.MemoSortTitle).Error("must be one of created_at, updated_at, title"),
+		),
+		validation.Field(
+			&query.Order,
+			validation.In(model.SortAsc, model.SortDesc).Error("must be asc or desc"),
+		),
+		validation.Field(
+			&query.TagMatch,
+			validation.In(model.TagMatchAny, model.TagMatchAll).Error("must be any or all"),
+		),
+	)
+}
+
+func (tv *memoValidator) MemoSearchValidate(query model.MemoSearchQuery) error {
+	return validation.ValidateStruct(&query,
+		validation.Field(
+			&query.Query,
+			validation.Required.Error("q is required"),
+			validation.RuneLength(1, 200).Error("limited max 200 length"),
+		),
+		validation.Field(
+			&query.Limit,
+			validation.Min(0).Error("must be between 1 and 100"),
+			validation.Max(100).Error("must be between 1 and 100"),
+		),
 	)
 }
@@ -0,0 +1,27 @@
+package validator
+
+import (
+	"echo-rest-api/model"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type INotebookValidator interface {
+	NotebookValidate(notebook model.Notebook) error
+}
+
+type notebookValidator struct{}
+
+func NewNotebookValidator() INotebookValidator {
+	return &notebookValidator{}
+}
+
+func (nv *notebookValidator) NotebookValidate(notebook model.Notebook) error {
+	return validation.ValidateStruct(&notebook,
+		validation.Field(
+			&notebook.Name,
+			validation.Required.Error("name is required"),
+			validation.RuneLength(1, 50).Error("limited max 50 length"),
+		),
+	)
+}
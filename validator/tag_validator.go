@@ -0,0 +1,28 @@
+package validator
+
+import (
+	"echo-rest-api/model"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type ITagValidator interface {
+	TagValidate(tag model.Tag) error
+}
+
+type tagValidator struct{}
+
+func NewTagValidator() ITagValidator {
+	return &tagValidator{}
+}
+
+var tagNameRules = []validation.Rule{
+	validation.Required.Error("tag name is required"),
+	validation.RuneLength(1, 30).Error("limited max 30 length"),
+}
+
+func (tv *tagValidator) TagValidate(tag model.Tag) error {
+	return validation.ValidateStruct(&tag,
+		validation.Field(&tag.Name, tagNameRules...),
+	)
+}
@@ -9,6 +9,8 @@ import (
 
 type IUserValidator interface {
 	UserValidate(user model.User) error
+	PasswordValidate(password string) error
+	EmailValidate(email string) error
 }
 
 type userValidator struct{}
@@ -17,17 +19,38 @@ func NewUserValidator() IUserValidator {
 	return &userValidator{}
 }
 
+var emailRules = []validation.Rule{
+	validation.Required.Error("email is required"),
+	validation.RuneLength(1, 30).Error("limited max 30 char"),
+	is.Email.Error("invalid email format"),
+}
+
+var passwordRules = []validation.Rule{
+	validation.Required.Error("password is required"),
+	validation.RuneLength(6, 30).Error("limited min 6 max 30 char"),
+}
+
 func (uv *userValidator) UserValidate(user model.User) error {
-	return validation.ValidateStruct(&user, validation.Field(
-		&user.Email,
-		validation.Required.Error("email is required"),
-		validation.RuneLength(1, 30).Error("limited max 30 char"),
-		is.Email.Error("invalid email format"),
-	),
-		validation.Field(
-			&user.Password,
-			validation.Required.Error("password is required"),
-			validation.RuneLength(6, 30).Error("limited min 6 max 30 char"),
-		),
+	return validation.ValidateStruct(&user,
+		validation.Field(&user.Email, emailRules...),
+		validation.Field(&user.Password, passwordRules...),
 	)
 }
+
+// PasswordValidate applies the password rules of UserValidate on their own,
+// reporting a failure under the "password" field.
+func (uv *userValidator) PasswordValidate(password string) error {
+	if err := validation.Validate(password, passwordRules...); err != nil {
+		return validation.Errors{"password": err}
+	}
+	return nil
+}
+
+// EmailValidate applies the email rules of UserValidate on their own,
+// reporting a failure under the "email" field.
+func (uv *userValidator) EmailValidate(email string) error {
+	if err := validation.Validate(email, emailRules...); err != nil {
+		return validation.Errors{"email": err}
+	}
+	return nil
+}